@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/config"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/converter"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/pathutil"
+	"github.com/spf13/cobra"
+)
+
+var (
+	allocateYear   string
+	allocateDate   string
+	allocateDryRun bool
+	allocateForce  bool
+)
+
+// allocateCmd represents the allocate command.
+var allocateCmd = &cobra.Command{
+	Use:   "allocate",
+	Short: "Generate the year-end home-business allocation (家事按分) journal",
+	Long: `Generate the year-end allocation journal for home-business allocation
+(家事按分) rules marked year_end in the account mapping.
+
+The expense deals of the year are fetched from freee and the private share
+of every line a year-end rule matches is moved from its expense (and VAT)
+account to the allocation account (Equity:Drawings, 事業主貸, by default)
+in a single entry on --date, by default the last day of the year.
+
+Rules without year_end are applied by sync to each deal instead, and the
+lines they match are not included here.
+
+The journal is written to YYYY/YYYY-allocation.beancount and replaced on
+every run; if it already holds entries, --force is required. The file is
+included from the main file unless an include there already matches it.
+
+Example:
+  freee-sync allocate --year 2025 --dry-run
+  freee-sync allocate --year 2025 --force`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := time.Parse("2006", allocateYear); err != nil {
+			return fmt.Errorf("invalid --year %q: expected YYYY", allocateYear)
+		}
+		if allocateDate == "" {
+			return nil
+		}
+		if _, err := time.Parse("2006-01-02", allocateDate); err != nil {
+			return fmt.Errorf("invalid --date %q: expected YYYY-MM-DD", allocateDate)
+		}
+		return nil
+	},
+	Run: runAllocate,
+}
+
+func init() {
+	allocateCmd.Flags().StringVar(&allocateYear, "year", "", "Year to allocate (YYYY) (required)")
+	allocateCmd.Flags().StringVar(&allocateDate, "date", "", "Date of the allocation entry (YYYY-MM-DD) (default: last day of the year)")
+	allocateCmd.Flags().BoolVar(&allocateDryRun, "dry-run", false, "Print the entry without writing the file")
+	allocateCmd.Flags().BoolVar(&allocateForce, "force", false, "Replace existing entries in the allocation file")
+
+	_ = allocateCmd.MarkFlagRequired("year")
+}
+
+// allocateOutput is the schema of allocate output.
+type allocateOutput struct {
+	DryRun   bool                        `json:"dry_run"`
+	Year     string                      `json:"year"`
+	Date     string                      `json:"date"`
+	File     string                      `json:"file"`
+	Written  bool                        `json:"written"`
+	Totals   []converter.AllocationTotal `json:"totals"`
+	Unopened []string                    `json:"unopened"` // accounts not open on the date
+	Included []string                    `json:"included"` // files added to the includes of the main file
+	Entry    string                      `json:"entry"`
+}
+
+func runAllocate(cmd *cobra.Command, args []string) {
+	cfg, err := loadConfig()
+	exitOnError(err, "failed to load configuration")
+
+	if err := cfg.Validate(
+		config.FreeeAPIURL,
+		config.FreeeAccessToken,
+		config.FreeeCompanyID,
+		config.BeancountRoot,
+	); err != nil {
+		exitOnError(err, "invalid configuration")
+	}
+
+	pathResolver := pathutil.New(pathutil.Config{
+		BeancountRoot:  cfg.Beancount.Root,
+		DatabasePath:   cfg.Beancount.DBPath,
+		AttachmentsDir: cfg.Beancount.AttachmentsDir,
+	})
+
+	mapper, err := converter.NewMapper(cfg.MappingPath)
+	exitOnError(err, "failed to load account mapping")
+
+	date := allocateDate
+	if date == "" {
+		date = allocateYear + "-12-31"
+	}
+
+	client := newFreeeClient(cfg)
+	from, to := allocateYear+"-01-01", allocateYear+"-12-31"
+	slog.Info("Fetching deals from freee", "from", from, "to", to)
+	deals, err := client.FetchAllDeals(from, to)
+	exitOnError(err, "failed to fetch deals")
+
+	cvtr := converter.NewConverter(mapper, "JPY")
+	txn, totals := cvtr.ConvertYearEndAllocation(deals, date)
+
+	out := allocateOutput{
+		DryRun: allocateDryRun,
+		Year:   allocateYear,
+		Date:   date,
+		File:   filepath.Join(pathResolver.GetYearDir(allocateYear), allocateYear+"-allocation.beancount"),
+		Totals: totals,
+	}
+	if len(txn.Postings) == 0 {
+		exitOnError(fmt.Errorf("no deals of %s match a year-end allocation rule", allocateYear), "nothing to write")
+	}
+	out.Entry = cvtr.FormatTransaction(txn)
+	out.Unopened = unopenedAccounts(pathResolver.GetMainFilePath(), txn, date)
+
+	// Month files may be included one by one; the allocation file needs its
+	// own include then
+	ledger, err := beancount.ParseFile(pathResolver.GetMainFilePath())
+	exitOnError(err, "failed to parse ledger")
+	if !ledger.Reaches(out.File) {
+		out.Included = append(out.Included, out.File)
+	}
+
+	if !allocateDryRun {
+		exitOnError(beancount.CheckWritable(out.File), "refusing to write the allocation journal")
+		exitOnError(checkGeneratedFile(out.File, allocateForce), "refusing to replace the allocation journal")
+		exitOnError(pathResolver.EnsureParentDir(out.File), "failed to create year directory")
+		content := fmt.Sprintf("; Home-Business Allocation %s (家事按分)\n;\n; Generated by freee-sync allocate from the year-end allocation rules\n; on %s. Regenerate with --force instead of editing by hand.\n\n%s",
+			allocateYear, time.Now().Format("2006-01-02"), out.Entry)
+		exitOnError(os.WriteFile(out.File, []byte(content), 0644), "failed to write allocation journal")
+		for _, path := range out.Included {
+			exitOnError(beancount.AddInclude(pathResolver.GetMainFilePath(), path), "failed to include "+filepath.Base(path))
+		}
+		out.Written = true
+	}
+
+	printOutput(out, func() { printAllocate(out) })
+}
+
+// printAllocate prints the allocation in human readable form.
+func printAllocate(out allocateOutput) {
+	fmt.Printf("%-32s %-36s %12s %12s\n", "RULE", "ACCOUNT", "TOTAL", "PRIVATE")
+	for _, total := range out.Totals {
+		fmt.Printf("%-32s %-36s %12d %12d\n", total.Rule, total.Account, total.Total, total.Private)
+	}
+
+	if out.DryRun {
+		fmt.Printf("\n[DRY RUN] Would write %s:\n\n%s\n", out.File, out.Entry)
+		for _, path := range out.Included {
+			fmt.Printf("[DRY RUN] Would include %s in the main file\n", path)
+		}
+	} else if out.Written {
+		fmt.Printf("\nWrote allocation journal on %s to %s\n", out.Date, out.File)
+		for _, path := range out.Included {
+			fmt.Printf("  Included in the main file: %s\n", path)
+		}
+	}
+
+	if len(out.Unopened) > 0 {
+		fmt.Printf("\nWarning: not open on %s: %s\n", out.Date, strings.Join(out.Unopened, ", "))
+	}
+}
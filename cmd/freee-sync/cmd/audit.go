@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/audit"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/config"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/converter"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/db"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/duplicate"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/pathutil"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/reconcile"
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditMonth            string
+	auditChecks           []string
+	auditReceiptThreshold float64
+	auditUnusualFactor    float64
+	auditUnusualMonths    int
+	auditDuplicateWindow  int
+	auditMinSimilarity    float64
+	auditOffline          bool
+	auditReport           string
+)
+
+// auditCmd represents the audit command.
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Run the monthly audit (月次監査) of the ledger",
+	Long: `Run the monthly audit (月次監査) of the ledger and report the entries
+that need a closer look.
+
+Checks (select with --check; all run by default):
+  unmapped         entries booked to accounts of unmapped freee account items
+  receipts         expense deals without a receipt in document_attachments
+  duplicates       entries suspected to book the same deal, as in the
+                   duplicates command (same receipt, or same amount within
+                   --duplicate-window days and a similar description)
+  unusual-amounts  expenses far above the median of the account
+  unbooked         wallet transactions not yet booked in freee
+
+The unbooked check queries freee; it is skipped with --offline or when
+freee is not configured. The receipts check is skipped when there is no
+sync database.
+
+The report is printed as Markdown (or JSON/YAML with --output) and can
+be saved with --report. Exits with status 1 if there are any findings.
+
+Example:
+  freee-sync audit --month 2024-03
+  freee-sync audit --month 2024-03 --check receipts,duplicates --report audit/2024-03.md
+  freee-sync audit --month 2024-03 --offline --output json`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(auditMonth) != len("2006-01") {
+			return fmt.Errorf("invalid --month %q: expected YYYY-MM", auditMonth)
+		}
+		_, _, err := reconcile.ParsePeriod(auditMonth)
+		return err
+	},
+	Run: runAudit,
+}
+
+func init() {
+	auditCmd.Flags().StringVar(&auditMonth, "month", "", "Month to audit (YYYY-MM) (required)")
+	auditCmd.Flags().StringSliceVar(&auditChecks, "check", nil, "Checks to run (default: all)")
+	auditCmd.Flags().Float64Var(&auditReceiptThreshold, "receipt-threshold", 0, "Smallest expense that needs a receipt")
+	auditCmd.Flags().Float64Var(&auditUnusualFactor, "unusual-factor", 3, "Times the median an expense must exceed to be unusual")
+	auditCmd.Flags().IntVar(&auditUnusualMonths, "unusual-months", 12, "Earlier months the median is taken over")
+	auditCmd.Flags().IntVar(&auditDuplicateWindow, "duplicate-window", 3, "Days apart entries of the same amount may be")
+	auditCmd.Flags().Float64Var(&auditMinSimilarity, "min-similarity", 0.5, "Similarity (0..1) of partner and description of duplicates")
+	auditCmd.Flags().BoolVar(&auditOffline, "offline", false, "Do not query freee (skips the unbooked check)")
+	auditCmd.Flags().StringVar(&auditReport, "report", "", "Also write the Markdown report to FILE")
+
+	_ = auditCmd.MarkFlagRequired("month")
+}
+
+func runAudit(cmd *cobra.Command, args []string) {
+	from, to, _ := reconcile.ParsePeriod(auditMonth)
+
+	cfg, err := loadConfig()
+	exitOnError(err, "failed to load configuration")
+	exitOnError(cfg.Validate(config.BeancountRoot), "invalid configuration")
+
+	checks, err := selectChecks(audit.DefaultChecks(audit.Options{
+		UnmappedPrefix:   converter.UnmappedPrefix,
+		ReceiptThreshold: auditReceiptThreshold,
+		UnusualFactor:    auditUnusualFactor,
+		UnusualMonths:    auditUnusualMonths,
+		Duplicates:       duplicate.Options{WindowDays: auditDuplicateWindow, MinSimilarity: auditMinSimilarity},
+	}), auditChecks)
+	exitOnError(err, "invalid --check")
+
+	pathResolver := pathutil.New(pathutil.Config{
+		BeancountRoot:  cfg.Beancount.Root,
+		DatabasePath:   cfg.Beancount.DBPath,
+		AttachmentsDir: cfg.Beancount.AttachmentsDir,
+	})
+
+	ledger, err := beancount.ParseFile(pathResolver.GetMainFilePath())
+	exitOnError(err, "failed to parse ledger")
+
+	in := &audit.Input{From: from, To: to, Currency: "JPY", Transactions: ledger.Transactions}
+
+	if dbPath := pathResolver.GetDatabasePath(); pathResolver.FileExists(dbPath) {
+		conn, err := db.Open(dbPath)
+		exitOnError(err, "failed to open database")
+		defer conn.Close()
+		in.Attachments, err = db.NewSyncHistory(conn).CountDocumentAttachments(from, to)
+		exitOnError(err, "failed to read document attachments")
+	}
+
+	if !auditOffline {
+		if err := cfg.Validate(config.FreeeAPIURL, config.FreeeAccessToken, config.FreeeCompanyID); err != nil {
+			slog.Warn("freee is not configured, skipping the checks that query it", "error", err)
+		} else {
+			slog.Info("Fetching wallet transactions from freee", "from", from, "to", to)
+			txns, err := newFreeeClient(cfg).FetchWalletTxns(from, to)
+			exitOnError(err, "failed to fetch wallet transactions")
+			in.WalletTxns = txns
+		}
+	}
+
+	report, err := audit.Run(in, checks)
+	exitOnError(err, "audit failed")
+
+	if auditReport != "" {
+		exitOnError(os.WriteFile(auditReport, []byte(report.Markdown()), 0644), "failed to write report")
+		fmt.Fprintf(humanOut, "Wrote report to %s\n", auditReport)
+	}
+
+	printOutput(report, func() { fmt.Print(report.Markdown()) })
+
+	if report.Findings(audit.SeverityError)+report.Findings(audit.SeverityWarning) > 0 {
+		os.Exit(exitFailure)
+	}
+}
+
+// selectChecks returns the checks named in names, in report order, or all
+// checks if names is empty.
+func selectChecks(checks []audit.Check, names []string) ([]audit.Check, error) {
+	if len(names) == 0 {
+		return checks, nil
+	}
+
+	selected := make(map[string]bool, len(names))
+	for _, name := range names {
+		selected[name] = true
+	}
+
+	var result []audit.Check
+	var available []string
+	for _, check := range checks {
+		available = append(available, check.Name())
+		if selected[check.Name()] {
+			result = append(result, check)
+			delete(selected, check.Name())
+		}
+	}
+	for name := range selected {
+		return nil, fmt.Errorf("unknown check %q (available: %s)", name, strings.Join(available, ", "))
+	}
+	return result, nil
+}
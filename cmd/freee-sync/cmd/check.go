@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/config"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/pathutil"
+	"github.com/spf13/cobra"
+)
+
+var checkStrict bool
+
+// checkCmd represents the check command.
+var checkCmd = &cobra.Command{
+	Use:   "check [file]",
+	Short: "Validate the Beancount ledger",
+	Long: `Validate the Beancount ledger without requiring bean-check.
+
+Checks:
+- Every transaction balances per currency (within tolerance)
+- Accounts are open on the transaction date and not closed
+- No two entries share the same freee_id metadata
+- Balance assertions and check_closing plugin semantics
+
+The file defaults to main.beancount in the Beancount root.
+
+Example:
+  freee-sync check
+  freee-sync check beancount/main.beancount --strict`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runCheck,
+}
+
+func init() {
+	checkCmd.Flags().BoolVar(&checkStrict, "strict", false, "Require open directives even when the auto_accounts plugin is enabled")
+}
+
+func runCheck(cmd *cobra.Command, args []string) {
+	// Load configuration
+	cfg, err := loadConfig()
+	exitOnError(err, "failed to load configuration")
+
+	if err := cfg.Validate(config.BeancountRoot); err != nil {
+		exitOnError(err, "invalid configuration")
+	}
+
+	pathResolver := pathutil.New(pathutil.Config{
+		BeancountRoot:  cfg.Beancount.Root,
+		DatabasePath:   cfg.Beancount.DBPath,
+		AttachmentsDir: cfg.Beancount.AttachmentsDir,
+	})
+
+	ledgerFile := pathResolver.GetMainFilePath()
+	if len(args) > 0 {
+		ledgerFile = args[0]
+	}
+
+	slog.Info("Checking ledger", "file", ledgerFile)
+	ledger, err := beancount.ParseFile(ledgerFile)
+	exitOnError(err, "failed to parse ledger")
+
+	validator := beancount.NewValidator(ledger, beancount.ValidatorConfig{StrictAccounts: checkStrict})
+	errs := validator.Validate()
+
+	out := checkOutput{
+		File:         ledgerFile,
+		Files:        len(ledger.Files),
+		Transactions: len(ledger.Transactions),
+		Errors:       []checkError{},
+	}
+	for _, e := range errs {
+		out.Errors = append(out.Errors, checkError{File: e.Source.File, Line: e.Source.Line, Message: e.Message})
+	}
+
+	printOutput(out, func() {
+		for _, e := range errs {
+			fmt.Println(e.Error())
+		}
+		if len(errs) == 0 {
+			fmt.Printf("No errors found (%d transactions in %d files)\n", out.Transactions, out.Files)
+		}
+	})
+
+	if len(errs) > 0 {
+		fmt.Fprintf(os.Stderr, "\n%d error(s) found in %d file(s)\n", len(errs), len(ledger.Files))
+		os.Exit(exitFailure)
+	}
+}
+
+// checkOutput is the schema of check output.
+type checkOutput struct {
+	File         string       `json:"file"`
+	Files        int          `json:"files"`
+	Transactions int          `json:"transactions"`
+	Errors       []checkError `json:"errors"`
+}
+
+// checkError is a problem found in the ledger.
+type checkError struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// loadLedgerValidator parses the main ledger so that new entries can be
+// checked before they are written. It returns nil if the ledger does not exist.
+func loadLedgerValidator(pathResolver *pathutil.PathResolver) (*beancount.Validator, error) {
+	mainFile := pathResolver.GetMainFilePath()
+	if !pathResolver.FileExists(mainFile) {
+		return nil, nil
+	}
+
+	ledger, err := beancount.ParseFile(mainFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return beancount.NewValidator(ledger, beancount.ValidatorConfig{}), nil
+}
+
+// checkEntry validates a formatted transaction against the ledger.
+// Valid entries are accepted by the validator so that a second entry
+// with the same freee_id in the same run is rejected.
+func checkEntry(validator *beancount.Validator, filePath, formatted string) error {
+	if validator == nil {
+		return nil
+	}
+
+	parsed, err := beancount.ParseString(filePath, formatted)
+	if err != nil {
+		return err
+	}
+	if len(parsed.Errors) > 0 {
+		return parsed.Errors[0]
+	}
+	if len(parsed.Transactions) != 1 {
+		return fmt.Errorf("expected one transaction, got %d", len(parsed.Transactions))
+	}
+
+	txn := parsed.Transactions[0]
+	if errs := validator.CheckTransaction(txn); len(errs) > 0 {
+		messages := make([]string, len(errs))
+		for i, e := range errs {
+			messages[i] = e.Message
+		}
+		return errors.New(strings.Join(messages, "; "))
+	}
+
+	validator.Accept(txn)
+	return nil
+}
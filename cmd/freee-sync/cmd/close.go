@@ -0,0 +1,299 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/config"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/converter"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/pathutil"
+	"github.com/spf13/cobra"
+)
+
+var (
+	closeYear           string
+	closeFrom           string
+	closeTo             string
+	closeSoleProprietor bool
+	closeEquity         string
+	closeDryRun         bool
+	closeForce          bool
+	closeReopen         bool
+)
+
+// closeYearCmd represents the close-year command.
+var closeYearCmd = &cobra.Command{
+	Use:   "close-year",
+	Short: "Close a year of the ledger",
+	Long: `Close a fiscal year of the ledger: book the closing entries, write the
+balances the next year opens with and make the files of the year read-only.
+
+--year is the year the fiscal year starts in. Its first and last day are
+taken from the fiscal years of the company in freee, or from --from and
+--to.
+
+The income and expense balances of the year are moved to retained
+earnings (繰越利益剰余金), or with --sole-proprietor to capital (元入金)
+together with the drawings (事業主貸) and contributions (事業主借) of the
+year. The accounts are those mapped in the account mapping, or
+--equity-account. The entry is written on the last day of the year to
+YYYY-closing.beancount in the directory of that day.
+
+The balance sheet balances after closing are written as balance
+assertions on the first day of the next year to YYYY+1-opening.beancount
+in the directory of that day, so later changes to the closed year fail
+the check. Both files are added to the includes of main.beancount unless
+its includes already match them.
+
+The files of the directory YYYY are then made read-only and sync, resync
+and rebuild refuse to write to them. Months of a fiscal year that ends in
+the next calendar year stay writable until that year is closed. Use
+--reopen to correct a closed year, then close it again with --force.
+
+Example:
+  freee-sync close-year --year 2024 --dry-run
+  freee-sync close-year --year 2024 --sole-proprietor
+  freee-sync close-year --year 2024 --from 2024-04-01 --to 2025-03-31
+  freee-sync close-year --year 2024 --reopen`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := time.Parse("2006", closeYear); err != nil {
+			return fmt.Errorf("invalid --year %q: expected YYYY", closeYear)
+		}
+		for _, date := range []string{closeFrom, closeTo} {
+			if _, err := time.Parse("2006-01-02", date); date != "" && err != nil {
+				return fmt.Errorf("invalid date %q: expected YYYY-MM-DD", date)
+			}
+		}
+		if (closeFrom == "") != (closeTo == "") {
+			return fmt.Errorf("--from and --to must be given together")
+		}
+		if closeFrom > closeTo {
+			return fmt.Errorf("--from %s is after --to %s", closeFrom, closeTo)
+		}
+		return nil
+	},
+	Run: runCloseYear,
+}
+
+func init() {
+	closeYearCmd.Flags().StringVar(&closeYear, "year", "", "Year to close (YYYY) (required)")
+	closeYearCmd.Flags().StringVar(&closeFrom, "from", "", "First day of the fiscal year (YYYY-MM-DD) (default: from freee)")
+	closeYearCmd.Flags().StringVar(&closeTo, "to", "", "Last day of the fiscal year (YYYY-MM-DD) (default: from freee)")
+	closeYearCmd.Flags().BoolVar(&closeSoleProprietor, "sole-proprietor", false, "Close into capital (元入金) with drawings and contributions")
+	closeYearCmd.Flags().StringVar(&closeEquity, "equity-account", "", "Equity account the year is closed into (default: mapped 繰越利益剰余金 or 元入金)")
+	closeYearCmd.Flags().BoolVar(&closeDryRun, "dry-run", false, "Print the entries without writing files")
+	closeYearCmd.Flags().BoolVar(&closeForce, "force", false, "Replace existing closing entries and opening balances")
+	closeYearCmd.Flags().BoolVar(&closeReopen, "reopen", false, "Make a closed year writable again (the closing entries are kept)")
+
+	_ = closeYearCmd.MarkFlagRequired("year")
+}
+
+// closeYearOutput is the schema of close-year output.
+type closeYearOutput struct {
+	DryRun        bool     `json:"dry_run"`
+	Year          string   `json:"year"`
+	From          string   `json:"from,omitempty"`
+	To            string   `json:"to,omitempty"`
+	Reopened      bool     `json:"reopened,omitempty"`
+	EquityAccount string   `json:"equity_account,omitempty"`
+	Profit        float64  `json:"profit"`
+	ClosingFile   string   `json:"closing_file,omitempty"`
+	OpeningFile   string   `json:"opening_file,omitempty"`
+	Closed        bool     `json:"closed"`
+	Pending       int      `json:"pending"`  // pending (!) entries of the year
+	Unopened      []string `json:"unopened"` // accounts not open on the closing date
+	Included      []string `json:"included"` // files added to the includes of the main file
+	ClosingEntry  string   `json:"closing_entry,omitempty"`
+	OpeningEntry  string   `json:"opening_entry,omitempty"`
+}
+
+func runCloseYear(cmd *cobra.Command, args []string) {
+	cfg, err := loadConfig()
+	exitOnError(err, "failed to load configuration")
+	exitOnError(cfg.Validate(config.BeancountRoot), "invalid configuration")
+
+	pathResolver := pathutil.New(pathutil.Config{
+		BeancountRoot:  cfg.Beancount.Root,
+		DatabasePath:   cfg.Beancount.DBPath,
+		AttachmentsDir: cfg.Beancount.AttachmentsDir,
+	})
+	repo := beancount.NewFileSystemRepository(pathResolver)
+
+	out := closeYearOutput{DryRun: closeDryRun, Year: closeYear}
+
+	if closeReopen {
+		if !repo.IsYearClosed(closeYear) {
+			exitOnError(fmt.Errorf("%s is not closed", closeYear), "nothing to reopen")
+		}
+		if !closeDryRun {
+			exitOnError(repo.ReopenYear(closeYear), "failed to reopen year")
+			out.Reopened = true
+		}
+		printOutput(out, func() { printCloseYear(out) })
+		return
+	}
+
+	if repo.IsYearClosed(closeYear) {
+		exitOnError(fmt.Errorf("%s is already closed; reopen it with --reopen first", closeYear), "refusing to close year")
+	}
+
+	mapper, err := converter.NewMapper(cfg.MappingPath)
+	exitOnError(err, "failed to load account mapping")
+
+	equity, others := mapper.ClosingAccounts(closeSoleProprietor)
+	if closeEquity != "" {
+		equity = closeEquity
+	}
+
+	from, to := closeFrom, closeTo
+	if from == "" {
+		fy, err := fiscalYearStarting(cfg, closeYear)
+		exitOnError(err, "failed to determine the fiscal year (use --from and --to)")
+		from, to = fy.StartDate, fy.EndDate
+	}
+	end, _ := time.Parse("2006-01-02", to)
+	opening := end.AddDate(0, 0, 1).Format("2006-01-02")
+
+	y, _ := strconv.Atoi(closeYear)
+	nextYear := strconv.Itoa(y + 1)
+	out.From, out.To = from, to
+	out.EquityAccount = equity
+	out.ClosingFile = filepath.Join(pathResolver.GetYearDir(to[:4]), beancount.ClosingFileName(closeYear))
+	out.OpeningFile = filepath.Join(pathResolver.GetYearDir(opening[:4]), beancount.OpeningFileName(nextYear))
+
+	slog.Info("Reading ledger", "file", pathResolver.GetMainFilePath())
+	ledger, err := beancount.ParseFile(pathResolver.GetMainFilePath())
+	exitOnError(err, "failed to parse ledger")
+	if len(ledger.Errors) > 0 {
+		for _, e := range ledger.Errors {
+			slog.Error("Ledger error", "error", e.Error())
+		}
+		exitOnError(fmt.Errorf("%d errors in the ledger", len(ledger.Errors)), "refusing to close year (see freee-sync check)")
+	}
+
+	// Closing entries written before are replaced, not closed again
+	closingFile, _ := filepath.Abs(out.ClosingFile)
+	var txns []beancount.Transaction
+	var inYear int
+	for _, txn := range ledger.Transactions {
+		if path, _ := filepath.Abs(txn.Source.File); path == closingFile {
+			continue
+		}
+		txns = append(txns, txn)
+		if txn.Date >= from && txn.Date <= to {
+			inYear++
+			if txn.Flag == "!" {
+				out.Pending++
+			}
+		}
+	}
+	if inYear == 0 {
+		exitOnError(fmt.Errorf("the ledger has no entries in %s", closeYear), "nothing to close")
+	}
+
+	cvtr := converter.NewConverter(mapper, "JPY")
+	closing := cvtr.ConvertClosing(txns, from, to, equity, others)
+	out.Profit = closing.Profit
+	if len(closing.Entry.Postings) > 0 {
+		out.ClosingEntry = cvtr.FormatTransaction(closing.Entry)
+		out.Unopened = unopenedAccounts(pathResolver.GetMainFilePath(), closing.Entry, to)
+	}
+	out.OpeningEntry = beancount.FormatBalances(opening, "JPY", closing.Balances)
+
+	// Month files may be included one by one; the new files need their own
+	// include then
+	for _, path := range []string{out.ClosingFile, out.OpeningFile} {
+		if !ledger.Reaches(path) {
+			out.Included = append(out.Included, path)
+		}
+	}
+
+	if !closeDryRun {
+		files := []struct{ path, content string }{
+			{out.ClosingFile, fmt.Sprintf("; Closing Entries %s (決算振替)\n;\n; Generated by freee-sync close-year on %s.\n; Reopen the year and regenerate with --force instead of editing by hand.\n\n%s",
+				closeYear, time.Now().Format("2006-01-02"), out.ClosingEntry)},
+			{out.OpeningFile, fmt.Sprintf("; Opening Balances %s (期首残高)\n;\n; Balances after closing %s, generated by freee-sync close-year on %s.\n\n%s",
+				nextYear, closeYear, time.Now().Format("2006-01-02"), out.OpeningEntry)},
+		}
+		for _, f := range files {
+			exitOnError(beancount.CheckWritable(f.path), "refusing to write "+filepath.Base(f.path))
+			exitOnError(checkGeneratedFile(f.path, closeForce), "refusing to replace "+filepath.Base(f.path))
+		}
+		for _, f := range files {
+			exitOnError(pathResolver.EnsureParentDir(f.path), "failed to create year directory")
+			exitOnError(os.WriteFile(f.path, []byte(f.content), 0644), "failed to write "+filepath.Base(f.path))
+		}
+		for _, path := range out.Included {
+			exitOnError(beancount.AddInclude(pathResolver.GetMainFilePath(), path), "failed to include "+filepath.Base(path))
+		}
+		exitOnError(repo.CloseYear(closeYear), "failed to close year")
+		out.Closed = true
+	}
+
+	printOutput(out, func() { printCloseYear(out) })
+}
+
+// printCloseYear prints the closing of a year in human readable form.
+func printCloseYear(out closeYearOutput) {
+	if out.Reopened {
+		fmt.Printf("Reopened %s; close it again with: freee-sync close-year --year %s --force\n", out.Year, out.Year)
+		return
+	}
+	if out.ClosingFile == "" {
+		fmt.Printf("[DRY RUN] Would reopen %s\n", out.Year)
+		return
+	}
+
+	if out.Profit < 0 {
+		fmt.Printf("Net loss of %s: %.0f\n", out.Year, -out.Profit)
+	} else {
+		fmt.Printf("Net income of %s: %.0f\n", out.Year, out.Profit)
+	}
+
+	if out.DryRun {
+		fmt.Printf("\n[DRY RUN] Would write %s:\n\n%s\n", out.ClosingFile, out.ClosingEntry)
+		fmt.Printf("[DRY RUN] Would write %s:\n\n%s", out.OpeningFile, out.OpeningEntry)
+		for _, path := range out.Included {
+			fmt.Printf("[DRY RUN] Would include %s in the main file\n", path)
+		}
+	} else if out.Closed {
+		fmt.Printf("Closed %s (%s to %s) into %s\n", out.Year, out.From, out.To, out.EquityAccount)
+		fmt.Printf("  Closing entries: %s\n", out.ClosingFile)
+		fmt.Printf("  Opening balances: %s\n", out.OpeningFile)
+		for _, path := range out.Included {
+			fmt.Printf("  Included in the main file: %s\n", path)
+		}
+	}
+
+	if out.Pending > 0 {
+		fmt.Printf("\nWarning: %d pending (!) entries in %s; book them in freee and reopen the year\n", out.Pending, out.Year)
+	}
+	if len(out.Unopened) > 0 {
+		fmt.Printf("\nWarning: not open on %s: %s\n", out.To, strings.Join(out.Unopened, ", "))
+	}
+}
+
+// fiscalYearStarting returns the fiscal year of the company in freee that
+// starts in year (YYYY).
+func fiscalYearStarting(cfg *config.Config, year string) (freee.FiscalYear, error) {
+	if err := cfg.Validate(config.FreeeAPIURL, config.FreeeAccessToken, config.FreeeCompanyID); err != nil {
+		return freee.FiscalYear{}, err
+	}
+	company, err := newFreeeClient(cfg).GetCompany()
+	if err != nil {
+		return freee.FiscalYear{}, err
+	}
+	// A fiscal year starting in year contains its last day
+	fy, ok := company.FiscalYearOf(year + "-12-31")
+	if !ok || !strings.HasPrefix(fy.StartDate, year) {
+		return freee.FiscalYear{}, fmt.Errorf("company %d has no fiscal year starting in %s", cfg.Freee.CompanyID, year)
+	}
+	return fy, nil
+}
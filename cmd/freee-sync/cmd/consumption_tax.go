@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/config"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/consumptiontax"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/converter"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/db"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/pathutil"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/reconcile"
+	"github.com/spf13/cobra"
+)
+
+var (
+	taxPeriod  string
+	taxRefresh bool
+	taxOffline bool
+	taxReport  string
+)
+
+// consumptionTaxCmd represents the consumption-tax command.
+var consumptionTaxCmd = &cobra.Command{
+	Use:   "consumption-tax",
+	Short: "Summarize consumption tax (消費税) for the return",
+	Long: `Summarize the consumption tax (消費税) of the synced deals and manual
+journals of a period by tax code and rate, and compute the figures of the
+consumption tax return (消費税申告) under the general method (一般課税).
+
+The detail lines of deals are cached by sync, resync and rebuild; deals
+synced before are read from freee with --refresh. The lines of manual
+journals (振替伝票) with a tax code other than 対象外 are read from freee;
+with --offline, or when freee is not configured, the synced manual
+journals of the period are reported as not included. Tax codes are classified
+by their Japanese names (10%, 8% reduced, exempt, zero-rated, non-taxable,
+and purchases with or without a qualified invoice) from the tax_codes of
+the account mapping, or from freee for codes not listed there.
+
+Sales tax is computed from the totals (割戻し計算) and purchase tax from
+the VAT of the deals (積上げ計算). Below a taxable sales ratio of 95% the
+purchase tax is prorated (一括比例配分方式). Amounts are taken to include
+VAT (税込経理). Check the figures before filing.
+
+The report is printed as Markdown (or JSON/YAML with --output) and can be
+saved with --report. Exits with status 1 if the figures are incomplete:
+deals without cached lines, manual journals not included or tax codes
+that could not be classified.
+
+Example:
+  freee-sync consumption-tax --period 2024
+  freee-sync consumption-tax --period 2024-01-01..2024-06-30 --refresh
+  freee-sync consumption-tax --period 2024 --report tax/2024-consumption-tax.md`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		_, _, err := reconcile.ParsePeriod(taxPeriod)
+		return err
+	},
+	Run: runConsumptionTax,
+}
+
+func init() {
+	consumptionTaxCmd.Flags().StringVar(&taxPeriod, "period", "", "Period to summarize (YYYY, YYYY-MM or YYYY-MM-DD..YYYY-MM-DD) (required)")
+	consumptionTaxCmd.Flags().BoolVar(&taxRefresh, "refresh", false, "Read the lines of the deals from freee first")
+	consumptionTaxCmd.Flags().BoolVar(&taxOffline, "offline", false, "Do not query freee for manual journals and tax codes missing from the mapping")
+	consumptionTaxCmd.Flags().StringVar(&taxReport, "report", "", "Also write the Markdown report to FILE")
+
+	_ = consumptionTaxCmd.MarkFlagRequired("period")
+}
+
+// consumptionTaxOutput is the schema of consumption-tax output.
+type consumptionTaxOutput struct {
+	consumptiontax.Report
+	Uncached  int `json:"uncached"`           // synced deals without cached lines
+	Uncovered int `json:"uncovered_journals"` // synced manual journals not included
+}
+
+func runConsumptionTax(cmd *cobra.Command, args []string) {
+	from, to, _ := reconcile.ParsePeriod(taxPeriod)
+
+	cfg, err := loadConfig()
+	exitOnError(err, "failed to load configuration")
+	exitOnError(cfg.Validate(config.BeancountRoot), "invalid configuration")
+	if taxRefresh && taxOffline {
+		exitOnError(fmt.Errorf("--refresh queries freee"), "--refresh and --offline cannot be combined")
+	}
+
+	pathResolver := pathutil.New(pathutil.Config{
+		BeancountRoot:  cfg.Beancount.Root,
+		DatabasePath:   cfg.Beancount.DBPath,
+		AttachmentsDir: cfg.Beancount.AttachmentsDir,
+	})
+
+	dbPath := pathResolver.GetDatabasePath()
+	if !pathResolver.FileExists(dbPath) {
+		exitOnError(fmt.Errorf("database not found: %s", dbPath), "nothing synced yet (run freee-sync sync first)")
+	}
+	conn, err := db.Open(dbPath)
+	exitOnError(err, "failed to open database")
+	defer conn.Close()
+	syncHistory := db.NewSyncHistory(conn)
+
+	if taxRefresh {
+		exitOnError(cfg.Validate(config.FreeeAPIURL, config.FreeeAccessToken, config.FreeeCompanyID), "invalid configuration")
+		slog.Info("Fetching deals from freee", "from", from, "to", to)
+		deals, err := newFreeeClient(cfg).FetchAllDeals(from, to)
+		exitOnError(err, "failed to fetch deals")
+		exitOnError(syncHistory.SaveDealDetails(dealDetails(deals)), "failed to cache deal details")
+	}
+
+	lines, uncached, err := syncHistory.GetSyncedDealLines(from, to)
+	exitOnError(err, "failed to read synced deals")
+	if uncached > 0 {
+		slog.Warn("Synced deals without cached lines are not included; read them with --refresh", "count", uncached)
+	}
+
+	// Manual journals are not cached; their lines are read from freee
+	var journals []consumptiontax.JournalLine
+	var uncovered int
+	if !taxOffline && cfg.Validate(config.FreeeAPIURL, config.FreeeAccessToken, config.FreeeCompanyID) == nil {
+		slog.Info("Fetching manual journals from freee", "from", from, "to", to)
+		manualJournals, err := newFreeeClient(cfg).FetchAllManualJournals(from, to)
+		exitOnError(err, "failed to fetch manual journals")
+		journals = journalTaxLines(manualJournals)
+	} else {
+		uncovered, err = syncHistory.CountSynced(db.SyncTypeJournal, from, to)
+		exitOnError(err, "failed to read synced manual journals")
+		if uncovered > 0 {
+			slog.Warn("Synced manual journals are not included without freee", "count", uncovered)
+		}
+	}
+
+	mapper, err := converter.NewMapper(cfg.MappingPath)
+	exitOnError(err, "failed to load account mapping")
+	var codes []int
+	for _, line := range lines {
+		codes = append(codes, line.TaxCode)
+	}
+	for _, line := range journals {
+		codes = append(codes, line.TaxCode)
+	}
+	names := taxCodeNames(cfg, mapper, codes)
+
+	out := consumptionTaxOutput{Report: consumptiontax.Summarize(from, to, lines, journals, names), Uncached: uncached, Uncovered: uncovered}
+	markdown := out.Markdown()
+	if uncached > 0 {
+		markdown += fmt.Sprintf("\n> %d synced deals have no cached lines and are not included; run with --refresh.\n", uncached)
+	}
+	if uncovered > 0 {
+		markdown += fmt.Sprintf("\n> %d synced manual journals are not included; run without --offline.\n", uncovered)
+	}
+
+	if taxReport != "" {
+		exitOnError(os.WriteFile(taxReport, []byte(markdown), 0644), "failed to write report")
+		fmt.Fprintf(humanOut, "Wrote report to %s\n", taxReport)
+	}
+
+	printOutput(out, func() { fmt.Print(markdown) })
+
+	if uncached > 0 || uncovered > 0 || len(out.Unclassified) > 0 {
+		os.Exit(exitFailure)
+	}
+}
+
+// journalTaxLines returns the lines of manual journals with a tax code.
+// Lines of code 0 (対象外), such as the bank side, are left out.
+func journalTaxLines(journals []freee.ManualJournal) []consumptiontax.JournalLine {
+	var lines []consumptiontax.JournalLine
+	for _, journal := range journals {
+		for _, detail := range journal.Details {
+			if detail.TaxCode == 0 {
+				continue
+			}
+			lines = append(lines, consumptiontax.JournalLine{
+				JournalID: journal.ID,
+				IssueDate: journal.IssueDate,
+				EntrySide: detail.EntrySide,
+				TaxCode:   detail.TaxCode,
+				Amount:    detail.Amount,
+				Vat:       detail.Vat,
+			})
+		}
+	}
+	return lines
+}
+
+// taxCodeNames returns the Japanese names of tax codes: from the tax_codes
+// of the account mapping, then from freee for the others.
+func taxCodeNames(cfg *config.Config, mapper *converter.Mapper, codes []int) map[int]string {
+	names := make(map[int]string)
+	var unknown int
+	for _, code := range codes {
+		if _, seen := names[code]; seen {
+			continue
+		}
+		names[code] = ""
+		if mapping := mapper.GetTaxCode(strconv.Itoa(code)); mapping != nil && mapping.Description != "" {
+			names[code] = mapping.Description
+		} else {
+			unknown++
+		}
+	}
+	if unknown == 0 || taxOffline {
+		return names
+	}
+
+	if err := cfg.Validate(config.FreeeAPIURL, config.FreeeAccessToken, config.FreeeCompanyID); err != nil {
+		slog.Warn("freee is not configured, tax codes missing from the mapping are unclassified", "count", unknown, "error", err)
+		return names
+	}
+	slog.Info("Fetching tax codes from freee", "missing", unknown)
+	taxCodes, err := newFreeeClient(cfg).ListTaxCodes()
+	exitOnError(err, "failed to fetch tax codes")
+	for _, tc := range taxCodes {
+		if name, used := names[tc.Code]; used && name == "" {
+			names[tc.Code] = tc.NameJa
+		}
+	}
+	return names
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/config"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/db"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/pathutil"
+	"github.com/spf13/cobra"
+)
+
+var dbNoBackup bool
+
+// dbCmd groups database maintenance commands.
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the sync database",
+	Long: `Manage the SQLite sync database (.sync/sync.db).
+
+Migrations are applied automatically when the database is opened;
+these commands let you inspect and apply them explicitly.`,
+}
+
+// dbMigrateCmd represents the db migrate command.
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply pending schema migrations",
+	Long: `Apply pending schema migrations to the sync database.
+
+A backup of the database is written next to it before migrating
+unless --no-backup is given.
+
+Example:
+  freee-sync db migrate`,
+	Run: runDBMigrate,
+}
+
+// dbStatusCmd represents the db status command.
+var dbStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show schema migration status",
+	Long: `Show which schema migrations have been applied to the sync database.
+
+Example:
+  freee-sync db status`,
+	Run: runDBStatus,
+}
+
+func init() {
+	dbMigrateCmd.Flags().BoolVar(&dbNoBackup, "no-backup", false, "Do not back up the database before migrating")
+
+	dbCmd.AddCommand(dbMigrateCmd)
+	dbCmd.AddCommand(dbStatusCmd)
+}
+
+// openDatabaseWithoutMigrations loads configuration and opens the sync
+// database without applying migrations.
+func openDatabaseWithoutMigrations() *db.Connection {
+	cfg, err := loadConfig()
+	exitOnError(err, "failed to load configuration")
+
+	if err := cfg.Validate(config.BeancountRoot); err != nil {
+		exitOnError(err, "invalid configuration")
+	}
+
+	pathResolver := pathutil.New(pathutil.Config{
+		BeancountRoot:  cfg.Beancount.Root,
+		DatabasePath:   cfg.Beancount.DBPath,
+		AttachmentsDir: cfg.Beancount.AttachmentsDir,
+	})
+
+	dbPath := pathResolver.GetDatabasePath()
+	slog.Debug("Opening database", "path", dbPath)
+
+	conn, err := db.OpenWithoutMigrations(dbPath)
+	exitOnError(err, "failed to open database")
+	return conn
+}
+
+func runDBMigrate(cmd *cobra.Command, args []string) {
+	conn := openDatabaseWithoutMigrations()
+	defer conn.Close()
+
+	pending, err := conn.PendingMigrations()
+	exitOnError(err, "failed to check migrations")
+
+	out := migrateOutput{Applied: []migrationOutput{}}
+	if len(pending) == 0 {
+		printOutput(out, func() { fmt.Println("Database is up to date") })
+		return
+	}
+
+	if !dbNoBackup {
+		out.Backup, err = conn.Backup()
+		exitOnError(err, "failed to back up database")
+		fmt.Fprintf(humanOut, "Backup written to %s\n", out.Backup)
+	}
+
+	applied, err := conn.Migrate()
+	for _, m := range applied {
+		fmt.Fprintf(humanOut, "Applied %04d_%s\n", m.Version, m.Name)
+		out.Applied = append(out.Applied, migrationOutput{Version: m.Version, Name: m.Name, Applied: true})
+	}
+	exitOnError(err, "migration failed")
+
+	if structuredOutput() {
+		printOutput(out, nil)
+	}
+
+	slog.Info("Migrations applied", "count", len(applied))
+}
+
+// migrateOutput is the schema of db migrate output.
+type migrateOutput struct {
+	Backup  string            `json:"backup,omitempty"`
+	Applied []migrationOutput `json:"applied"`
+}
+
+// migrationOutput is the schema of a migration.
+type migrationOutput struct {
+	Version   int    `json:"version"`
+	Name      string `json:"name"`
+	Applied   bool   `json:"applied"`
+	AppliedAt string `json:"applied_at,omitempty"`
+}
+
+func runDBStatus(cmd *cobra.Command, args []string) {
+	conn := openDatabaseWithoutMigrations()
+	defer conn.Close()
+
+	statuses, err := conn.MigrationStatus()
+	exitOnError(err, "failed to get migration status")
+
+	out := dbStatusOutput{Database: conn.GetPath(), Migrations: []migrationOutput{}}
+	for _, s := range statuses {
+		out.Migrations = append(out.Migrations, migrationOutput{
+			Version:   s.Version,
+			Name:      s.Name,
+			Applied:   s.Applied,
+			AppliedAt: s.AppliedAt.String,
+		})
+		if !s.Applied {
+			out.Pending++
+		}
+	}
+
+	printOutput(out, func() {
+		fmt.Printf("Database: %s\n\n", out.Database)
+		for _, m := range out.Migrations {
+			state := "pending"
+			if m.Applied {
+				state = "applied " + m.AppliedAt
+			}
+			fmt.Printf("  %04d_%-32s %s\n", m.Version, m.Name, state)
+		}
+		fmt.Printf("\n%d migration(s), %d pending\n", len(out.Migrations), out.Pending)
+	})
+}
+
+// dbStatusOutput is the schema of db status output.
+type dbStatusOutput struct {
+	Database   string            `json:"database"`
+	Migrations []migrationOutput `json:"migrations"`
+	Pending    int               `json:"pending"`
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"mime"
+	"path/filepath"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/db"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/pathutil"
+)
+
+// receiptExtensions maps receipt MIME types to file extensions.
+var receiptExtensions = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/heic":      ".heic",
+}
+
+// receiptDownloader downloads receipt files of deals into the attachments
+// directory and records them in document_attachments.
+type receiptDownloader struct {
+	client       *freee.Client
+	syncHistory  *db.SyncHistory
+	pathResolver *pathutil.PathResolver
+}
+
+// download stores every receipt of a deal and returns the document paths
+// relative to beancountFile. Receipts already on disk are not downloaded
+// again, so an interrupted sync can be retried safely.
+func (d *receiptDownloader) download(deal freee.Deal, beancountFile string) ([]string, error) {
+	var paths []string
+
+	for _, receipt := range deal.Receipts {
+		docPath, err := d.receiptPath(deal, receipt)
+		if err != nil {
+			return paths, err
+		}
+
+		if !d.pathResolver.FileExists(docPath) {
+			data, _, err := d.client.DownloadReceipt(receipt.ID)
+			if err != nil {
+				return paths, fmt.Errorf("failed to download receipt %d: %w", receipt.ID, err)
+			}
+			if err := d.pathResolver.EnsureParentDir(docPath); err != nil {
+				return paths, err
+			}
+			if err := beancount.WriteFileAtomic(docPath, data); err != nil {
+				return paths, fmt.Errorf("failed to save receipt %d: %w", receipt.ID, err)
+			}
+		}
+
+		attached, err := d.syncHistory.IsDocumentAttached(docPath)
+		if err != nil {
+			return paths, err
+		}
+		if !attached {
+			if err := d.syncHistory.RecordDocumentAttachment(db.DocumentAttachment{
+				TransactionDate: deal.IssueDate,
+				RefNumber:       sql.NullString{String: ptrValue(deal.RefNumber), Valid: deal.RefNumber != nil},
+				DealID:          sql.NullInt64{Int64: deal.ID, Valid: true},
+				DocumentPath:    docPath,
+			}); err != nil {
+				return paths, err
+			}
+		}
+
+		paths = append(paths, documentPath(docPath, beancountFile))
+	}
+
+	return paths, nil
+}
+
+// plan returns the document paths download would return for a deal,
+// without downloading or recording anything.
+func (d *receiptDownloader) plan(deal freee.Deal, beancountFile string) ([]string, error) {
+	var paths []string
+	for _, receipt := range deal.Receipts {
+		docPath, err := d.receiptPath(deal, receipt)
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, documentPath(docPath, beancountFile))
+	}
+	return paths, nil
+}
+
+// receiptPath returns the path a receipt of a deal is stored at.
+func (d *receiptDownloader) receiptPath(deal freee.Deal, receipt freee.Receipt) (string, error) {
+	filename := fmt.Sprintf("%s.deal-%d.receipt-%d%s", deal.IssueDate, deal.ID, receipt.ID, receiptExtension(receipt.MimeType))
+	return d.pathResolver.GetAttachmentPath(deal.IssueDate, filename)
+}
+
+// documentPath returns docPath relative to beancountFile, as linked in
+// document directives.
+func documentPath(docPath, beancountFile string) string {
+	relPath, err := filepath.Rel(filepath.Dir(beancountFile), docPath)
+	if err != nil {
+		relPath = docPath
+	}
+	return filepath.ToSlash(relPath)
+}
+
+// receiptExtension returns the file extension for a receipt MIME type.
+func receiptExtension(mimeType string) string {
+	if ext, ok := receiptExtensions[mimeType]; ok {
+		return ext
+	}
+	if exts, err := mime.ExtensionsByType(mimeType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ".bin"
+}
+
+func ptrValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/config"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/db"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/duplicate"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/pathutil"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/reconcile"
+	"github.com/spf13/cobra"
+)
+
+var (
+	duplicatesPeriod        string
+	duplicatesWindow        int
+	duplicatesMinSimilarity float64
+	duplicatesSkipLedger    bool
+	duplicatesSkipReceipts  bool
+)
+
+// duplicatesCmd represents the duplicates command.
+var duplicatesCmd = &cobra.Command{
+	Use:   "duplicates",
+	Short: "Report deals suspected to be booked twice",
+	Long: `Report deals suspected to be booked twice, e.g. a receipt booked by
+auto-fetch and again by hand in freee, or in freee and again by hand in
+the ledger.
+
+The deals of the period in freee and the ledger entries not synced from
+freee are compared. Entries are suspected duplicates when
+  - they have the same receipt file (by SHA-256), or
+  - they have the same type and amount, are at most --window days apart
+    and their partner and description are at least --min-similarity alike
+    (or one of them has neither)
+
+Receipts are the files sync downloaded into the attachments directory and
+the document metadata of ledger entries.
+
+Exits with status 1 if there are any suspected duplicates.
+
+Example:
+  freee-sync duplicates --period 2024-03
+  freee-sync duplicates --period 2024 --window 7 --output json`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		_, _, err := reconcile.ParsePeriod(duplicatesPeriod)
+		return err
+	},
+	Run: runDuplicates,
+}
+
+func init() {
+	duplicatesCmd.Flags().StringVar(&duplicatesPeriod, "period", "", "Period to check (YYYY, YYYY-MM or YYYY-MM-DD..YYYY-MM-DD) (required)")
+	duplicatesCmd.Flags().IntVar(&duplicatesWindow, "window", 3, "Days apart entries of the same amount may be")
+	duplicatesCmd.Flags().Float64Var(&duplicatesMinSimilarity, "min-similarity", 0.5, "Similarity (0..1) of partner and description of duplicates")
+	duplicatesCmd.Flags().BoolVar(&duplicatesSkipLedger, "skip-ledger", false, "Compare freee deals only")
+	duplicatesCmd.Flags().BoolVar(&duplicatesSkipReceipts, "skip-receipts", false, "Do not compare receipt files")
+
+	_ = duplicatesCmd.MarkFlagRequired("period")
+}
+
+// duplicatesOutput is the schema of duplicates output.
+type duplicatesOutput struct {
+	From    string            `json:"from"`
+	To      string            `json:"to"`
+	Deals   int               `json:"deals"`          // freee deals compared
+	Ledger  int               `json:"ledger_entries"` // ledger entries not synced from freee compared
+	Groups  []duplicate.Group `json:"groups"`
+	Skipped []string          `json:"skipped,omitempty"` // receipts that could not be read
+}
+
+func runDuplicates(cmd *cobra.Command, args []string) {
+	from, to, _ := reconcile.ParsePeriod(duplicatesPeriod)
+
+	cfg, err := loadConfig()
+	exitOnError(err, "failed to load configuration")
+	exitOnError(cfg.Validate(config.FreeeAPIURL, config.FreeeAccessToken, config.FreeeCompanyID, config.BeancountRoot), "invalid configuration")
+
+	pathResolver := pathutil.New(pathutil.Config{
+		BeancountRoot:  cfg.Beancount.Root,
+		DatabasePath:   cfg.Beancount.DBPath,
+		AttachmentsDir: cfg.Beancount.AttachmentsDir,
+	})
+
+	// The window reaches into the neighbouring days, so entries at the
+	// edges of the period are compared with those just outside it
+	windowFrom, windowTo := shiftDate(from, -duplicatesWindow), shiftDate(to, duplicatesWindow)
+
+	out := duplicatesOutput{From: from, To: to, Groups: []duplicate.Group{}}
+
+	receipts := make(map[int64][]string)
+	if dbPath := pathResolver.GetDatabasePath(); !duplicatesSkipReceipts && pathResolver.FileExists(dbPath) {
+		conn, err := db.Open(dbPath)
+		exitOnError(err, "failed to open database")
+		attachments, err := db.NewSyncHistory(conn).ListDocumentAttachments(windowFrom, windowTo)
+		conn.Close()
+		exitOnError(err, "failed to read document attachments")
+		for _, a := range attachments {
+			hash, err := duplicate.HashFile(a.DocumentPath)
+			if err != nil {
+				out.Skipped = append(out.Skipped, a.DocumentPath)
+				continue
+			}
+			receipts[a.DealID.Int64] = append(receipts[a.DealID.Int64], hash)
+		}
+	}
+
+	slog.Info("Fetching deals from freee", "from", windowFrom, "to", windowTo)
+	deals, err := newFreeeClient(cfg).FetchAllDeals(windowFrom, windowTo)
+	exitOnError(err, "failed to fetch deals")
+
+	var entries []duplicate.Entry
+	for _, deal := range deals {
+		entries = append(entries, dealEntry(deal, receipts[deal.ID]))
+		if deal.IssueDate >= from && deal.IssueDate <= to {
+			out.Deals++
+		}
+	}
+
+	if !duplicatesSkipLedger {
+		ledger, err := beancount.ParseFile(pathResolver.GetMainFilePath())
+		exitOnError(err, "failed to parse ledger")
+		for _, txn := range ledger.Transactions {
+			if txn.Date < windowFrom || txn.Date > windowTo {
+				continue
+			}
+			// Transactions synced from freee are the freee deals themselves
+			if txn.FreeeKey() != "" {
+				continue
+			}
+			entry, ok := duplicate.LedgerEntry(txn, "JPY", !duplicatesSkipReceipts)
+			if !ok {
+				continue
+			}
+			entries = append(entries, entry)
+			if txn.Date >= from && txn.Date <= to {
+				out.Ledger++
+			}
+		}
+	}
+
+	for _, g := range duplicate.Find(entries, duplicate.Options{WindowDays: duplicatesWindow, MinSimilarity: duplicatesMinSimilarity}) {
+		// Groups entirely outside the period are reported for their own period
+		for _, e := range g.Entries {
+			if e.Date >= from && e.Date <= to {
+				out.Groups = append(out.Groups, g)
+				break
+			}
+		}
+	}
+
+	printOutput(out, func() { printDuplicates(out) })
+
+	if len(out.Groups) > 0 {
+		os.Exit(exitFailure)
+	}
+}
+
+// printDuplicates prints suspected duplicates in human readable form.
+func printDuplicates(out duplicatesOutput) {
+	fmt.Printf("Duplicates %s .. %s\n", out.From, out.To)
+	fmt.Printf("  freee deals:    %d\n", out.Deals)
+	fmt.Printf("  Ledger entries: %d\n", out.Ledger)
+	fmt.Printf("  Suspected:      %d\n", len(out.Groups))
+	if len(out.Skipped) > 0 {
+		fmt.Printf("  Unreadable receipts: %d\n", len(out.Skipped))
+	}
+
+	for i, g := range out.Groups {
+		fmt.Printf("\n%d. %s\n", i+1, strings.Join(g.Reasons, ", "))
+		for _, e := range g.Entries {
+			fmt.Printf("   %-10s %-10s %-7s %10d  %s  %s\n", e.Source, e.Date, e.Type, e.Amount, e.ID, strings.TrimSpace(e.Partner+" "+e.Description))
+		}
+	}
+}
+
+// dealEntry returns a freee deal for duplicate detection.
+func dealEntry(deal freee.Deal, receipts []string) duplicate.Entry {
+	var descriptions []string
+	for _, line := range deal.Details {
+		if line.Description != nil && *line.Description != "" {
+			descriptions = append(descriptions, *line.Description)
+		}
+	}
+	return duplicate.Entry{
+		Source:      "freee",
+		ID:          beancount.FreeeKey("deal", deal.ID),
+		Type:        deal.Type,
+		Date:        deal.IssueDate,
+		Amount:      deal.Amount,
+		Partner:     ptrValue(deal.PartnerCode),
+		Description: strings.Join(descriptions, " "),
+		Receipts:    receipts,
+	}
+}
+
+// shiftDate returns the date (YYYY-MM-DD) days later, or date if it is invalid.
+func shiftDate(date string, days int) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return t.AddDate(0, 0, days).Format("2006-01-02")
+}
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/config"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/converter"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/db"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/pathutil"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportFormat     string
+	exportFrom       string
+	exportTo         string
+	exportOut        string
+	exportSyncedOnly bool
+	exportJournals   bool
+	exportTimeout    time.Duration
+)
+
+// Journal export settings: the 汎用形式 download is polled every interval.
+const (
+	journalExportType     = "generic_v2"
+	journalExportInterval = 2 * time.Second
+)
+
+// exportCmd represents the export command.
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export freee transactions to other accounting formats",
+	Long: `Export the deals, manual journals and transfers of a date range in another
+plain-text accounting dialect or as a flat CSV journal.
+
+Transactions are converted exactly as sync converts them, with the same
+account mapping and tax postings, and written in date order.
+
+Formats:
+  beancount  the entries sync writes to the ledger
+  ledger     ledger-cli journal (payee and tags as metadata comments)
+  hledger    hledger journal ("payee | narration", tags as name: tags)
+  csv        one row per posting: date, freee_type, freee_id, payee,
+             narration, tags, account, amount, currency, comment
+
+With --synced-only, only items recorded in the sync history are exported.
+
+With --journal-export, the transactions are taken from freee's journal
+export (仕訳帳) instead: freee prepares the full book of the date range in
+the background, the export is polled until it is ready and then
+downloaded. It covers every entry of the books, including those sync does
+not fetch individually, and is the way to import a complete ledger.
+Accounts are mapped by name since the export has no account item IDs.
+
+Example:
+  freee-sync export --format hledger --from 2024-01-01 --to 2024-12-31 --out 2024.journal
+  freee-sync export --format csv --from 2024-04-01 --to 2024-04-30 > 2024-04.csv
+  freee-sync export --journal-export --from 2024-01-01 --to 2024-12-31 --out 2024-full.beancount`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		_, err := converter.NewWriter(exportFormat, io.Discard)
+		return err
+	},
+	Run: runExport,
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&exportFormat, "format", "beancount", "Output format: "+strings.Join(converter.WriterFormats(), ", "))
+	exportCmd.Flags().StringVar(&exportFrom, "from", "", "Start date (YYYY-MM-DD) (required)")
+	exportCmd.Flags().StringVar(&exportTo, "to", "", "End date (YYYY-MM-DD) (required)")
+	exportCmd.Flags().StringVar(&exportOut, "out", "", "Write to this file instead of stdout")
+	exportCmd.Flags().BoolVar(&exportSyncedOnly, "synced-only", false, "Export only items recorded in the sync history")
+	exportCmd.Flags().BoolVar(&exportJournals, "journal-export", false, "Export the full book through freee's asynchronous journal export")
+	exportCmd.Flags().DurationVar(&exportTimeout, "journal-export-timeout", 5*time.Minute, "How long to wait for the journal export to be ready")
+	exportCmd.MarkFlagsMutuallyExclusive("synced-only", "journal-export")
+
+	_ = exportCmd.MarkFlagRequired("from")
+	_ = exportCmd.MarkFlagRequired("to")
+}
+
+func runExport(cmd *cobra.Command, args []string) {
+	cfg, err := loadConfig()
+	exitOnError(err, "failed to load configuration")
+
+	if err := cfg.Validate(
+		config.FreeeAPIURL,
+		config.FreeeAccessToken,
+		config.FreeeCompanyID,
+	); err != nil {
+		exitOnError(err, "invalid configuration")
+	}
+
+	mapper, err := converter.NewMapper(cfg.MappingPath)
+	exitOnError(err, "failed to load account mapping")
+	cvtr := converter.NewConverter(mapper, "JPY")
+
+	client := newFreeeClient(cfg)
+
+	var txns []converter.BeancountTransaction
+	if exportJournals {
+		txns = exportJournalBook(client, cvtr)
+	} else {
+		txns = exportItems(cfg, client, cvtr)
+	}
+
+	out := io.Writer(os.Stdout)
+	if exportOut != "" {
+		f, err := os.Create(exportOut)
+		exitOnError(err, "failed to create output file")
+		defer f.Close()
+		out = f
+	}
+	buffered := bufio.NewWriter(out)
+
+	writer, err := converter.NewWriter(exportFormat, buffered)
+	exitOnError(err, "invalid format")
+	for _, txn := range txns {
+		exitOnError(writer.WriteTransaction(txn), "failed to write transaction")
+	}
+	exitOnError(writer.Flush(), "failed to write output")
+	exitOnError(buffered.Flush(), "failed to write output")
+
+	slog.Info("Export completed", "format", exportFormat, "transactions", len(txns))
+	if exportOut != "" {
+		fmt.Fprintf(os.Stderr, "Exported %d transaction(s) to %s\n", len(txns), exportOut)
+	}
+}
+
+// exportItems fetches the deals, manual journals and transfers of the
+// export range and converts them as sync does.
+func exportItems(cfg *config.Config, client *freee.Client, cvtr *converter.Converter) []converter.BeancountTransaction {
+	slog.Info("Fetching deals, manual journals and transfers from freee", "from", exportFrom, "to", exportTo)
+	deals, err := client.FetchAllDeals(exportFrom, exportTo)
+	exitOnError(err, "failed to fetch deals")
+	journals, err := client.FetchAllManualJournals(exportFrom, exportTo)
+	exitOnError(err, "failed to fetch manual journals")
+	transfers, err := client.FetchTransfers(exportFrom, exportTo)
+	exitOnError(err, "failed to fetch transfers")
+
+	var synced map[db.SyncType]map[int64]bool
+	if exportSyncedOnly {
+		synced = loadSyncedIDs(cfg, db.SyncTypeDeal, db.SyncTypeJournal, db.SyncTypeTransfer)
+	}
+	exported := func(syncType db.SyncType, id int64) bool {
+		return synced == nil || synced[syncType][id]
+	}
+
+	var txns []converter.BeancountTransaction
+	for _, deal := range deals {
+		if exported(db.SyncTypeDeal, deal.ID) {
+			txns = append(txns, cvtr.ConvertDeal(deal))
+		}
+	}
+	for _, journal := range journals {
+		if exported(db.SyncTypeJournal, journal.ID) {
+			txns = append(txns, cvtr.ConvertManualJournal(journal))
+		}
+	}
+	for _, transfer := range transfers {
+		if exported(db.SyncTypeTransfer, transfer.ID) {
+			txns = append(txns, cvtr.ConvertTransfer(transfer))
+		}
+	}
+	sort.SliceStable(txns, func(i, j int) bool { return txns[i].Date < txns[j].Date })
+	return txns
+}
+
+// exportJournalBook downloads freee's journal export of the export range
+// and converts its slips.
+func exportJournalBook(client *freee.Client, cvtr *converter.Converter) []converter.BeancountTransaction {
+	slog.Info("Requesting journal export from freee", "from", exportFrom, "to", exportTo)
+	data, err := client.ExportJournals(journalExportType, exportFrom, exportTo, journalExportInterval, exportTimeout)
+	exitOnError(err, "failed to export journals")
+
+	lines, err := freee.ParseJournalExport(data)
+	exitOnError(err, "failed to parse journal export")
+	slog.Info("Downloaded journal export", "rows", len(lines))
+
+	return cvtr.ConvertJournalExport(lines)
+}
+
+// loadSyncedIDs returns the IDs of the items of each type in the sync history.
+func loadSyncedIDs(cfg *config.Config, syncTypes ...db.SyncType) map[db.SyncType]map[int64]bool {
+	pathResolver := pathutil.New(pathutil.Config{
+		BeancountRoot:  cfg.Beancount.Root,
+		DatabasePath:   cfg.Beancount.DBPath,
+		AttachmentsDir: cfg.Beancount.AttachmentsDir,
+	})
+
+	conn, err := db.Open(pathResolver.GetDatabasePath())
+	exitOnError(err, "failed to open database")
+	defer conn.Close()
+
+	syncHistory := db.NewSyncHistory(conn)
+	synced := make(map[db.SyncType]map[int64]bool, len(syncTypes))
+	for _, syncType := range syncTypes {
+		ids, err := syncHistory.GetSyncedIDs(syncType)
+		exitOnError(err, fmt.Sprintf("failed to get synced %s IDs", syncType))
+		synced[syncType] = idSet(ids)
+	}
+
+	return synced
+}
+
+func idSet(ids []int64) map[int64]bool {
+	set := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/config"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/db"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/pathutil"
+	"github.com/spf13/cobra"
+)
+
+var historyLimit int
+
+// historyCmd represents the history command.
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List recent sync runs",
+	Long: `List recent sync runs with their date range and counts.
+
+Example:
+  freee-sync history
+  freee-sync history --limit 5 --output json`,
+	Run: runHistory,
+}
+
+// showRunCmd represents the show-run command.
+var showRunCmd = &cobra.Command{
+	Use:   "show-run <id>",
+	Short: "Show details of a sync run",
+	Long: `Show details of a sync run: counts, errors, files touched and
+the deals/journals it wrote.
+
+Example:
+  freee-sync show-run 42
+  freee-sync show-run 42 --output yaml`,
+	Args: cobra.ExactArgs(1),
+	Run:  runShowRun,
+}
+
+func init() {
+	historyCmd.Flags().IntVar(&historyLimit, "limit", 20, "Number of runs to show")
+}
+
+// openSyncHistory loads configuration and opens the sync database.
+func openSyncHistory() (*db.Connection, *db.SyncHistory) {
+	cfg, err := loadConfig()
+	exitOnError(err, "failed to load configuration")
+
+	if err := cfg.Validate(config.BeancountRoot); err != nil {
+		exitOnError(err, "invalid configuration")
+	}
+
+	pathResolver := pathutil.New(pathutil.Config{
+		BeancountRoot:  cfg.Beancount.Root,
+		DatabasePath:   cfg.Beancount.DBPath,
+		AttachmentsDir: cfg.Beancount.AttachmentsDir,
+	})
+
+	conn, err := db.Open(pathResolver.GetDatabasePath())
+	exitOnError(err, "failed to open database")
+
+	return conn, db.NewSyncHistory(conn)
+}
+
+func runHistory(cmd *cobra.Command, args []string) {
+	conn, syncHistory := openSyncHistory()
+	defer conn.Close()
+
+	runs, err := syncHistory.ListRuns(historyLimit)
+	exitOnError(err, "failed to list sync runs")
+
+	if structuredOutput() {
+		if runs == nil {
+			runs = []db.SyncRun{}
+		}
+		printOutput(runs, nil)
+		return
+	}
+
+	if len(runs) == 0 {
+		fmt.Println("No sync runs recorded")
+		return
+	}
+
+	fmt.Printf("%-6s %-20s %-9s %-10s %-23s %7s %5s %7s %6s\n",
+		"ID", "STARTED", "DURATION", "STATUS", "RANGE", "FETCHED", "NEW", "UPDATED", "FAILED")
+	for _, run := range runs {
+		fmt.Printf("%-6d %-20s %-9s %-10s %-23s %7d %5d %7d %6d\n",
+			run.ID,
+			run.StartedAt.Local().Format("2006-01-02 15:04:05"),
+			formatRunDuration(run),
+			run.Status,
+			run.DateFrom+".."+run.DateTo,
+			run.Fetched, run.New, run.Updated, run.Failed,
+		)
+	}
+}
+
+func runShowRun(cmd *cobra.Command, args []string) {
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	exitOnError(err, "invalid run ID")
+
+	conn, syncHistory := openSyncHistory()
+	defer conn.Close()
+
+	run, err := syncHistory.GetRun(id)
+	exitOnError(err, "failed to get sync run")
+	if run == nil {
+		exitOnError(fmt.Errorf("sync run %d not found", id), "failed to get sync run")
+	}
+
+	records, err := syncHistory.GetRunRecords(id)
+	exitOnError(err, "failed to get run records")
+
+	if structuredOutput() {
+		if records == nil {
+			records = []db.SyncRecord{}
+		}
+		printOutput(struct {
+			Run     *db.SyncRun     `json:"run"`
+			Records []db.SyncRecord `json:"records"`
+		}{run, records}, nil)
+		return
+	}
+
+	fmt.Printf("\n=== Sync Run #%d ===\n", run.ID)
+	fmt.Printf("Status:    %s\n", run.Status)
+	fmt.Printf("Started:   %s\n", run.StartedAt.Local().Format(time.RFC3339))
+	if run.FinishedAt != nil {
+		fmt.Printf("Finished:  %s (%s)\n", run.FinishedAt.Local().Format(time.RFC3339), formatRunDuration(*run))
+	}
+	fmt.Printf("Range:     %s to %s\n", run.DateFrom, run.DateTo)
+	if run.CompanyID != 0 {
+		fmt.Printf("Company:   %d\n", run.CompanyID)
+	}
+	fmt.Printf("Fetched:   %d\n", run.Fetched)
+	fmt.Printf("New:       %d\n", run.New)
+	fmt.Printf("Updated:   %d\n", run.Updated)
+	fmt.Printf("Failed:    %d\n", run.Failed)
+
+	if len(run.Files) > 0 {
+		fmt.Println("\nFiles:")
+		for _, f := range run.Files {
+			fmt.Printf("  %s\n", f)
+		}
+	}
+
+	if len(run.Errors) > 0 {
+		fmt.Println("\nErrors:")
+		for _, e := range run.Errors {
+			fmt.Printf("  %s\n", e)
+		}
+	}
+
+	if len(records) > 0 {
+		fmt.Println("\nRecords:")
+		for _, r := range records {
+			fmt.Printf("  %-8s %-12d %s %12d  %s\n", r.SyncType, r.FreeeID, r.IssueDate, r.Amount, r.BeancountFile)
+		}
+	}
+	fmt.Println()
+}
+
+// formatRunDuration returns the elapsed time of a run, or "-" if unfinished.
+func formatRunDuration(run db.SyncRun) string {
+	if run.FinishedAt == nil {
+		return "-"
+	}
+	return run.FinishedAt.Sub(run.StartedAt).Round(time.Second).String()
+}
@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/config"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/converter"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/db"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/pathutil"
+	"github.com/spf13/cobra"
+)
+
+var (
+	initRoot      string
+	initStartDate string
+	initTitle     string
+	initOffline   bool
+	initForce     bool
+)
+
+// initCmd represents the init command.
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Set up a new Beancount ledger for a freee company",
+	Long: `Set up a new Beancount ledger for a freee company.
+
+This command:
+1. Fetches the company's account items, walletables and tax codes from freee
+   and writes the account mapping (config/account-mapping.yaml), placing
+   account items by their category and walletables by their type
+2. Writes main.beancount, opening-balances.beancount and accounts.beancount
+   with an open directive for every mapped account
+3. Creates the month file of the start date and the sync database
+4. Writes a config file (freee-automation.yaml, or --config) with a
+   profile holding the freee and Beancount settings; --profile names it
+
+With --offline, freee is not contacted and accounts.beancount is generated
+from the existing account mapping.
+
+Existing files are left alone unless --force is given. Review the mapping
+and the accounts before the first sync.
+
+Example:
+  freee-sync init --root ./beancount --start-date 2024-01-01
+  freee-sync init --offline --force`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := time.Parse("2006-01-02", initStartDate); err != nil {
+			return fmt.Errorf("invalid --start-date %q: expected YYYY-MM-DD", initStartDate)
+		}
+		return nil
+	},
+	Run: runInit,
+}
+
+func init() {
+	initCmd.Flags().StringVar(&initRoot, "root", "", "Beancount root directory (default BEANCOUNT_ROOT)")
+	initCmd.Flags().StringVar(&initStartDate, "start-date", fmt.Sprintf("%d-01-01", time.Now().Year()), "Date the accounts are opened (YYYY-MM-DD)")
+	initCmd.Flags().StringVar(&initTitle, "title", "Accounting System (freee + Beancount)", "Ledger title")
+	initCmd.Flags().BoolVar(&initOffline, "offline", false, "Do not fetch master data from freee; use the existing account mapping")
+	initCmd.Flags().BoolVar(&initForce, "force", false, "Overwrite existing files")
+}
+
+// initOutput is the schema of init output.
+type initOutput struct {
+	Root         string     `json:"root"`
+	AccountItems int        `json:"account_items"`
+	Walletables  int        `json:"walletables"`
+	TaxCodes     int        `json:"tax_codes"`
+	Accounts     int        `json:"accounts"`
+	Files        []initFile `json:"files"`
+}
+
+// initFile is a file written or left alone by init.
+type initFile struct {
+	Path   string `json:"path"`
+	Action string `json:"action"` // created, overwritten or skipped
+}
+
+func runInit(cmd *cobra.Command, args []string) {
+	// The profile being created need not exist yet
+	cfg, err := loadConfig()
+	var unknown *config.UnknownProfileError
+	if errors.As(err, &unknown) {
+		cfg, err = config.LoadWith(config.Options{File: unknown.File})
+	}
+	exitOnError(err, "failed to load configuration")
+	if initRoot != "" {
+		cfg.Beancount.Root = initRoot
+	}
+
+	pathResolver := pathutil.New(pathutil.Config{
+		BeancountRoot:  cfg.Beancount.Root,
+		DatabasePath:   cfg.Beancount.DBPath,
+		AttachmentsDir: cfg.Beancount.AttachmentsDir,
+	})
+	root := pathResolver.GetBeancountRoot()
+	out := initOutput{Root: root}
+
+	write := func(path, content string) {
+		action, err := writeScaffoldFile(path, content)
+		exitOnError(err, "failed to write "+path)
+		out.Files = append(out.Files, initFile{Path: path, Action: action})
+	}
+
+	// Account mapping from freee master data, or the existing one
+	var accounts map[string]string
+	if initOffline {
+		mapper, err := converter.NewMapper(cfg.MappingPath)
+		exitOnError(err, "failed to load account mapping")
+		accounts = make(map[string]string)
+		for name, account := range mapper.GetAllMappings() {
+			accounts[account] = name
+		}
+	} else {
+		if err := cfg.Validate(
+			config.FreeeAPIURL,
+			config.FreeeAccessToken,
+			config.FreeeCompanyID,
+		); err != nil {
+			exitOnError(err, "invalid configuration (use --offline to skip freee)")
+		}
+
+		client := newFreeeClient(cfg)
+		slog.Info("Fetching master data from freee", "company_id", cfg.Freee.CompanyID)
+		items, err := client.ListAccountItems()
+		exitOnError(err, "failed to fetch account items")
+		walletables, err := client.ListWalletables()
+		exitOnError(err, "failed to fetch walletables")
+		taxCodes, err := client.ListTaxCodes()
+		exitOnError(err, "failed to fetch tax codes")
+
+		out.AccountItems, out.Walletables, out.TaxCodes = len(items), len(walletables), len(taxCodes)
+
+		patch := converter.MappingFromMaster(items, walletables, taxCodes)
+		data, err := patch.Marshal()
+		exitOnError(err, "failed to encode account mapping")
+		header := fmt.Sprintf("# Account Mapping Configuration\n# Generated by freee-sync init for company %d on %s.\n# Review the accounts before the first sync.\n\n",
+			cfg.Freee.CompanyID, time.Now().Format("2006-01-02"))
+		write(cfg.MappingPath, header+string(data))
+
+		accounts = patch.OpenAccounts()
+	}
+
+	// Accounts the converter, opening balances and pending entries use without a mapping
+	for account, desc := range map[string]string{
+		"Assets:Current:Bank:Ordinary":   "普通預金",
+		beancount.OpeningBalancesAccount: "開始残高",
+		beancount.SuspenseAccount:        "未確定勘定",
+	} {
+		if _, ok := accounts[account]; !ok {
+			accounts[account] = desc
+		}
+	}
+	out.Accounts = len(accounts)
+
+	// Ledger files
+	write(filepath.Join(root, beancount.MainFile), beancount.MainFileContent(initTitle, "JPY"))
+	write(filepath.Join(root, beancount.AccountsFile), beancount.FormatOpens(initStartDate, "JPY", accounts))
+	write(filepath.Join(root, beancount.OpeningBalancesFile), beancount.OpeningBalancesContent())
+
+	// The month file lets the include of month files match from the start
+	month := initStartDate[:7]
+	monthFile, err := pathResolver.GetMonthFilePath(month)
+	exitOnError(err, "invalid start date")
+	action := "skipped"
+	if !pathResolver.FileExists(monthFile) {
+		exitOnError(beancount.NewFileSystemRepository(pathResolver).EnsureMonthFile(month), "failed to create month file")
+		action = "created"
+	}
+	out.Files = append(out.Files, initFile{Path: monthFile, Action: action})
+
+	exitOnError(pathResolver.EnsureDir(pathResolver.GetAttachmentsDir()), "failed to create attachments directory")
+
+	// Sync database, migrated on open
+	dbPath := pathResolver.GetDatabasePath()
+	action = "skipped"
+	if !pathResolver.FileExists(dbPath) {
+		action = "created"
+	}
+	conn, err := db.Open(dbPath)
+	exitOnError(err, "failed to create database")
+	conn.Close()
+	out.Files = append(out.Files, initFile{Path: dbPath, Action: action})
+
+	configFile := config.FileName + ".yaml"
+	if config.IsConfigFile(cfgFile) {
+		configFile = cfgFile
+	}
+	profileName := profile
+	if profileName == "" {
+		profileName = "default"
+	}
+	write(configFile, starterConfig(cfg, profileName))
+
+	printOutput(out, func() {
+		fmt.Printf("Initialized ledger in %s\n", out.Root)
+		if !initOffline {
+			fmt.Printf("freee master data: %d account items, %d walletables, %d tax codes\n", out.AccountItems, out.Walletables, out.TaxCodes)
+		}
+		fmt.Printf("Accounts opened:   %d\n\n", out.Accounts)
+		for _, f := range out.Files {
+			fmt.Printf("  %-12s %s\n", f.Action, f.Path)
+		}
+		fmt.Println("\nReview the account mapping and accounts.beancount, then run: freee-sync sync --incremental --from <date> --to <date>")
+	})
+}
+
+// writeScaffoldFile writes a file unless it exists and --force is not set.
+func writeScaffoldFile(path, content string) (string, error) {
+	action := "created"
+	if _, err := os.Stat(path); err == nil {
+		if !initForce {
+			return "skipped", nil
+		}
+		action = "overwritten"
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+	return action, nil
+}
+
+// starterConfig returns a config file with a profile holding the current
+// settings, or placeholders. The access token is never written: the file
+// usually sits next to the ledger and may be committed with it.
+func starterConfig(cfg *config.Config, profileName string) string {
+	token := fmt.Sprintf("# access_token: your_access_token_here  # or %s", config.FreeeAccessToken.Env())
+	return fmt.Sprintf(`# freee-automation configuration written by freee-sync init
+#
+# Shared by freee-sync, the receipt fetcher and the checker. Settings at
+# the top level apply to every profile; select a profile with --profile
+# or FREEE_PROFILE. Environment variables (FREEE_*, BEANCOUNT_*) override
+# this file.
+
+default_profile: %s
+
+profiles:
+  %s:
+    freee:
+      api_url: %s
+      company_id: %d
+      %s
+      # token_store: ~/.config/gmail-fetcher/freee_token.json
+    beancount:
+      root: %s
+      # db_path: %s
+      # attachments_dir: %s
+    mapping: %s
+`, profileName, profileName,
+		strconv.Quote(cfg.Freee.APIURL), cfg.Freee.CompanyID, token,
+		strconv.Quote(cfg.Beancount.Root),
+		filepath.Join(cfg.Beancount.Root, ".sync", "sync.db"),
+		filepath.Join(cfg.Beancount.Root, "attachments"),
+		strconv.Quote(cfg.MappingPath))
+}
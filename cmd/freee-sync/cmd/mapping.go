@@ -0,0 +1,570 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/config"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/converter"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/db"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/pathutil"
+	"github.com/spf13/cobra"
+)
+
+// Sources of the usage analyzed by mapping check.
+const (
+	mappingSourceFreee   = "freee"
+	mappingSourceHistory = "history"
+)
+
+// Kinds of items reported by mapping check.
+const (
+	kindAccountItem = "account_item"
+	kindTaxCode     = "tax_code"
+	kindWalletable  = "walletable"
+	kindAccount     = "account" // Ledger account of a synced entry (history source)
+)
+
+// Statuses of items reported by mapping check.
+const (
+	statusUnmapped   = "unmapped"
+	statusUndeclared = "undeclared"
+)
+
+var (
+	mappingFrom      string
+	mappingTo        string
+	mappingSource    string
+	mappingPatchFile string
+)
+
+// mappingCmd represents the mapping command.
+var mappingCmd = &cobra.Command{
+	Use:   "mapping",
+	Short: "Inspect the account mapping",
+	Long:  `Inspect the account mapping (the mapping file of the profile, by default ` + config.DefaultMappingPath + `).`,
+}
+
+// mappingCheckCmd represents the mapping check command.
+var mappingCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Report account items, tax codes and walletables missing from the mapping",
+	Long: `Report the account items, tax codes and walletables used in a date range
+that are not in the account mapping, and mapped Beancount accounts that
+have no open directive in the ledger.
+
+Usage is read from freee (--source freee, the default) or from the entries
+already synced to the ledger (--source history). The history source works
+offline but only sees account items that fell back to Expenses:Unmapped
+and the accounts of synced entries; tax codes and walletables are not
+recorded in the ledger.
+
+With --write-patch, suggested mappings for the unmapped items are written
+as YAML in the format of the mapping file. Account items are placed by
+their account_category, walletables by their type. Review the suggestions
+before merging them.
+
+Exits with status 1 if anything is unmapped or undeclared.
+
+Example:
+  freee-sync mapping check --from 2024-01-01 --to 2024-12-31
+  freee-sync mapping check --from 2024-01-01 --to 2024-12-31 --source history
+  freee-sync mapping check --from 2024-01-01 --to 2024-12-31 --write-patch mapping-patch.yaml`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if mappingSource != mappingSourceFreee && mappingSource != mappingSourceHistory {
+			return fmt.Errorf("invalid --source %q: expected %s or %s", mappingSource, mappingSourceFreee, mappingSourceHistory)
+		}
+		return nil
+	},
+	Run: runMappingCheck,
+}
+
+func init() {
+	mappingCmd.AddCommand(mappingCheckCmd)
+
+	mappingCheckCmd.Flags().StringVar(&mappingFrom, "from", "", "Start date (YYYY-MM-DD) (required)")
+	mappingCheckCmd.Flags().StringVar(&mappingTo, "to", "", "End date (YYYY-MM-DD) (required)")
+	mappingCheckCmd.Flags().StringVar(&mappingSource, "source", mappingSourceFreee, "Where to read usage from: freee or history")
+	mappingCheckCmd.Flags().StringVar(&mappingPatchFile, "write-patch", "", "Write suggested mappings for unmapped items to this YAML file")
+
+	_ = mappingCheckCmd.MarkFlagRequired("from")
+	_ = mappingCheckCmd.MarkFlagRequired("to")
+}
+
+// mappingIssue is an item that is unmapped or mapped to an undeclared account.
+type mappingIssue struct {
+	Kind      string `json:"kind"`
+	Key       string `json:"key"`
+	Name      string `json:"name,omitempty"`
+	Category  string `json:"category,omitempty"`
+	Status    string `json:"status"`
+	Account   string `json:"account,omitempty"`
+	Suggested string `json:"suggested,omitempty"`
+	Uses      int    `json:"uses"`
+}
+
+// mappingReport is the result of mapping check.
+type mappingReport struct {
+	From      string         `json:"from"`
+	To        string         `json:"to"`
+	Source    string         `json:"source"`
+	Deals     int            `json:"deals"`
+	Journals  int            `json:"journals"`
+	Transfers int            `json:"transfers"`
+	Used      map[string]int `json:"used"`
+	Issues    []mappingIssue `json:"issues"`
+}
+
+// masterData holds the freee master data used to describe items.
+type masterData struct {
+	accountItems map[int64]freee.AccountItem
+	walletables  map[string]freee.Walletable // by converter.WalletableKey
+	taxCodes     map[int]freee.TaxCode
+}
+
+func runMappingCheck(cmd *cobra.Command, args []string) {
+	cfg, err := loadConfig()
+	exitOnError(err, "failed to load configuration")
+
+	if err := cfg.Validate(config.BeancountRoot); err != nil {
+		exitOnError(err, "invalid configuration")
+	}
+
+	freeeErr := cfg.Validate(
+		config.FreeeAPIURL,
+		config.FreeeAccessToken,
+		config.FreeeCompanyID,
+	)
+	if mappingSource == mappingSourceFreee {
+		exitOnError(freeeErr, "invalid configuration")
+	}
+
+	pathResolver := pathutil.New(pathutil.Config{
+		BeancountRoot:  cfg.Beancount.Root,
+		DatabasePath:   cfg.Beancount.DBPath,
+		AttachmentsDir: cfg.Beancount.AttachmentsDir,
+	})
+
+	mapper, err := converter.NewMapper(cfg.MappingPath)
+	exitOnError(err, "failed to load account mapping")
+
+	// Accounts opened in the ledger; nil if there is no ledger yet
+	var ledger *beancount.Ledger
+	if mainFile := pathResolver.GetMainFilePath(); pathResolver.FileExists(mainFile) {
+		ledger, err = beancount.ParseFile(mainFile)
+		exitOnError(err, "failed to parse ledger")
+	} else {
+		slog.Warn("Ledger not found, declared accounts are not checked", "path", mainFile)
+	}
+
+	report := &mappingReport{
+		From:   mappingFrom,
+		To:     mappingTo,
+		Source: mappingSource,
+		Used:   make(map[string]int),
+	}
+
+	var usage *converter.Usage
+	var ledgerAccounts map[string]int
+	if mappingSource == mappingSourceFreee {
+		usage = fetchFreeeUsage(newFreeeClient(cfg), report)
+	} else {
+		if ledger == nil {
+			exitOnError(fmt.Errorf("ledger not found: %s", pathResolver.GetMainFilePath()), "history source requires the ledger")
+		}
+		conn, err := db.Open(pathResolver.GetDatabasePath())
+		exitOnError(err, "failed to open database")
+		defer conn.Close()
+
+		usage, ledgerAccounts, err = historyUsage(db.NewSyncHistory(conn), ledger, report)
+		exitOnError(err, "failed to read sync history")
+	}
+
+	// Master data describes the items; it is optional for the history source
+	master := &masterData{}
+	if freeeErr == nil {
+		master = fetchMasterData(newFreeeClient(cfg))
+	}
+
+	var declared map[string]bool
+	if ledger != nil {
+		declared = make(map[string]bool, len(ledger.Opens))
+		for _, open := range ledger.Opens {
+			declared[open.Account] = true
+		}
+	}
+
+	report.Issues = checkMapping(mapper, declared, usage, ledgerAccounts, master, report.Used)
+
+	if mappingPatchFile != "" {
+		writeMappingPatch(report.Issues, cfg.MappingPath)
+	}
+
+	if report.Issues == nil {
+		report.Issues = []mappingIssue{}
+	}
+	printOutput(report, func() { printMappingReport(report) })
+
+	if len(report.Issues) > 0 {
+		os.Exit(exitFailure)
+	}
+}
+
+// fetchFreeeUsage fetches the deals, journals and transfers of the date
+// range and counts the items they reference.
+func fetchFreeeUsage(client *freee.Client, report *mappingReport) *converter.Usage {
+	slog.Info("Fetching deals from freee", "from", mappingFrom, "to", mappingTo)
+	deals, err := client.FetchAllDeals(mappingFrom, mappingTo)
+	exitOnError(err, "failed to fetch deals")
+
+	slog.Info("Fetching manual journals from freee", "from", mappingFrom, "to", mappingTo)
+	journals, err := client.FetchAllManualJournals(mappingFrom, mappingTo)
+	exitOnError(err, "failed to fetch manual journals")
+
+	slog.Info("Fetching transfers from freee", "from", mappingFrom, "to", mappingTo)
+	transfers, err := client.FetchTransfers(mappingFrom, mappingTo)
+	exitOnError(err, "failed to fetch transfers")
+
+	usage := converter.NewUsage()
+	for _, deal := range deals {
+		usage.AddDeal(deal)
+	}
+	for _, journal := range journals {
+		usage.AddManualJournal(journal)
+	}
+	for _, transfer := range transfers {
+		usage.AddTransfer(transfer)
+	}
+
+	report.Deals = len(deals)
+	report.Journals = len(journals)
+	report.Transfers = len(transfers)
+	return usage
+}
+
+// historyUsage reads the entries synced in the date range from the ledger.
+// Postings to Expenses:Unmapped are counted as unmapped account items; the
+// other accounts are returned with their number of postings.
+func historyUsage(syncHistory *db.SyncHistory, ledger *beancount.Ledger, report *mappingReport) (*converter.Usage, map[string]int, error) {
+	synced := make(map[string]bool)
+	for _, syncType := range []db.SyncType{db.SyncTypeDeal, db.SyncTypeJournal, db.SyncTypeTransfer} {
+		records, err := syncHistory.GetSyncRecordsByType(syncType)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, record := range records {
+			if record.Status != db.SyncStatusCommitted || record.IssueDate < mappingFrom || record.IssueDate > mappingTo {
+				continue
+			}
+			synced[beancount.FreeeKey(string(record.SyncType), record.FreeeID)] = true
+		}
+	}
+
+	usage := converter.NewUsage()
+	accounts := make(map[string]int)
+	for _, txn := range ledger.Transactions {
+		key := txn.FreeeKey()
+		if !synced[key] {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(key, string(db.SyncTypeDeal)+":"):
+			report.Deals++
+		case strings.HasPrefix(key, string(db.SyncTypeTransfer)+":"):
+			report.Transfers++
+		default:
+			report.Journals++
+		}
+
+		for _, posting := range txn.Postings {
+			if name, ok := strings.CutPrefix(posting.Account, converter.UnmappedPrefix); ok {
+				usage.AccountItems[name]++
+			} else {
+				accounts[posting.Account]++
+			}
+		}
+	}
+
+	return usage, accounts, nil
+}
+
+// accountItemByName looks up an account item by name; used when the ID
+// is unknown, as for entries read from the ledger.
+func (m *masterData) accountItemByName(name string) (freee.AccountItem, bool) {
+	for _, item := range m.accountItems {
+		if item.Name == name {
+			return item, true
+		}
+	}
+	return freee.AccountItem{}, false
+}
+
+// fetchMasterData fetches account items, walletables and tax codes.
+// Failures are logged; the report then lacks names and categories.
+func fetchMasterData(client *freee.Client) *masterData {
+	master := &masterData{
+		accountItems: make(map[int64]freee.AccountItem),
+		walletables:  make(map[string]freee.Walletable),
+		taxCodes:     make(map[int]freee.TaxCode),
+	}
+
+	if items, err := client.ListAccountItems(); err != nil {
+		slog.Warn("Failed to fetch account items", "error", err)
+	} else {
+		for _, item := range items {
+			master.accountItems[item.ID] = item
+		}
+	}
+
+	if walletables, err := client.ListWalletables(); err != nil {
+		slog.Warn("Failed to fetch walletables", "error", err)
+	} else {
+		for _, w := range walletables {
+			master.walletables[converter.WalletableKey(w.Type, w.ID)] = w
+		}
+	}
+
+	if taxCodes, err := client.ListTaxCodes(); err != nil {
+		slog.Warn("Failed to fetch tax codes", "error", err)
+	} else {
+		for _, tax := range taxCodes {
+			master.taxCodes[tax.Code] = tax
+		}
+	}
+
+	return master
+}
+
+// checkMapping compares the usage with the mapping and the declared
+// accounts. declared is nil if the ledger does not exist, in which case
+// only unmapped items are reported. used receives the number of distinct
+// items per kind.
+func checkMapping(mapper *converter.Mapper, declared map[string]bool, usage *converter.Usage, ledgerAccounts map[string]int, master *masterData, used map[string]int) []mappingIssue {
+	var issues []mappingIssue
+	undeclared := func(account string) bool {
+		return declared != nil && account != "" && !declared[account]
+	}
+
+	for name, uses := range usage.AccountItems {
+		used[kindAccountItem]++
+		issue := mappingIssue{Kind: kindAccountItem, Key: name, Uses: uses}
+		if item, ok := master.accountItems[usage.AccountItemIDs[name]]; ok {
+			issue.Category = item.AccountCategory
+		} else if item, ok := master.accountItemByName(name); ok {
+			issue.Category = item.AccountCategory
+		}
+
+		account := mapper.GetBeancountAccount(name)
+		switch {
+		case account == "":
+			issue.Status = statusUnmapped
+			issue.Suggested = converter.SuggestAccount(name, issue.Category)
+		case undeclared(account):
+			issue.Status = statusUndeclared
+			issue.Account = account
+		default:
+			continue
+		}
+		issues = append(issues, issue)
+	}
+
+	for code, uses := range usage.TaxCodes {
+		used[kindTaxCode]++
+		if code == 0 { // 対象外
+			continue
+		}
+		issue := mappingIssue{Kind: kindTaxCode, Key: strconv.Itoa(code), Uses: uses}
+		if tax, ok := master.taxCodes[code]; ok {
+			issue.Name = tax.NameJa
+			issue.Category = tax.Name
+		}
+
+		mapping := mapper.GetTaxCode(issue.Key)
+		if mapping == nil && issue.Category != "" {
+			mapping = mapper.GetTaxCode(issue.Category)
+		}
+		switch {
+		case mapping == nil:
+			issue.Status = statusUnmapped
+		case mapping.BeancountAccount != nil && undeclared(*mapping.BeancountAccount):
+			issue.Status = statusUndeclared
+			issue.Account = *mapping.BeancountAccount
+		default:
+			continue
+		}
+		issues = append(issues, issue)
+	}
+
+	for key, uses := range usage.Walletables {
+		used[kindWalletable]++
+		issue := mappingIssue{Kind: kindWalletable, Key: key, Uses: uses}
+		walletableType, walletableID, _ := strings.Cut(key, ":")
+		if w, ok := master.walletables[key]; ok {
+			issue.Name = w.Name
+		}
+
+		id, _ := strconv.ParseInt(walletableID, 10, 64)
+		account := mapper.GetWalletableAccount(walletableType, id)
+		switch {
+		case account == "":
+			issue.Status = statusUnmapped
+			name := issue.Name
+			if name == "" {
+				name = strings.ReplaceAll(key, ":", "")
+			}
+			issue.Suggested = converter.SuggestWalletableAccount(walletableType, name)
+		case undeclared(account):
+			issue.Status = statusUndeclared
+			issue.Account = account
+		default:
+			continue
+		}
+		issues = append(issues, issue)
+	}
+
+	for account, uses := range ledgerAccounts {
+		used[kindAccount]++
+		if undeclared(account) {
+			issues = append(issues, mappingIssue{
+				Kind:    kindAccount,
+				Key:     account,
+				Status:  statusUndeclared,
+				Account: account,
+				Uses:    uses,
+			})
+		}
+	}
+
+	kindOrder := map[string]int{kindAccountItem: 0, kindTaxCode: 1, kindWalletable: 2, kindAccount: 3}
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].Kind != issues[j].Kind {
+			return kindOrder[issues[i].Kind] < kindOrder[issues[j].Kind]
+		}
+		if issues[i].Status != issues[j].Status {
+			return issues[i].Status < issues[j].Status
+		}
+		return issues[i].Key < issues[j].Key
+	})
+
+	return issues
+}
+
+// writeMappingPatch writes the suggested mappings for unmapped items.
+func writeMappingPatch(issues []mappingIssue, mappingPath string) {
+	var patch converter.MappingPatch
+	for _, issue := range issues {
+		if issue.Status != statusUnmapped {
+			continue
+		}
+		switch issue.Kind {
+		case kindAccountItem:
+			patch.Accounts = append(patch.Accounts, converter.PatchEntry{
+				Key:     issue.Key,
+				Account: issue.Suggested,
+				Comment: issue.Category,
+			})
+		case kindWalletable:
+			patch.Walletables = append(patch.Walletables, converter.PatchEntry{
+				Key:     issue.Key,
+				Account: issue.Suggested,
+				Comment: issue.Name,
+			})
+		case kindTaxCode:
+			code, _ := strconv.Atoi(issue.Key)
+			patch.AddTaxCode(code, issue.Name)
+		}
+	}
+
+	if patch.IsEmpty() {
+		slog.Info("Nothing unmapped, no patch written", "path", mappingPatchFile)
+		return
+	}
+
+	data, err := patch.Marshal()
+	exitOnError(err, "failed to generate mapping patch")
+
+	header := fmt.Sprintf("# Suggested additions to %s for %s to %s.\n"+
+		"# Generated by freee-sync mapping check; review before merging.\n"+
+		"# Tax codes need a rate and beancount_account.\n\n", mappingPath, mappingFrom, mappingTo)
+
+	err = os.WriteFile(mappingPatchFile, append([]byte(header), data...), 0644)
+	exitOnError(err, "failed to write mapping patch")
+
+	slog.Info("Mapping patch written", "path", mappingPatchFile)
+}
+
+// printMappingReport prints the report in human readable form.
+func printMappingReport(report *mappingReport) {
+	fmt.Printf("\n=== Mapping Coverage (%s to %s) ===\n", report.From, report.To)
+	fmt.Printf("Source: %s (%d deals, %d journals, %d transfers)\n\n", report.Source, report.Deals, report.Journals, report.Transfers)
+
+	counts := make(map[string]map[string]int)
+	for _, issue := range report.Issues {
+		if counts[issue.Kind] == nil {
+			counts[issue.Kind] = make(map[string]int)
+		}
+		counts[issue.Kind][issue.Status]++
+	}
+
+	labels := []struct{ kind, label string }{
+		{kindAccountItem, "Account items"},
+		{kindTaxCode, "Tax codes"},
+		{kindWalletable, "Walletables"},
+		{kindAccount, "Accounts"},
+	}
+	for _, l := range labels {
+		if report.Used[l.kind] == 0 && counts[l.kind] == nil {
+			continue
+		}
+		fmt.Printf("%-14s %d used, %d unmapped, %d undeclared\n", l.label+":",
+			report.Used[l.kind], counts[l.kind][statusUnmapped], counts[l.kind][statusUndeclared])
+	}
+
+	if len(report.Issues) == 0 {
+		fmt.Println("\nAll items are mapped to declared accounts")
+		return
+	}
+
+	fmt.Println()
+	for _, issue := range report.Issues {
+		fmt.Printf("  [%s] %s %s\n", issue.Status, issue.Kind, describeIssue(issue))
+	}
+	fmt.Println()
+}
+
+// describeIssue returns a one-line description of an issue.
+func describeIssue(issue mappingIssue) string {
+	var b strings.Builder
+	b.WriteString(issue.Key)
+	if issue.Name != "" && issue.Name != issue.Key {
+		fmt.Fprintf(&b, " %s", issue.Name)
+	}
+
+	details := []string{}
+	if issue.Category != "" {
+		details = append(details, issue.Category)
+	}
+	uses := "uses"
+	if issue.Uses == 1 {
+		uses = "use"
+	}
+	details = append(details, fmt.Sprintf("%d %s", issue.Uses, uses))
+	fmt.Fprintf(&b, " (%s)", strings.Join(details, ", "))
+
+	switch issue.Status {
+	case statusUnmapped:
+		if issue.Suggested != "" {
+			fmt.Fprintf(&b, ": suggest %s", issue.Suggested)
+		}
+	case statusUndeclared:
+		fmt.Fprintf(&b, ": %s has no open directive", issue.Account)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/config"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/converter"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/pathutil"
+	"github.com/spf13/cobra"
+)
+
+var (
+	openingDate   string
+	openingEquity string
+	openingDryRun bool
+	openingForce  bool
+)
+
+// openingCmd represents the opening-balances command.
+var openingCmd = &cobra.Command{
+	Use:   "opening-balances",
+	Short: "Generate opening balances from freee's balance sheet",
+	Long: `Generate opening-balances.beancount from freee's trial balance sheet.
+
+The balance of every balance sheet account item at the start of --date
+(by default the start of the current fiscal year) is booked to its mapped
+account against ` + beancount.OpeningBalancesAccount + `, so a ledger can start in the
+middle of freee's history. The equity account also receives the profit
+of the fiscal year so far when the date is not a fiscal year start.
+
+Account items with a balance but no mapping stop the command; map them
+first (freee-sync mapping check --write-patch FILE). Accounts not opened in
+the ledger on the date are reported.
+
+The file is replaced; if it already holds entries, --force is required.
+
+Example:
+  freee-sync opening-balances
+  freee-sync opening-balances --date 2024-07-01 --dry-run`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if openingDate == "" {
+			return nil
+		}
+		if _, err := time.Parse("2006-01-02", openingDate); err != nil {
+			return fmt.Errorf("invalid --date %q: expected YYYY-MM-DD", openingDate)
+		}
+		return nil
+	},
+	Run: runOpening,
+}
+
+func init() {
+	openingCmd.Flags().StringVar(&openingDate, "date", "", "Date of the opening balances (YYYY-MM-DD) (default: start of the current fiscal year)")
+	openingCmd.Flags().StringVar(&openingEquity, "equity-account", beancount.OpeningBalancesAccount, "Equity account the balances are booked against")
+	openingCmd.Flags().BoolVar(&openingDryRun, "dry-run", false, "Print the entry without writing the file")
+	openingCmd.Flags().BoolVar(&openingForce, "force", false, "Replace existing entries in the opening balances file")
+}
+
+// openingOutput is the schema of opening-balances output.
+type openingOutput struct {
+	DryRun   bool                     `json:"dry_run"`
+	Date     string                   `json:"date"`
+	File     string                   `json:"file"`
+	Written  bool                     `json:"written"`
+	Postings []openingPosting         `json:"postings"`
+	Unmapped []converter.UnmappedItem `json:"unmapped"`
+	Unopened []string                 `json:"unopened"` // accounts not open on the date
+	Entry    string                   `json:"entry"`
+}
+
+// openingPosting is an account of the opening balance entry.
+type openingPosting struct {
+	Account    string  `json:"account"`
+	Amount     float64 `json:"amount"`
+	FreeeItems string  `json:"freee_items,omitempty"`
+}
+
+func runOpening(cmd *cobra.Command, args []string) {
+	cfg, err := loadConfig()
+	exitOnError(err, "failed to load configuration")
+
+	if err := cfg.Validate(
+		config.FreeeAPIURL,
+		config.FreeeAccessToken,
+		config.FreeeCompanyID,
+		config.BeancountRoot,
+	); err != nil {
+		exitOnError(err, "invalid configuration")
+	}
+
+	pathResolver := pathutil.New(pathutil.Config{
+		BeancountRoot:  cfg.Beancount.Root,
+		DatabasePath:   cfg.Beancount.DBPath,
+		AttachmentsDir: cfg.Beancount.AttachmentsDir,
+	})
+
+	mapper, err := converter.NewMapper(cfg.MappingPath)
+	exitOnError(err, "failed to load account mapping")
+
+	client := newFreeeClient(cfg)
+
+	date := openingDate
+	if date == "" {
+		company, err := client.GetCompany()
+		exitOnError(err, "failed to fetch fiscal years (use --date)")
+		fy, ok := company.FiscalYearOf(time.Now().Format("2006-01-02"))
+		if !ok {
+			exitOnError(fmt.Errorf("no fiscal year of company %d contains today", cfg.Freee.CompanyID), "failed to determine the fiscal year start (use --date)")
+		}
+		date = fy.StartDate
+	}
+
+	slog.Info("Fetching trial balance sheet from freee", "date", date)
+	trialBS, err := client.GetTrialBS(date, date)
+	exitOnError(err, "failed to fetch trial balance sheet")
+
+	cvtr := converter.NewConverter(mapper, "JPY")
+	txn, unmapped := cvtr.ConvertOpeningBalances(trialBS, date, openingEquity)
+
+	out := openingOutput{
+		DryRun:   openingDryRun,
+		Date:     date,
+		File:     filepath.Join(pathResolver.GetBeancountRoot(), beancount.OpeningBalancesFile),
+		Unmapped: unmapped,
+	}
+	for _, p := range txn.Postings {
+		out.Postings = append(out.Postings, openingPosting{Account: p.Account, Amount: p.Amount, FreeeItems: p.Comment})
+	}
+	if len(txn.Postings) > 0 {
+		out.Entry = cvtr.FormatTransaction(txn)
+	}
+	out.Unopened = unopenedAccounts(pathResolver.GetMainFilePath(), txn, date)
+
+	switch {
+	case len(unmapped) > 0:
+		slog.Error("Refusing to write opening balances: account items without a mapping", "count", len(unmapped))
+		printOutput(out, func() { printOpening(out) })
+		os.Exit(exitFailure)
+	case len(txn.Postings) == 0:
+		exitOnError(fmt.Errorf("freee reports no balances on %s", date), "nothing to write")
+	case !openingDryRun:
+		exitOnError(checkGeneratedFile(out.File, openingForce), "refusing to replace opening balances")
+		content := fmt.Sprintf("; Opening Balances (期首残高)\n;\n; Generated by freee-sync opening-balances from freee's trial balance sheet\n; on %s. Regenerate with --force instead of editing by hand.\n\n%s",
+			time.Now().Format("2006-01-02"), out.Entry)
+		exitOnError(os.WriteFile(out.File, []byte(content), 0644), "failed to write opening balances")
+		out.Written = true
+	}
+
+	printOutput(out, func() { printOpening(out) })
+}
+
+// checkGeneratedFile returns an error if a generated file holds entries
+// and force is not set.
+func checkGeneratedFile(path string, force bool) error {
+	if force {
+		return nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	ledger, err := beancount.ParseFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if n := len(ledger.Transactions) + len(ledger.Balances); n > 0 {
+		return fmt.Errorf("%s already has %d entries; use --force to replace them", path, n)
+	}
+	return nil
+}
+
+// unopenedAccounts returns the accounts of txn that the ledger does not
+// open on or before date. It returns nil if there is no ledger.
+func unopenedAccounts(mainFile string, txn converter.BeancountTransaction, date string) []string {
+	if _, err := os.Stat(mainFile); err != nil {
+		return nil
+	}
+	ledger, err := beancount.ParseFile(mainFile)
+	if err != nil {
+		slog.Warn("Failed to parse ledger, opened accounts are not checked", "error", err)
+		return nil
+	}
+
+	opened := make(map[string]bool)
+	for _, open := range ledger.Opens {
+		if open.Date <= date {
+			opened[open.Account] = true
+		}
+	}
+
+	var unopened []string
+	for _, p := range txn.Postings {
+		if !opened[p.Account] {
+			unopened = append(unopened, p.Account)
+		}
+	}
+	sort.Strings(unopened)
+	return unopened
+}
+
+// printOpening prints the opening balances in human readable form.
+func printOpening(out openingOutput) {
+	if len(out.Unmapped) > 0 {
+		fmt.Printf("Account items with a balance on %s but no mapping:\n", out.Date)
+		for _, item := range out.Unmapped {
+			fmt.Printf("  %-20s %-12s %12d\n", item.Name, item.Category, item.Balance)
+		}
+		fmt.Println("\nMap them first: freee-sync mapping check --write-patch FILE")
+		return
+	}
+
+	if out.DryRun {
+		fmt.Printf("[DRY RUN] Would write %s:\n\n%s\n", out.File, out.Entry)
+	} else if out.Written {
+		fmt.Printf("Wrote opening balances on %s to %s (%d postings)\n", out.Date, out.File, len(out.Postings))
+	}
+
+	if len(out.Unopened) > 0 {
+		fmt.Printf("\nWarning: not open on %s: %s\n", out.Date, strings.Join(out.Unopened, ", "))
+	}
+}
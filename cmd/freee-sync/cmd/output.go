@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/config"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/db"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats selected with --output.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// Exit codes shared by all commands. Commands that report findings
+// (check, mapping check, reconcile) exit with exitFailure when they find any.
+const (
+	exitOK      = 0
+	exitFailure = 1 // Nothing was done, or an error stopped the command
+	exitPartial = 2 // Some items failed; the others were written
+)
+
+var outputFormat string
+
+// humanOut receives progress messages. With json or yaml output they go to
+// stderr so that stdout holds only the document.
+var humanOut io.Writer = os.Stdout
+
+// setupOutput validates --output.
+func setupOutput() error {
+	switch outputFormat {
+	case outputTable:
+		humanOut = os.Stdout
+	case outputJSON, outputYAML:
+		humanOut = os.Stderr
+	default:
+		return fmt.Errorf("invalid --output %q: expected json, yaml or table", outputFormat)
+	}
+	return nil
+}
+
+// structuredOutput reports whether a document is printed instead of text.
+func structuredOutput() bool {
+	return outputFormat == outputJSON || outputFormat == outputYAML
+}
+
+// printOutput prints v as JSON or YAML, or calls table for table output.
+// The YAML document has the same keys as the JSON one.
+func printOutput(v interface{}, table func()) {
+	switch outputFormat {
+	case outputJSON:
+		printJSON(v)
+	case outputYAML:
+		printYAML(v)
+	default:
+		table()
+	}
+}
+
+// printJSON writes v to stdout as indented JSON.
+func printJSON(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		slog.Error("failed to encode JSON", "error", err)
+		os.Exit(exitFailure)
+	}
+}
+
+// printYAML writes v to stdout as YAML. It goes through JSON so that the
+// json struct tags name the keys and their order is kept.
+func printYAML(v interface{}) {
+	data, err := json.Marshal(v)
+	if err == nil {
+		var node yaml.Node
+		if err = yaml.Unmarshal(data, &node); err == nil {
+			blockStyle(&node)
+			enc := yaml.NewEncoder(os.Stdout)
+			enc.SetIndent(2)
+			if err = enc.Encode(&node); err == nil {
+				err = enc.Close()
+			}
+		}
+	}
+	if err != nil {
+		slog.Error("failed to encode YAML", "error", err)
+		os.Exit(exitFailure)
+	}
+}
+
+// blockStyle clears the flow and quoting styles of a document parsed from
+// JSON; the encoder quotes strings where YAML needs it.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// errorOutput is printed on stdout when a command fails with json or yaml output.
+type errorOutput struct {
+	Error    string `json:"error"` // What failed
+	Cause    string `json:"cause"` // Underlying error
+	ExitCode int    `json:"exit_code"`
+	// Missing lists the settings a configuration error is about.
+	Missing []config.Field `json:"missing,omitempty"`
+}
+
+// fail reports an error and exits with exitFailure.
+func fail(msg string, err error) {
+	slog.Error(msg, "error", err)
+	if structuredOutput() {
+		out := errorOutput{Error: msg, Cause: err.Error(), ExitCode: exitFailure}
+		var missing *config.MissingFieldsError
+		if errors.As(err, &missing) {
+			out.Missing = missing.Fields
+		}
+		printOutput(out, nil)
+	} else {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", msg, err)
+	}
+	exit(exitFailure)
+}
+
+// exitHooks run before fail and exitForRun exit the process, which skips
+// deferred calls.
+var exitHooks []func()
+
+// atExit registers f to run before the process exits early.
+func atExit(f func()) {
+	exitHooks = append(exitHooks, f)
+}
+
+// exit runs the exit hooks in reverse order and exits with code.
+func exit(code int) {
+	for i := len(exitHooks) - 1; i >= 0; i-- {
+		exitHooks[i]()
+	}
+	os.Exit(code)
+}
+
+// runExitCode returns the exit code for the outcome of a run.
+func runExitCode(run *db.SyncRun) int {
+	switch run.Status {
+	case db.RunStatusSucceeded:
+		return exitOK
+	case db.RunStatusPartial:
+		return exitPartial
+	default:
+		return exitFailure
+	}
+}
+
+// exitForRun exits with the code for a run that did not succeed.
+func exitForRun(run *db.SyncRun) {
+	if code := runExitCode(run); code != exitOK {
+		exit(code)
+	}
+}
+
+// statsOutput is the schema of sync statistics.
+type statsOutput struct {
+	TotalDeals     int     `json:"total_deals"`
+	TotalJournals  int     `json:"total_journals"`
+	TotalTransfers int     `json:"total_transfers"`
+	TotalDocuments int     `json:"total_documents"`
+	LastSync       *string `json:"last_sync"` // null if never synced
+}
+
+func newStatsOutput(stats *db.Stats) statsOutput {
+	out := statsOutput{
+		TotalDeals:     stats.TotalDeals,
+		TotalJournals:  stats.TotalJournals,
+		TotalTransfers: stats.TotalTransfers,
+		TotalDocuments: stats.TotalDocuments,
+	}
+	if stats.LastSync.Valid {
+		lastSync := stats.LastSync.String
+		out.LastSync = &lastSync
+	}
+	return out
+}
+
+// printStats prints statistics in table form.
+func printStats(stats statsOutput) {
+	fmt.Println("\n=== Sync Statistics ===")
+	fmt.Printf("Total synced deals:     %d\n", stats.TotalDeals)
+	fmt.Printf("Total synced journals:  %d\n", stats.TotalJournals)
+	fmt.Printf("Total synced transfers: %d\n", stats.TotalTransfers)
+	fmt.Printf("Total documents:        %d\n", stats.TotalDocuments)
+	if stats.LastSync != nil {
+		fmt.Printf("Last sync:              %s\n", *stats.LastSync)
+	} else {
+		fmt.Printf("Last sync:              (never)\n")
+	}
+	fmt.Println()
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/db"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+)
+
+// syncWalletTxns writes the unbooked wallet transactions of the sync range
+// as pending entries and removes the pending entries of transactions that
+// have been booked since. Failures are recorded on the run. Dry runs write
+// nothing and return the entries they would add.
+func (c *syncContext) syncWalletTxns(opts syncOptions, run *db.SyncRun) []plannedEntry {
+	records, err := c.syncHistory.GetSyncRecordsByType(db.SyncTypeWalletTxn)
+	if err != nil {
+		slog.Error("Failed to read pending entries", "error", err)
+		run.AddError(fmt.Sprintf("wallet transactions: %v", err))
+		return nil
+	}
+
+	from, to := opts.from, opts.to
+	if opts.incremental && from == "" && to == "" {
+		if from, err = c.walletTxnsFrom(opts, records); err != nil {
+			slog.Error("Failed to read wallet transactions watermark", "error", err)
+			run.AddError(fmt.Sprintf("wallet transactions: %v", err))
+			return nil
+		}
+	}
+
+	slog.Info("Fetching wallet transactions from freee", "from", from, "to", to)
+	txns, err := c.client.FetchWalletTxns(from, to)
+	if err != nil {
+		slog.Error("Failed to fetch wallet transactions", "error", err)
+		run.AddError(fmt.Sprintf("wallet transactions: %v", err))
+		return nil
+	}
+	run.Fetched += len(txns)
+
+	fetched := make(map[int64]freee.WalletTxn, len(txns))
+	for _, txn := range txns {
+		fetched[txn.ID] = txn
+	}
+
+	// Remove the entries of transactions booked (or deleted) in freee
+	written := make(map[int64]bool, len(records))
+	for _, record := range records {
+		written[record.FreeeID] = true
+		if !c.pendingResolved(record, fetched, from, to) {
+			continue
+		}
+		if opts.dryRun {
+			slog.Info("Would remove pending entry", "wallet_txn_id", record.FreeeID, "file", record.BeancountFile)
+			continue
+		}
+		if err := c.removeEntry(record, run); err != nil {
+			slog.Error("Failed to remove pending entry", "wallet_txn_id", record.FreeeID, "error", err)
+			run.AddError(fmt.Sprintf("wallet_txn %d: %v", record.FreeeID, err))
+			continue
+		}
+		slog.Info("Removed pending entry", "wallet_txn_id", record.FreeeID, "file", record.BeancountFile)
+	}
+
+	var unbooked []freee.WalletTxn
+	for _, txn := range txns {
+		if txn.IsUnbooked() && !written[txn.ID] {
+			unbooked = append(unbooked, txn)
+		}
+	}
+	sort.SliceStable(unbooked, func(i, j int) bool { return unbooked[i].Date < unbooked[j].Date })
+	slog.Info("Unbooked wallet transactions to write", "count", len(unbooked))
+
+	var plan []plannedEntry
+	for _, txn := range unbooked {
+		monthKey := txn.Date[:7]
+		filePath, err := c.pathResolver.GetMonthFilePath(monthKey)
+		if err != nil {
+			run.AddError(fmt.Sprintf("wallet_txn %d: %v", txn.ID, err))
+			continue
+		}
+
+		formatted, record := c.builder.walletTxn(txn, opts.suspense, filePath, run)
+		if opts.dryRun {
+			entry := plannedEntry{Type: db.SyncTypeWalletTxn, FreeeID: txn.ID, File: filePath, Entry: formatted}
+			if err := checkEntry(c.validator, filePath, formatted); err != nil {
+				entry.Error = err.Error()
+			}
+			plan = append(plan, entry)
+			continue
+		}
+
+		if err := c.repo.EnsureMonthFile(monthKey); err != nil {
+			run.AddError(fmt.Sprintf("wallet_txn %d: %v", txn.ID, err))
+			continue
+		}
+		if err := checkEntry(c.validator, filePath, formatted); err != nil {
+			slog.Error("Refusing to write invalid pending entry", "wallet_txn_id", txn.ID, "error", err)
+			run.AddError(fmt.Sprintf("wallet_txn %d: %v", txn.ID, err))
+			continue
+		}
+		if err := writeEntry(c.syncHistory, c.repo, monthKey, record, formatted, run); err != nil {
+			slog.Error("Failed to write pending entry", "wallet_txn_id", txn.ID, "error", err)
+			run.AddError(fmt.Sprintf("wallet_txn %d: %v", txn.ID, err))
+		}
+	}
+
+	return plan
+}
+
+// pendingResolved reports whether the pending entry of a wallet transaction
+// has served its purpose: the transaction is no longer unbooked and the
+// deal it was booked to, if freee reports one, has been synced. A
+// transaction missing from a fetch that covered its date was deleted.
+func (c *syncContext) pendingResolved(record db.SyncRecord, fetched map[int64]freee.WalletTxn, from, to string) bool {
+	txn, ok := fetched[record.FreeeID]
+	if !ok {
+		inRange := (from == "" || record.IssueDate >= from) && (to == "" || record.IssueDate <= to)
+		return inRange
+	}
+	if txn.IsUnbooked() {
+		return false
+	}
+	if txn.DealID == nil {
+		return true
+	}
+
+	synced, err := c.syncHistory.IsSynced(db.SyncTypeDeal, *txn.DealID)
+	if err != nil {
+		slog.Warn("Failed to look up deal of wallet transaction", "wallet_txn_id", txn.ID, "deal_id", *txn.DealID, "error", err)
+		return false
+	}
+	if !synced {
+		slog.Info("Keeping pending entry until its deal is synced", "wallet_txn_id", txn.ID, "deal_id", *txn.DealID)
+	}
+	return synced
+}
+
+// removeEntry removes the entry of a synced item from its Beancount file
+// and forgets its sync record.
+func (c *syncContext) removeEntry(record db.SyncRecord, run *db.SyncRun) error {
+	key := beancount.FreeeKey(string(record.SyncType), record.FreeeID)
+	if err := replaceFileEntries(record.BeancountFile, key, "", false); err != nil {
+		return err
+	}
+	if c.validator != nil {
+		c.validator.Remove(key)
+	}
+	run.Updated++
+	run.AddFile(record.BeancountFile)
+
+	if _, err := c.syncHistory.DeleteSyncRecord(record.SyncType, record.FreeeID); err != nil {
+		return fmt.Errorf("entry removed but sync record kept: %w", err)
+	}
+	return nil
+}
+
+// walletTxnsFrom returns the first date of wallet transactions fetched by
+// an incremental run: --lookback-days before the previous successful run,
+// or the date of the oldest pending entry if that is earlier, so that its
+// transaction is seen once it is booked. An empty string (everything) is
+// returned before the first run.
+func (c *syncContext) walletTxnsFrom(opts syncOptions, records []db.SyncRecord) (string, error) {
+	from, err := incrementalDateFrom(c.syncHistory, db.WatermarkWalletTxns, c.cfg.Freee.CompanyID, opts.lookback)
+	if err != nil || from == "" {
+		return "", err
+	}
+	for _, record := range records {
+		if record.IssueDate < from {
+			from = record.IssueDate
+		}
+	}
+	return from, nil
+}
@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/config"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/converter"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/db"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/pathutil"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/reconcile"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reconcilePeriod    string
+	reconcileTolerance float64
+)
+
+// reconcileCmd represents the reconcile command.
+var reconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "Compare the ledger with freee's trial balance",
+	Long: `Compare the Beancount ledger with freee's trial balance for a period.
+
+freee's trial balance sheet (trial_bs) and profit and loss (trial_pl) are
+mapped to Beancount accounts through the account mapping and compared with
+the same figures computed from the ledger: balance sheet accounts as of the
+end of the period, income and expense accounts over the period.
+
+For each account that differs, the deals and journals of the period are
+converted again and compared with the ledger entry by entry; entries that
+are missing, changed or hand-written are listed with their freee IDs.
+
+The period is YYYY, YYYY-MM or YYYY-MM-DD..YYYY-MM-DD.
+Exits with status 1 if any account differs.
+
+Example:
+  freee-sync reconcile --period 2024-03
+  freee-sync reconcile --period 2024-04-01..2025-03-31 --output json`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		_, _, err := reconcile.ParsePeriod(reconcilePeriod)
+		return err
+	},
+	Run: runReconcile,
+}
+
+func init() {
+	reconcileCmd.Flags().StringVar(&reconcilePeriod, "period", "", "Period to reconcile (YYYY, YYYY-MM or YYYY-MM-DD..YYYY-MM-DD) (required)")
+	reconcileCmd.Flags().Float64Var(&reconcileTolerance, "tolerance", 0.5, "Largest difference treated as equal")
+
+	_ = reconcileCmd.MarkFlagRequired("period")
+}
+
+// reconcileReport is the result of reconcile.
+type reconcileReport struct {
+	From        string                 `json:"from"`
+	To          string                 `json:"to"`
+	Accounts    int                    `json:"accounts"`
+	Differences []reconcile.Difference `json:"differences"`
+}
+
+func runReconcile(cmd *cobra.Command, args []string) {
+	from, to, _ := reconcile.ParsePeriod(reconcilePeriod)
+
+	cfg, err := loadConfig()
+	exitOnError(err, "failed to load configuration")
+
+	if err := cfg.Validate(
+		config.FreeeAPIURL,
+		config.FreeeAccessToken,
+		config.FreeeCompanyID,
+		config.BeancountRoot,
+	); err != nil {
+		exitOnError(err, "invalid configuration")
+	}
+
+	pathResolver := pathutil.New(pathutil.Config{
+		BeancountRoot:  cfg.Beancount.Root,
+		DatabasePath:   cfg.Beancount.DBPath,
+		AttachmentsDir: cfg.Beancount.AttachmentsDir,
+	})
+
+	mapper, err := converter.NewMapper(cfg.MappingPath)
+	exitOnError(err, "failed to load account mapping")
+
+	ledger, err := beancount.ParseFile(pathResolver.GetMainFilePath())
+	exitOnError(err, "failed to parse ledger")
+
+	client := newFreeeClient(cfg)
+
+	slog.Info("Fetching trial balance from freee", "from", from, "to", to)
+	trialBS, err := client.GetTrialBS(from, to)
+	exitOnError(err, "failed to fetch trial balance sheet")
+	trialPL, err := client.GetTrialPL(from, to)
+	exitOnError(err, "failed to fetch trial profit and loss")
+
+	freeeFigures, items := reconcile.FreeeFigures(trialBS, mapper.ResolveAccount)
+	plFigures, plItems := reconcile.FreeeFigures(trialPL, mapper.ResolveAccount)
+	for account, amount := range plFigures {
+		freeeFigures[account] += amount
+		items[account] = append(items[account], plItems[account]...)
+	}
+
+	// Pending entries for unbooked wallet transactions are not in freee's books
+	booked := bookedTransactions(ledger.Transactions)
+	ledgerFigures := reconcile.LedgerFigures(booked, from, to, "JPY")
+
+	accounts := make(map[string]bool)
+	for account := range freeeFigures {
+		accounts[account] = true
+	}
+	for account := range ledgerFigures {
+		accounts[account] = true
+	}
+
+	report := reconcileReport{
+		From:        from,
+		To:          to,
+		Accounts:    len(accounts),
+		Differences: reconcile.Compare(freeeFigures, ledgerFigures, items, reconcileTolerance),
+	}
+
+	if len(report.Differences) > 0 {
+		expected := expectedContributions(client, converter.NewConverter(mapper, "JPY"), from, to)
+
+		actual := reconcile.NewContributions()
+		for _, txn := range booked {
+			if txn.Date >= from && txn.Date <= to {
+				actual.AddTransaction(txn, "JPY")
+			}
+		}
+
+		reconcile.Explain(report.Differences, expected, actual, reconcileTolerance)
+	}
+
+	if report.Differences == nil {
+		report.Differences = []reconcile.Difference{}
+	}
+	printOutput(report, func() { printReconcileReport(report) })
+
+	if len(report.Differences) > 0 {
+		os.Exit(exitFailure)
+	}
+}
+
+// bookedTransactions returns the transactions other than the pending
+// entries sync --pending writes for unbooked wallet transactions.
+func bookedTransactions(txns []beancount.Transaction) []beancount.Transaction {
+	var booked []beancount.Transaction
+	for _, txn := range txns {
+		if !strings.HasPrefix(txn.FreeeKey(), string(db.SyncTypeWalletTxn)+":") {
+			booked = append(booked, txn)
+		}
+	}
+	return booked
+}
+
+// expectedContributions converts the deals, journals and transfers of the
+// period as sync would and records what each contributes to each account.
+func expectedContributions(client *freee.Client, cvtr *converter.Converter, from, to string) *reconcile.Contributions {
+	slog.Info("Fetching deals, manual journals and transfers to explain differences", "from", from, "to", to)
+	deals, err := client.FetchAllDeals(from, to)
+	exitOnError(err, "failed to fetch deals")
+	journals, err := client.FetchAllManualJournals(from, to)
+	exitOnError(err, "failed to fetch manual journals")
+	transfers, err := client.FetchTransfers(from, to)
+	exitOnError(err, "failed to fetch transfers")
+
+	contributions := reconcile.NewContributions()
+	add := func(key string, txn converter.BeancountTransaction) {
+		label := strings.TrimSpace(txn.Date + " " + txn.Narration)
+		for _, posting := range txn.Postings {
+			contributions.Add(key, label, posting.Account, posting.Amount)
+		}
+	}
+
+	// The API may ignore the date filter; keep to the period like the ledger side
+	for _, deal := range deals {
+		if deal.IssueDate >= from && deal.IssueDate <= to {
+			add(beancount.FreeeKey(string(db.SyncTypeDeal), deal.ID), cvtr.ConvertDeal(deal))
+		}
+	}
+	for _, journal := range journals {
+		if journal.IssueDate >= from && journal.IssueDate <= to {
+			add(beancount.FreeeKey(string(db.SyncTypeJournal), journal.ID), cvtr.ConvertManualJournal(journal))
+		}
+	}
+	for _, transfer := range transfers {
+		add(beancount.FreeeKey(string(db.SyncTypeTransfer), transfer.ID), cvtr.ConvertTransfer(transfer))
+	}
+
+	return contributions
+}
+
+// printReconcileReport prints the report in human readable form.
+func printReconcileReport(report reconcileReport) {
+	fmt.Printf("\n=== Reconcile (%s to %s) ===\n", report.From, report.To)
+	fmt.Printf("Accounts compared: %d\n", report.Accounts)
+	fmt.Printf("Differences:       %d\n", len(report.Differences))
+
+	if len(report.Differences) == 0 {
+		fmt.Println("\nThe ledger matches freee")
+		return
+	}
+
+	for _, d := range report.Differences {
+		fmt.Printf("\n%s", d.Account)
+		if len(d.FreeeItems) > 0 {
+			fmt.Printf(" (freee: %s)", strings.Join(d.FreeeItems, ", "))
+		}
+		fmt.Println()
+		fmt.Printf("  freee %s  ledger %s  diff %s\n", formatAmount(d.Freee), formatAmount(d.Ledger), formatAmount(d.Diff()))
+
+		for _, e := range d.Explanations {
+			fmt.Printf("    %-16s %s  %s\n", e.Key, describeExplanation(e), e.Label)
+		}
+		if rest := d.Unexplained(); math.Abs(rest) > reconcileTolerance {
+			fmt.Printf("    %-16s %s  (entries before the period, conversion or account mapping)\n", "unexplained", formatAmount(rest))
+		}
+	}
+	fmt.Println()
+}
+
+// describeExplanation summarizes how an entry differs.
+func describeExplanation(e reconcile.Explanation) string {
+	switch {
+	case e.Ledger == 0:
+		return fmt.Sprintf("missing from ledger (freee %s)", formatAmount(e.Freee))
+	case e.Freee == 0 && strings.Contains(e.Key, ".beancount:"):
+		return fmt.Sprintf("hand-written entry (ledger %s)", formatAmount(e.Ledger))
+	case e.Freee == 0:
+		return fmt.Sprintf("not in freee (ledger %s)", formatAmount(e.Ledger))
+	default:
+		return fmt.Sprintf("freee %s, ledger %s", formatAmount(e.Freee), formatAmount(e.Ledger))
+	}
+}
+
+// formatAmount formats an amount with thousands separators.
+func formatAmount(amount float64) string {
+	s := fmt.Sprintf("%.0f", math.Abs(amount))
+	if amount != math.Trunc(amount) {
+		s = strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", math.Abs(amount)), "0"), ".")
+	}
+
+	intPart, frac, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	if frac != "" {
+		b.WriteString("." + frac)
+	}
+
+	if amount < 0 && b.String() != "0" {
+		return "-" + b.String()
+	}
+	return b.String()
+}
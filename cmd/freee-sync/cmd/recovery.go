@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/db"
+)
+
+// recoverySummary reports how pending records were resolved.
+type recoverySummary struct {
+	Committed   int // entry found in the file
+	Aborted     int // entry missing, record removed so the item is synced again
+	Interrupted int64
+}
+
+// recoverPendingSyncs reconciles sync_history with the Beancount files after
+// an interrupted run. A pending record whose entry is present in its file is
+// committed; one whose entry never made it to disk is removed so the next
+// sync writes it exactly once. Runs left in the running state are marked failed.
+func recoverPendingSyncs(syncHistory *db.SyncHistory) (recoverySummary, error) {
+	var summary recoverySummary
+
+	interrupted, err := syncHistory.FailInterruptedRuns()
+	if err != nil {
+		return summary, err
+	}
+	summary.Interrupted = interrupted
+
+	pending, err := syncHistory.GetPendingSyncs()
+	if err != nil {
+		return summary, err
+	}
+
+	keysByFile := make(map[string]map[string]bool)
+	for _, record := range pending {
+		keys, ok := keysByFile[record.BeancountFile]
+		if !ok {
+			keys, err = loadFreeeKeys(record.BeancountFile)
+			if err != nil {
+				return summary, err
+			}
+			keysByFile[record.BeancountFile] = keys
+		}
+
+		if keys[beancount.FreeeKey(string(record.SyncType), record.FreeeID)] {
+			if err := syncHistory.CommitSync(record.SyncType, record.FreeeID); err != nil {
+				return summary, err
+			}
+			summary.Committed++
+			slog.Info("Recovered pending sync", "type", record.SyncType, "freee_id", record.FreeeID, "file", record.BeancountFile)
+			continue
+		}
+
+		if err := syncHistory.AbortSync(record.SyncType, record.FreeeID); err != nil {
+			return summary, err
+		}
+		summary.Aborted++
+		slog.Info("Discarded pending sync", "type", record.SyncType, "freee_id", record.FreeeID, "file", record.BeancountFile)
+	}
+
+	return summary, nil
+}
+
+// loadFreeeKeys returns the freee entries present in a Beancount file.
+// A missing file holds no entries.
+func loadFreeeKeys(path string) (map[string]bool, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return map[string]bool{}, nil
+	}
+
+	ledger, err := beancount.ParseFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return ledger.FreeeKeys(), nil
+}
@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/config"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/db"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/pathutil"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/reconcile"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/recurring"
+	"github.com/spf13/cobra"
+)
+
+var (
+	recurringMonth     string
+	recurringLookback  int
+	recurringMinMonths int
+	recurringGraceDays int
+	recurringTolerance float64
+	recurringAsOf      string
+	recurringRefresh   bool
+	recurringAll       bool
+)
+
+// recurringCmd represents the recurring command.
+var recurringCmd = &cobra.Command{
+	Use:   "recurring",
+	Short: "Report missing, late or changed recurring deals of a month",
+	Long: `Learn the deals that recur every month (subscriptions, rent, utilities)
+from the synced deals of the previous months and report their occurrences
+in a month.
+
+Deals recur when they have the same partner and account item (or, without
+a partner, the same account item and description apart from digits) in at
+least --min-months of the --lookback months, including one of the last two.
+
+An occurrence is
+  missing  when no deal is synced more than --grace-days after its usual day
+  late     when the deal is dated more than --grace-days after its usual day
+  changed  when the amount is more than --amount-tolerance outside the
+           amounts seen before
+  due      when it has not occurred yet but is not missing
+
+The details of deals (partner, account item, description) are cached by
+sync, resync and rebuild. Deals synced before are read from freee with
+--refresh.
+
+Exits with status 1 if any occurrence is missing, late or changed.
+
+Example:
+  freee-sync recurring --month 2024-03
+  freee-sync recurring --month 2024-03 --refresh
+  freee-sync recurring --month 2024-03 --lookback 12 --output json`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(recurringMonth) != len("2006-01") {
+			return fmt.Errorf("invalid --month %q: expected YYYY-MM", recurringMonth)
+		}
+		if _, _, err := reconcile.ParsePeriod(recurringMonth); err != nil {
+			return err
+		}
+		if recurringAsOf != "" {
+			if _, err := time.Parse("2006-01-02", recurringAsOf); err != nil {
+				return fmt.Errorf("invalid --as-of %q: expected YYYY-MM-DD", recurringAsOf)
+			}
+		}
+		if recurringLookback < 1 || recurringMinMonths < 1 || recurringMinMonths > recurringLookback {
+			return fmt.Errorf("--min-months must be between 1 and --lookback")
+		}
+		return nil
+	},
+	Run: runRecurring,
+}
+
+func init() {
+	recurringCmd.Flags().StringVar(&recurringMonth, "month", "", "Month to check (YYYY-MM) (required)")
+	recurringCmd.Flags().IntVar(&recurringLookback, "lookback", 6, "Previous months recurring deals are learned from")
+	recurringCmd.Flags().IntVar(&recurringMinMonths, "min-months", 3, "Months a deal must occur in to be recurring")
+	recurringCmd.Flags().IntVar(&recurringGraceDays, "grace-days", 5, "Days after the usual day before an occurrence is late")
+	recurringCmd.Flags().Float64Var(&recurringTolerance, "amount-tolerance", 0.05, "Fraction the amount may differ before it is changed")
+	recurringCmd.Flags().StringVar(&recurringAsOf, "as-of", "", "Date missing occurrences are judged on (default: today)")
+	recurringCmd.Flags().BoolVar(&recurringRefresh, "refresh", false, "Read the details of the deals from freee first")
+	recurringCmd.Flags().BoolVar(&recurringAll, "all", false, "Also list occurrences that are ok or due")
+
+	_ = recurringCmd.MarkFlagRequired("month")
+}
+
+// recurringOutput is the schema of recurring output.
+type recurringOutput struct {
+	Month       string                 `json:"month"`
+	AsOf        string                 `json:"as_of"`
+	Deals       int                    `json:"deals"`    // deals the patterns were learned from
+	Uncached    int                    `json:"uncached"` // synced deals without cached details
+	Patterns    int                    `json:"patterns"` // recurring deals found
+	Problems    int                    `json:"problems"` // missing, late or changed occurrences
+	Occurrences []recurring.Occurrence `json:"occurrences"`
+}
+
+func runRecurring(cmd *cobra.Command, args []string) {
+	monthStart, to, _ := reconcile.ParsePeriod(recurringMonth)
+	start, _ := time.Parse("2006-01-02", monthStart)
+	from := start.AddDate(0, -recurringLookback, 0).Format("2006-01-02")
+
+	asOf := recurringAsOf
+	if asOf == "" {
+		asOf = time.Now().Format("2006-01-02")
+	}
+
+	cfg, err := loadConfig()
+	exitOnError(err, "failed to load configuration")
+	exitOnError(cfg.Validate(config.BeancountRoot), "invalid configuration")
+
+	pathResolver := pathutil.New(pathutil.Config{
+		BeancountRoot:  cfg.Beancount.Root,
+		DatabasePath:   cfg.Beancount.DBPath,
+		AttachmentsDir: cfg.Beancount.AttachmentsDir,
+	})
+
+	dbPath := pathResolver.GetDatabasePath()
+	if !pathResolver.FileExists(dbPath) {
+		exitOnError(fmt.Errorf("database not found: %s", dbPath), "nothing synced yet (run freee-sync sync first)")
+	}
+	conn, err := db.Open(dbPath)
+	exitOnError(err, "failed to open database")
+	defer conn.Close()
+	syncHistory := db.NewSyncHistory(conn)
+
+	if recurringRefresh {
+		exitOnError(cfg.Validate(config.FreeeAPIURL, config.FreeeAccessToken, config.FreeeCompanyID), "invalid configuration")
+		slog.Info("Fetching deals from freee", "from", from, "to", to)
+		deals, err := newFreeeClient(cfg).FetchAllDeals(from, to)
+		exitOnError(err, "failed to fetch deals")
+		exitOnError(syncHistory.SaveDealDetails(dealDetails(deals)), "failed to cache deal details")
+	}
+
+	deals, uncached, err := syncHistory.GetSyncedDealDetails(from, to)
+	exitOnError(err, "failed to read synced deals")
+	if uncached > 0 {
+		slog.Warn("Synced deals without cached details are ignored; read them with --refresh", "count", uncached)
+	}
+
+	opts := recurring.Options{
+		Lookback:        recurringLookback,
+		MinMonths:       recurringMinMonths,
+		GraceDays:       recurringGraceDays,
+		AmountTolerance: recurringTolerance,
+	}
+	patterns, err := recurring.Learn(deals, recurringMonth, opts)
+	exitOnError(err, "failed to learn recurring deals")
+	occurrences, err := recurring.Check(patterns, deals, recurringMonth, asOf, opts)
+	exitOnError(err, "failed to check recurring deals")
+
+	out := recurringOutput{
+		Month:       recurringMonth,
+		AsOf:        asOf,
+		Uncached:    uncached,
+		Patterns:    len(patterns),
+		Occurrences: []recurring.Occurrence{},
+	}
+	for _, d := range deals {
+		if d.IssueDate < monthStart {
+			out.Deals++
+		}
+	}
+	for _, o := range occurrences {
+		if o.Status.IsProblem() {
+			out.Problems++
+		}
+		if recurringAll || o.Status.IsProblem() {
+			out.Occurrences = append(out.Occurrences, o)
+		}
+	}
+
+	printOutput(out, func() { printRecurring(out) })
+
+	if out.Problems > 0 {
+		os.Exit(exitFailure)
+	}
+}
+
+// printRecurring prints the recurring deals of a month in human readable form.
+func printRecurring(out recurringOutput) {
+	fmt.Printf("Recurring deals in %s (as of %s)\n", out.Month, out.AsOf)
+	fmt.Printf("  Learned from: %d deals\n", out.Deals)
+	fmt.Printf("  Recurring:    %d\n", out.Patterns)
+	fmt.Printf("  Problems:     %d\n", out.Problems)
+	if out.Uncached > 0 {
+		fmt.Printf("  Uncached:     %d (use --refresh)\n", out.Uncached)
+	}
+
+	if len(out.Occurrences) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Printf("%-8s %-10s %-10s %10s  %s\n", "STATUS", "EXPECTED", "DATE", "AMOUNT", "DEAL")
+	for _, o := range out.Occurrences {
+		date, amount := "-", "-"
+		if o.DealID != 0 {
+			date, amount = o.Date, fmt.Sprintf("%d", o.Amount)
+		}
+		fmt.Printf("%-8s %-10s %-10s %10s  %s\n", o.Status, o.Expected, date, amount, o.Pattern.Label())
+		if o.Note != "" {
+			fmt.Printf("%-8s %s\n", "", o.Note)
+		}
+	}
+}
+
+// cacheDealDetails caches the details of fetched deals for the recurring
+// command. A failure only costs the detection, so it does not stop a sync.
+func (c *syncContext) cacheDealDetails(deals []freee.Deal) {
+	if err := c.syncHistory.SaveDealDetails(dealDetails(deals)); err != nil {
+		slog.Warn("Failed to cache deal details", "error", err)
+	}
+}
+
+// dealDetails returns the details of deals to cache with their lines. A
+// deal is described by its largest line.
+func dealDetails(deals []freee.Deal) []db.DealDetail {
+	details := make([]db.DealDetail, 0, len(deals))
+	for _, deal := range deals {
+		d := db.DealDetail{DealID: deal.ID, IssueDate: deal.IssueDate, Type: deal.Type, Amount: deal.Amount}
+		if deal.PartnerID != nil {
+			d.PartnerID = *deal.PartnerID
+		}
+		if deal.PartnerCode != nil {
+			d.PartnerCode = *deal.PartnerCode
+		}
+		var largest int64 = -1
+		for _, line := range deal.Details {
+			d.Lines = append(d.Lines, db.DealLine{
+				AccountItem: line.AccountItemName,
+				TaxCode:     line.TaxCode,
+				Amount:      line.Amount,
+				Vat:         line.Vat,
+			})
+			if line.Amount <= largest {
+				continue
+			}
+			largest = line.Amount
+			d.AccountItem = line.AccountItemName
+			d.Description = ""
+			if line.Description != nil {
+				d.Description = *line.Description
+			}
+		}
+		details = append(details, d)
+	}
+	return details
+}
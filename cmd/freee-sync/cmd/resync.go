@@ -0,0 +1,531 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/db"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+	"github.com/spf13/cobra"
+)
+
+var (
+	targetID     int64
+	targetType   string
+	targetMonth  string
+	rebuildForce bool
+)
+
+// resyncCmd represents the resync command.
+var resyncCmd = &cobra.Command{
+	Use:   "resync",
+	Short: "Re-fetch synced items from freee and replace their entries",
+	Long: `Re-fetch deals, journals or transfers from freee and replace their
+entries in the Beancount files in place. Items that were never synced are
+appended.
+
+Use this after an item was edited in freee. If its issue date moved to
+another month, the entry is moved to the new month file.
+
+Example:
+  freee-sync resync --id 123
+  freee-sync resync --id 45 --type journal
+  freee-sync resync --id 7 --type transfer
+  freee-sync resync --month 2024-01`,
+	PreRunE: requireTarget,
+	Run:     runResync,
+}
+
+// forgetCmd represents the forget command.
+var forgetCmd = &cobra.Command{
+	Use:   "forget",
+	Short: "Remove sync history records so items are synced again",
+	Long: `Remove sync history records so the next sync writes the items again.
+
+The Beancount files are not touched: delete the entries first, otherwise
+the next sync refuses to write them as duplicates. To regenerate entries
+from freee, use resync or rebuild instead.
+
+Example:
+  freee-sync forget --id 123
+  freee-sync forget --month 2024-01`,
+	PreRunE: requireTarget,
+	Run:     runForget,
+}
+
+// rebuildCmd represents the rebuild command.
+var rebuildCmd = &cobra.Command{
+	Use:   "rebuild",
+	Short: "Regenerate a month file from freee",
+	Long: `Regenerate a month file from freee, keeping its header.
+
+All synced entries are replaced by freshly converted deals, journals and
+transfers of the month; items deleted in freee disappear. Hand-written transactions
+tagged #` + beancount.ProtectedTag + ` are preserved. Other hand-written
+content makes the command fail unless --force is given, in which case it
+is dropped.
+
+Example:
+  freee-sync rebuild --month 2024-01
+  freee-sync rebuild --month 2024-01 --force`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if targetMonth == "" {
+			return fmt.Errorf("--month is required")
+		}
+		return validateMonth(targetMonth)
+	},
+	Run: runRebuild,
+}
+
+func init() {
+	for _, c := range []*cobra.Command{resyncCmd, forgetCmd} {
+		c.Flags().Int64Var(&targetID, "id", 0, "freee ID of the item")
+		c.Flags().StringVar(&targetType, "type", string(db.SyncTypeDeal), "Item type with --id (deal, journal for a manual journal, or transfer)")
+		c.Flags().StringVar(&targetMonth, "month", "", "Month of the items (YYYY-MM)")
+		c.MarkFlagsMutuallyExclusive("id", "month")
+	}
+	resyncCmd.Flags().BoolVar(&skipValidation, "skip-validation", false, "Write entries without validating them against the ledger")
+	resyncCmd.Flags().BoolVar(&skipDocuments, "skip-documents", false, "Do not download receipts attached to deals")
+
+	rebuildCmd.Flags().StringVar(&targetMonth, "month", "", "Month to rebuild (YYYY-MM) (required)")
+	rebuildCmd.Flags().BoolVar(&rebuildForce, "force", false, "Drop hand-written entries not tagged #"+beancount.ProtectedTag)
+	rebuildCmd.Flags().BoolVar(&skipValidation, "skip-validation", false, "Write entries without validating them against the ledger")
+	rebuildCmd.Flags().BoolVar(&skipDocuments, "skip-documents", false, "Do not download receipts attached to deals")
+}
+
+// requireTarget checks that exactly one of --id and --month is given.
+func requireTarget(cmd *cobra.Command, args []string) error {
+	if targetID == 0 && targetMonth == "" {
+		return fmt.Errorf("either --id or --month is required")
+	}
+	if targetMonth != "" {
+		return validateMonth(targetMonth)
+	}
+	switch db.SyncType(targetType) {
+	case db.SyncTypeDeal, db.SyncTypeJournal, db.SyncTypeTransfer:
+	default:
+		return fmt.Errorf("invalid --type %q: expected deal, journal or transfer", targetType)
+	}
+	return nil
+}
+
+// validateMonth checks a YYYY-MM month argument.
+func validateMonth(month string) error {
+	if _, err := time.Parse("2006-01", month); err != nil {
+		return fmt.Errorf("invalid month %q: expected YYYY-MM", month)
+	}
+	return nil
+}
+
+// monthRange returns the first and last day of a YYYY-MM month.
+func monthRange(month string) (string, string) {
+	first, _ := time.Parse("2006-01", month)
+	last := first.AddDate(0, 1, -1)
+	return first.Format("2006-01-02"), last.Format("2006-01-02")
+}
+
+func runResync(cmd *cobra.Command, args []string) {
+	sc := newSyncContext(!skipValidation, !skipDocuments)
+	defer sc.Close()
+	release := sc.begin()
+	defer release()
+
+	run := &db.SyncRun{CompanyID: sc.cfg.Freee.CompanyID}
+	var deals []freee.Deal
+	var journals []freee.ManualJournal
+	var transfers []freee.Transfer
+
+	if targetMonth != "" {
+		run.DateFrom, run.DateTo = monthRange(targetMonth)
+		exitOnError(sc.syncHistory.StartRun(run), "failed to start sync run")
+
+		var err error
+		deals, err = sc.client.FetchAllDeals(run.DateFrom, run.DateTo)
+		exitOnRunError(sc.syncHistory, run, err, "failed to fetch deals")
+		journals, err = sc.client.FetchAllManualJournals(run.DateFrom, run.DateTo)
+		exitOnRunError(sc.syncHistory, run, err, "failed to fetch manual journals")
+		transfers, err = sc.client.FetchTransfers(run.DateFrom, run.DateTo)
+		exitOnRunError(sc.syncHistory, run, err, "failed to fetch transfers")
+	} else {
+		switch db.SyncType(targetType) {
+		case db.SyncTypeJournal:
+			journal, err := sc.client.GetManualJournal(targetID)
+			exitOnError(err, "failed to fetch manual journal")
+			journals = append(journals, *journal)
+			run.DateFrom, run.DateTo = journal.IssueDate, journal.IssueDate
+		case db.SyncTypeTransfer:
+			transfer, err := sc.client.GetTransfer(targetID)
+			exitOnError(err, "failed to fetch transfer")
+			transfers = append(transfers, *transfer)
+			run.DateFrom, run.DateTo = transfer.Date, transfer.Date
+		default:
+			deal, err := sc.client.GetDeal(targetID)
+			exitOnError(err, "failed to fetch deal")
+			deals = append(deals, *deal)
+			run.DateFrom, run.DateTo = deal.IssueDate, deal.IssueDate
+		}
+		exitOnError(sc.syncHistory.StartRun(run), "failed to start sync run")
+	}
+	run.Fetched = len(deals) + len(journals) + len(transfers)
+	sc.cacheDealDetails(deals)
+
+	for _, deal := range deals {
+		filePath, err := sc.pathResolver.GetMonthFilePath(deal.IssueDate[:7])
+		if err != nil {
+			run.AddError(fmt.Sprintf("deal %d: %v", deal.ID, err))
+			continue
+		}
+		formatted, record := sc.builder.deal(deal, filePath, run)
+		if err := sc.replaceEntry(record, formatted, run); err != nil {
+			slog.Error("Failed to resync deal", "deal_id", deal.ID, "error", err)
+			run.AddError(fmt.Sprintf("deal %d: %v", deal.ID, err))
+		}
+	}
+	for _, journal := range journals {
+		filePath, err := sc.pathResolver.GetMonthFilePath(journal.IssueDate[:7])
+		if err != nil {
+			run.AddError(fmt.Sprintf("journal %d: %v", journal.ID, err))
+			continue
+		}
+		formatted, record := sc.builder.journal(journal, filePath, run)
+		if err := sc.replaceEntry(record, formatted, run); err != nil {
+			slog.Error("Failed to resync journal", "journal_id", journal.ID, "error", err)
+			run.AddError(fmt.Sprintf("journal %d: %v", journal.ID, err))
+		}
+	}
+	for _, transfer := range transfers {
+		filePath, err := sc.pathResolver.GetMonthFilePath(transfer.Date[:7])
+		if err != nil {
+			run.AddError(fmt.Sprintf("transfer %d: %v", transfer.ID, err))
+			continue
+		}
+		formatted, record := sc.builder.transfer(transfer, filePath, run)
+		if err := sc.replaceEntry(record, formatted, run); err != nil {
+			slog.Error("Failed to resync transfer", "transfer_id", transfer.ID, "error", err)
+			run.AddError(fmt.Sprintf("transfer %d: %v", transfer.ID, err))
+		}
+	}
+
+	finishRun(sc.syncHistory, run)
+	printOutput(runOutput{Run: run}, func() {
+		fmt.Printf("Resync run #%d: %s (updated: %d, new: %d, failed: %d)\n", run.ID, run.Status, run.Updated, run.New, run.Failed)
+	})
+	exitForRun(run)
+}
+
+// runOutput is the schema of resync output.
+type runOutput struct {
+	Run *db.SyncRun `json:"run"`
+}
+
+// replaceEntry writes a regenerated entry in place of the existing one,
+// using the same pending/committed protocol as sync. If the issue date
+// moved to another month, the new file is written before the entry is
+// removed from the old one.
+func (c *syncContext) replaceEntry(record db.SyncRecord, formatted string, run *db.SyncRun) error {
+	existing, err := c.syncHistory.GetSyncRecord(record.SyncType, record.FreeeID)
+	if err != nil {
+		return err
+	}
+
+	key := beancount.FreeeKey(string(record.SyncType), record.FreeeID)
+	if c.validator != nil {
+		c.validator.Remove(key)
+	}
+	if err := checkEntry(c.validator, record.BeancountFile, formatted); err != nil {
+		return err
+	}
+
+	if err := c.syncHistory.BeginSync(record); err != nil {
+		return err
+	}
+
+	if err := c.repo.EnsureMonthFile(record.IssueDate[:7]); err != nil {
+		return c.abortReplace(record, existing, err)
+	}
+	// A synced entry must be found where it was written; appending a
+	// second copy would duplicate entries the file lost or never keyed
+	moved := existing != nil && existing.BeancountFile != record.BeancountFile
+	if err := replaceFileEntries(record.BeancountFile, key, formatted, existing != nil && !moved); err != nil {
+		return c.abortReplace(record, existing, err)
+	}
+	if moved {
+		if err := replaceFileEntries(existing.BeancountFile, key, "", true); err != nil {
+			if undoErr := replaceFileEntries(record.BeancountFile, key, "", false); undoErr != nil {
+				slog.Error("Failed to undo moved entry", "file", record.BeancountFile, "key", key, "error", undoErr)
+			}
+			return c.abortReplace(record, existing, fmt.Errorf("remove from %s: %w", existing.BeancountFile, err))
+		}
+		run.AddFile(existing.BeancountFile)
+	}
+
+	if existing != nil {
+		run.Updated++
+	} else {
+		run.New++
+	}
+	run.AddFile(record.BeancountFile)
+
+	if err := c.syncHistory.CommitSync(record.SyncType, record.FreeeID); err != nil {
+		slog.Warn("Failed to commit sync, will recover on next run", "key", key, "error", err)
+		run.AddError(fmt.Sprintf("%s: %v", key, err))
+	}
+	return nil
+}
+
+// abortReplace undoes the pending record of a failed replacement: the
+// previous record is restored, or the pending one dropped for a new item.
+func (c *syncContext) abortReplace(record db.SyncRecord, existing *db.SyncRecord, err error) error {
+	var undoErr error
+	if existing != nil {
+		undoErr = c.syncHistory.RecordSync(*existing)
+	} else {
+		undoErr = c.syncHistory.AbortSync(record.SyncType, record.FreeeID)
+	}
+	if undoErr != nil {
+		slog.Error("Failed to abort sync", "type", record.SyncType, "freee_id", record.FreeeID, "error", undoErr)
+	}
+	return err
+}
+
+// replaceFileEntries replaces the entries of a freee item in a Beancount
+// file with formatted, at the position of the first old entry. New entries
+// are appended, unless update is set, which requires the item to be in the
+// file. An empty formatted removes the item. The file is replaced
+// atomically. Files of a closed year are not written.
+func replaceFileEntries(path, key, formatted string, update bool) error {
+	if err := beancount.CheckWritable(path); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	header, entries := beancount.SplitEntries(string(data))
+	var replacement []beancount.Entry
+	if formatted != "" {
+		_, replacement = beancount.SplitEntries(formatted)
+	}
+
+	var result []beancount.Entry
+	inserted := false
+	for _, e := range entries {
+		if e.FreeeKey() != key {
+			result = append(result, e)
+			continue
+		}
+		if !inserted {
+			result = append(result, replacement...)
+			inserted = true
+		}
+	}
+	if !inserted {
+		if update {
+			return fmt.Errorf("no entry for %s in %s", key, path)
+		}
+		result = append(result, replacement...)
+	}
+
+	return beancount.WriteFileAtomic(path, []byte(beancount.JoinEntries(header, result)))
+}
+
+func runForget(cmd *cobra.Command, args []string) {
+	conn, syncHistory := openSyncHistory()
+	defer conn.Close()
+
+	out := forgetOutput{Month: targetMonth}
+	if targetMonth != "" {
+		count, err := syncHistory.DeleteSyncRecordsByMonth(targetMonth)
+		exitOnError(err, "failed to forget sync records")
+		out.Forgotten = int(count)
+	} else {
+		deleted, err := syncHistory.DeleteSyncRecord(db.SyncType(targetType), targetID)
+		exitOnError(err, "failed to forget sync record")
+		if !deleted {
+			exitOnError(fmt.Errorf("no sync record for %s %d", targetType, targetID), "failed to forget sync record")
+		}
+		out.Type = db.SyncType(targetType)
+		out.FreeeID = targetID
+		out.Forgotten = 1
+	}
+
+	printOutput(out, func() {
+		if out.Month != "" {
+			fmt.Printf("Forgot %d sync record(s) for %s\n", out.Forgotten, out.Month)
+		} else {
+			fmt.Printf("Forgot %s %d\n", out.Type, out.FreeeID)
+		}
+		fmt.Println("Remove the entries from the Beancount files before the next sync, or it will refuse them as duplicates.")
+	})
+}
+
+// forgetOutput is the schema of forget output.
+type forgetOutput struct {
+	Month     string      `json:"month,omitempty"`
+	Type      db.SyncType `json:"type,omitempty"`
+	FreeeID   int64       `json:"freee_id,omitempty"`
+	Forgotten int         `json:"forgotten"`
+}
+
+func runRebuild(cmd *cobra.Command, args []string) {
+	sc := newSyncContext(!skipValidation, !skipDocuments)
+	defer sc.Close()
+	release := sc.begin()
+	defer release()
+
+	filePath, err := sc.pathResolver.GetMonthFilePath(targetMonth)
+	exitOnError(err, "invalid month")
+
+	// Classify the current content of the month file
+	exitOnError(sc.repo.EnsureMonthFile(targetMonth), "failed to ensure month file")
+	data, err := os.ReadFile(filePath)
+	exitOnError(err, "failed to read month file")
+
+	header, entries := beancount.SplitEntries(string(data))
+	var kept []beancount.Entry
+	var unprotected []beancount.Entry
+	oldKeys := make(map[string]bool)
+	for _, e := range entries {
+		switch {
+		case e.FreeeKey() != "":
+			oldKeys[e.FreeeKey()] = true
+		case e.IsProtected():
+			kept = append(kept, e)
+		default:
+			unprotected = append(unprotected, e)
+		}
+	}
+
+	if len(unprotected) > 0 && !rebuildForce {
+		for _, e := range unprotected {
+			fmt.Fprintf(os.Stderr, "  %s\n", strings.SplitN(e.Text, "\n", 2)[0])
+		}
+		fmt.Fprintf(os.Stderr, "Tag them #%s to keep them, or use --force to drop them.\n", beancount.ProtectedTag)
+		exitOnError(fmt.Errorf("%s contains %d hand-written entries not tagged #%s", filePath, len(unprotected), beancount.ProtectedTag), "refusing to rebuild")
+	}
+
+	run := &db.SyncRun{CompanyID: sc.cfg.Freee.CompanyID}
+	run.DateFrom, run.DateTo = monthRange(targetMonth)
+	exitOnError(sc.syncHistory.StartRun(run), "failed to start sync run")
+
+	deals, err := sc.client.FetchAllDeals(run.DateFrom, run.DateTo)
+	exitOnRunError(sc.syncHistory, run, err, "failed to fetch deals")
+	journals, err := sc.client.FetchAllManualJournals(run.DateFrom, run.DateTo)
+	exitOnRunError(sc.syncHistory, run, err, "failed to fetch manual journals")
+	transfers, err := sc.client.FetchTransfers(run.DateFrom, run.DateTo)
+	exitOnRunError(sc.syncHistory, run, err, "failed to fetch transfers")
+	run.Fetched = len(deals) + len(journals) + len(transfers)
+	sc.cacheDealDetails(deals)
+
+	// Regenerated entries replace the old ones, so they are not duplicates
+	if sc.validator != nil {
+		for key := range oldKeys {
+			sc.validator.Remove(key)
+		}
+	}
+
+	protected := len(kept)
+	var records []db.SyncRecord
+	addEntry := func(formatted string, record db.SyncRecord) {
+		key := beancount.FreeeKey(string(record.SyncType), record.FreeeID)
+		if err := checkEntry(sc.validator, filePath, formatted); err != nil {
+			slog.Error("Refusing to write invalid entry", "key", key, "error", err)
+			run.AddError(fmt.Sprintf("%s: %v", key, err))
+			return
+		}
+		_, generated := beancount.SplitEntries(formatted)
+		kept = append(kept, generated...)
+		records = append(records, record)
+	}
+	for _, deal := range deals {
+		addEntry(sc.builder.deal(deal, filePath, run))
+	}
+	for _, journal := range journals {
+		addEntry(sc.builder.journal(journal, filePath, run))
+	}
+	for _, transfer := range transfers {
+		addEntry(sc.builder.transfer(transfer, filePath, run))
+	}
+
+	// Two-phase: pending records, atomic file replacement, commit
+	previous := make([]*db.SyncRecord, len(records))
+	for i, record := range records {
+		previous[i], err = sc.syncHistory.GetSyncRecord(record.SyncType, record.FreeeID)
+		exitOnRunError(sc.syncHistory, run, err, "failed to read sync record")
+		err = sc.syncHistory.BeginSync(record)
+		exitOnRunError(sc.syncHistory, run, err, "failed to record pending sync")
+	}
+
+	beancount.SortEntries(kept)
+	err = beancount.WriteFileAtomic(filePath, []byte(beancount.JoinEntries(header, kept)))
+	if err != nil {
+		for i, record := range records {
+			sc.abortReplace(record, previous[i], err)
+		}
+		exitOnRunError(sc.syncHistory, run, err, "failed to write month file")
+	}
+	run.AddFile(filePath)
+
+	written := make(map[string]bool)
+	for i, record := range records {
+		key := beancount.FreeeKey(string(record.SyncType), record.FreeeID)
+		written[key] = true
+		if previous[i] != nil {
+			run.Updated++
+		} else {
+			run.New++
+		}
+		if err := sc.syncHistory.CommitSync(record.SyncType, record.FreeeID); err != nil {
+			slog.Warn("Failed to commit sync, will recover on next run", "key", key, "error", err)
+			run.AddError(fmt.Sprintf("%s: %v", key, err))
+		}
+	}
+
+	// Forget items that are no longer in the file (deleted in freee or invalid)
+	stale, err := sc.syncHistory.GetSyncRecordsByFile(filePath)
+	exitOnRunError(sc.syncHistory, run, err, "failed to read sync records")
+	removed := 0
+	for _, record := range stale {
+		if written[beancount.FreeeKey(string(record.SyncType), record.FreeeID)] {
+			continue
+		}
+		if _, err := sc.syncHistory.DeleteSyncRecord(record.SyncType, record.FreeeID); err != nil {
+			run.AddError(fmt.Sprintf("%s %d: %v", record.SyncType, record.FreeeID, err))
+			continue
+		}
+		removed++
+	}
+
+	finishRun(sc.syncHistory, run)
+
+	out := rebuildOutput{
+		Run:       run,
+		File:      filePath,
+		FromFreee: len(records),
+		Kept:      protected,
+		Dropped:   len(unprotected),
+		Removed:   removed,
+	}
+	printOutput(out, func() {
+		fmt.Printf("Rebuilt %s: %d entries from freee, %d hand-written kept, %d dropped, %d removed item(s)\n",
+			out.File, out.FromFreee, out.Kept, out.Dropped, out.Removed)
+		fmt.Printf("Rebuild run #%d: %s (updated: %d, new: %d, failed: %d)\n", run.ID, run.Status, run.Updated, run.New, run.Failed)
+	})
+	exitForRun(run)
+}
+
+// rebuildOutput is the schema of rebuild output.
+type rebuildOutput struct {
+	Run       *db.SyncRun `json:"run"`
+	File      string      `json:"file"`
+	FromFreee int         `json:"from_freee"` // Entries regenerated from freee
+	Kept      int         `json:"kept"`       // Hand-written entries tagged #manual
+	Dropped   int         `json:"dropped"`    // Hand-written entries dropped with --force
+	Removed   int         `json:"removed"`    // Sync records of items no longer in the file
+}
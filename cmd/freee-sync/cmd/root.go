@@ -2,15 +2,16 @@
 package cmd
 
 import (
-	"fmt"
 	"log/slog"
 	"os"
 
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/config"
 	"github.com/spf13/cobra"
 )
 
 var (
 	cfgFile string
+	profile string
 	debug   bool
 )
 
@@ -25,12 +26,28 @@ It supports:
 - Syncing deals and journals from freee
 - Converting to Beancount format
 - Preventing duplicate syncs with SQLite history
+- Validating the ledger before writing
 - Dry-run mode for testing
 
+Settings are read from a config file (freee-automation.yaml or .toml)
+with named profiles, one per company or ledger; select one with
+--profile or FREEE_PROFILE. Environment variables and .env override the
+file.
+
+Commands print tables by default; --output json or --output yaml prints
+a document with a stable schema instead, including on errors.
+
+Exit codes:
+  0  success
+  1  failure: nothing was done, an error stopped the command, or a
+     check found problems
+  2  partial failure: some items failed, the others were written
+
 Example:
   freee-sync sync --from 2024-01-01 --to 2024-01-31
+  freee-sync check
   freee-sync stats`,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		// Setup logging
 		logLevel := slog.LevelInfo
 		if debug {
@@ -41,6 +58,8 @@ Example:
 			Level: logLevel,
 		}))
 		slog.SetDefault(logger)
+
+		return setupOutput()
 	},
 }
 
@@ -52,27 +71,49 @@ func Execute() error {
 
 func init() {
 	// Global flags
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is .env)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (.yaml, .yml or .toml; any other file is loaded as .env) (default: search freee-automation.yaml)")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "config profile to use (default: FREEE_PROFILE or default_profile)")
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "enable debug logging")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputTable, "output format: table, json or yaml")
 
 	// Add subcommands
 	rootCmd.AddCommand(syncCmd)
 	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(checkCmd)
+	rootCmd.AddCommand(dbCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(showRunCmd)
+	rootCmd.AddCommand(resyncCmd)
+	rootCmd.AddCommand(forgetCmd)
+	rootCmd.AddCommand(rebuildCmd)
+	rootCmd.AddCommand(mappingCmd)
+	rootCmd.AddCommand(reconcileCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(openingCmd)
+	rootCmd.AddCommand(allocateCmd)
+	rootCmd.AddCommand(closeYearCmd)
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(recurringCmd)
+	rootCmd.AddCommand(duplicatesCmd)
+	rootCmd.AddCommand(consumptionTaxCmd)
 }
 
-// Helper function to get config file path.
-func getConfigFile() string {
-	if cfgFile != "" {
-		return cfgFile
+// loadConfig loads the configuration of the selected profile.
+func loadConfig() (*config.Config, error) {
+	opts := config.Options{Profile: profile}
+	if config.IsConfigFile(cfgFile) {
+		opts.File = cfgFile
+	} else {
+		opts.EnvFile = cfgFile
 	}
-	return "" // Will use default .env loading
+	return config.LoadWith(opts)
 }
 
 // Helper function to handle errors and exit.
 func exitOnError(err error, msg string) {
 	if err != nil {
-		slog.Error(msg, "error", err)
-		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", msg, err)
-		os.Exit(1)
+		fail(msg, err)
 	}
 }
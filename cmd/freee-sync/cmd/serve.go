@@ -0,0 +1,368 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveInterval time.Duration
+	serveAddr     string
+)
+
+// serveCmd represents the serve command.
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run incremental syncs on a schedule",
+	Long: `Run as a daemon that performs an incremental sync at start and then
+every --interval.
+
+Only one process writes the ledger at a time: each run takes a lock in the
+SQLite database, and a run that finds it held by sync, resync or rebuild
+is skipped. --from/--to are used until the first run has stored the
+incremental watermarks. With --pending, unbooked wallet transactions are
+written as pending entries as in sync --pending.
+
+On SIGINT or SIGTERM the current run stops before its next entry, is
+recorded as incomplete without advancing the watermarks, and the daemon
+exits.
+
+HTTP endpoints (disabled with --addr ""):
+  /health   JSON status of the daemon and the last run
+  /metrics  Prometheus text format metrics
+
+Example:
+  freee-sync serve --interval 15m --addr :9090
+  freee-sync serve --from 2024-01-01 --to 2024-12-31`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if serveInterval <= 0 {
+			return fmt.Errorf("--interval must be positive")
+		}
+		if (dateFrom == "") != (dateTo == "") {
+			return fmt.Errorf("--from and --to must be given together")
+		}
+		return nil
+	},
+	Run: runServe,
+}
+
+func init() {
+	serveCmd.Flags().DurationVar(&serveInterval, "interval", 15*time.Minute, "Time between sync runs")
+	serveCmd.Flags().StringVar(&serveAddr, "addr", ":9090", "Listen address for /health and /metrics (empty to disable)")
+	serveCmd.Flags().StringVar(&dateFrom, "from", "", "Start date (YYYY-MM-DD) for the first run without watermarks")
+	serveCmd.Flags().StringVar(&dateTo, "to", "", "End date (YYYY-MM-DD) for the first run without watermarks")
+	serveCmd.Flags().DurationVar(&overlap, "overlap", 10*time.Minute, "Overlap window subtracted from the watermark")
+	serveCmd.Flags().BoolVar(&skipValidation, "skip-validation", false, "Write entries without validating them against the ledger")
+	serveCmd.Flags().BoolVar(&skipDocuments, "skip-documents", false, "Do not download receipts attached to deals")
+	serveCmd.Flags().BoolVar(&syncPending, "pending", false, "Write unbooked wallet transactions as pending entries")
+	serveCmd.Flags().IntVar(&lookbackDays, "lookback-days", 31, "Days before the previous run fetched by date")
+	serveCmd.Flags().StringVar(&suspenseAcct, "suspense-account", beancount.SuspenseAccount, "Account pending entries are booked against")
+}
+
+// serveState tracks the runs of the daemon for /health and /metrics.
+type serveState struct {
+	mu          sync.Mutex
+	started     time.Time
+	running     bool
+	runs        map[string]int // outcome -> runs
+	written     int
+	failed      int
+	lastRun     *db.SyncRun
+	lastError   string
+	lastRunAt   time.Time
+	lastSuccess time.Time
+	lastTook    time.Duration
+}
+
+// Run outcomes counted by the daemon besides the run statuses.
+const (
+	outcomeSkipped = "skipped" // lock held by another process
+	outcomeError   = "error"   // run could not be carried out
+)
+
+func newServeState() *serveState {
+	return &serveState{
+		started: time.Now(),
+		runs:    make(map[string]int),
+	}
+}
+
+func (s *serveState) begin() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = true
+}
+
+// finish records the outcome of a run. run is nil if the run was skipped
+// or could not be started.
+func (s *serveState) finish(start time.Time, run *db.SyncRun, outcome string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.running = false
+	s.runs[outcome]++
+	s.lastRunAt = start
+	s.lastTook = time.Since(start)
+	s.lastError = ""
+	if err != nil {
+		s.lastError = err.Error()
+	}
+	if run != nil {
+		s.lastRun = run
+		s.written += run.New
+		s.failed += run.Failed
+		if run.Status == db.RunStatusSucceeded {
+			s.lastSuccess = start
+		}
+	}
+}
+
+// serveHealth is the response of /health.
+type serveHealth struct {
+	Status      string      `json:"status"` // ok, or degraded after a failed run
+	Running     bool        `json:"running"`
+	Uptime      string      `json:"uptime"`
+	Interval    string      `json:"interval"`
+	LastRunAt   *time.Time  `json:"last_run_at,omitempty"`
+	LastSuccess *time.Time  `json:"last_success_at,omitempty"`
+	LastError   string      `json:"last_error,omitempty"`
+	LastRun     *db.SyncRun `json:"last_run,omitempty"`
+}
+
+func (s *serveState) health() serveHealth {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h := serveHealth{
+		Status:    "ok",
+		Running:   s.running,
+		Uptime:    time.Since(s.started).Round(time.Second).String(),
+		Interval:  serveInterval.String(),
+		LastError: s.lastError,
+		LastRun:   s.lastRun,
+	}
+	if !s.lastRunAt.IsZero() {
+		at := s.lastRunAt
+		h.LastRunAt = &at
+	}
+	if !s.lastSuccess.IsZero() {
+		at := s.lastSuccess
+		h.LastSuccess = &at
+	}
+	if s.lastError != "" || (s.lastRun != nil && s.lastRun.Status != db.RunStatusSucceeded) {
+		h.Status = "degraded"
+	}
+	return h
+}
+
+func (s *serveState) handleHealth(w http.ResponseWriter, r *http.Request) {
+	h := s.health()
+	w.Header().Set("Content-Type", "application/json")
+	if h.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(h); err != nil {
+		slog.Error("Failed to write health response", "error", err)
+	}
+}
+
+func (s *serveState) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintln(w, "# HELP freee_sync_runs_total Sync runs by outcome.")
+	fmt.Fprintln(w, "# TYPE freee_sync_runs_total counter")
+	for _, outcome := range []string{
+		string(db.RunStatusSucceeded), string(db.RunStatusPartial), string(db.RunStatusFailed),
+		outcomeSkipped, outcomeError,
+	} {
+		fmt.Fprintf(w, "freee_sync_runs_total{outcome=%q} %d\n", outcome, s.runs[outcome])
+	}
+
+	fmt.Fprintln(w, "# HELP freee_sync_items_written_total Deals and journals written to the ledger.")
+	fmt.Fprintln(w, "# TYPE freee_sync_items_written_total counter")
+	fmt.Fprintf(w, "freee_sync_items_written_total %d\n", s.written)
+
+	fmt.Fprintln(w, "# HELP freee_sync_items_failed_total Deals and journals that could not be written.")
+	fmt.Fprintln(w, "# TYPE freee_sync_items_failed_total counter")
+	fmt.Fprintf(w, "freee_sync_items_failed_total %d\n", s.failed)
+
+	fmt.Fprintln(w, "# HELP freee_sync_running Whether a sync run is in progress.")
+	fmt.Fprintln(w, "# TYPE freee_sync_running gauge")
+	running := 0
+	if s.running {
+		running = 1
+	}
+	fmt.Fprintf(w, "freee_sync_running %d\n", running)
+
+	fmt.Fprintln(w, "# HELP freee_sync_last_run_timestamp_seconds Start time of the last run.")
+	fmt.Fprintln(w, "# TYPE freee_sync_last_run_timestamp_seconds gauge")
+	fmt.Fprintf(w, "freee_sync_last_run_timestamp_seconds %d\n", unixOrZero(s.lastRunAt))
+
+	fmt.Fprintln(w, "# HELP freee_sync_last_success_timestamp_seconds Start time of the last run without failures.")
+	fmt.Fprintln(w, "# TYPE freee_sync_last_success_timestamp_seconds gauge")
+	fmt.Fprintf(w, "freee_sync_last_success_timestamp_seconds %d\n", unixOrZero(s.lastSuccess))
+
+	fmt.Fprintln(w, "# HELP freee_sync_last_run_duration_seconds Duration of the last run.")
+	fmt.Fprintln(w, "# TYPE freee_sync_last_run_duration_seconds gauge")
+	fmt.Fprintf(w, "freee_sync_last_run_duration_seconds %.3f\n", s.lastTook.Seconds())
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func runServe(cmd *cobra.Command, args []string) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// The ledger is validated per run; it may change between runs
+	sc := newSyncContext(false, !skipDocuments)
+	defer sc.Close()
+
+	state := newServeState()
+
+	var server *http.Server
+	if serveAddr != "" {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/health", state.handleHealth)
+		mux.HandleFunc("/metrics", state.handleMetrics)
+		server = &http.Server{Addr: serveAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+		go func() {
+			slog.Info("Serving health and metrics", "addr", serveAddr)
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("HTTP server failed", "error", err)
+				stop()
+			}
+		}()
+	}
+
+	slog.Info("Starting scheduler", "interval", serveInterval)
+	ticker := time.NewTicker(serveInterval)
+	defer ticker.Stop()
+
+	sc.scheduledRun(ctx, state)
+	for ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+			sc.scheduledRun(ctx, state)
+		}
+	}
+
+	slog.Info("Shutting down")
+	if server != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Failed to shut down HTTP server", "error", err)
+		}
+	}
+}
+
+// scheduledRun performs one incremental sync under the sync lock and
+// records its outcome. Errors are logged; the daemon keeps running.
+func (c *syncContext) scheduledRun(ctx context.Context, state *serveState) {
+	start := time.Now()
+	state.begin()
+
+	release, err := c.lock()
+	if errors.Is(err, db.ErrLockHeld) {
+		slog.Warn("Skipping run, another sync is running", "error", err)
+		state.finish(start, nil, outcomeSkipped, nil)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to take sync lock", "error", err)
+		state.finish(start, nil, outcomeError, err)
+		return
+	}
+	defer release()
+
+	if err := c.recover(); err != nil {
+		slog.Error("Failed to recover pending syncs", "error", err)
+		state.finish(start, nil, outcomeError, err)
+		return
+	}
+
+	c.validator = nil
+	if !skipValidation {
+		c.validator, err = loadLedgerValidator(c.pathResolver)
+		if err != nil {
+			slog.Error("Failed to load ledger for validation", "error", err)
+			state.finish(start, nil, outcomeError, err)
+			return
+		}
+	}
+
+	// The date range only bounds the run that establishes the watermarks;
+	// later runs fetch everything updated since, whatever its issue date
+	from, to := dateFrom, dateTo
+	stored, err := watermarksStored(c.syncHistory, c.cfg.Freee.CompanyID)
+	if err != nil {
+		slog.Error("Failed to read watermarks", "error", err)
+		state.finish(start, nil, outcomeError, err)
+		return
+	}
+	if stored {
+		from, to = "", ""
+	}
+
+	run, _, err := c.syncItems(ctx, syncOptions{
+		from:        from,
+		to:          to,
+		incremental: true,
+		overlap:     overlap,
+		lookback:    lookbackDays,
+		pending:     syncPending,
+		suspense:    suspenseAcct,
+	})
+	if err != nil {
+		slog.Error("Sync run failed", "error", err)
+		outcome := outcomeError
+		if run != nil && run.ID != 0 {
+			outcome = string(run.Status)
+		}
+		state.finish(start, run, outcome, err)
+		return
+	}
+
+	slog.Info("Sync run finished", "run_id", run.ID, "status", run.Status, "new", run.New, "failed", run.Failed, "took", time.Since(start).Round(time.Millisecond))
+	state.finish(start, run, string(run.Status), nil)
+}
+
+// watermarksStored reports whether incremental runs have stored the
+// watermarks of all resources they fetch by update time.
+func watermarksStored(syncHistory *db.SyncHistory, companyID int64) (bool, error) {
+	for _, resource := range []string{db.WatermarkDeals, db.WatermarkJournals} {
+		watermark, err := syncHistory.GetWatermark(resource, companyID)
+		if err != nil {
+			return false, err
+		}
+		if watermark.IsZero() {
+			return false, nil
+		}
+	}
+	return true, nil
+}
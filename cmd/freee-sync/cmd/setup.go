@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/config"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/converter"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/db"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/pathutil"
+)
+
+// syncContext holds the components shared by commands that write freee
+// data to the ledger (sync, resync, rebuild).
+type syncContext struct {
+	cfg          *config.Config
+	pathResolver *pathutil.PathResolver
+	conn         *db.Connection
+	syncHistory  *db.SyncHistory
+	client       *freee.Client
+	cvtr         *converter.Converter
+	repo         *beancount.FileSystemRepository
+	validator    *beancount.Validator // nil when validation is skipped
+	builder      *entryBuilder
+}
+
+// newSyncContext loads configuration and initializes all components.
+// It exits on error like the other command helpers.
+func newSyncContext(validate, documents bool) *syncContext {
+	// Load configuration
+	cfg, err := loadConfig()
+	exitOnError(err, "failed to load configuration")
+
+	// Validate required fields
+	if err := cfg.Validate(
+		config.FreeeAPIURL,
+		config.FreeeAccessToken,
+		config.FreeeCompanyID,
+		config.BeancountRoot,
+	); err != nil {
+		exitOnError(err, "invalid configuration")
+	}
+
+	// Initialize components
+	pathResolver := pathutil.New(pathutil.Config{
+		BeancountRoot:  cfg.Beancount.Root,
+		DatabasePath:   cfg.Beancount.DBPath,
+		AttachmentsDir: cfg.Beancount.AttachmentsDir,
+	})
+
+	// Open database
+	dbPath := pathResolver.GetDatabasePath()
+	slog.Debug("Opening database", "path", dbPath)
+	conn, err := db.Open(dbPath)
+	exitOnError(err, "failed to open database")
+
+	syncHistory := db.NewSyncHistory(conn)
+
+	// Initialize freee API client
+	freeeClient := newFreeeClient(cfg)
+
+	// Initialize account mapper
+	mapper, err := converter.NewMapper(cfg.MappingPath)
+	exitOnError(err, "failed to load account mapping")
+
+	// Initialize converter
+	cvtr := converter.NewConverter(mapper, "JPY")
+
+	// Load ledger validator
+	var validator *beancount.Validator
+	if validate {
+		validator, err = loadLedgerValidator(pathResolver)
+		exitOnError(err, "failed to load ledger for validation")
+		if validator == nil {
+			slog.Warn("Ledger not found, entries will not be validated", "path", pathResolver.GetMainFilePath())
+		}
+	}
+
+	// Receipts of deals are downloaded next to the ledger
+	builder := &entryBuilder{cvtr: cvtr, companyID: cfg.Freee.CompanyID}
+	if documents {
+		builder.receipts = &receiptDownloader{
+			client:       freeeClient,
+			syncHistory:  syncHistory,
+			pathResolver: pathResolver,
+		}
+	}
+
+	return &syncContext{
+		cfg:          cfg,
+		pathResolver: pathResolver,
+		conn:         conn,
+		syncHistory:  syncHistory,
+		client:       freeeClient,
+		cvtr:         cvtr,
+		repo:         beancount.NewFileSystemRepository(pathResolver),
+		validator:    validator,
+		builder:      builder,
+	}
+}
+
+// newFreeeClient creates a freee API client from configuration.
+func newFreeeClient(cfg *config.Config) *freee.Client {
+	return freee.NewClient(freee.ClientConfig{
+		APIURL:      cfg.Freee.APIURL,
+		AccessToken: cfg.Freee.AccessToken,
+		CompanyID:   cfg.Freee.CompanyID,
+		Timeout:     30 * time.Second,
+	})
+}
+
+// Close closes the database connection.
+func (c *syncContext) Close() {
+	c.conn.Close()
+}
+
+// syncLockTTL is how long the sync lock outlives a holder that stopped
+// refreshing it, e.g. after a crash.
+const syncLockTTL = 2 * time.Minute
+
+// lock takes the sync lock and keeps it alive until the returned release
+// function is called. It returns an error wrapping db.ErrLockHeld if
+// another process is writing the ledger.
+func (c *syncContext) lock() (func(), error) {
+	owner := db.LockOwner()
+	if err := c.syncHistory.AcquireLock(db.SyncLock, owner, syncLockTTL); err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(syncLockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := c.syncHistory.RefreshLock(db.SyncLock, owner, syncLockTTL); err != nil {
+					slog.Error("Failed to refresh sync lock", "error", err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+			if err := c.syncHistory.ReleaseLock(db.SyncLock, owner); err != nil {
+				slog.Error("Failed to release sync lock", "error", err)
+			}
+		})
+	}
+	return release, nil
+}
+
+// begin takes the sync lock and recovers records left pending by an
+// interrupted run. It exits on error like the other command helpers; the
+// returned function releases the lock. The lock is also released when the
+// command exits early through fail or exitForRun.
+func (c *syncContext) begin() func() {
+	release, err := c.lock()
+	if errors.Is(err, db.ErrLockHeld) {
+		exitOnError(err, "another sync is running")
+	}
+	exitOnError(err, "failed to take sync lock")
+
+	atExit(release)
+
+	if err := c.recover(); err != nil {
+		exitOnError(err, "failed to recover pending syncs")
+	}
+	return release
+}
+
+// recover reconciles records left pending by an interrupted run. The
+// caller must hold the sync lock, or it would fail runs still in progress.
+func (c *syncContext) recover() error {
+	summary, err := recoverPendingSyncs(c.syncHistory)
+	if err != nil {
+		return err
+	}
+	if summary.Committed+summary.Aborted > 0 || summary.Interrupted > 0 {
+		fmt.Fprintf(humanOut, "Recovered interrupted sync: %d committed, %d discarded, %d run(s) marked failed\n",
+			summary.Committed, summary.Aborted, summary.Interrupted)
+	}
+	return nil
+}
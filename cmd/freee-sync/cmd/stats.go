@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"fmt"
 	"log/slog"
 
 	"github.com/shunichi-ikebuchi/accounting-system/pkg/config"
@@ -19,11 +18,13 @@ var statsCmd = &cobra.Command{
 Shows:
 - Total number of synced deals
 - Total number of synced journals
+- Total number of synced transfers
 - Total number of attached documents
 - Last sync timestamp
 
 Example:
-  freee-sync stats`,
+  freee-sync stats
+  freee-sync stats --output json`,
 	Run: runStats,
 }
 
@@ -31,11 +32,11 @@ func runStats(cmd *cobra.Command, args []string) {
 	slog.Info("Loading configuration")
 
 	// Load configuration
-	cfg, err := config.Load(getConfigFile())
+	cfg, err := loadConfig()
 	exitOnError(err, "failed to load configuration")
 
 	// Validate required fields
-	if err := cfg.Validate([]string{"beancount", "root"}); err != nil {
+	if err := cfg.Validate(config.BeancountRoot); err != nil {
 		exitOnError(err, "invalid configuration")
 	}
 
@@ -62,18 +63,8 @@ func runStats(cmd *cobra.Command, args []string) {
 	exitOnError(err, "failed to get statistics")
 
 	// Display statistics
-	fmt.Println("\n=== Sync Statistics ===")
-	fmt.Printf("Total synced deals:    %d\n", stats.TotalDeals)
-	fmt.Printf("Total synced journals: %d\n", stats.TotalJournals)
-	fmt.Printf("Total documents:       %d\n", stats.TotalDocuments)
-
-	if stats.LastSync.Valid {
-		fmt.Printf("Last sync:             %s\n", stats.LastSync.String)
-	} else {
-		fmt.Printf("Last sync:             (never)\n")
-	}
-
-	fmt.Println()
+	out := newStatsOutput(stats)
+	printOutput(out, func() { printStats(out) })
 
 	slog.Info("Statistics displayed successfully")
 }
@@ -1,24 +1,34 @@
 package cmd
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log/slog"
-	"path/filepath"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
-	"github.com/shunichi-ikebuchi/accounting-system/pkg/config"
 	"github.com/shunichi-ikebuchi/accounting-system/pkg/converter"
 	"github.com/shunichi-ikebuchi/accounting-system/pkg/db"
 	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
-	"github.com/shunichi-ikebuchi/accounting-system/pkg/pathutil"
 	"github.com/spf13/cobra"
 )
 
 var (
-	dateFrom string
-	dateTo   string
-	dryRun   bool
+	dateFrom       string
+	dateTo         string
+	dryRun         bool
+	skipValidation bool
+	skipDocuments  bool
+	incremental    bool
+	overlap        time.Duration
+	lookbackDays   int
+	syncPending    bool
+	suspenseAcct   string
 )
 
 // syncCmd represents the sync command.
@@ -28,187 +38,402 @@ var syncCmd = &cobra.Command{
 	Long: `Sync transactions from freee Accounting API to Beancount files.
 
 This command:
-1. Fetches deals and journals from freee API
+1. Fetches deals, manual journals (振替伝票) and transfers (口座振替) from freee API
 2. Filters out already synced items
-3. Converts them to Beancount format
-4. Appends to monthly Beancount files
-5. Records sync history in SQLite
+3. Converts them to Beancount format, downloading receipts attached to
+   deals into the attachments directory and linking them with document
+   directives and document metadata
+4. Validates each entry against the ledger (balance, open accounts, duplicates)
+5. Appends to monthly Beancount files, recording each entry in SQLite
+   as pending before the write and committed after it
+
+Records left pending by an interrupted run are reconciled against the
+Beancount files at the start of the next run, so every item is written
+exactly once.
+
+With --incremental, only deals and manual journals updated since the last
+successful incremental run are fetched (minus --overlap to tolerate clock
+skew). The watermark is stored per resource and company in sync_metadata.
+The first incremental run needs --from/--to to establish it. Items that
+were synced before and changed in freee since are rewritten in place, like
+resync does; unchanged items are compared by content hash and left alone.
+Transfers have no update time; without --from/--to they are fetched by
+date from --lookback-days before the previous successful run.
+
+With --pending, wallet transactions still waiting in freee's 自動で経理
+queue are written as pending (!) entries between their walletable account
+and --suspense-account, so month-end balances include them. A pending
+entry is removed once its wallet transaction is booked in freee and the
+deal it was booked to (when freee reports it) has been synced. In
+incremental mode without --from/--to, wallet transactions are fetched by
+date from --lookback-days before the previous successful run (and from the
+oldest pending entry), as freee cannot filter them by update time.
+
+With --output json or yaml, the run summary (or the planned entries of a
+dry run) is printed as a document. Exits with status 2 if some items
+failed and 1 if the run failed.
 
 Example:
   freee-sync sync --from 2024-01-01 --to 2024-01-31
-  freee-sync sync --from 2024-01-01 --to 2024-01-31 --dry-run`,
+  freee-sync sync --from 2024-01-01 --to 2024-01-31 --dry-run
+  freee-sync sync --incremental
+  freee-sync sync --incremental --output json
+  freee-sync sync --from 2024-01-01 --to 2024-01-31 --pending`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if !incremental && (dateFrom == "" || dateTo == "") {
+			return fmt.Errorf("--from and --to are required unless --incremental is set")
+		}
+		return nil
+	},
 	Run: runSync,
 }
 
 func init() {
 	// Flags
-	syncCmd.Flags().StringVar(&dateFrom, "from", "", "Start date (YYYY-MM-DD) (required)")
-	syncCmd.Flags().StringVar(&dateTo, "to", "", "End date (YYYY-MM-DD) (required)")
+	syncCmd.Flags().StringVar(&dateFrom, "from", "", "Start date (YYYY-MM-DD) (required without --incremental)")
+	syncCmd.Flags().StringVar(&dateTo, "to", "", "End date (YYYY-MM-DD) (required without --incremental)")
 	syncCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Dry run mode (no file writes)")
-
-	syncCmd.MarkFlagRequired("from")
-	syncCmd.MarkFlagRequired("to")
+	syncCmd.Flags().BoolVar(&skipValidation, "skip-validation", false, "Write entries without validating them against the ledger")
+	syncCmd.Flags().BoolVar(&skipDocuments, "skip-documents", false, "Do not download receipts attached to deals")
+	syncCmd.Flags().BoolVar(&incremental, "incremental", false, "Fetch only items updated since the last incremental sync")
+	syncCmd.Flags().DurationVar(&overlap, "overlap", 10*time.Minute, "Overlap window subtracted from the watermark in incremental mode")
+	syncCmd.Flags().BoolVar(&syncPending, "pending", false, "Write unbooked wallet transactions as pending entries")
+	syncCmd.Flags().IntVar(&lookbackDays, "lookback-days", 31, "Days before the previous run fetched by date in incremental mode")
+	syncCmd.Flags().StringVar(&suspenseAcct, "suspense-account", beancount.SuspenseAccount, "Account pending entries are booked against")
 }
 
 func runSync(cmd *cobra.Command, args []string) {
-	slog.Info("Starting sync", "from", dateFrom, "to", dateTo, "dry_run", dryRun)
-
-	// Load configuration
-	cfg, err := config.Load(getConfigFile())
-	exitOnError(err, "failed to load configuration")
-
-	// Validate required fields
-	if err := cfg.Validate(
-		[]string{"freee", "apiUrl"},
-		[]string{"freee", "accessToken"},
-		[]string{"freee", "companyId"},
-		[]string{"beancount", "root"},
-	); err != nil {
-		exitOnError(err, "invalid configuration")
-	}
-
-	// Initialize components
-	pathResolver := pathutil.New(pathutil.Config{
-		BeancountRoot:  cfg.Beancount.Root,
-		DatabasePath:   cfg.Beancount.DBPath,
-		AttachmentsDir: cfg.Beancount.AttachmentsDir,
-	})
-
-	// Open database
-	dbPath := pathResolver.GetDatabasePath()
-	slog.Debug("Opening database", "path", dbPath)
-	conn, err := db.Open(dbPath)
-	exitOnError(err, "failed to open database")
-	defer conn.Close()
-
-	syncHistory := db.NewSyncHistory(conn)
-
-	// Initialize freee API client
-	freeeClient := freee.NewClient(freee.ClientConfig{
-		APIURL:      cfg.Freee.APIURL,
-		AccessToken: cfg.Freee.AccessToken,
-		CompanyID:   cfg.Freee.CompanyID,
-		Timeout:     30 * time.Second,
-	})
-
-	// Initialize account mapper
-	mappingFilePath := filepath.Join("config", "account-mapping.yaml")
-	mapper, err := converter.NewMapper(mappingFilePath)
-	exitOnError(err, "failed to load account mapping")
-
-	// Initialize converter
-	cvtr := converter.NewConverter(mapper, "JPY")
-
-	// Initialize Beancount repository
-	beancountRepo := beancount.NewFileSystemRepository(pathResolver)
+	slog.Info("Starting sync", "from", dateFrom, "to", dateTo, "incremental", incremental, "dry_run", dryRun)
+
+	sc := newSyncContext(!skipValidation, !skipDocuments)
+	defer sc.Close()
+
+	// Hold the sync lock and reconcile records left pending by an
+	// interrupted run (dry runs write nothing)
+	if !dryRun {
+		release := sc.begin()
+		defer release()
+	}
+
+	opts := syncOptions{
+		from:        dateFrom,
+		to:          dateTo,
+		incremental: incremental,
+		overlap:     overlap,
+		lookback:    lookbackDays,
+		dryRun:      dryRun,
+		pending:     syncPending,
+		suspense:    suspenseAcct,
+	}
+
+	// Stop between entries on Ctrl-C; the run is recorded as partial
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	run, plan, err := sc.syncItems(ctx, opts)
+	exitOnError(err, "sync failed")
+
+	out := syncOutput{DryRun: dryRun, Plan: plan}
+	if !dryRun {
+		out.Run = run
+		if stats, err := sc.syncHistory.GetStats(); err == nil {
+			totals := newStatsOutput(stats)
+			out.Totals = &totals
+		}
+	}
+
+	printOutput(out, func() { printSyncSummary(out) })
+	if !dryRun {
+		exitForRun(run)
+	}
+}
+
+// syncOutput is the schema of sync output.
+type syncOutput struct {
+	DryRun bool           `json:"dry_run"`
+	Run    *db.SyncRun    `json:"run,omitempty"`    // Not recorded for dry runs
+	Plan   []plannedEntry `json:"plan,omitempty"`   // Dry runs only
+	Totals *statsOutput   `json:"totals,omitempty"` // Statistics after the run
+}
+
+// plannedEntry is an entry a dry run would write.
+type plannedEntry struct {
+	Type    db.SyncType `json:"type"`
+	FreeeID int64       `json:"freee_id"`
+	File    string      `json:"file"`
+	Entry   string      `json:"entry"`
+	Replace bool        `json:"replace,omitempty"` // Replaces the entry of an item changed in freee
+	Error   string      `json:"error,omitempty"`   // Validation error; the entry would be refused
+}
+
+// printSyncSummary prints the outcome of sync in table form.
+func printSyncSummary(out syncOutput) {
+	if len(out.Plan) == 0 && (out.Run == nil || (out.Run.New == 0 && out.Run.Updated == 0 && out.Run.Failed == 0)) {
+		fmt.Println("No new items to sync")
+	}
+
+	file := ""
+	replace := false
+	for _, entry := range out.Plan {
+		if entry.File != file || entry.Replace != replace {
+			file, replace = entry.File, entry.Replace
+			if replace {
+				fmt.Printf("[DRY RUN] Would replace in %s\n", file)
+			} else {
+				fmt.Printf("[DRY RUN] Would append to %s\n", file)
+			}
+		}
+		if entry.Error != "" {
+			fmt.Printf("; [INVALID] %s\n", entry.Error)
+		}
+		fmt.Println(entry.Entry)
+	}
+
+	if out.Run != nil {
+		fmt.Printf("\nSync run #%d: %s (new: %d, updated: %d, failed: %d)\n", out.Run.ID, out.Run.Status, out.Run.New, out.Run.Updated, out.Run.Failed)
+	}
+	if out.Totals != nil {
+		printStats(*out.Totals)
+	}
+}
+
+// syncOptions controls a sync run.
+type syncOptions struct {
+	from        string // Issue date range; may be empty in incremental mode
+	to          string
+	incremental bool
+	overlap     time.Duration
+	lookback    int // Days before the watermark fetched by date in incremental mode
+	dryRun      bool
+	pending     bool   // Also write unbooked wallet transactions
+	suspense    string // Account pending entries are booked against
+}
+
+// syncItems fetches new deals and journals and writes them to the monthly
+// files, or returns the entries it would write for a dry run. Failures of
+// single items are recorded on the returned run; an error is returned only
+// if the run could not be carried out. When ctx is
+// cancelled, the run stops between entries and is recorded as incomplete.
+// The caller must hold the sync lock unless opts.dryRun is set.
+func (c *syncContext) syncItems(ctx context.Context, opts syncOptions) (*db.SyncRun, []plannedEntry, error) {
+	cfg := c.cfg
+	pathResolver := c.pathResolver
+	syncHistory := c.syncHistory
+	freeeClient := c.client
+	beancountRepo := c.repo
+	validator := c.validator
+	builder := c.builder
+
+	var err error
+
+	// Resolve incremental watermarks (zero means a full fetch)
+	var dealsSince, journalsSince time.Time
+	if opts.incremental {
+		dealsSince, err = incrementalSince(syncHistory, db.WatermarkDeals, cfg.Freee.CompanyID, opts.overlap)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read deals watermark: %w", err)
+		}
+		journalsSince, err = incrementalSince(syncHistory, db.WatermarkJournals, cfg.Freee.CompanyID, opts.overlap)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read journals watermark: %w", err)
+		}
+
+		if (dealsSince.IsZero() || journalsSince.IsZero()) && (opts.from == "" || opts.to == "") {
+			return nil, nil, fmt.Errorf("no watermark stored yet: first incremental sync needs --from and --to")
+		}
+	}
+
+	// Start run journal (dry runs are not recorded)
+	run := &db.SyncRun{
+		DateFrom:  opts.from,
+		DateTo:    opts.to,
+		CompanyID: cfg.Freee.CompanyID,
+	}
+	if !opts.dryRun {
+		if err := syncHistory.StartRun(run); err != nil {
+			return nil, nil, err
+		}
+		slog.Info("Started sync run", "run_id", run.ID)
+	}
 
 	// Fetch deals from freee
-	slog.Info("Fetching deals from freee", "from", dateFrom, "to", dateTo)
-	allDeals, err := freeeClient.FetchAllDeals(dateFrom, dateTo)
-	exitOnError(err, "failed to fetch deals")
+	slog.Info("Fetching deals from freee", "from", opts.from, "to", opts.to, "updated_since", dealsSince)
+	allDeals, err := freeeClient.FetchDealsUpdatedSince(opts.from, opts.to, dealsSince)
+	if err != nil {
+		return run, nil, abortRun(syncHistory, run, err, "failed to fetch deals")
+	}
 	slog.Info("Fetched deals", "count", len(allDeals))
+	if !opts.dryRun {
+		c.cacheDealDetails(allDeals)
+	}
 
-	// Fetch journals from freee
-	slog.Info("Fetching journals from freee", "from", dateFrom, "to", dateTo)
-	allJournals, err := freeeClient.FetchAllJournals(dateFrom, dateTo)
-	exitOnError(err, "failed to fetch journals")
-	slog.Info("Fetched journals", "count", len(allJournals))
+	// Fetch manual journals from freee
+	slog.Info("Fetching manual journals from freee", "from", opts.from, "to", opts.to, "updated_since", journalsSince)
+	allJournals, err := freeeClient.FetchManualJournalsUpdatedSince(opts.from, opts.to, journalsSince)
+	if err != nil {
+		return run, nil, abortRun(syncHistory, run, err, "failed to fetch manual journals")
+	}
+	slog.Info("Fetched manual journals", "count", len(allJournals))
+
+	// Fetch transfers from freee
+	transfersFrom := opts.from
+	if opts.incremental && opts.from == "" && opts.to == "" {
+		transfersFrom, err = incrementalDateFrom(syncHistory, db.WatermarkTransfers, cfg.Freee.CompanyID, opts.lookback)
+		if err != nil {
+			return run, nil, abortRun(syncHistory, run, err, "failed to read transfers watermark")
+		}
+	}
+	slog.Info("Fetching transfers from freee", "from", transfersFrom, "to", opts.to)
+	allTransfers, err := freeeClient.FetchTransfers(transfersFrom, opts.to)
+	if err != nil {
+		return run, nil, abortRun(syncHistory, run, err, "failed to fetch transfers")
+	}
+	slog.Info("Fetched transfers", "count", len(allTransfers))
+
+	run.Fetched = len(allDeals) + len(allJournals) + len(allTransfers)
 
 	// Filter out already synced items
 	slog.Info("Checking for already synced items")
 	syncedDealIDs, err := syncHistory.GetSyncedIDs(db.SyncTypeDeal)
-	exitOnError(err, "failed to get synced deal IDs")
+	if err != nil {
+		return run, nil, abortRun(syncHistory, run, err, "failed to get synced deal IDs")
+	}
 
 	syncedJournalIDs, err := syncHistory.GetSyncedIDs(db.SyncTypeJournal)
-	exitOnError(err, "failed to get synced journal IDs")
+	if err != nil {
+		return run, nil, abortRun(syncHistory, run, err, "failed to get synced journal IDs")
+	}
+
+	syncedTransferIDs, err := syncHistory.GetSyncedIDs(db.SyncTypeTransfer)
+	if err != nil {
+		return run, nil, abortRun(syncHistory, run, err, "failed to get synced transfer IDs")
+	}
 
 	newDeals := filterDeals(allDeals, syncedDealIDs)
 	newJournals := filterJournals(allJournals, syncedJournalIDs)
+	newTransfers := filterTransfers(allTransfers, syncedTransferIDs)
 
 	slog.Info("New items to sync",
 		"new_deals", len(newDeals),
 		"new_journals", len(newJournals),
+		"new_transfers", len(newTransfers),
 		"skipped_deals", len(allDeals)-len(newDeals),
 		"skipped_journals", len(allJournals)-len(newJournals),
+		"skipped_transfers", len(allTransfers)-len(newTransfers),
 	)
 
-	if len(newDeals) == 0 && len(newJournals) == 0 {
-		fmt.Println("No new items to sync")
-		return
+	// Incremental runs also rewrite the entries of items changed in freee
+	if len(newDeals) == 0 && len(newJournals) == 0 && len(newTransfers) == 0 && !opts.pending && !opts.incremental {
+		finishRun(syncHistory, run)
+		return run, nil, nil
 	}
 
 	// Group by month
 	dealsByMonth := groupDealsByMonth(newDeals)
 	journalsByMonth := groupJournalsByMonth(newJournals)
+	transfersByMonth := groupTransfersByMonth(newTransfers)
 
 	// Get all unique months
-	allMonths := getAllMonths(dealsByMonth, journalsByMonth)
+	allMonths := getAllMonths(dealsByMonth, journalsByMonth, transfersByMonth)
 
 	filesWritten := []string{}
+	var plan []plannedEntry
+
+	// interrupted reports whether the run must stop before the next entry.
+	// The recorded error keeps the watermarks from advancing.
+	stopped := false
+	interrupted := func() bool {
+		if ctx.Err() == nil {
+			return false
+		}
+		if !stopped {
+			stopped = true
+			slog.Warn("Sync interrupted, stopping before the next entry", "run_id", run.ID)
+			run.AddError(fmt.Sprintf("interrupted: %v", context.Cause(ctx)))
+		}
+		return true
+	}
 
 	// Process each month
 	for _, monthKey := range allMonths {
+		if interrupted() {
+			break
+		}
+
 		monthDeals := dealsByMonth[monthKey]
 		monthJournals := journalsByMonth[monthKey]
+		monthTransfers := transfersByMonth[monthKey]
 
 		filePath, err := pathResolver.GetMonthFilePath(monthKey)
 		if err != nil {
 			slog.Error("Failed to get month file path", "month", monthKey, "error", err)
+			run.AddError(fmt.Sprintf("month %s: %v", monthKey, err))
 			continue
 		}
 
-		if !dryRun {
+		if !opts.dryRun {
 			// Ensure month file exists
 			if err := beancountRepo.EnsureMonthFile(monthKey); err != nil {
 				slog.Error("Failed to ensure month file", "month", monthKey, "error", err)
+				run.AddError(fmt.Sprintf("month %s: %v", monthKey, err))
 				continue
 			}
 
 			// Append transactions
 			for _, deal := range monthDeals {
-				txn := cvtr.ConvertDeal(deal)
-				formatted := cvtr.FormatTransaction(txn)
+				if interrupted() {
+					break
+				}
 
-				if err := beancountRepo.AppendTransaction(monthKey, formatted); err != nil {
-					slog.Error("Failed to append deal", "deal_id", deal.ID, "error", err)
+				formatted, record := builder.deal(deal, filePath, run)
+
+				if err := checkEntry(validator, filePath, formatted); err != nil {
+					slog.Error("Refusing to write invalid deal", "deal_id", deal.ID, "error", err)
+					run.AddError(fmt.Sprintf("deal %d: %v", deal.ID, err))
 					continue
 				}
 
-				// Record sync history
-				if err := syncHistory.RecordSync(db.SyncRecord{
-					SyncType:      db.SyncTypeDeal,
-					FreeeID:       deal.ID,
-					IssueDate:     deal.IssueDate,
-					Amount:        deal.Amount,
-					BeancountFile: filePath,
-				}); err != nil {
-					slog.Error("Failed to record sync", "deal_id", deal.ID, "error", err)
+				if err := writeEntry(syncHistory, beancountRepo, monthKey, record, formatted, run); err != nil {
+					slog.Error("Failed to write deal", "deal_id", deal.ID, "error", err)
+					run.AddError(fmt.Sprintf("deal %d: %v", deal.ID, err))
 				}
 			}
 
 			for _, journal := range monthJournals {
-				txn := cvtr.ConvertJournal(journal)
-				formatted := cvtr.FormatTransaction(txn)
+				if interrupted() {
+					break
+				}
+
+				formatted, record := builder.journal(journal, filePath, run)
 
-				if err := beancountRepo.AppendTransaction(monthKey, formatted); err != nil {
-					slog.Error("Failed to append journal", "journal_id", journal.ID, "error", err)
+				if err := checkEntry(validator, filePath, formatted); err != nil {
+					slog.Error("Refusing to write invalid journal", "journal_id", journal.ID, "error", err)
+					run.AddError(fmt.Sprintf("journal %d: %v", journal.ID, err))
 					continue
 				}
 
-				amount := int64(0)
-				if len(journal.Details) > 0 {
-					amount = journal.Details[0].Amount
+				if err := writeEntry(syncHistory, beancountRepo, monthKey, record, formatted, run); err != nil {
+					slog.Error("Failed to write journal", "journal_id", journal.ID, "error", err)
+					run.AddError(fmt.Sprintf("journal %d: %v", journal.ID, err))
+				}
+			}
+
+			for _, transfer := range monthTransfers {
+				if interrupted() {
+					break
 				}
 
-				// Record sync history
-				if err := syncHistory.RecordSync(db.SyncRecord{
-					SyncType:      db.SyncTypeJournal,
-					FreeeID:       journal.ID,
-					IssueDate:     journal.IssueDate,
-					Amount:        amount,
-					BeancountFile: filePath,
-				}); err != nil {
-					slog.Error("Failed to record sync", "journal_id", journal.ID, "error", err)
+				formatted, record := builder.transfer(transfer, filePath, run)
+
+				if err := checkEntry(validator, filePath, formatted); err != nil {
+					slog.Error("Refusing to write invalid transfer", "transfer_id", transfer.ID, "error", err)
+					run.AddError(fmt.Sprintf("transfer %d: %v", transfer.ID, err))
+					continue
+				}
+
+				if err := writeEntry(syncHistory, beancountRepo, monthKey, record, formatted, run); err != nil {
+					slog.Error("Failed to write transfer", "transfer_id", transfer.ID, "error", err)
+					run.AddError(fmt.Sprintf("transfer %d: %v", transfer.ID, err))
 				}
 			}
 
@@ -217,45 +442,366 @@ func runSync(cmd *cobra.Command, args []string) {
 				"path", filePath,
 				"deals", len(monthDeals),
 				"journals", len(monthJournals),
+				"transfers", len(monthTransfers),
 			)
 		} else {
-			// Dry run: print transactions
-			fmt.Printf("[DRY RUN] Would append to %s\n", filePath)
+			// Dry run: collect the transactions as the real run would write them
+			planner := builder.planner()
+			addPlanned := func(formatted string, record db.SyncRecord) {
+				entry := plannedEntry{Type: record.SyncType, FreeeID: record.FreeeID, File: filePath, Entry: formatted}
+				if err := checkEntry(validator, filePath, formatted); err != nil {
+					entry.Error = err.Error()
+				}
+				plan = append(plan, entry)
+			}
 			for _, deal := range monthDeals {
-				txn := cvtr.ConvertDeal(deal)
-				fmt.Println(cvtr.FormatTransaction(txn))
+				addPlanned(planner.deal(deal, filePath, run))
 			}
 			for _, journal := range monthJournals {
-				txn := cvtr.ConvertJournal(journal)
-				fmt.Println(cvtr.FormatTransaction(txn))
+				addPlanned(planner.journal(journal, filePath, run))
+			}
+			for _, transfer := range monthTransfers {
+				addPlanned(planner.transfer(transfer, filePath, run))
 			}
 		}
 	}
 
-	// Display final statistics
-	if !dryRun {
-		stats, err := syncHistory.GetStats()
-		if err == nil {
-			fmt.Println("\n=== Sync Statistics ===")
-			fmt.Printf("Total synced deals:    %d\n", stats.TotalDeals)
-			fmt.Printf("Total synced journals: %d\n", stats.TotalJournals)
-			fmt.Printf("Total documents:       %d\n", stats.TotalDocuments)
-			if stats.LastSync.Valid {
-				fmt.Printf("Last sync:             %s\n", stats.LastSync.String)
-			}
-			fmt.Println()
-		}
+	if opts.incremental && !interrupted() {
+		plan = append(plan, c.syncChanged(opts, run, allDeals, allJournals, allTransfers)...)
 	}
 
+	// Pending entries go last, so that deals booked from wallet
+	// transactions are synced before the entries they replace are removed
+	if opts.pending && !interrupted() {
+		plan = append(plan, c.syncWalletTxns(opts, run)...)
+	}
+
+	if opts.incremental {
+		advanceWatermarks(syncHistory, run, allDeals, allJournals, opts.pending)
+	}
+	finishRun(syncHistory, run)
+
 	slog.Info("Sync completed",
+		"run_id", run.ID,
+		"status", run.Status,
 		"new_deals", len(newDeals),
 		"new_journals", len(newJournals),
+		"new_transfers", len(newTransfers),
 		"files_written", len(filesWritten),
 	)
+
+	return run, plan, nil
 }
 
 // Helper functions
 
+// abortRun marks the run as failed and returns err wrapped with msg.
+func abortRun(syncHistory *db.SyncHistory, run *db.SyncRun, err error, msg string) error {
+	if run.ID != 0 {
+		run.Errors = append(run.Errors, fmt.Sprintf("%s: %v", msg, err))
+		run.Status = db.RunStatusFailed
+		if ferr := syncHistory.FinishRun(run); ferr != nil {
+			slog.Error("Failed to record sync run", "run_id", run.ID, "error", ferr)
+		}
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// exitOnRunError records the run as failed and exits if err is not nil.
+func exitOnRunError(syncHistory *db.SyncHistory, run *db.SyncRun, err error, msg string) {
+	if err == nil {
+		return
+	}
+	_ = abortRun(syncHistory, run, err, msg)
+	exitOnError(err, msg)
+}
+
+// finishRun records the final state of a run. Dry runs are not recorded.
+func finishRun(syncHistory *db.SyncHistory, run *db.SyncRun) {
+	if run.ID == 0 {
+		return
+	}
+	if err := syncHistory.FinishRun(run); err != nil {
+		slog.Error("Failed to record sync run", "run_id", run.ID, "error", err)
+	}
+}
+
+// incrementalSince returns the time from which a resource is fetched in
+// incremental mode: the stored watermark minus the overlap window, or the
+// zero time if no watermark has been stored yet.
+func incrementalSince(syncHistory *db.SyncHistory, resource string, companyID int64, overlap time.Duration) (time.Time, error) {
+	watermark, err := syncHistory.GetWatermark(resource, companyID)
+	if err != nil || watermark.IsZero() {
+		return time.Time{}, err
+	}
+	return watermark.Add(-overlap), nil
+}
+
+// incrementalDateFrom returns the first issue date fetched in incremental
+// mode for a resource freee cannot filter by update time: lookbackDays
+// before its watermark, or an empty string if none has been stored yet.
+func incrementalDateFrom(syncHistory *db.SyncHistory, resource string, companyID int64, lookbackDays int) (string, error) {
+	watermark, err := syncHistory.GetWatermark(resource, companyID)
+	if err != nil || watermark.IsZero() {
+		return "", err
+	}
+	return watermark.AddDate(0, 0, -lookbackDays).Format("2006-01-02"), nil
+}
+
+// advanceWatermarks stores the latest updated_at seen per resource.
+// Watermarks only move after a run without failures, so items that could
+// not be written are fetched again next time. Dry runs are not recorded.
+func advanceWatermarks(syncHistory *db.SyncHistory, run *db.SyncRun, deals []freee.Deal, journals []freee.ManualJournal, pending bool) {
+	if run.ID == 0 {
+		return
+	}
+	if run.Failed > 0 {
+		slog.Warn("Run had failures, watermarks not advanced", "run_id", run.ID, "failed", run.Failed)
+		return
+	}
+
+	// With nothing fetched, the run start bounds what has been seen
+	dealsMark, journalsMark := run.StartedAt, run.StartedAt
+	if len(deals) > 0 {
+		dealsMark = time.Time{}
+		for _, deal := range deals {
+			if deal.UpdatedAt.After(dealsMark) {
+				dealsMark = deal.UpdatedAt
+			}
+		}
+	}
+	if len(journals) > 0 {
+		journalsMark = time.Time{}
+		for _, journal := range journals {
+			if journal.UpdatedAt.After(journalsMark) {
+				journalsMark = journal.UpdatedAt
+			}
+		}
+	}
+
+	if err := syncHistory.SetWatermark(db.WatermarkDeals, run.CompanyID, dealsMark); err != nil {
+		slog.Error("Failed to store deals watermark", "error", err)
+	}
+	if err := syncHistory.SetWatermark(db.WatermarkJournals, run.CompanyID, journalsMark); err != nil {
+		slog.Error("Failed to store journals watermark", "error", err)
+	}
+	if err := syncHistory.SetWatermark(db.WatermarkTransfers, run.CompanyID, run.StartedAt); err != nil {
+		slog.Error("Failed to store transfers watermark", "error", err)
+	}
+	if pending {
+		if err := syncHistory.SetWatermark(db.WatermarkWalletTxns, run.CompanyID, run.StartedAt); err != nil {
+			slog.Error("Failed to store wallet transactions watermark", "error", err)
+		}
+	}
+}
+
+// syncChanged rewrites the entries of already synced items whose entry
+// changed in freee, found by comparing content hashes. New items are
+// skipped; they are written by syncItems. Records without a hash only get
+// it backfilled. Dry runs return the replacements.
+func (c *syncContext) syncChanged(opts syncOptions, run *db.SyncRun, deals []freee.Deal, journals []freee.ManualJournal, transfers []freee.Transfer) []plannedEntry {
+	builder := c.builder
+	if opts.dryRun {
+		builder = builder.planner()
+	}
+
+	var plan []plannedEntry
+	update := func(month string, build func(filePath string) (string, db.SyncRecord)) {
+		filePath, err := c.pathResolver.GetMonthFilePath(month)
+		if err != nil {
+			run.AddError(fmt.Sprintf("month %s: %v", month, err))
+			return
+		}
+		formatted, record := build(filePath)
+		key := beancount.FreeeKey(string(record.SyncType), record.FreeeID)
+
+		existing, err := c.syncHistory.GetSyncRecord(record.SyncType, record.FreeeID)
+		if err != nil {
+			run.AddError(fmt.Sprintf("%s: %v", key, err))
+			return
+		}
+		if existing == nil || existing.Status != db.SyncStatusCommitted || existing.ContentHash == record.ContentHash {
+			return
+		}
+		// Records synced before hashes were stored cannot tell whether the
+		// entry changed, so the current hash is only backfilled
+		if existing.ContentHash == "" {
+			if opts.dryRun {
+				return
+			}
+			if err := c.syncHistory.SetContentHash(record.SyncType, record.FreeeID, record.ContentHash); err != nil {
+				run.AddError(fmt.Sprintf("%s: %v", key, err))
+			}
+			return
+		}
+
+		if opts.dryRun {
+			entry := plannedEntry{Type: record.SyncType, FreeeID: record.FreeeID, File: filePath, Entry: formatted, Replace: true}
+			if c.validator != nil {
+				c.validator.Remove(key)
+			}
+			if err := checkEntry(c.validator, filePath, formatted); err != nil {
+				entry.Error = err.Error()
+			}
+			plan = append(plan, entry)
+			return
+		}
+
+		slog.Info("Updating entry changed in freee", "key", key, "file", filePath)
+		if err := c.replaceEntry(record, formatted, run); err != nil {
+			slog.Error("Failed to update entry", "key", key, "error", err)
+			run.AddError(fmt.Sprintf("%s: %v", key, err))
+		}
+	}
+
+	for _, deal := range deals {
+		update(deal.IssueDate[:7], func(filePath string) (string, db.SyncRecord) { return builder.deal(deal, filePath, run) })
+	}
+	for _, journal := range journals {
+		update(journal.IssueDate[:7], func(filePath string) (string, db.SyncRecord) { return builder.journal(journal, filePath, run) })
+	}
+	for _, transfer := range transfers {
+		update(transfer.Date[:7], func(filePath string) (string, db.SyncRecord) { return builder.transfer(transfer, filePath, run) })
+	}
+	return plan
+}
+
+// entryBuilder converts freee items into formatted Beancount entries and
+// the sync records describing them.
+type entryBuilder struct {
+	cvtr      *converter.Converter
+	receipts  *receiptDownloader // nil when receipts are skipped
+	companyID int64
+	dryRun    bool // link receipts without downloading them
+}
+
+// planner returns a builder for dry runs, which builds the same entries
+// without downloading receipts.
+func (b *entryBuilder) planner() *entryBuilder {
+	planner := *b
+	planner.dryRun = true
+	return &planner
+}
+
+// deal converts a deal, downloading and linking its receipts.
+// A failed download is recorded on the run; the deal is still returned
+// with the receipts that were saved.
+func (b *entryBuilder) deal(deal freee.Deal, filePath string, run *db.SyncRun) (string, db.SyncRecord) {
+	txn := b.cvtr.ConvertDeal(deal)
+	if b.receipts != nil && len(deal.Receipts) > 0 {
+		var docs []string
+		var err error
+		if b.dryRun {
+			docs, err = b.receipts.plan(deal, filePath)
+		} else {
+			docs, err = b.receipts.download(deal, filePath)
+		}
+		if err != nil {
+			slog.Warn("Failed to download receipts", "deal_id", deal.ID, "error", err)
+			run.AddError(fmt.Sprintf("deal %d receipts: %v", deal.ID, err))
+		}
+		b.cvtr.AttachDocuments(&txn, docs)
+	}
+	formatted := b.cvtr.FormatTransaction(txn)
+
+	return formatted, db.SyncRecord{
+		SyncType:      db.SyncTypeDeal,
+		FreeeID:       deal.ID,
+		IssueDate:     deal.IssueDate,
+		Amount:        deal.Amount,
+		BeancountFile: filePath,
+		CompanyID:     b.companyID,
+		ContentHash:   contentHash(formatted),
+		RunID:         run.ID,
+	}
+}
+
+// journal converts a journal.
+func (b *entryBuilder) journal(journal freee.ManualJournal, filePath string, run *db.SyncRun) (string, db.SyncRecord) {
+	formatted := b.cvtr.FormatTransaction(b.cvtr.ConvertManualJournal(journal))
+
+	amount := int64(0)
+	if len(journal.Details) > 0 {
+		amount = journal.Details[0].Amount
+	}
+
+	return formatted, db.SyncRecord{
+		SyncType:      db.SyncTypeJournal,
+		FreeeID:       journal.ID,
+		IssueDate:     journal.IssueDate,
+		Amount:        amount,
+		BeancountFile: filePath,
+		CompanyID:     b.companyID,
+		ContentHash:   contentHash(formatted),
+		RunID:         run.ID,
+	}
+}
+
+// transfer converts a transfer.
+func (b *entryBuilder) transfer(transfer freee.Transfer, filePath string, run *db.SyncRun) (string, db.SyncRecord) {
+	formatted := b.cvtr.FormatTransaction(b.cvtr.ConvertTransfer(transfer))
+
+	return formatted, db.SyncRecord{
+		SyncType:      db.SyncTypeTransfer,
+		FreeeID:       transfer.ID,
+		IssueDate:     transfer.Date,
+		Amount:        transfer.Amount,
+		BeancountFile: filePath,
+		CompanyID:     b.companyID,
+		ContentHash:   contentHash(formatted),
+		RunID:         run.ID,
+	}
+}
+
+// walletTxn converts an unbooked wallet transaction to a pending entry.
+func (b *entryBuilder) walletTxn(txn freee.WalletTxn, suspense, filePath string, run *db.SyncRun) (string, db.SyncRecord) {
+	formatted := b.cvtr.FormatTransaction(b.cvtr.ConvertWalletTxn(txn, suspense))
+
+	return formatted, db.SyncRecord{
+		SyncType:      db.SyncTypeWalletTxn,
+		FreeeID:       txn.ID,
+		IssueDate:     txn.Date,
+		Amount:        txn.Amount,
+		BeancountFile: filePath,
+		CompanyID:     b.companyID,
+		ContentHash:   contentHash(formatted),
+		RunID:         run.ID,
+	}
+}
+
+// writeEntry appends a formatted entry to its month file in two phases:
+// the sync record is stored as pending, the file is replaced atomically and
+// the record is then committed. If the process dies in between, the pending
+// record is reconciled against the file on the next run.
+func writeEntry(syncHistory *db.SyncHistory, repo beancount.Repository, monthKey string, record db.SyncRecord, formatted string, run *db.SyncRun) error {
+	if err := syncHistory.BeginSync(record); err != nil {
+		return err
+	}
+
+	if err := repo.AppendTransaction(monthKey, formatted); err != nil {
+		if abortErr := syncHistory.AbortSync(record.SyncType, record.FreeeID); abortErr != nil {
+			slog.Error("Failed to abort sync", "type", record.SyncType, "freee_id", record.FreeeID, "error", abortErr)
+		}
+		return err
+	}
+	run.New++
+	run.AddFile(record.BeancountFile)
+
+	if err := syncHistory.CommitSync(record.SyncType, record.FreeeID); err != nil {
+		// The entry is on disk; recovery commits the record on the next run.
+		slog.Warn("Failed to commit sync, will recover on next run", "type", record.SyncType, "freee_id", record.FreeeID, "error", err)
+		run.AddError(fmt.Sprintf("%s %d: %v", record.SyncType, record.FreeeID, err))
+	}
+
+	return nil
+}
+
+// contentHash returns the SHA-256 of a formatted Beancount entry.
+func contentHash(formatted string) string {
+	sum := sha256.Sum256([]byte(formatted))
+	return hex.EncodeToString(sum[:])
+}
+
 func filterDeals(deals []freee.Deal, syncedIDs []int64) []freee.Deal {
 	syncedIDMap := make(map[int64]bool)
 	for _, id := range syncedIDs {
@@ -271,13 +817,13 @@ func filterDeals(deals []freee.Deal, syncedIDs []int64) []freee.Deal {
 	return result
 }
 
-func filterJournals(journals []freee.Journal, syncedIDs []int64) []freee.Journal {
+func filterJournals(journals []freee.ManualJournal, syncedIDs []int64) []freee.ManualJournal {
 	syncedIDMap := make(map[int64]bool)
 	for _, id := range syncedIDs {
 		syncedIDMap[id] = true
 	}
 
-	var result []freee.Journal
+	var result []freee.ManualJournal
 	for _, journal := range journals {
 		if !syncedIDMap[journal.ID] {
 			result = append(result, journal)
@@ -286,6 +832,21 @@ func filterJournals(journals []freee.Journal, syncedIDs []int64) []freee.Journal
 	return result
 }
 
+func filterTransfers(transfers []freee.Transfer, syncedIDs []int64) []freee.Transfer {
+	syncedIDMap := make(map[int64]bool)
+	for _, id := range syncedIDs {
+		syncedIDMap[id] = true
+	}
+
+	var result []freee.Transfer
+	for _, transfer := range transfers {
+		if !syncedIDMap[transfer.ID] {
+			result = append(result, transfer)
+		}
+	}
+	return result
+}
+
 func groupDealsByMonth(deals []freee.Deal) map[string][]freee.Deal {
 	groups := make(map[string][]freee.Deal)
 	for _, deal := range deals {
@@ -295,8 +856,8 @@ func groupDealsByMonth(deals []freee.Deal) map[string][]freee.Deal {
 	return groups
 }
 
-func groupJournalsByMonth(journals []freee.Journal) map[string][]freee.Journal {
-	groups := make(map[string][]freee.Journal)
+func groupJournalsByMonth(journals []freee.ManualJournal) map[string][]freee.ManualJournal {
+	groups := make(map[string][]freee.ManualJournal)
 	for _, journal := range journals {
 		monthKey := journal.IssueDate[:7] // YYYY-MM
 		groups[monthKey] = append(groups[monthKey], journal)
@@ -304,7 +865,16 @@ func groupJournalsByMonth(journals []freee.Journal) map[string][]freee.Journal {
 	return groups
 }
 
-func getAllMonths(dealGroups map[string][]freee.Deal, journalGroups map[string][]freee.Journal) []string {
+func groupTransfersByMonth(transfers []freee.Transfer) map[string][]freee.Transfer {
+	groups := make(map[string][]freee.Transfer)
+	for _, transfer := range transfers {
+		monthKey := transfer.Date[:7] // YYYY-MM
+		groups[monthKey] = append(groups[monthKey], transfer)
+	}
+	return groups
+}
+
+func getAllMonths(dealGroups map[string][]freee.Deal, journalGroups map[string][]freee.ManualJournal, transferGroups map[string][]freee.Transfer) []string {
 	monthsMap := make(map[string]bool)
 	for month := range dealGroups {
 		monthsMap[month] = true
@@ -312,6 +882,9 @@ func getAllMonths(dealGroups map[string][]freee.Deal, journalGroups map[string][
 	for month := range journalGroups {
 		monthsMap[month] = true
 	}
+	for month := range transferGroups {
+		monthsMap[month] = true
+	}
 
 	months := []string{}
 	for month := range monthsMap {
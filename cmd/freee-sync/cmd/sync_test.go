@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/converter"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/db"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/pathutil"
+)
+
+// newTestSyncContext returns a sync context writing to a temporary ledger,
+// without a freee client or validation.
+func newTestSyncContext(t *testing.T) *syncContext {
+	t.Helper()
+	pathResolver := pathutil.New(pathutil.Config{BeancountRoot: t.TempDir()})
+	conn, err := db.Open(pathResolver.GetDatabasePath())
+	if err != nil {
+		t.Fatalf("db.Open() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	cvtr := converter.NewConverter(&converter.Mapper{}, "JPY")
+	return &syncContext{
+		pathResolver: pathResolver,
+		conn:         conn,
+		syncHistory:  db.NewSyncHistory(conn),
+		cvtr:         cvtr,
+		repo:         beancount.NewFileSystemRepository(pathResolver),
+		builder:      &entryBuilder{cvtr: cvtr, companyID: 1},
+	}
+}
+
+func testTransfer(id int64, date string, amount int64) freee.Transfer {
+	return freee.Transfer{
+		ID: id, CompanyID: 1, Date: date, Amount: amount,
+		FromWalletableType: "bank_account", FromWalletableID: 1,
+		ToWalletableType: "wallet", ToWalletableID: 2,
+	}
+}
+
+// writeTransfer syncs a transfer as a first sync would.
+func writeTransfer(t *testing.T, c *syncContext, transfer freee.Transfer) string {
+	t.Helper()
+	filePath, err := c.pathResolver.GetMonthFilePath(transfer.Date[:7])
+	if err != nil {
+		t.Fatalf("GetMonthFilePath() error = %v", err)
+	}
+	run := &db.SyncRun{}
+	formatted, record := c.builder.transfer(transfer, filePath, run)
+	if err := c.replaceEntry(record, formatted, run); err != nil {
+		t.Fatalf("replaceEntry() error = %v", err)
+	}
+	return filePath
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	return string(data)
+}
+
+// hasEntry reports whether a Beancount file has an entry for key.
+func hasEntry(t *testing.T, path, key string) bool {
+	t.Helper()
+	_, entries := beancount.SplitEntries(readFile(t, path))
+	for _, e := range entries {
+		if e.FreeeKey() == key {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSyncChangedUnchanged(t *testing.T) {
+	c := newTestSyncContext(t)
+	transfer := testTransfer(1, "2024-01-15", 1000)
+	filePath := writeTransfer(t, c, transfer)
+	before := readFile(t, filePath)
+
+	run := &db.SyncRun{}
+	c.syncChanged(syncOptions{incremental: true}, run, nil, nil, []freee.Transfer{transfer})
+
+	if run.Updated != 0 || run.Failed != 0 {
+		t.Errorf("syncChanged() updated %d, failed %d; expected nothing", run.Updated, run.Failed)
+	}
+	if got := readFile(t, filePath); got != before {
+		t.Errorf("syncChanged() rewrote an unchanged file:\n%s", got)
+	}
+}
+
+func TestSyncChangedRewritesInPlace(t *testing.T) {
+	c := newTestSyncContext(t)
+	writeTransfer(t, c, testTransfer(1, "2024-01-15", 1000))
+	filePath := writeTransfer(t, c, testTransfer(2, "2024-01-20", 2000))
+
+	run := &db.SyncRun{}
+	c.syncChanged(syncOptions{incremental: true}, run, nil, nil, []freee.Transfer{testTransfer(1, "2024-01-15", 1500)})
+
+	if run.Updated != 1 || run.Failed != 0 {
+		t.Fatalf("syncChanged() updated %d, failed %d (%v); expected one update", run.Updated, run.Failed, run.Errors)
+	}
+	_, entries := beancount.SplitEntries(readFile(t, filePath))
+	if len(entries) != 2 {
+		t.Fatalf("file has %d entries, expected 2", len(entries))
+	}
+	if key := entries[0].FreeeKey(); key != beancount.FreeeKey("transfer", 1) {
+		t.Errorf("first entry is %s, expected the rewritten transfer in place", key)
+	}
+	if !strings.Contains(entries[0].Text, "1500") || strings.Contains(entries[0].Text, "1000") {
+		t.Errorf("entry was not rewritten:\n%s", entries[0].Text)
+	}
+}
+
+func TestSyncChangedMovesMonth(t *testing.T) {
+	c := newTestSyncContext(t)
+	oldPath := writeTransfer(t, c, testTransfer(1, "2024-01-15", 1000))
+
+	run := &db.SyncRun{}
+	c.syncChanged(syncOptions{incremental: true}, run, nil, nil, []freee.Transfer{testTransfer(1, "2024-02-01", 1000)})
+	if run.Failed != 0 {
+		t.Fatalf("syncChanged() errors = %v", run.Errors)
+	}
+
+	key := beancount.FreeeKey("transfer", 1)
+	if hasEntry(t, oldPath, key) {
+		t.Errorf("entry left in %s", oldPath)
+	}
+	newPath, _ := c.pathResolver.GetMonthFilePath("2024-02")
+	if !hasEntry(t, newPath, key) {
+		t.Errorf("entry not written to %s", newPath)
+	}
+	record, _ := c.syncHistory.GetSyncRecord(db.SyncTypeTransfer, 1)
+	if record == nil || record.BeancountFile != newPath {
+		t.Errorf("GetSyncRecord() = %+v, expected file %s", record, newPath)
+	}
+}
+
+func TestSyncChangedLegacyRecord(t *testing.T) {
+	c := newTestSyncContext(t)
+	if err := c.repo.EnsureMonthFile("2024-01"); err != nil {
+		t.Fatalf("EnsureMonthFile() error = %v", err)
+	}
+	filePath, _ := c.pathResolver.GetMonthFilePath("2024-01")
+
+	// Entries of early versions had no freee metadata and no stored hash
+	legacy := readFile(t, filePath) + "\n2024-01-15 * \"口座振替\"\n  Assets:Wallet  1000 JPY\n  Assets:Bank  -1000 JPY\n"
+	if err := os.WriteFile(filePath, []byte(legacy), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	err := c.syncHistory.RecordSync(db.SyncRecord{
+		SyncType: db.SyncTypeTransfer, FreeeID: 1, IssueDate: "2024-01-15", Amount: 1000, BeancountFile: filePath,
+	})
+	if err != nil {
+		t.Fatalf("RecordSync() error = %v", err)
+	}
+
+	run := &db.SyncRun{}
+	transfer := testTransfer(1, "2024-01-15", 1000)
+	c.syncChanged(syncOptions{incremental: true}, run, nil, nil, []freee.Transfer{transfer})
+
+	if run.Updated != 0 || run.Failed != 0 {
+		t.Errorf("syncChanged() updated %d, failed %d (%v); expected nothing", run.Updated, run.Failed, run.Errors)
+	}
+	if got := readFile(t, filePath); got != legacy {
+		t.Errorf("syncChanged() rewrote a legacy entry:\n%s", got)
+	}
+	_, expected := c.builder.transfer(transfer, filePath, run)
+	record, _ := c.syncHistory.GetSyncRecord(db.SyncTypeTransfer, 1)
+	if record == nil || record.ContentHash != expected.ContentHash {
+		t.Errorf("GetSyncRecord() = %+v, expected the hash to be backfilled", record)
+	}
+
+	// Replacing an entry the file does not key fails instead of appending
+	formatted, changed := c.builder.transfer(testTransfer(1, "2024-01-15", 1500), filePath, run)
+	if err := c.replaceEntry(changed, formatted, run); err == nil {
+		t.Error("replaceEntry() of an entry missing from the file succeeded")
+	}
+	if got := readFile(t, filePath); got != legacy {
+		t.Errorf("replaceEntry() changed the file:\n%s", got)
+	}
+	if record, _ := c.syncHistory.GetSyncRecord(db.SyncTypeTransfer, 1); record == nil || record.Status != db.SyncStatusCommitted {
+		t.Errorf("GetSyncRecord() = %+v, expected the committed record to be restored", record)
+	}
+}
+
+func TestAdvanceWatermarks(t *testing.T) {
+	updatedAt := time.Date(2024, 1, 20, 10, 0, 0, 0, time.UTC)
+	deals := []freee.Deal{{ID: 1, UpdatedAt: updatedAt}}
+
+	tests := []struct {
+		name     string
+		failed   bool
+		expected time.Time
+	}{
+		{name: "successful run", expected: updatedAt},
+		{name: "failed run", failed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestSyncContext(t)
+			run := &db.SyncRun{CompanyID: 1}
+			if err := c.syncHistory.StartRun(run); err != nil {
+				t.Fatalf("StartRun() error = %v", err)
+			}
+			if tt.failed {
+				run.AddError("deal 1: write failed")
+			}
+
+			advanceWatermarks(c.syncHistory, run, deals, nil, false)
+
+			got, err := c.syncHistory.GetWatermark(db.WatermarkDeals, 1)
+			if err != nil {
+				t.Fatalf("GetWatermark() error = %v", err)
+			}
+			if !got.Equal(tt.expected) {
+				t.Errorf("deals watermark = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
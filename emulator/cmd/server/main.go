@@ -87,10 +87,14 @@ func main() {
 	companiesHandler := api.NewCompaniesHandler()
 	accountItemsHandler := api.NewAccountItemsHandler()
 	walletablesHandler := api.NewWalletablesHandler()
+	taxesHandler := api.NewTaxesHandler()
 	dealsHandler := api.NewDealsHandler(st)
-	journalsHandler := api.NewJournalsHandler(st)
+	manualJournalsHandler := api.NewManualJournalsHandler(st)
 	walletTxnsHandler := api.NewWalletTxnsHandler(st)
+	transfersHandler := api.NewTransfersHandler(st)
 	receiptsHandler := api.NewReceiptsHandler(st, uploadDir)
+	reportsHandler := api.NewReportsHandler(st, accountItemsHandler)
+	journalsHandler := api.NewJournalsHandler(st, reportsHandler)
 
 	// Setup router.
 	r := chi.NewRouter()
@@ -115,6 +119,7 @@ func main() {
 
 		// Companies endpoint.
 		r.Get("/companies", companiesHandler.List)
+		r.Get("/companies/{id}", companiesHandler.Get)
 
 		// Account Items endpoint.
 		r.Get("/account_items", accountItemsHandler.List)
@@ -122,6 +127,13 @@ func main() {
 		// Walletables endpoint.
 		r.Get("/walletables", walletablesHandler.List)
 
+		// Taxes endpoint.
+		r.Get("/taxes/codes", taxesHandler.ListCodes)
+
+		// Reports endpoints.
+		r.Get("/reports/trial_bs", reportsHandler.TrialBS)
+		r.Get("/reports/trial_pl", reportsHandler.TrialPL)
+
 		// Deals endpoints.
 		r.Route("/deals", func(r chi.Router) {
 			r.Get("/", dealsHandler.List)
@@ -131,11 +143,18 @@ func main() {
 			r.Delete("/{id}", dealsHandler.Delete)
 		})
 
-		// Journals endpoints.
+		// Manual Journals endpoints.
+		r.Route("/manual_journals", func(r chi.Router) {
+			r.Get("/", manualJournalsHandler.List)
+			r.Post("/", manualJournalsHandler.Create)
+			r.Get("/{id}", manualJournalsHandler.Get)
+		})
+
+		// Journals (asynchronous export) endpoints.
 		r.Route("/journals", func(r chi.Router) {
-			r.Get("/", journalsHandler.List)
-			r.Post("/", journalsHandler.Create)
-			r.Get("/{id}", journalsHandler.Get)
+			r.Get("/", journalsHandler.Export)
+			r.Get("/reports/{id}/status", journalsHandler.Status)
+			r.Get("/reports/{id}/download", journalsHandler.Download)
 		})
 
 		// Wallet Transactions endpoints.
@@ -147,11 +166,20 @@ func main() {
 			r.Delete("/{id}", walletTxnsHandler.Delete)
 		})
 
+		// Transfers endpoints.
+		r.Route("/transfers", func(r chi.Router) {
+			r.Get("/", transfersHandler.List)
+			r.Post("/", transfersHandler.Create)
+			r.Get("/{id}", transfersHandler.Get)
+			r.Delete("/{id}", transfersHandler.Delete)
+		})
+
 		// Receipts endpoints.
 		r.Route("/receipts", func(r chi.Router) {
 			r.Get("/", receiptsHandler.List)
 			r.Post("/", receiptsHandler.Create)
 			r.Get("/{id}", receiptsHandler.Get)
+			r.Get("/{id}/download", receiptsHandler.Download)
 			r.Delete("/{id}", receiptsHandler.Delete)
 		})
 	})
@@ -6,12 +6,14 @@ require (
 	github.com/go-chi/chi/v5 v5.2.3
 	github.com/pigeonworks-llc/go-portalloc v0.0.0-20251002123114-79f930784862
 	github.com/playwright-community/playwright-go v0.5200.1
+	github.com/shunichi-ikebuchi/accounting-system v0.0.0
 	github.com/swaggo/swag v1.16.6
 	go.etcd.io/bbolt v1.4.3
 	golang.org/x/oauth2 v0.31.0
 )
 
 require (
+	github.com/BurntSushi/toml v1.6.0 // indirect
 	github.com/KyleBanks/depth v1.2.1 // indirect
 	github.com/PuerkitoBio/purell v1.1.1 // indirect
 	github.com/PuerkitoBio/urlesc v0.0.0-20170810143723-de5bf2ad4578 // indirect
@@ -22,6 +24,7 @@ require (
 	github.com/go-openapi/spec v0.20.4 // indirect
 	github.com/go-openapi/swag v0.19.15 // indirect
 	github.com/go-stack/stack v1.8.1 // indirect
+	github.com/joho/godotenv v1.5.1 // indirect
 	github.com/josharian/intern v1.0.0 // indirect
 	github.com/mailru/easyjson v0.7.6 // indirect
 	golang.org/x/mod v0.17.0 // indirect
@@ -30,4 +33,7 @@ require (
 	golang.org/x/text v0.21.0 // indirect
 	golang.org/x/tools v0.21.1-0.20240508182429-e35e4ccd0d2d // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/shunichi-ikebuchi/accounting-system => ../
@@ -2,8 +2,12 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/pigeonworks-llc/freee-emulator/internal/models"
 )
 
@@ -45,3 +49,49 @@ func (h *CompaniesHandler) List(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(response)
 }
+
+// Get handles GET /api/1/companies/{id}.
+// @Summary Get company
+// @Description Get a company with its fiscal years (calendar years up to next year)
+// @Tags companies
+// @Accept json
+// @Produce json
+// @Param id path int true "Company ID"
+// @Success 200 {object} models.CompanyResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /companies/{id} [get]
+// @Security BearerAuth
+func (h *CompaniesHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Invalid company ID")
+		return
+	}
+
+	for _, company := range h.companies {
+		if company.ID != id {
+			continue
+		}
+		company.FiscalYears = calendarFiscalYears(2020, time.Now().Year()+1)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(models.CompanyResponse{Company: company})
+		return
+	}
+
+	writeJSONError(w, http.StatusNotFound, "not_found", "Company not found")
+}
+
+// calendarFiscalYears returns fiscal years from January to December, newest first.
+func calendarFiscalYears(from, to int) []models.FiscalYear {
+	var years []models.FiscalYear
+	for year := to; year >= from; year-- {
+		years = append(years, models.FiscalYear{
+			StartDate: fmt.Sprintf("%d-01-01", year),
+			EndDate:   fmt.Sprintf("%d-12-31", year),
+		})
+	}
+	return years
+}
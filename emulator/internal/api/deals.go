@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/pigeonworks-llc/freee-emulator/internal/models"
@@ -56,6 +57,22 @@ func (h *DealsHandler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Optional updated_at_from filter (RFC3339) for incremental sync.
+	if updatedFrom := r.URL.Query().Get("updated_at_from"); updatedFrom != "" {
+		since, err := time.Parse(time.RFC3339, updatedFrom)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Invalid updated_at_from")
+			return
+		}
+		filtered := deals[:0]
+		for _, item := range deals {
+			if !item.UpdatedAt.Before(since) {
+				filtered = append(filtered, item)
+			}
+		}
+		deals = filtered
+	}
+
 	response := map[string]interface{}{
 		"deals": deals,
 	}
@@ -1,115 +1,187 @@
 package api
 
 import (
+	"encoding/csv"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/pigeonworks-llc/freee-emulator/internal/models"
 	"github.com/pigeonworks-llc/freee-emulator/internal/store"
 )
 
-// JournalsHandler handles journal-related API endpoints.
-type JournalsHandler struct {
-	store *store.Store
+// journalExportTypes are the download types the emulator can generate.
+// All of them produce the same UTF-8 CSV in the 汎用形式 layout.
+var journalExportTypes = map[string]bool{
+	"csv":        true,
+	"generic":    true,
+	"generic_v2": true,
 }
 
-// NewJournalsHandler creates a new JournalsHandler.
-func NewJournalsHandler(s *store.Store) *JournalsHandler {
-	return &JournalsHandler{store: s}
+// journalExportHeader is the header row of a journal export.
+var journalExportHeader = []string{
+	"日付", "伝票番号", "決算整理仕訳",
+	"借方勘定科目", "借方金額", "借方税額",
+	"貸方勘定科目", "貸方金額", "貸方税額",
+	"摘要",
 }
 
-// List handles GET /api/1/journals.
-func (h *JournalsHandler) List(w http.ResponseWriter, r *http.Request) {
-	companyIDStr := r.URL.Query().Get("company_id")
-	var companyID *int64
+// JournalsHandler handles the asynchronous journal export (仕訳帳) endpoints.
+type JournalsHandler struct {
+	store   *store.Store
+	reports *ReportsHandler
+}
 
-	if companyIDStr != "" {
-		id, err := strconv.ParseInt(companyIDStr, 10, 64)
-		if err != nil {
-			writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Invalid company_id")
-			return
-		}
-		companyID = &id
-	}
+// NewJournalsHandler creates a new JournalsHandler. Exports are built from
+// the same slips as the trial balance reports.
+func NewJournalsHandler(s *store.Store, reports *ReportsHandler) *JournalsHandler {
+	return &JournalsHandler{store: s, reports: reports}
+}
 
-	journals, err := h.store.ListJournals(companyID)
+// Export handles GET /api/1/journals. It enqueues an export and responds
+// with 202 Accepted; the export is then polled through Status.
+func (h *JournalsHandler) Export(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	companyID, err := strconv.ParseInt(query.Get("company_id"), 10, 64)
 	if err != nil {
-		writeJSONError(w, http.StatusInternalServerError, "server_error", "Failed to list journals")
+		writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Invalid company_id")
 		return
 	}
 
-	response := map[string]interface{}{
-		"journals": journals,
+	downloadType := query.Get("download_type")
+	if !journalExportTypes[downloadType] {
+		writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Unsupported download_type")
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(response)
-}
-
-// Get handles GET /api/1/journals/{id}.
-func (h *JournalsHandler) Get(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Invalid journal ID")
+	startDate := query.Get("start_date")
+	endDate := query.Get("end_date")
+	if startDate == "" || endDate == "" {
+		writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "start_date and end_date are required")
 		return
 	}
 
-	journal, err := h.store.GetJournal(id)
+	export, err := h.store.CreateJournalExport(companyID, downloadType, startDate, endDate)
 	if err != nil {
-		if err == store.ErrNotFound {
-			writeJSONError(w, http.StatusNotFound, "not_found", "Journal not found")
-			return
-		}
-		writeJSONError(w, http.StatusInternalServerError, "server_error", "Failed to get journal")
+		writeJSONError(w, http.StatusInternalServerError, "server_error", "Failed to create journal export")
 		return
 	}
-
-	response := map[string]interface{}{
-		"journal": journal,
+	export.StatusURL = reportURL(r, export.ID, "status")
+	if err := h.store.UpdateJournalExport(export); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "server_error", "Failed to create journal export")
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(response)
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(models.JournalExportResponse{Journals: *export})
 }
 
-// Create handles POST /api/1/journals.
-func (h *JournalsHandler) Create(w http.ResponseWriter, r *http.Request) {
-	var req models.CreateJournalRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSONError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
+// Status handles GET /api/1/journals/reports/{id}/status. Every poll moves
+// the export one step from enqueued through working to uploaded, so
+// clients have to poll as they do against freee.
+func (h *JournalsHandler) Status(w http.ResponseWriter, r *http.Request) {
+	export, ok := h.export(w, r)
+	if !ok {
 		return
 	}
 
-	// Validate required fields.
-	if req.CompanyID == 0 {
-		writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Missing company_id")
+	switch export.Status {
+	case models.JournalExportEnqueued:
+		export.Status = models.JournalExportWorking
+	case models.JournalExportWorking:
+		export.Status = models.JournalExportUploaded
+		export.DownloadURL = reportURL(r, export.ID, "download")
+	}
+	if err := h.store.UpdateJournalExport(export); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "server_error", "Failed to update journal export")
 		return
 	}
-	if req.IssueDate == "" {
-		writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Missing issue_date")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(models.JournalExportResponse{Journals: *export})
+}
+
+// Download handles GET /api/1/journals/reports/{id}/download. Each posting
+// is written as a row with either its debit or credit side filled in; the
+// rows of one slip share the date and the 伝票番号.
+func (h *JournalsHandler) Download(w http.ResponseWriter, r *http.Request) {
+	export, ok := h.export(w, r)
+	if !ok {
 		return
 	}
-	if len(req.Details) == 0 {
-		writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Missing details")
+	if export.Status != models.JournalExportUploaded {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", "Journal export is not ready")
 		return
 	}
 
-	journal, err := h.store.CreateJournal(&req)
+	slips, err := h.reports.slips(export.CompanyID)
 	if err != nil {
-		writeJSONError(w, http.StatusInternalServerError, "server_error", "Failed to create journal")
+		writeJSONError(w, http.StatusInternalServerError, "server_error", "Failed to aggregate transactions")
 		return
 	}
 
-	response := map[string]interface{}{
-		"journal": journal,
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"journals_%d.csv\"", export.ID))
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	_ = cw.Write(journalExportHeader)
+	number := 0
+	for _, s := range slips {
+		if s.date < export.StartDate || s.date > export.EndDate {
+			continue
+		}
+		number++
+
+		adjustment := ""
+		if s.adjustment {
+			adjustment = "決算整理"
+		}
+		date := strings.ReplaceAll(s.date, "-", "/")
+		for _, p := range s.postings {
+			row := []string{date, strconv.Itoa(number), adjustment, "", "", "", "", "", "", s.description}
+			if p.amount >= 0 {
+				row[3], row[4], row[5] = h.reports.name(p.accountItemID), strconv.FormatInt(p.amount, 10), strconv.FormatInt(p.vat, 10)
+			} else {
+				row[6], row[7], row[8] = h.reports.name(p.accountItemID), strconv.FormatInt(-p.amount, 10), strconv.FormatInt(-p.vat, 10)
+			}
+			_ = cw.Write(row)
+		}
 	}
+	cw.Flush()
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	_ = json.NewEncoder(w).Encode(response)
+// export loads the journal export of the request. It writes an error
+// response and returns false on failure.
+func (h *JournalsHandler) export(w http.ResponseWriter, r *http.Request) (*models.JournalExport, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Invalid journal export ID")
+		return nil, false
+	}
+
+	export, err := h.store.GetJournalExport(id)
+	if err != nil {
+		if err == store.ErrNotFound {
+			writeJSONError(w, http.StatusNotFound, "not_found", "Journal export not found")
+			return nil, false
+		}
+		writeJSONError(w, http.StatusInternalServerError, "server_error", "Failed to get journal export")
+		return nil, false
+	}
+	return export, true
+}
+
+// reportURL returns the absolute URL of a journal export endpoint.
+func reportURL(r *http.Request, id int64, action string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/api/1/journals/reports/%d/%s", scheme, r.Host, id, action)
 }
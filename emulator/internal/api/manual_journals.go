@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pigeonworks-llc/freee-emulator/internal/models"
+	"github.com/pigeonworks-llc/freee-emulator/internal/store"
+)
+
+// ManualJournalsHandler handles manual journal (振替伝票) API endpoints.
+type ManualJournalsHandler struct {
+	store *store.Store
+}
+
+// NewManualJournalsHandler creates a new ManualJournalsHandler.
+func NewManualJournalsHandler(s *store.Store) *ManualJournalsHandler {
+	return &ManualJournalsHandler{store: s}
+}
+
+// List handles GET /api/1/manual_journals.
+func (h *ManualJournalsHandler) List(w http.ResponseWriter, r *http.Request) {
+	companyIDStr := r.URL.Query().Get("company_id")
+	var companyID *int64
+
+	if companyIDStr != "" {
+		id, err := strconv.ParseInt(companyIDStr, 10, 64)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Invalid company_id")
+			return
+		}
+		companyID = &id
+	}
+
+	journals, err := h.store.ListManualJournals(companyID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "server_error", "Failed to list manual journals")
+		return
+	}
+
+	// Optional start_issue_date/end_issue_date filter (YYYY-MM-DD).
+	startDate := r.URL.Query().Get("start_issue_date")
+	endDate := r.URL.Query().Get("end_issue_date")
+	inRange := journals[:0]
+	for _, item := range journals {
+		if (startDate == "" || item.IssueDate >= startDate) && (endDate == "" || item.IssueDate <= endDate) {
+			inRange = append(inRange, item)
+		}
+	}
+	journals = inRange
+
+	// Optional updated_at_from filter (RFC3339) for incremental sync.
+	if updatedFrom := r.URL.Query().Get("updated_at_from"); updatedFrom != "" {
+		since, err := time.Parse(time.RFC3339, updatedFrom)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Invalid updated_at_from")
+			return
+		}
+		filtered := journals[:0]
+		for _, item := range journals {
+			if !item.UpdatedAt.Before(since) {
+				filtered = append(filtered, item)
+			}
+		}
+		journals = filtered
+	}
+
+	response := map[string]interface{}{
+		"manual_journals": journals,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// Get handles GET /api/1/manual_journals/{id}.
+func (h *ManualJournalsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Invalid manual journal ID")
+		return
+	}
+
+	journal, err := h.store.GetManualJournal(id)
+	if err != nil {
+		if err == store.ErrNotFound {
+			writeJSONError(w, http.StatusNotFound, "not_found", "Manual journal not found")
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, "server_error", "Failed to get manual journal")
+		return
+	}
+
+	response := map[string]interface{}{
+		"manual_journal": journal,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// Create handles POST /api/1/manual_journals.
+func (h *ManualJournalsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateManualJournalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
+		return
+	}
+
+	// Validate required fields.
+	if req.CompanyID == 0 {
+		writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Missing company_id")
+		return
+	}
+	if req.IssueDate == "" {
+		writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Missing issue_date")
+		return
+	}
+	if len(req.Details) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Missing details")
+		return
+	}
+
+	journal, err := h.store.CreateManualJournal(&req)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "server_error", "Failed to create manual journal")
+		return
+	}
+
+	response := map[string]interface{}{
+		"manual_journal": journal,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(response)
+}
@@ -181,6 +181,39 @@ func (h *ReceiptsHandler) Get(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(response)
 }
 
+// Download handles GET /api/1/receipts/{id}/download
+func (h *ReceiptsHandler) Download(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Invalid receipt ID")
+		return
+	}
+
+	receipt, err := h.store.GetReceipt(id)
+	if err != nil {
+		if err == store.ErrNotFound {
+			writeJSONError(w, http.StatusNotFound, "not_found", "Receipt not found")
+		} else {
+			writeJSONError(w, http.StatusInternalServerError, "server_error", "Failed to get receipt")
+		}
+		return
+	}
+
+	// Files are stored as {uploadDir}/{company_id}/{id}.pdf by Create.
+	filePath := filepath.Join(h.uploadDir, fmt.Sprintf("%d", receipt.CompanyID), fmt.Sprintf("%d.pdf", receipt.ID))
+	file, err := os.Open(filePath)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, "not_found", "Receipt file not found")
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, file)
+}
+
 // Delete handles DELETE /api/1/receipts/{id}
 func (h *ReceiptsHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
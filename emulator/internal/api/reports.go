@@ -0,0 +1,309 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/pigeonworks-llc/freee-emulator/internal/models"
+	"github.com/pigeonworks-llc/freee-emulator/internal/store"
+)
+
+// walletableAccountItems maps walletable types to the account items
+// their payments are booked to.
+var walletableAccountItems = map[string]int64{
+	"bank_account": 102, // 普通預金
+	"credit_card":  203, // クレジットカード
+	"wallet":       101, // 現金
+}
+
+// Account items for the unpaid part of deals.
+const (
+	accountsReceivableID = 103 // 売掛金
+	accountsPayableID    = 202 // 未払金
+)
+
+// ReportsHandler handles report-related API endpoints.
+type ReportsHandler struct {
+	store        *store.Store
+	accountItems *AccountItemsHandler
+}
+
+// NewReportsHandler creates a new ReportsHandler.
+func NewReportsHandler(s *store.Store, accountItems *AccountItemsHandler) *ReportsHandler {
+	return &ReportsHandler{store: s, accountItems: accountItems}
+}
+
+// posting is a single debit (positive) or credit (negative) amount and
+// the VAT included in it.
+type posting struct {
+	date          string
+	accountItemID int64
+	name          string
+	amount        int64
+	vat           int64
+}
+
+// TrialBS handles GET /api/1/reports/trial_bs.
+// @Summary Get trial balance sheet
+// @Description Get the trial balance of balance sheet accounts for a date range
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param company_id query int true "Company ID"
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Success 200 {object} models.TrialBSResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /reports/trial_bs [get]
+// @Security BearerAuth
+func (h *ReportsHandler) TrialBS(w http.ResponseWriter, r *http.Request) {
+	trial, ok := h.trialBalance(w, r, true)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(models.TrialBSResponse{TrialBS: trial})
+}
+
+// TrialPL handles GET /api/1/reports/trial_pl.
+// @Summary Get trial profit and loss
+// @Description Get the trial balance of income and expense accounts for a date range
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param company_id query int true "Company ID"
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Success 200 {object} models.TrialPLResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /reports/trial_pl [get]
+// @Security BearerAuth
+func (h *ReportsHandler) TrialPL(w http.ResponseWriter, r *http.Request) {
+	trial, ok := h.trialBalance(w, r, false)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(models.TrialPLResponse{TrialPL: trial})
+}
+
+// trialBalance aggregates deals, manual journals and transfers into a trial balance of
+// balance sheet (bs) or profit and loss accounts. It writes an error
+// response and returns false on failure.
+func (h *ReportsHandler) trialBalance(w http.ResponseWriter, r *http.Request, bs bool) (models.TrialBalance, bool) {
+	companyIDStr := r.URL.Query().Get("company_id")
+	if companyIDStr == "" {
+		writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "company_id is required")
+		return models.TrialBalance{}, false
+	}
+	companyID, err := strconv.ParseInt(companyIDStr, 10, 64)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Invalid company_id")
+		return models.TrialBalance{}, false
+	}
+
+	startDate := r.URL.Query().Get("start_date")
+	endDate := r.URL.Query().Get("end_date")
+
+	postings, err := h.postings(companyID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "server_error", "Failed to aggregate transactions")
+		return models.TrialBalance{}, false
+	}
+
+	entries := make(map[int64]*models.TrialBalanceEntry)
+	for _, p := range postings {
+		if endDate != "" && p.date > endDate {
+			continue
+		}
+		category := h.category(p.accountItemID)
+		if isBalanceSheet(category) != bs {
+			continue
+		}
+
+		before := startDate != "" && p.date < startDate
+		if before && !bs {
+			continue // Profit and loss accounts start from zero
+		}
+
+		entry, ok := entries[p.accountItemID]
+		if !ok {
+			entry = &models.TrialBalanceEntry{
+				AccountItemID:       p.accountItemID,
+				AccountItemName:     p.name,
+				AccountCategoryName: category,
+				HierarchyLevel:      1,
+			}
+			entries[p.accountItemID] = entry
+		}
+
+		// Balances are positive on the normal side of the account
+		amount := p.amount
+		if !isDebitNormal(category) {
+			amount = -amount
+		}
+
+		switch {
+		case before:
+			entry.OpeningBalance += amount
+		case p.amount >= 0:
+			entry.DebitAmount += p.amount
+		default:
+			entry.CreditAmount += -p.amount
+		}
+		entry.ClosingBalance += amount
+	}
+
+	trial := models.TrialBalance{
+		CompanyID: companyID,
+		StartDate: startDate,
+		EndDate:   endDate,
+		Balances:  []models.TrialBalanceEntry{},
+	}
+	for _, entry := range entries {
+		trial.Balances = append(trial.Balances, *entry)
+	}
+	sort.Slice(trial.Balances, func(i, j int) bool {
+		return trial.Balances[i].AccountItemID < trial.Balances[j].AccountItemID
+	})
+
+	return trial, true
+}
+
+// slip is the balanced set of postings booked by one deal, manual
+// journal or transfer.
+type slip struct {
+	date        string
+	adjustment  bool
+	description string
+	postings    []posting
+}
+
+// postings converts the deals, manual journals and transfers of a company
+// to postings.
+func (h *ReportsHandler) postings(companyID int64) ([]posting, error) {
+	slips, err := h.slips(companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var postings []posting
+	for _, s := range slips {
+		postings = append(postings, s.postings...)
+	}
+	return postings, nil
+}
+
+// slips converts the deals, manual journals and transfers of a company to
+// slips ordered by date. Deal details are booked including VAT; payments
+// are booked to the account item of the walletable and the unpaid rest to
+// receivables or payables.
+func (h *ReportsHandler) slips(companyID int64) ([]slip, error) {
+	var slips []slip
+
+	deals, err := h.store.ListDeals(&companyID)
+	if err != nil {
+		return nil, err
+	}
+	for _, deal := range deals {
+		sign := int64(1)
+		if deal.Type == "income" {
+			sign = -1
+		}
+
+		s := slip{date: deal.IssueDate}
+		for _, d := range deal.Details {
+			if s.description == "" && d.Description != nil {
+				s.description = *d.Description
+			}
+			s.postings = append(s.postings, posting{deal.IssueDate, d.AccountItemID, d.AccountItemName, sign * (d.Amount + d.Vat), sign * d.Vat})
+		}
+
+		unpaid := deal.Amount
+		for _, p := range deal.Payments {
+			id := walletableAccountItems[p.FromWalletableType]
+			s.postings = append(s.postings, posting{deal.IssueDate, id, h.name(id), -sign * p.Amount, 0})
+			unpaid -= p.Amount
+		}
+		if unpaid != 0 {
+			id := int64(accountsPayableID)
+			if deal.Type == "income" {
+				id = accountsReceivableID
+			}
+			s.postings = append(s.postings, posting{deal.IssueDate, id, h.name(id), -sign * unpaid, 0})
+		}
+		slips = append(slips, s)
+	}
+
+	journals, err := h.store.ListManualJournals(&companyID)
+	if err != nil {
+		return nil, err
+	}
+	for _, journal := range journals {
+		s := slip{date: journal.IssueDate, adjustment: journal.Adjustment}
+		for _, d := range journal.Details {
+			if s.description == "" && d.Description != nil {
+				s.description = *d.Description
+			}
+			amount, vat := d.Amount, d.Vat
+			if d.EntrySide == "credit" {
+				amount, vat = -amount, -vat
+			}
+			s.postings = append(s.postings, posting{journal.IssueDate, d.AccountItemID, d.AccountItemName, amount, vat})
+		}
+		slips = append(slips, s)
+	}
+
+	transfers, err := h.store.ListTransfers(&companyID)
+	if err != nil {
+		return nil, err
+	}
+	for _, transfer := range transfers {
+		from := walletableAccountItems[transfer.FromWalletableType]
+		to := walletableAccountItems[transfer.ToWalletableType]
+		slips = append(slips, slip{
+			date:        transfer.Date,
+			description: transfer.Description,
+			postings: []posting{
+				{transfer.Date, to, h.name(to), transfer.Amount, 0},
+				{transfer.Date, from, h.name(from), -transfer.Amount, 0},
+			},
+		})
+	}
+
+	sort.SliceStable(slips, func(i, j int) bool { return slips[i].date < slips[j].date })
+	return slips, nil
+}
+
+// category returns the account category of an account item. Unknown
+// account items are treated as expenses.
+func (h *ReportsHandler) category(accountItemID int64) string {
+	if item := h.accountItems.GetByID(accountItemID); item != nil {
+		return item.AccountCategory
+	}
+	return "expense"
+}
+
+// name returns the name of an account item.
+func (h *ReportsHandler) name(accountItemID int64) string {
+	if item := h.accountItems.GetByID(accountItemID); item != nil {
+		return item.Name
+	}
+	return "Account Item " + strconv.FormatInt(accountItemID, 10)
+}
+
+func isBalanceSheet(category string) bool {
+	return category == "asset" || category == "liability" || category == "equity"
+}
+
+func isDebitNormal(category string) bool {
+	return category == "asset" || category == "expense"
+}
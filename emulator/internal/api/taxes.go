@@ -0,0 +1,52 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pigeonworks-llc/freee-emulator/internal/models"
+)
+
+// TaxesHandler handles tax-related API endpoints.
+type TaxesHandler struct {
+	taxCodes []models.TaxCode
+}
+
+// NewTaxesHandler creates a new TaxesHandler with default tax codes.
+func NewTaxesHandler() *TaxesHandler {
+	return &TaxesHandler{
+		taxCodes: defaultTaxCodes(),
+	}
+}
+
+// defaultTaxCodes returns the tax codes used by the default account items.
+func defaultTaxCodes() []models.TaxCode {
+	return []models.TaxCode{
+		{Code: 0, Name: "none", NameJa: "対象外"},
+		{Code: 2, Name: "non_taxable", NameJa: "非課税売上"},
+		{Code: 21, Name: "sales_with_tax_10", NameJa: "課税売上10%"},
+		{Code: 34, Name: "purchase_with_tax_8", NameJa: "課対仕入8%"},
+		{Code: 129, Name: "sales_with_tax_reduced_8", NameJa: "課税売上8%（軽）"},
+		{Code: 136, Name: "purchase_with_tax_10", NameJa: "課対仕入10%"},
+		{Code: 163, Name: "purchase_with_tax_reduced_8", NameJa: "課対仕入8%（軽）"},
+	}
+}
+
+// ListCodes handles GET /api/1/taxes/codes.
+// @Summary List tax codes
+// @Description Get list of tax codes
+// @Tags taxes
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.TaxCodesResponse
+// @Router /taxes/codes [get]
+// @Security BearerAuth
+func (h *TaxesHandler) ListCodes(w http.ResponseWriter, r *http.Request) {
+	response := models.TaxCodesResponse{
+		Taxes: h.taxCodes,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pigeonworks-llc/freee-emulator/internal/models"
+	"github.com/pigeonworks-llc/freee-emulator/internal/store"
+)
+
+// TransfersHandler handles transfer-related API endpoints.
+type TransfersHandler struct {
+	store *store.Store
+}
+
+// NewTransfersHandler creates a new TransfersHandler.
+func NewTransfersHandler(s *store.Store) *TransfersHandler {
+	return &TransfersHandler{store: s}
+}
+
+// List handles GET /api/1/transfers.
+func (h *TransfersHandler) List(w http.ResponseWriter, r *http.Request) {
+	companyIDStr := r.URL.Query().Get("company_id")
+	var companyID *int64
+
+	if companyIDStr != "" {
+		id, err := strconv.ParseInt(companyIDStr, 10, 64)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Invalid company_id")
+			return
+		}
+		companyID = &id
+	}
+
+	transfers, err := h.store.ListTransfers(companyID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "server_error", "Failed to list transfers")
+		return
+	}
+
+	// Optional start_date/end_date filter (YYYY-MM-DD).
+	startDate := r.URL.Query().Get("start_date")
+	endDate := r.URL.Query().Get("end_date")
+	filtered := transfers[:0]
+	for _, transfer := range transfers {
+		if (startDate == "" || transfer.Date >= startDate) && (endDate == "" || transfer.Date <= endDate) {
+			filtered = append(filtered, transfer)
+		}
+	}
+
+	response := map[string]interface{}{
+		"transfers": filtered,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// Get handles GET /api/1/transfers/{id}.
+func (h *TransfersHandler) Get(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Invalid transfer ID")
+		return
+	}
+
+	transfer, err := h.store.GetTransfer(id)
+	if err != nil {
+		if err == store.ErrNotFound {
+			writeJSONError(w, http.StatusNotFound, "not_found", "Transfer not found")
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, "server_error", "Failed to get transfer")
+		return
+	}
+
+	response := map[string]interface{}{
+		"transfer": transfer,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// Create handles POST /api/1/transfers.
+func (h *TransfersHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
+		return
+	}
+
+	// Validate required fields.
+	if req.CompanyID == 0 {
+		writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Missing company_id")
+		return
+	}
+	if req.Date == "" {
+		writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Missing date")
+		return
+	}
+	if req.FromWalletableType == "" || req.ToWalletableType == "" {
+		writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Missing from_walletable_type or to_walletable_type")
+		return
+	}
+	if req.Amount <= 0 {
+		writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Amount must be positive")
+		return
+	}
+
+	transfer, err := h.store.CreateTransfer(&req)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "server_error", "Failed to create transfer")
+		return
+	}
+
+	response := map[string]interface{}{
+		"transfer": transfer,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// Delete handles DELETE /api/1/transfers/{id}.
+func (h *TransfersHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_parameter", "Invalid transfer ID")
+		return
+	}
+
+	if err := h.store.DeleteTransfer(id); err != nil {
+		if err == store.ErrNotFound {
+			writeJSONError(w, http.StatusNotFound, "not_found", "Transfer not found")
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, "server_error", "Failed to delete transfer")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
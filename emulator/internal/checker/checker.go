@@ -12,8 +12,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"time"
 
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/config"
 	"golang.org/x/oauth2"
 )
 
@@ -116,21 +118,36 @@ func (ts *TokenStore) Save(token *oauth2.Token) error {
 	return nil
 }
 
-// LoadConfig loads configuration from environment variables
+// LoadConfig loads configuration from the shared config file (FREEE_CONFIG,
+// profile FREEE_PROFILE) and environment variables
 func LoadConfig() (*Config, error) {
+	shared, err := config.LoadWith(config.Options{
+		Defaults: &config.Config{Freee: config.FreeeConfig{APIURL: "https://api.freee.co.jp"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := &Config{
-		ClientID:     os.Getenv("FREEE_CLIENT_ID"),
-		ClientSecret: os.Getenv("FREEE_CLIENT_SECRET"),
+		ClientID:     shared.Freee.ClientID,
+		ClientSecret: shared.Freee.ClientSecret,
 		RedirectURL:  os.Getenv("FREEE_REDIRECT_URL"),
-		CompanyID:    os.Getenv("FREEE_COMPANY_ID"),
 		WebhookURL:   os.Getenv("GOOGLE_CHAT_WEBHOOK"),
-		BaseURL:      os.Getenv("FREEE_API_URL"),
+		BaseURL:      shared.Freee.APIURL,
 		TokenFile:    os.Getenv("FREEE_TOKEN_FILE"),
 		AutoReauth:   os.Getenv("FREEE_AUTO_REAUTH") == "true",
 	}
 
-	if cfg.BaseURL == "" {
-		cfg.BaseURL = "https://api.freee.co.jp"
+	if shared.Freee.CompanyID != 0 {
+		cfg.CompanyID = strconv.FormatInt(shared.Freee.CompanyID, 10)
+	}
+
+	if cfg.RedirectURL == "" {
+		cfg.RedirectURL = shared.Freee.RedirectURI
+	}
+
+	if cfg.TokenFile == "" {
+		cfg.TokenFile = shared.Freee.TokenStore
 	}
 
 	if cfg.TokenFile == "" {
@@ -142,8 +159,8 @@ func LoadConfig() (*Config, error) {
 		cfg.RedirectURL = "urn:ietf:wg:oauth:2.0:oob"
 	}
 
-	if cfg.CompanyID == "" {
-		return nil, fmt.Errorf("FREEE_COMPANY_ID is required")
+	if err := shared.Validate(config.FreeeCompanyID); err != nil {
+		return nil, err
 	}
 
 	return cfg, nil
@@ -6,9 +6,22 @@ type Company struct {
 	DisplayName string `json:"display_name"`
 	Name        string `json:"name"`
 	NameKana    string `json:"name_kana"`
+	// FiscalYears is only returned by GET /api/1/companies/{id}.
+	FiscalYears []FiscalYear `json:"fiscal_years,omitempty"`
+}
+
+// FiscalYear represents a fiscal year (会計期間) of a company.
+type FiscalYear struct {
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
 }
 
 // CompaniesResponse represents the response for GET /api/1/companies
 type CompaniesResponse struct {
 	Companies []Company `json:"companies"`
 }
+
+// CompanyResponse represents the response for GET /api/1/companies/{id}
+type CompanyResponse struct {
+	Company Company `json:"company"`
+}
@@ -16,6 +16,7 @@ type Deal struct {
 	RefNumber   *string     `json:"ref_number,omitempty"`
 	PartnerID   *int64      `json:"partner_id,omitempty"`
 	PartnerCode *string     `json:"partner_code,omitempty"`
+	Receipts    []DealReceipt `json:"receipts,omitempty"`
 	CreatedAt   time.Time   `json:"created_at"`
 	UpdatedAt   time.Time   `json:"updated_at"`
 }
@@ -62,6 +63,7 @@ type CreateDealRequest struct {
 	Payments  []CreatePaymentRequest `json:"payments,omitempty"`
 	RefNumber *string                `json:"ref_number,omitempty"`
 	PartnerID *int64                 `json:"partner_id,omitempty"`
+	ReceiptIDs []int64               `json:"receipt_ids,omitempty"`
 }
 
 // CreatePaymentRequest represents payment information in create deal request.
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Journal export statuses, in the order an export goes through them.
+const (
+	JournalExportEnqueued = "enqueued"
+	JournalExportWorking  = "working"
+	JournalExportUploaded = "uploaded"
+	JournalExportFailed   = "failed"
+)
+
+// JournalExport represents an asynchronous journal export (仕訳帳
+// ダウンロード) requested through GET /api/1/journals.
+type JournalExport struct {
+	ID           int64     `json:"id"`
+	CompanyID    int64     `json:"company_id"`
+	DownloadType string    `json:"download_type"` // csv, generic or generic_v2
+	StartDate    string    `json:"start_date"`
+	EndDate      string    `json:"end_date"`
+	Status       string    `json:"status"`
+	Messages     []string  `json:"messages"`
+	StatusURL    string    `json:"status_url,omitempty"`
+	DownloadURL  string    `json:"download_url,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// JournalExportResponse represents the response of the journal export
+// request and status endpoints.
+type JournalExportResponse struct {
+	Journals JournalExport `json:"journals"`
+}
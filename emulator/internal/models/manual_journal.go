@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// ManualJournal represents a manual journal entry (振替伝票) in freee accounting API.
+type ManualJournal struct {
+	ID         int64                 `json:"id"`
+	CompanyID  int64                 `json:"company_id"`
+	IssueDate  string                `json:"issue_date"` // YYYY-MM-DD
+	Adjustment bool                  `json:"adjustment"` // 決算整理仕訳
+	TxnNumber  *string               `json:"txn_number,omitempty"`
+	Details    []ManualJournalDetail `json:"details"`
+	CreatedAt  time.Time             `json:"created_at"`
+	UpdatedAt  time.Time             `json:"updated_at"`
+}
+
+// ManualJournalDetail represents a single line in a manual journal entry.
+type ManualJournalDetail struct {
+	ID              int64    `json:"id"`
+	EntrySide       string   `json:"entry_side"` // debit or credit
+	AccountItemID   int64    `json:"account_item_id"`
+	AccountItemName string   `json:"account_item_name"`
+	TaxCode         int      `json:"tax_code"`
+	PartnerID       *int64   `json:"partner_id,omitempty"`
+	PartnerCode     *string  `json:"partner_code,omitempty"`
+	Amount          int64    `json:"amount"`
+	Vat             int64    `json:"vat"`
+	Description     *string  `json:"description,omitempty"`
+	ItemID          *int64   `json:"item_id,omitempty"`
+	ItemName        *string  `json:"item_name,omitempty"`
+	SectionID       *int64   `json:"section_id,omitempty"`
+	SectionName     *string  `json:"section_name,omitempty"`
+	TagIDs          []int64  `json:"tag_ids,omitempty"`
+	TagNames        []string `json:"tag_names,omitempty"`
+	Segment1TagID   *int64   `json:"segment_1_tag_id,omitempty"`
+	Segment1TagName *string  `json:"segment_1_tag_name,omitempty"`
+	Segment2TagID   *int64   `json:"segment_2_tag_id,omitempty"`
+	Segment2TagName *string  `json:"segment_2_tag_name,omitempty"`
+	Segment3TagID   *int64   `json:"segment_3_tag_id,omitempty"`
+	Segment3TagName *string  `json:"segment_3_tag_name,omitempty"`
+}
+
+// CreateManualJournalRequest represents the request to create a manual journal entry.
+type CreateManualJournalRequest struct {
+	CompanyID  int64                              `json:"company_id"`
+	IssueDate  string                             `json:"issue_date"`
+	Adjustment bool                               `json:"adjustment"`
+	TxnNumber  *string                            `json:"txn_number,omitempty"`
+	Details    []CreateManualJournalDetailRequest `json:"details"`
+}
+
+// CreateManualJournalDetailRequest represents a detail in create manual journal request.
+type CreateManualJournalDetailRequest struct {
+	EntrySide     string  `json:"entry_side"`
+	AccountItemID int64   `json:"account_item_id"`
+	TaxCode       int     `json:"tax_code"`
+	PartnerID     *int64  `json:"partner_id,omitempty"`
+	Amount        int64   `json:"amount"`
+	Vat           int64   `json:"vat"`
+	Description   *string `json:"description,omitempty"`
+	ItemID        *int64  `json:"item_id,omitempty"`
+	SectionID     *int64  `json:"section_id,omitempty"`
+}
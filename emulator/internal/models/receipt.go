@@ -15,6 +15,15 @@ type Receipt struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// DealReceipt represents a receipt attached to a deal.
+type DealReceipt struct {
+	ID          int64  `json:"id"`
+	Status      string `json:"status"`
+	Description string `json:"description"`
+	MimeType    string `json:"mime_type"`
+	IssueDate   string `json:"issue_date"`
+}
+
 // CreateReceiptRequest represents the request to create a receipt
 // Note: File upload is handled separately via multipart/form-data
 type CreateReceiptRequest struct {
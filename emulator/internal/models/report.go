@@ -0,0 +1,31 @@
+package models
+
+// TrialBalance represents a trial balance report (試算表) in freee.
+type TrialBalance struct {
+	CompanyID int64               `json:"company_id"`
+	StartDate string              `json:"start_date"`
+	EndDate   string              `json:"end_date"`
+	Balances  []TrialBalanceEntry `json:"balances"`
+}
+
+// TrialBalanceEntry represents a line of a trial balance report.
+type TrialBalanceEntry struct {
+	AccountItemID       int64  `json:"account_item_id"`
+	AccountItemName     string `json:"account_item_name"`
+	AccountCategoryName string `json:"account_category_name"`
+	HierarchyLevel      int    `json:"hierarchy_level"`
+	OpeningBalance      int64  `json:"opening_balance"`
+	DebitAmount         int64  `json:"debit_amount"`
+	CreditAmount        int64  `json:"credit_amount"`
+	ClosingBalance      int64  `json:"closing_balance"`
+}
+
+// TrialBSResponse represents the response for GET /api/1/reports/trial_bs
+type TrialBSResponse struct {
+	TrialBS TrialBalance `json:"trial_bs"`
+}
+
+// TrialPLResponse represents the response for GET /api/1/reports/trial_pl
+type TrialPLResponse struct {
+	TrialPL TrialBalance `json:"trial_pl"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// TaxCode represents a tax code (税区分) in freee.
+type TaxCode struct {
+	Code   int    `json:"code"`
+	Name   string `json:"name"`
+	NameJa string `json:"name_ja"`
+}
+
+// TaxCodesResponse represents the response for GET /api/1/taxes/codes
+type TaxCodesResponse struct {
+	Taxes []TaxCode `json:"taxes"`
+}
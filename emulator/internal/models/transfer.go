@@ -0,0 +1,26 @@
+package models
+
+// Transfer represents a transfer between walletables (口座振替) in freee accounting API.
+type Transfer struct {
+	ID                 int64  `json:"id"`
+	CompanyID          int64  `json:"company_id"`
+	Date               string `json:"date"` // YYYY-MM-DD
+	Amount             int64  `json:"amount"`
+	FromWalletableType string `json:"from_walletable_type"` // bank_account, credit_card or wallet
+	FromWalletableID   int64  `json:"from_walletable_id"`
+	ToWalletableType   string `json:"to_walletable_type"`
+	ToWalletableID     int64  `json:"to_walletable_id"`
+	Description        string `json:"description"`
+}
+
+// CreateTransferRequest represents the request to create a transfer.
+type CreateTransferRequest struct {
+	CompanyID          int64  `json:"company_id"`
+	Date               string `json:"date"`
+	Amount             int64  `json:"amount"`
+	FromWalletableType string `json:"from_walletable_type"`
+	FromWalletableID   int64  `json:"from_walletable_id"`
+	ToWalletableType   string `json:"to_walletable_type"`
+	ToWalletableID     int64  `json:"to_walletable_id"`
+	Description        string `json:"description"`
+}
@@ -79,6 +79,21 @@ func (s *Store) CreateDeal(req *models.CreateDealRequest) (*models.Deal, error)
 		deal.Payments = payments
 	}
 
+	// Attach uploaded receipts.
+	for _, receiptID := range req.ReceiptIDs {
+		receipt, err := s.GetReceipt(receiptID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get receipt %d: %w", receiptID, err)
+		}
+		deal.Receipts = append(deal.Receipts, models.DealReceipt{
+			ID:          receipt.ID,
+			Status:      receipt.Status,
+			Description: receipt.Description,
+			MimeType:    "application/pdf",
+			IssueDate:   receipt.IssueDate,
+		})
+	}
+
 	if err := s.Put(BucketDeals, id, deal); err != nil {
 		return nil, fmt.Errorf("failed to save deal: %w", err)
 	}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pigeonworks-llc/freee-emulator/internal/models"
+)
+
+// CreateJournalExport creates a new enqueued journal export in the database.
+func (s *Store) CreateJournalExport(companyID int64, downloadType, startDate, endDate string) (*models.JournalExport, error) {
+	id, err := s.NextID(BucketJournalExports)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ID: %w", err)
+	}
+
+	export := &models.JournalExport{
+		ID:           id,
+		CompanyID:    companyID,
+		DownloadType: downloadType,
+		StartDate:    startDate,
+		EndDate:      endDate,
+		Status:       models.JournalExportEnqueued,
+		Messages:     []string{"集計を開始しました。"},
+		CreatedAt:    time.Now(),
+	}
+
+	if err := s.Put(BucketJournalExports, id, export); err != nil {
+		return nil, fmt.Errorf("failed to save journal export: %w", err)
+	}
+
+	return export, nil
+}
+
+// GetJournalExport retrieves a journal export by ID.
+func (s *Store) GetJournalExport(id int64) (*models.JournalExport, error) {
+	var export models.JournalExport
+	if err := s.Get(BucketJournalExports, id, &export); err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// UpdateJournalExport saves the state of a journal export.
+func (s *Store) UpdateJournalExport(export *models.JournalExport) error {
+	if err := s.Put(BucketJournalExports, export.ID, export); err != nil {
+		return fmt.Errorf("failed to save journal export: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pigeonworks-llc/freee-emulator/internal/models"
+)
+
+// CreateManualJournal creates a new manual journal entry in the database.
+func (s *Store) CreateManualJournal(req *models.CreateManualJournalRequest) (*models.ManualJournal, error) {
+	id, err := s.NextID(BucketManualJournals)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ID: %w", err)
+	}
+
+	now := time.Now()
+	journal := &models.ManualJournal{
+		ID:         id,
+		CompanyID:  req.CompanyID,
+		IssueDate:  req.IssueDate,
+		Adjustment: req.Adjustment,
+		TxnNumber:  req.TxnNumber,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	// Convert CreateManualJournalDetailRequest to ManualJournalDetail.
+	details := make([]models.ManualJournalDetail, len(req.Details))
+	for i, d := range req.Details {
+		detailID, err := s.NextID(BucketManualJournals)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate detail ID: %w", err)
+		}
+
+		details[i] = models.ManualJournalDetail{
+			ID:              detailID,
+			EntrySide:       d.EntrySide,
+			AccountItemID:   d.AccountItemID,
+			AccountItemName: fmt.Sprintf("Account Item %d", d.AccountItemID),
+			TaxCode:         d.TaxCode,
+			PartnerID:       d.PartnerID,
+			Amount:          d.Amount,
+			Vat:             d.Vat,
+			Description:     d.Description,
+			ItemID:          d.ItemID,
+			SectionID:       d.SectionID,
+		}
+	}
+
+	journal.Details = details
+
+	if err := s.Put(BucketManualJournals, id, journal); err != nil {
+		return nil, fmt.Errorf("failed to save manual journal: %w", err)
+	}
+
+	return journal, nil
+}
+
+// GetManualJournal retrieves a manual journal entry by ID.
+func (s *Store) GetManualJournal(id int64) (*models.ManualJournal, error) {
+	var journal models.ManualJournal
+	if err := s.Get(BucketManualJournals, id, &journal); err != nil {
+		return nil, err
+	}
+	return &journal, nil
+}
+
+// ListManualJournals retrieves all manual journal entries, optionally filtered by company ID.
+func (s *Store) ListManualJournals(companyID *int64) ([]*models.ManualJournal, error) {
+	filter := func(data []byte) bool {
+		if companyID == nil {
+			return true
+		}
+
+		var journal models.ManualJournal
+		if err := json.Unmarshal(data, &journal); err != nil {
+			return false
+		}
+		return journal.CompanyID == *companyID
+	}
+
+	results, err := s.List(BucketManualJournals, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	journals := make([]*models.ManualJournal, 0, len(results))
+	for _, data := range results {
+		var journal models.ManualJournal
+		if err := json.Unmarshal(data, &journal); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal manual journal: %w", err)
+		}
+		journals = append(journals, &journal)
+	}
+
+	return journals, nil
+}
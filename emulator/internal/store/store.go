@@ -19,11 +19,13 @@ var (
 
 // Bucket names.
 const (
-	BucketTokens     = "tokens"
-	BucketDeals      = "deals"
-	BucketJournals   = "journals"
-	BucketWalletTxns = "wallet_txns"
-	BucketReceipts   = "receipts"
+	BucketTokens         = "tokens"
+	BucketDeals          = "deals"
+	BucketManualJournals = "manual_journals"
+	BucketWalletTxns     = "wallet_txns"
+	BucketReceipts       = "receipts"
+	BucketTransfers      = "transfers"
+	BucketJournalExports = "journal_exports"
 )
 
 // Store represents the bbolt database wrapper.
@@ -40,7 +42,7 @@ func New(dbPath string) (*Store, error) {
 
 	// Initialize buckets.
 	err = db.Update(func(tx *bolt.Tx) error {
-		buckets := []string{BucketTokens, BucketDeals, BucketJournals, BucketWalletTxns, BucketReceipts}
+		buckets := []string{BucketTokens, BucketDeals, BucketManualJournals, BucketWalletTxns, BucketReceipts, BucketTransfers, BucketJournalExports}
 		for _, bucket := range buckets {
 			if _, err := tx.CreateBucketIfNotExists([]byte(bucket)); err != nil {
 				return fmt.Errorf("failed to create bucket %s: %w", bucket, err)
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pigeonworks-llc/freee-emulator/internal/models"
+)
+
+// CreateTransfer creates a new transfer in the database.
+func (s *Store) CreateTransfer(req *models.CreateTransferRequest) (*models.Transfer, error) {
+	id, err := s.NextID(BucketTransfers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ID: %w", err)
+	}
+
+	transfer := &models.Transfer{
+		ID:                 id,
+		CompanyID:          req.CompanyID,
+		Date:               req.Date,
+		Amount:             req.Amount,
+		FromWalletableType: req.FromWalletableType,
+		FromWalletableID:   req.FromWalletableID,
+		ToWalletableType:   req.ToWalletableType,
+		ToWalletableID:     req.ToWalletableID,
+		Description:        req.Description,
+	}
+
+	if err := s.Put(BucketTransfers, id, transfer); err != nil {
+		return nil, fmt.Errorf("failed to save transfer: %w", err)
+	}
+
+	return transfer, nil
+}
+
+// GetTransfer retrieves a transfer by ID.
+func (s *Store) GetTransfer(id int64) (*models.Transfer, error) {
+	var transfer models.Transfer
+	if err := s.Get(BucketTransfers, id, &transfer); err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// ListTransfers retrieves all transfers, optionally filtered by company ID.
+func (s *Store) ListTransfers(companyID *int64) ([]*models.Transfer, error) {
+	filter := func(data []byte) bool {
+		if companyID == nil {
+			return true
+		}
+
+		var transfer models.Transfer
+		if err := json.Unmarshal(data, &transfer); err != nil {
+			return false
+		}
+		return transfer.CompanyID == *companyID
+	}
+
+	results, err := s.List(BucketTransfers, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	transfers := make([]*models.Transfer, 0, len(results))
+	for _, data := range results {
+		var transfer models.Transfer
+		if err := json.Unmarshal(data, &transfer); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal transfer: %w", err)
+		}
+		transfers = append(transfers, &transfer)
+	}
+
+	return transfers, nil
+}
+
+// DeleteTransfer deletes a transfer by ID.
+func (s *Store) DeleteTransfer(id int64) error {
+	return s.Delete(BucketTransfers, id)
+}
@@ -35,7 +35,8 @@ echo ""
 
 # 1. Docker イメージのビルド
 echo -e "${GREEN}[1/4] Docker イメージをビルド中...${NC}"
-docker build -f Dockerfile.checker -t ${IMAGE_NAME}:latest .
+# 共有設定パッケージを含めるためリポジトリルートをコンテキストにする
+docker build -f Dockerfile.checker -t ${IMAGE_NAME}:latest ..
 
 # 2. GCR へプッシュ
 echo -e "${GREEN}[2/4] GCR へプッシュ中...${NC}"
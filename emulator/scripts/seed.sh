@@ -105,7 +105,7 @@ echo ""
 echo "3. Creating sample journals..."
 
 # Journal 1: Simple entry
-JOURNAL1=$(curl -s -X POST "$API_URL/api/1/journals" \
+JOURNAL1=$(curl -s -X POST "$API_URL/api/1/manual_journals" \
   -H "Authorization: Bearer $TOKEN" \
   -H "Content-Type: application/json" \
   -d '{
@@ -113,7 +113,7 @@ JOURNAL1=$(curl -s -X POST "$API_URL/api/1/journals" \
     "issue_date": "2025-02-01",
     "details": [
       {
-        "entry_type": "debit",
+        "entry_side": "debit",
         "account_item_id": 135,
         "tax_code": 0,
         "amount": 100000,
@@ -121,7 +121,7 @@ JOURNAL1=$(curl -s -X POST "$API_URL/api/1/journals" \
         "description": "普通預金"
       },
       {
-        "entry_type": "credit",
+        "entry_side": "credit",
         "account_item_id": 400,
         "tax_code": 1,
         "amount": 90909,
@@ -135,7 +135,7 @@ JOURNAL1_ID=$(echo "$JOURNAL1" | grep -o '"id":[0-9]*' | head -1 | cut -d':' -f2
 echo "   ✓ Created journal entry (ID: $JOURNAL1_ID)"
 
 # Journal 2: Complex entry
-JOURNAL2=$(curl -s -X POST "$API_URL/api/1/journals" \
+JOURNAL2=$(curl -s -X POST "$API_URL/api/1/manual_journals" \
   -H "Authorization: Bearer $TOKEN" \
   -H "Content-Type: application/json" \
   -d '{
@@ -143,7 +143,7 @@ JOURNAL2=$(curl -s -X POST "$API_URL/api/1/journals" \
     "issue_date": "2025-02-05",
     "details": [
       {
-        "entry_type": "debit",
+        "entry_side": "debit",
         "account_item_id": 801,
         "tax_code": 1,
         "amount": 45454,
@@ -151,7 +151,7 @@ JOURNAL2=$(curl -s -X POST "$API_URL/api/1/journals" \
         "description": "仕入高"
       },
       {
-        "entry_type": "debit",
+        "entry_side": "debit",
         "account_item_id": 138,
         "tax_code": 0,
         "amount": 5000,
@@ -159,7 +159,7 @@ JOURNAL2=$(curl -s -X POST "$API_URL/api/1/journals" \
         "description": "支払手数料"
       },
       {
-        "entry_type": "credit",
+        "entry_side": "credit",
         "account_item_id": 135,
         "tax_code": 0,
         "amount": 55000,
@@ -180,7 +180,7 @@ echo "4. Verifying created data..."
 DEALS_COUNT=$(curl -s -X GET "$API_URL/api/1/deals?company_id=1" \
   -H "Authorization: Bearer $TOKEN" | grep -o '"id":[0-9]*' | wc -l)
 
-JOURNALS_COUNT=$(curl -s -X GET "$API_URL/api/1/journals?company_id=1" \
+JOURNALS_COUNT=$(curl -s -X GET "$API_URL/api/1/manual_journals?company_id=1" \
   -H "Authorization: Bearer $TOKEN" | grep -o '"id":[0-9]*' | wc -l)
 
 echo "   ✓ Deals created: $DEALS_COUNT"
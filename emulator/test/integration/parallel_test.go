@@ -51,7 +51,7 @@ func setupParallelTestServer(t *testing.T) *parallelTestClient {
 	tokenManager := oauth.NewTokenManager(st)
 	oauthHandler := oauth.NewHandler(tokenManager)
 	dealsHandler := api.NewDealsHandler(st)
-	journalsHandler := api.NewJournalsHandler(st)
+	manualJournalsHandler := api.NewManualJournalsHandler(st)
 
 	// Setup router
 	r := chi.NewRouter()
@@ -71,10 +71,10 @@ func setupParallelTestServer(t *testing.T) *parallelTestClient {
 			r.Delete("/{id}", dealsHandler.Delete)
 		})
 
-		r.Route("/journals", func(r chi.Router) {
-			r.Get("/", journalsHandler.List)
-			r.Post("/", journalsHandler.Create)
-			r.Get("/{id}", journalsHandler.Get)
+		r.Route("/manual_journals", func(r chi.Router) {
+			r.Get("/", manualJournalsHandler.List)
+			r.Post("/", manualJournalsHandler.Create)
+			r.Get("/{id}", manualJournalsHandler.Get)
 		})
 	})
 
@@ -247,19 +247,19 @@ func TestParallelJournalOperations(t *testing.T) {
 	client := setupParallelTestServer(t)
 
 	t.Run("Create journal entry", func(t *testing.T) {
-		req := models.CreateJournalRequest{
+		req := models.CreateManualJournalRequest{
 			CompanyID: 1,
 			IssueDate: "2025-03-01",
-			Details: []models.CreateJournalDetailRequest{
+			Details: []models.CreateManualJournalDetailRequest{
 				{
-					EntryType:     "debit",
+					EntrySide:     "debit",
 					AccountItemID: 135,
 					TaxCode:       0,
 					Amount:        50000,
 					Vat:           0,
 				},
 				{
-					EntryType:     "credit",
+					EntrySide:     "credit",
 					AccountItemID: 400,
 					TaxCode:       1,
 					Amount:        45454,
@@ -268,7 +268,7 @@ func TestParallelJournalOperations(t *testing.T) {
 			},
 		}
 
-		resp := client.request(t, "POST", "/api/1/journals", req)
+		resp := client.request(t, "POST", "/api/1/manual_journals", req)
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusCreated {
@@ -365,19 +365,19 @@ func TestParallelStressTest(t *testing.T) {
 					resp.Body.Close()
 				} else {
 					// Create journal
-					req := models.CreateJournalRequest{
+					req := models.CreateManualJournalRequest{
 						CompanyID: 1,
 						IssueDate: "2025-03-01",
-						Details: []models.CreateJournalDetailRequest{
+						Details: []models.CreateManualJournalDetailRequest{
 							{
-								EntryType:     "debit",
+								EntrySide:     "debit",
 								AccountItemID: 135,
 								TaxCode:       0,
 								Amount:        int64(1000 * (i + 1)),
 								Vat:           0,
 							},
 							{
-								EntryType:     "credit",
+								EntrySide:     "credit",
 								AccountItemID: 400,
 								TaxCode:       0,
 								Amount:        int64(1000 * (i + 1)),
@@ -386,7 +386,7 @@ func TestParallelStressTest(t *testing.T) {
 						},
 					}
 
-					resp := client.request(t, "POST", "/api/1/journals", req)
+					resp := client.request(t, "POST", "/api/1/manual_journals", req)
 					resp.Body.Close()
 				}
 			})
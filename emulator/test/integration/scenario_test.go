@@ -45,7 +45,7 @@ func setupTestServer(t *testing.T) *testClient {
 	tokenManager := oauth.NewTokenManager(st)
 	oauthHandler := oauth.NewHandler(tokenManager)
 	dealsHandler := api.NewDealsHandler(st)
-	journalsHandler := api.NewJournalsHandler(st)
+	manualJournalsHandler := api.NewManualJournalsHandler(st)
 
 	// Setup router
 	r := chi.NewRouter()
@@ -65,10 +65,10 @@ func setupTestServer(t *testing.T) *testClient {
 			r.Delete("/{id}", dealsHandler.Delete)
 		})
 
-		r.Route("/journals", func(r chi.Router) {
-			r.Get("/", journalsHandler.List)
-			r.Post("/", journalsHandler.Create)
-			r.Get("/{id}", journalsHandler.Get)
+		r.Route("/manual_journals", func(r chi.Router) {
+			r.Get("/", manualJournalsHandler.List)
+			r.Post("/", manualJournalsHandler.Create)
+			r.Get("/{id}", manualJournalsHandler.Get)
 		})
 	})
 
@@ -292,19 +292,19 @@ func TestJournalCreation(t *testing.T) {
 	client := setupTestServer(t)
 
 	t.Run("Create balanced journal", func(t *testing.T) {
-		req := models.CreateJournalRequest{
+		req := models.CreateManualJournalRequest{
 			CompanyID: 1,
 			IssueDate: "2025-02-01",
-			Details: []models.CreateJournalDetailRequest{
+			Details: []models.CreateManualJournalDetailRequest{
 				{
-					EntryType:     "debit",
+					EntrySide:     "debit",
 					AccountItemID: 135,
 					TaxCode:       0,
 					Amount:        100000,
 					Vat:           0,
 				},
 				{
-					EntryType:     "credit",
+					EntrySide:     "credit",
 					AccountItemID: 400,
 					TaxCode:       1,
 					Amount:        90909,
@@ -313,7 +313,7 @@ func TestJournalCreation(t *testing.T) {
 			},
 		}
 
-		resp := client.request(t, "POST", "/api/1/journals", req)
+		resp := client.request(t, "POST", "/api/1/manual_journals", req)
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusCreated {
@@ -322,7 +322,7 @@ func TestJournalCreation(t *testing.T) {
 		}
 
 		var result struct {
-			Journal models.Journal `json:"journal"`
+			Journal models.ManualJournal `json:"manual_journal"`
 		}
 		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 			t.Fatalf("Failed to decode response: %v", err)
@@ -376,19 +376,19 @@ func TestCompleteScenario(t *testing.T) {
 
 		// Step 3: Create journal entry for payment
 		t.Log("Creating payment journal...")
-		journalReq := models.CreateJournalRequest{
+		journalReq := models.CreateManualJournalRequest{
 			CompanyID: 1,
 			IssueDate: "2025-03-10",
-			Details: []models.CreateJournalDetailRequest{
+			Details: []models.CreateManualJournalDetailRequest{
 				{
-					EntryType:     "debit",
+					EntrySide:     "debit",
 					AccountItemID: 135,
 					TaxCode:       0,
 					Amount:        200000,
 					Vat:           0,
 				},
 				{
-					EntryType:     "credit",
+					EntrySide:     "credit",
 					AccountItemID: 141,
 					TaxCode:       0,
 					Amount:        200000,
@@ -397,7 +397,7 @@ func TestCompleteScenario(t *testing.T) {
 			},
 		}
 
-		resp = client.request(t, "POST", "/api/1/journals", journalReq)
+		resp = client.request(t, "POST", "/api/1/manual_journals", journalReq)
 		resp.Body.Close()
 
 		// Step 4: Verify all data
@@ -414,11 +414,11 @@ func TestCompleteScenario(t *testing.T) {
 			t.Errorf("Expected 2 deals, got %d", len(dealsResult.Deals))
 		}
 
-		resp = client.request(t, "GET", "/api/1/journals?company_id=1", nil)
+		resp = client.request(t, "GET", "/api/1/manual_journals?company_id=1", nil)
 		defer resp.Body.Close()
 
 		var journalsResult struct {
-			Journals []*models.Journal `json:"journals"`
+			Journals []*models.ManualJournal `json:"manual_journals"`
 		}
 		json.NewDecoder(resp.Body).Decode(&journalsResult)
 
@@ -81,7 +81,7 @@ func (b *TestDataBuilder) MultiDetailDeal(amounts []int64, issueDate string) mod
 }
 
 // SimpleJournal creates a simple balanced journal entry.
-func (b *TestDataBuilder) SimpleJournal(amount int64, issueDate string) models.CreateJournalRequest {
+func (b *TestDataBuilder) SimpleJournal(amount int64, issueDate string) models.CreateManualJournalRequest {
 	if issueDate == "" {
 		issueDate = time.Now().Format("2006-01-02")
 	}
@@ -90,19 +90,19 @@ func (b *TestDataBuilder) SimpleJournal(amount int64, issueDate string) models.C
 	amountWithoutTax := amount * 10 / 11
 	vat := amount - amountWithoutTax
 
-	return models.CreateJournalRequest{
+	return models.CreateManualJournalRequest{
 		CompanyID: b.companyID,
 		IssueDate: issueDate,
-		Details: []models.CreateJournalDetailRequest{
+		Details: []models.CreateManualJournalDetailRequest{
 			{
-				EntryType:     "debit",
+				EntrySide:     "debit",
 				AccountItemID: 135, // 普通預金
 				TaxCode:       0,
 				Amount:        amount,
 				Vat:           0,
 			},
 			{
-				EntryType:     "credit",
+				EntrySide:     "credit",
 				AccountItemID: 400, // 売上高
 				TaxCode:       1,
 				Amount:        amountWithoutTax,
@@ -113,31 +113,31 @@ func (b *TestDataBuilder) SimpleJournal(amount int64, issueDate string) models.C
 }
 
 // ComplexJournal creates a complex journal entry with multiple lines.
-func (b *TestDataBuilder) ComplexJournal(issueDate string) models.CreateJournalRequest {
+func (b *TestDataBuilder) ComplexJournal(issueDate string) models.CreateManualJournalRequest {
 	if issueDate == "" {
 		issueDate = time.Now().Format("2006-01-02")
 	}
 
-	return models.CreateJournalRequest{
+	return models.CreateManualJournalRequest{
 		CompanyID: b.companyID,
 		IssueDate: issueDate,
-		Details: []models.CreateJournalDetailRequest{
+		Details: []models.CreateManualJournalDetailRequest{
 			{
-				EntryType:     "debit",
+				EntrySide:     "debit",
 				AccountItemID: 801, // 仕入高
 				TaxCode:       1,
 				Amount:        45454,
 				Vat:           4546,
 			},
 			{
-				EntryType:     "debit",
+				EntrySide:     "debit",
 				AccountItemID: 138, // 支払手数料
 				TaxCode:       0,
 				Amount:        5000,
 				Vat:           0,
 			},
 			{
-				EntryType:     "credit",
+				EntrySide:     "credit",
 				AccountItemID: 135, // 普通預金
 				TaxCode:       0,
 				Amount:        50000,
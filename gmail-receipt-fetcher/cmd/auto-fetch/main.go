@@ -9,11 +9,14 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/shunichi-ikebuchi/accounting-system/gmail-receipt-fetcher/internal/freee"
 	"github.com/shunichi-ikebuchi/accounting-system/gmail-receipt-fetcher/internal/gmail"
 	"github.com/shunichi-ikebuchi/accounting-system/gmail-receipt-fetcher/internal/receipt"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/config"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/duplicate"
 )
 
 // receiptWithSearcher pairs a receipt with its corresponding Gmail searcher
@@ -30,6 +33,8 @@ type transactionPair struct {
 
 func main() {
 	// Parse flags
+	configFile := flag.String("config", "", "Shared config file (or FREEE_CONFIG env, default: search freee-automation.yaml)")
+	profile := flag.String("profile", "", "Config profile to use (or FREEE_PROFILE env)")
 	freeeAPI := flag.String("freee-api", "", "freee API URL (required, or FREEE_API_URL env)")
 	freeeToken := flag.String("freee-token", "", "freee access token (or FREEE_ACCESS_TOKEN env)")
 	freeeCompany := flag.String("freee-company", "", "freee company ID (or FREEE_COMPANY_ID env)")
@@ -45,6 +50,7 @@ func main() {
 	toleranceDays := flag.Int("tolerance", 0, "Date tolerance in days (or RECEIPT_TOLERANCE_DAYS env, default: 3)")
 	dryRun := flag.Bool("dry-run", false, "Preview without downloading")
 	createDeals := flag.Bool("create-deals", false, "Create deals in freee for matched transactions")
+	allowDuplicates := flag.Bool("allow-duplicates", false, "Create deals even if a deal suspected to be the same exists")
 	help := flag.Bool("help", false, "Show help")
 	flag.BoolVar(help, "h", false, "Show help")
 
@@ -55,13 +61,27 @@ func main() {
 		os.Exit(0)
 	}
 
+	// freee settings come from the shared config file and profile, with
+	// FREEE_* environment variables overriding it (flag takes precedence)
+	cfg, err := config.LoadWith(config.Options{
+		File:     *configFile,
+		Profile:  *profile,
+		Defaults: &config.Config{Freee: config.FreeeConfig{APIURL: "https://api.freee.co.jp"}},
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+	*freeeAPI = flagOrDefault(*freeeAPI, cfg.Freee.APIURL)
+	*freeeToken = flagOrDefault(*freeeToken, cfg.Freee.AccessToken)
+	if cfg.Freee.CompanyID != 0 {
+		*freeeCompany = flagOrDefault(*freeeCompany, strconv.FormatInt(cfg.Freee.CompanyID, 10))
+	}
+	*freeeClientID = flagOrDefault(*freeeClientID, cfg.Freee.ClientID)
+	*freeeClientSecret = flagOrDefault(*freeeClientSecret, cfg.Freee.ClientSecret)
+	*freeeTokenPath = flagOrDefault(*freeeTokenPath, cfg.Freee.TokenStore)
+
 	// Apply environment variable defaults (flag takes precedence)
-	*freeeAPI = envOrDefault(*freeeAPI, "FREEE_API_URL", "https://api.freee.co.jp")
-	*freeeToken = envOrDefault(*freeeToken, "FREEE_ACCESS_TOKEN", "")
-	*freeeCompany = envOrDefault(*freeeCompany, "FREEE_COMPANY_ID", "")
-	*freeeClientID = envOrDefault(*freeeClientID, "FREEE_CLIENT_ID", "")
-	*freeeClientSecret = envOrDefault(*freeeClientSecret, "FREEE_CLIENT_SECRET", "")
-	*freeeTokenPath = envOrDefault(*freeeTokenPath, "FREEE_TOKEN_PATH", "")
 	*gmailAPI = envOrDefault(*gmailAPI, "GMAIL_API_URL", "")
 	*gmailLabel = envOrDefault(*gmailLabel, "GMAIL_LABEL", "")
 	*vendors = envOrDefault(*vendors, "RECEIPT_VENDORS", "")
@@ -107,7 +127,6 @@ func main() {
 	// Step 1: Fetch unregistered transactions from freee
 	fmt.Println("Fetching unregistered transactions from freee...")
 	var freeeClient *freee.Client
-	var err error
 	if useTokenManager {
 		tokenManager := freee.NewTokenManager(*freeeClientID, *freeeClientSecret, *freeeTokenPath)
 		freeeClient, err = freee.NewClientWithTokenManager(*freeeAPI, tokenManager)
@@ -306,6 +325,21 @@ func main() {
 	// Initialize account mapper for deal creation
 	accountMapper := freee.NewAccountMapper()
 
+	// Deals already booked (e.g. by hand in freee) that a new deal could duplicate
+	var bookedDeals []duplicate.Entry
+	if *createDeals && !*allowDuplicates {
+		fmt.Println()
+		fmt.Println("Checking freee for deals already booked...")
+		deals, err := freeeClient.FetchDeals(fromDate.Format("2006-01-02"), toDate.Format("2006-01-02"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to fetch deals from freee: %v\n", err)
+			fmt.Fprintln(os.Stderr, "Use --allow-duplicates to create deals without checking")
+			os.Exit(1)
+		}
+		bookedDeals = dealEntries(deals)
+		fmt.Printf("Found %d deals\n", len(deals))
+	}
+
 	// Step 5: Download matched receipts
 	if *dryRun {
 		fmt.Println()
@@ -319,6 +353,10 @@ func main() {
 				m.Receipt.Subject,
 			)
 			if *createDeals {
+				if matches := duplicate.Check(walletTxnEntry(m.TransactionPair.WalletTxn, ""), bookedDeals, duplicateOptions(*toleranceDays)); len(matches) > 0 {
+					fmt.Printf("    -> Would skip deal: %s\n", describeDuplicate(matches[0]))
+					continue
+				}
 				fmt.Printf("    -> Would create deal: %s (%d), Tax: %s (%d)\n",
 					mapping.AccountItemName, mapping.AccountItemID,
 					mapping.TaxCodeName, mapping.TaxCode,
@@ -331,6 +369,7 @@ func main() {
 	fmt.Println()
 	fmt.Println("Downloading receipts...")
 	downloaded := 0
+	receiptFiles := make(map[string]string) // by message ID
 	for i, m := range matches {
 		fmt.Printf("[%d/%d] ", i+1, len(matches))
 
@@ -344,8 +383,10 @@ func main() {
 		case "downloaded":
 			fmt.Printf("Downloaded: %s\n", result.FilePath)
 			downloaded++
+			receiptFiles[m.Receipt.MessageID] = result.FilePath
 		case "skipped":
 			fmt.Printf("Skipped (exists): %s\n", result.FilePath)
+			receiptFiles[m.Receipt.MessageID] = result.FilePath
 		case "error":
 			fmt.Printf("Error: %s\n", result.Error)
 		}
@@ -355,6 +396,7 @@ func main() {
 
 	// Step 6: Create deals in freee (optional)
 	dealsCreated := 0
+	duplicatesSkipped := 0
 	if *createDeals {
 		fmt.Println()
 		fmt.Println("Creating deals in freee...")
@@ -368,6 +410,15 @@ func main() {
 				formatAmount(abs(wt.Amount)),
 			)
 
+			candidate := walletTxnEntry(wt, receiptFiles[m.Receipt.MessageID])
+			if !*allowDuplicates {
+				if matches := duplicate.Check(candidate, bookedDeals, duplicateOptions(*toleranceDays)); len(matches) > 0 {
+					fmt.Printf("Skipped: %s\n", describeDuplicate(matches[0]))
+					duplicatesSkipped++
+					continue
+				}
+			}
+
 			dealResp, err := freeeClient.CreateDealFromTransaction(wt, mapping.AccountItemID, mapping.TaxCode)
 			if err != nil {
 				fmt.Printf("Error: %v\n", err)
@@ -376,6 +427,8 @@ func main() {
 
 			fmt.Printf("Created deal ID: %d (%s)\n", dealResp.Deal.ID, mapping.AccountItemName)
 			dealsCreated++
+			candidate.Source, candidate.ID = "freee", fmt.Sprintf("deal:%d", dealResp.Deal.ID)
+			bookedDeals = append(bookedDeals, candidate)
 			time.Sleep(500 * time.Millisecond)
 		}
 	}
@@ -388,11 +441,79 @@ func main() {
 	fmt.Printf("Downloaded: %d\n", downloaded)
 	if *createDeals {
 		fmt.Printf("Deals created: %d\n", dealsCreated)
+		if duplicatesSkipped > 0 {
+			fmt.Printf("Skipped as suspected duplicates: %d (check them in freee, or use --allow-duplicates)\n", duplicatesSkipped)
+		}
 	}
 	fmt.Println()
 	fmt.Printf("Receipts saved to: %s\n", *outputDir)
 }
 
+// duplicateMinSimilarity is how alike the descriptions of a new deal and a
+// booked deal of the same amount must be to skip the new deal.
+const duplicateMinSimilarity = 0.5
+
+// duplicateOptions returns the duplicate check options for a date tolerance.
+func duplicateOptions(toleranceDays int) duplicate.Options {
+	return duplicate.Options{WindowDays: toleranceDays, MinSimilarity: duplicateMinSimilarity}
+}
+
+// dealEntries converts booked freee deals for the duplicate check. Their
+// receipts are not fetched, so they are matched without receipt hashes.
+func dealEntries(deals []freee.Deal) []duplicate.Entry {
+	entries := make([]duplicate.Entry, 0, len(deals))
+	for _, d := range deals {
+		var descriptions []string
+		for _, detail := range d.Details {
+			if detail.Description != nil && *detail.Description != "" {
+				descriptions = append(descriptions, *detail.Description)
+			}
+		}
+		entry := duplicate.Entry{
+			Source:      "freee",
+			ID:          fmt.Sprintf("deal:%d", d.ID),
+			Type:        d.Type,
+			Date:        d.IssueDate,
+			Amount:      d.Amount,
+			Description: strings.Join(descriptions, " "),
+		}
+		if d.PartnerCode != nil {
+			entry.Partner = *d.PartnerCode
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// walletTxnEntry converts the wallet transaction a deal is created from for
+// the duplicate check. The receipt file downloaded for it, if any, is
+// hashed so that a second deal for the same receipt in this run is found.
+// Deals fetched from freee carry no receipts and are matched by date,
+// amount and description only.
+func walletTxnEntry(wt freee.WalletTransaction, receiptPath string) duplicate.Entry {
+	entry := duplicate.Entry{
+		Source:      "wallet_txn",
+		ID:          fmt.Sprintf("wallet_txn:%d", wt.ID),
+		Type:        "expense",
+		Date:        wt.Date,
+		Amount:      int64(abs(wt.Amount)),
+		Description: wt.Description,
+	}
+	if receiptPath != "" {
+		if hash, err := duplicate.HashFile(receiptPath); err == nil {
+			entry.Receipts = []string{hash}
+		}
+	}
+	return entry
+}
+
+// describeDuplicate describes the deal a new deal is suspected to duplicate.
+func describeDuplicate(m duplicate.Match) string {
+	return fmt.Sprintf("suspected duplicate of %s %s ¥%s %q (%s)",
+		m.Entry.ID, m.Entry.Date, formatAmount(int(m.Entry.Amount)),
+		strings.TrimSpace(m.Entry.Partner+" "+m.Entry.Description), strings.Join(m.Reasons, ", "))
+}
+
 func abs(n int) int {
 	if n < 0 {
 		return -n
@@ -417,6 +538,12 @@ Authentication (choose one):
     --freee-client-secret SEC  freee OAuth client secret (or FREEE_CLIENT_SECRET)
     --freee-token-path FILE    freee token file path (or FREEE_TOKEN_PATH)
 
+Configuration:
+  --config FILE          Shared config file (FREEE_CONFIG, default: freee-automation.yaml,
+                         ./config/freee-automation.yaml or ~/.config/freee-automation/config.yaml)
+  --profile NAME         Config profile to use (FREEE_PROFILE)
+  The freee settings below default to the selected profile; FREEE_* variables override it.
+
 Options:
   --freee-api URL        freee API URL (FREEE_API_URL, default: https://api.freee.co.jp)
   --gmail-api URL        Gmail API URL for emulator (GMAIL_API_URL)
@@ -428,9 +555,13 @@ Options:
   --tolerance DAYS       Date matching tolerance (RECEIPT_TOLERANCE_DAYS, default: 3)
   --dry-run              Preview without downloading
   --create-deals         Create deals in freee for matched transactions
+  --allow-duplicates     Create deals even if freee has a deal with the same amount,
+                         a date within --tolerance and a similar description
   -h, --help             Show this help
 
 Environment Variables:
+  FREEE_CONFIG            Shared config file
+  FREEE_PROFILE           Config profile to use
   FREEE_API_URL           freee API URL
   FREEE_ACCESS_TOKEN      freee access token
   FREEE_COMPANY_ID        freee company ID
@@ -528,6 +659,14 @@ func envOrDefault(flagVal, envKey, defaultVal string) string {
 	return defaultVal
 }
 
+// flagOrDefault returns the flag value if set, otherwise the default.
+func flagOrDefault(flagVal, defaultVal string) string {
+	if flagVal != "" {
+		return flagVal
+	}
+	return defaultVal
+}
+
 // envOrDefaultInt returns the env var as int, or the default value.
 func envOrDefaultInt(envKey string, defaultVal int) int {
 	if v := os.Getenv(envKey); v != "" {
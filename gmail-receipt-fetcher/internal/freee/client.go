@@ -348,3 +348,67 @@ func (c *Client) CreateDealFromTransaction(txn WalletTransaction, accountItemID
 
 	return c.CreateDeal(req)
 }
+
+// Deal represents a booked deal, as far as duplicate checks need it.
+type Deal struct {
+	ID          int64   `json:"id"`
+	IssueDate   string  `json:"issue_date"`
+	Type        string  `json:"type"` // "expense" or "income"
+	Amount      int64   `json:"amount"`
+	PartnerCode *string `json:"partner_code,omitempty"`
+	Details     []struct {
+		Description *string `json:"description,omitempty"`
+	} `json:"details"`
+}
+
+// FetchDeals fetches the deals issued in a date range (YYYY-MM-DD).
+// Uses pagination to fetch all deals.
+func (c *Client) FetchDeals(from, to string) ([]Deal, error) {
+	if err := c.ensureValidToken(); err != nil {
+		return nil, fmt.Errorf("token validation failed: %w", err)
+	}
+
+	var allDeals []Deal
+	offset := 0
+	limit := 100 // Maximum allowed by freee API
+
+	for {
+		url := fmt.Sprintf("%s/api/1/deals?company_id=%s&issue_date_from=%s&issue_date_to=%s&limit=%d&offset=%d",
+			c.apiURL, c.companyID, from, to, limit, offset)
+
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.accessToken)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("API error: %s", resp.Status)
+		}
+
+		var result struct {
+			Deals []Deal `json:"deals"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		resp.Body.Close()
+
+		allDeals = append(allDeals, result.Deals...)
+
+		if len(result.Deals) < limit {
+			break
+		}
+		offset += limit
+	}
+
+	return allDeals, nil
+}
@@ -0,0 +1,183 @@
+// Package audit runs the monthly audit (月次監査) of the Beancount ledger:
+// a set of checks, each reporting the entries that need a closer look.
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+)
+
+// Severity is the severity of a finding.
+type Severity string
+
+const (
+	// SeverityError marks entries that are wrong and must be fixed.
+	SeverityError Severity = "error"
+	// SeverityWarning marks entries that should be reviewed.
+	SeverityWarning Severity = "warning"
+)
+
+// ErrSkipped is returned (wrapped with the reason) by a check whose input
+// is not available, e.g. when freee was not queried.
+var ErrSkipped = errors.New("skipped")
+
+// Finding is an entry reported by a check.
+type Finding struct {
+	Severity Severity `json:"severity"`
+	Date     string   `json:"date,omitempty"`
+	Key      string   `json:"key,omitempty"` // freee item, e.g. deal:123
+	Amount   float64  `json:"amount,omitempty"`
+	Message  string   `json:"message"`
+	File     string   `json:"file,omitempty"`
+	Line     int      `json:"line,omitempty"`
+}
+
+// Input is what the checks audit.
+type Input struct {
+	From     string // first day of the audited month
+	To       string // last day of the audited month
+	Currency string
+
+	// Transactions are all ledger transactions; checks comparing the month
+	// with earlier months use the history.
+	Transactions []beancount.Transaction
+
+	// Attachments is the number of documents attached to each deal, or nil
+	// if the sync database is not available.
+	Attachments map[int64]int
+
+	// WalletTxns are freee's wallet transactions of the month, or nil if
+	// freee was not queried.
+	WalletTxns []freee.WalletTxn
+}
+
+// Month returns the transactions of the audited month. Closing entries
+// are not audited.
+func (in *Input) Month() []beancount.Transaction {
+	var txns []beancount.Transaction
+	for _, txn := range in.Transactions {
+		if txn.Date >= in.From && txn.Date <= in.To && !txn.IsClosing() {
+			txns = append(txns, txn)
+		}
+	}
+	return txns
+}
+
+// Check is one check of the audit.
+type Check interface {
+	// Name identifies the check, e.g. on the command line.
+	Name() string
+	// Description says what the check reports.
+	Description() string
+	// Run returns the findings of the check. An error wrapping ErrSkipped
+	// means the check could not run on the input.
+	Run(in *Input) ([]Finding, error)
+}
+
+// Result is the outcome of a check.
+type Result struct {
+	Check       string    `json:"check"`
+	Description string    `json:"description"`
+	Skipped     string    `json:"skipped,omitempty"` // reason the check did not run
+	Findings    []Finding `json:"findings"`
+}
+
+// Report is the result of an audit.
+type Report struct {
+	From         string   `json:"from"`
+	To           string   `json:"to"`
+	Transactions int      `json:"transactions"`
+	Results      []Result `json:"results"`
+}
+
+// Run runs checks on in. A check that fails for a reason other than
+// ErrSkipped stops the audit.
+func Run(in *Input, checks []Check) (Report, error) {
+	report := Report{From: in.From, To: in.To, Transactions: len(in.Month())}
+
+	for _, check := range checks {
+		result := Result{Check: check.Name(), Description: check.Description(), Findings: []Finding{}}
+
+		findings, err := check.Run(in)
+		switch {
+		case errors.Is(err, ErrSkipped):
+			result.Skipped = strings.TrimSuffix(err.Error(), ": "+ErrSkipped.Error())
+		case err != nil:
+			return report, fmt.Errorf("check %s failed: %w", check.Name(), err)
+		default:
+			sort.SliceStable(findings, func(i, j int) bool { return findings[i].Date < findings[j].Date })
+			result.Findings = append(result.Findings, findings...)
+		}
+
+		report.Results = append(report.Results, result)
+	}
+
+	return report, nil
+}
+
+// Findings returns the number of findings of a severity.
+func (r Report) Findings(severity Severity) int {
+	count := 0
+	for _, result := range r.Results {
+		for _, f := range result.Findings {
+			if f.Severity == severity {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// Markdown formats the report as a Markdown document.
+func (r Report) Markdown() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "# 月次監査 %s\n\n", r.From[:7])
+	fmt.Fprintf(&sb, "- Period: %s .. %s\n", r.From, r.To)
+	fmt.Fprintf(&sb, "- Transactions: %d\n", r.Transactions)
+	fmt.Fprintf(&sb, "- Errors: %d, warnings: %d\n\n", r.Findings(SeverityError), r.Findings(SeverityWarning))
+
+	sb.WriteString("| Check | Result |\n|---|---|\n")
+	for _, result := range r.Results {
+		status := "OK"
+		switch {
+		case result.Skipped != "":
+			status = "skipped: " + result.Skipped
+		case len(result.Findings) > 0:
+			status = fmt.Sprintf("%d finding(s)", len(result.Findings))
+		}
+		fmt.Fprintf(&sb, "| %s | %s |\n", result.Check, markdownEscape(status))
+	}
+
+	for _, result := range r.Results {
+		if len(result.Findings) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n## %s\n\n%s\n\n", result.Check, result.Description)
+		sb.WriteString("| Severity | Date | Item | Amount | Message | Location |\n|---|---|---|---:|---|---|\n")
+		for _, f := range result.Findings {
+			amount := ""
+			if f.Amount != 0 {
+				amount = fmt.Sprintf("%.0f", f.Amount)
+			}
+			location := ""
+			if f.File != "" {
+				location = fmt.Sprintf("%s:%d", f.File, f.Line)
+			}
+			fmt.Fprintf(&sb, "| %s | %s | %s | %s | %s | %s |\n",
+				f.Severity, f.Date, f.Key, amount, markdownEscape(f.Message), markdownEscape(location))
+		}
+	}
+
+	return sb.String()
+}
+
+// markdownEscape escapes the characters that break a Markdown table cell.
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
@@ -0,0 +1,163 @@
+package audit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/duplicate"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+)
+
+const testLedger = `
+2024-01-10 * "Phone"
+  Expenses:Communication   5000 JPY
+  Assets:Bank
+
+2024-02-10 * "Phone"
+  Expenses:Communication   5500 JPY
+  Assets:Bank
+
+2024-03-10 * "Phone"
+  Expenses:Communication   4800 JPY
+  Assets:Bank
+
+2024-04-05 * "Supplies"
+  freee_id: "1"
+  freee_type: "deal"
+  Expenses:Supplies        2200 JPY
+  Assets:Bank
+
+2024-04-05 * "Supplies"
+  freee_id: "2"
+  freee_type: "deal"
+  Expenses:Supplies        2200 JPY
+  Assets:Bank
+
+2024-04-10 * "Phone"
+  freee_id: "3"
+  freee_type: "deal"
+  Expenses:Communication  30000 JPY
+  Assets:Bank
+
+2024-04-20 * "Unknown"
+  freee_id: "4"
+  freee_type: "deal"
+  Expenses:Unmapped:Item9  1000 JPY
+  Assets:Bank
+`
+
+func testInput(t *testing.T) *Input {
+	t.Helper()
+	ledger, err := beancount.ParseString("test.beancount", testLedger)
+	if err != nil || len(ledger.Errors) > 0 {
+		t.Fatalf("failed to parse ledger: %v %v", err, ledger.Errors)
+	}
+	return &Input{
+		From:         "2024-04-01",
+		To:           "2024-04-30",
+		Currency:     "JPY",
+		Transactions: ledger.Transactions,
+		Attachments:  map[int64]int{1: 1},
+		WalletTxns: []freee.WalletTxn{
+			{ID: 7, Date: "2024-04-15", Amount: 800, Status: freee.WalletTxnUnbooked},
+			{ID: 8, Date: "2024-04-16", Amount: 900, Status: freee.WalletTxnSettled},
+			{ID: 9, Date: "2024-05-01", Amount: 100, Status: freee.WalletTxnUnbooked},
+		},
+	}
+}
+
+func TestChecks(t *testing.T) {
+	tests := []struct {
+		check Check
+		keys  []string
+	}{
+		{UnmappedAccounts{Prefix: "Expenses:Unmapped:"}, []string{"deal:4"}},
+		{MissingReceipts{}, []string{"deal:2", "deal:3", "deal:4"}},
+		{MissingReceipts{Threshold: 3000}, []string{"deal:3"}},
+		{Duplicates{}, []string{"deal:2"}},
+		{UnusualAmounts{Factor: 3, Months: 12}, []string{"deal:3"}},
+		{UnbookedWalletTxns{}, []string{"wallet_txn:7"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.check.Name(), func(t *testing.T) {
+			findings, err := tt.check.Run(testInput(t))
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			var keys []string
+			for _, f := range findings {
+				keys = append(keys, f.Key)
+			}
+			if strings.Join(keys, ",") != strings.Join(tt.keys, ",") {
+				t.Errorf("findings = %v, want %v", keys, tt.keys)
+			}
+		})
+	}
+}
+
+func TestRunSkipsChecksWithoutInput(t *testing.T) {
+	in := testInput(t)
+	in.Attachments = nil
+	in.WalletTxns = nil
+
+	report, err := Run(in, DefaultChecks(Options{UnmappedPrefix: "Expenses:Unmapped:", UnusualFactor: 3, UnusualMonths: 12}))
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	skipped := make(map[string]string)
+	for _, result := range report.Results {
+		if result.Skipped != "" {
+			skipped[result.Check] = result.Skipped
+		}
+	}
+	if skipped["receipts"] != "sync database not available" || skipped["unbooked"] != "freee not queried" || len(skipped) != 2 {
+		t.Errorf("skipped = %v, want receipts and unbooked", skipped)
+	}
+	if report.Transactions != 4 || report.Findings(SeverityError) != 1 || report.Findings(SeverityWarning) != 2 {
+		t.Errorf("report = %d transactions, %d errors, %d warnings, want 4, 1, 2",
+			report.Transactions, report.Findings(SeverityError), report.Findings(SeverityWarning))
+	}
+	if md := report.Markdown(); !strings.Contains(md, "| receipts | skipped: sync database not available |") {
+		t.Errorf("Markdown() does not list the skipped check:\n%s", md)
+	}
+}
+
+func TestDuplicatesAcrossMonths(t *testing.T) {
+	ledger, err := beancount.ParseString("test.beancount", `
+2024-03-30 * "Amazon" "Printer paper"
+  Expenses:Supplies        5000 JPY
+  Assets:Bank
+
+2024-04-01 * "Amazon.co.jp" "Printer paper"
+  freee_id: "5"
+  freee_type: "deal"
+  Expenses:Supplies        5000 JPY
+  Assets:Bank
+`)
+	if err != nil || len(ledger.Errors) > 0 {
+		t.Fatalf("failed to parse ledger: %v %v", err, ledger.Errors)
+	}
+	in := &Input{From: "2024-04-01", To: "2024-04-30", Currency: "JPY", Transactions: ledger.Transactions}
+
+	for _, tt := range []struct {
+		window int
+		want   int
+	}{
+		{0, 0},
+		{3, 1},
+	} {
+		findings, err := Duplicates{Options: duplicate.Options{WindowDays: tt.window, MinSimilarity: 0.5}}.Run(in)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if len(findings) != tt.want {
+			t.Errorf("window %d: got %d findings, want %d: %+v", tt.window, len(findings), tt.want, findings)
+		}
+		if len(findings) > 0 && findings[0].Key != "deal:5" {
+			t.Errorf("window %d: finding for %s, want deal:5", tt.window, findings[0].Key)
+		}
+	}
+}
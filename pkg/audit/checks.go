@@ -0,0 +1,301 @@
+package audit
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/duplicate"
+)
+
+// Options configures the default checks.
+type Options struct {
+	// UnmappedPrefix is the account prefix sync books unmapped freee
+	// account items to.
+	UnmappedPrefix string
+	// ReceiptThreshold is the smallest expense that needs a receipt.
+	ReceiptThreshold float64
+	// UnusualFactor is how many times the median of earlier months an
+	// expense must exceed to be unusual.
+	UnusualFactor float64
+	// UnusualMonths is the number of earlier months the median is taken over.
+	UnusualMonths int
+	// Duplicates configures which entries are suspected duplicates.
+	Duplicates duplicate.Options
+}
+
+// DefaultChecks returns the checks of the monthly audit, in report order.
+func DefaultChecks(opts Options) []Check {
+	return []Check{
+		UnmappedAccounts{Prefix: opts.UnmappedPrefix},
+		MissingReceipts{Threshold: opts.ReceiptThreshold},
+		Duplicates{Options: opts.Duplicates},
+		UnusualAmounts{Factor: opts.UnusualFactor, Months: opts.UnusualMonths},
+		UnbookedWalletTxns{},
+	}
+}
+
+// UnmappedAccounts reports entries booked to accounts of unmapped freee
+// account items.
+type UnmappedAccounts struct {
+	Prefix string
+}
+
+// Name implements Check.
+func (c UnmappedAccounts) Name() string { return "unmapped" }
+
+// Description implements Check.
+func (c UnmappedAccounts) Description() string {
+	return "Entries booked to " + c.Prefix + "*: map the freee account items and resync."
+}
+
+// Run implements Check.
+func (c UnmappedAccounts) Run(in *Input) ([]Finding, error) {
+	var findings []Finding
+	for _, txn := range in.Month() {
+		var accounts []string
+		var amount float64
+		for _, p := range txn.Postings {
+			if strings.HasPrefix(p.Account, c.Prefix) {
+				accounts = append(accounts, p.Account)
+				amount += p.Amount
+			}
+		}
+		if len(accounts) == 0 {
+			continue
+		}
+		findings = append(findings, finding(txn, SeverityError, amount,
+			fmt.Sprintf("%s: unmapped %s", txn.Narration, strings.Join(accounts, ", "))))
+	}
+	return findings, nil
+}
+
+// MissingReceipts reports expense deals without an attached document.
+type MissingReceipts struct {
+	Threshold float64
+}
+
+// Name implements Check.
+func (c MissingReceipts) Name() string { return "receipts" }
+
+// Description implements Check.
+func (c MissingReceipts) Description() string {
+	if c.Threshold <= 0 {
+		return "Expense deals without a receipt (証憑) in document_attachments."
+	}
+	return fmt.Sprintf("Expense deals of %.0f or more without a receipt (証憑) in document_attachments.", c.Threshold)
+}
+
+// Run implements Check.
+func (c MissingReceipts) Run(in *Input) ([]Finding, error) {
+	if in.Attachments == nil {
+		return nil, fmt.Errorf("sync database not available: %w", ErrSkipped)
+	}
+
+	var findings []Finding
+	for _, txn := range in.Month() {
+		dealID, ok := dealID(txn)
+		if !ok || in.Attachments[dealID] > 0 {
+			continue
+		}
+		expense := expenseAmount(txn, in.Currency)
+		if expense <= 0 || expense < c.Threshold {
+			continue
+		}
+		findings = append(findings, finding(txn, SeverityWarning, expense, txn.Narration+": no receipt attached"))
+	}
+	return findings, nil
+}
+
+// Duplicates reports entries of the month suspected to book the same deal
+// as an earlier entry, as the duplicates command does for the ledger.
+type Duplicates struct {
+	Options duplicate.Options
+}
+
+// Name implements Check.
+func (Duplicates) Name() string { return "duplicates" }
+
+// Description implements Check.
+func (Duplicates) Description() string {
+	return "Entries with the same receipt, or the same amount and a similar description, as an earlier entry."
+}
+
+// Run implements Check. Entries up to the window before the month are
+// compared too, so a duplicate of the previous month's last days is found.
+func (c Duplicates) Run(in *Input) ([]Finding, error) {
+	windowFrom := in.From
+	if from, err := time.Parse("2006-01-02", in.From); err == nil {
+		windowFrom = from.AddDate(0, 0, -c.Options.WindowDays).Format("2006-01-02")
+	}
+
+	txns := make(map[string]beancount.Transaction)
+	var entries []duplicate.Entry
+	for _, txn := range in.Transactions {
+		if txn.Date < windowFrom || txn.Date > in.To {
+			continue
+		}
+		entry, ok := duplicate.LedgerEntry(txn, in.Currency, true)
+		if !ok {
+			continue
+		}
+		txns[entry.ID] = txn
+		entries = append(entries, entry)
+	}
+
+	var findings []Finding
+	for _, group := range duplicate.Find(entries, c.Options) {
+		original := txns[group.Entries[0].ID]
+		for _, e := range group.Entries[1:] {
+			if e.Date < in.From {
+				continue
+			}
+			txn := txns[e.ID]
+			findings = append(findings, finding(txn, SeverityWarning, float64(e.Amount),
+				fmt.Sprintf("%s: same as %s (%s)", txn.Narration, describe(original), strings.Join(group.Reasons, ", "))))
+		}
+	}
+	return findings, nil
+}
+
+// UnusualAmounts reports expenses far above what the account usually books.
+type UnusualAmounts struct {
+	Factor float64
+	Months int
+}
+
+// minSamples is the number of earlier postings needed to judge an amount.
+const minSamples = 3
+
+// Name implements Check.
+func (c UnusualAmounts) Name() string { return "unusual-amounts" }
+
+// Description implements Check.
+func (c UnusualAmounts) Description() string {
+	return fmt.Sprintf("Expenses more than %g times the median of the account over the previous %d months.", c.Factor, c.Months)
+}
+
+// Run implements Check.
+func (c UnusualAmounts) Run(in *Input) ([]Finding, error) {
+	start, err := time.Parse("2006-01-02", in.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date %q: %w", in.From, err)
+	}
+	historyFrom := start.AddDate(0, -c.Months, 0).Format("2006-01-02")
+
+	history := make(map[string][]float64)
+	for _, txn := range in.Transactions {
+		if txn.Date < historyFrom || txn.Date >= in.From || txn.IsClosing() {
+			continue
+		}
+		for account, amount := range txn.Amounts(in.Currency) {
+			if strings.HasPrefix(account, "Expenses:") && amount > 0 {
+				history[account] = append(history[account], amount)
+			}
+		}
+	}
+
+	var findings []Finding
+	for _, txn := range in.Month() {
+		for account, amount := range txn.Amounts(in.Currency) {
+			samples := history[account]
+			if !strings.HasPrefix(account, "Expenses:") || amount <= 0 || len(samples) < minSamples {
+				continue
+			}
+			if m := median(samples); amount > c.Factor*m {
+				findings = append(findings, finding(txn, SeverityWarning, amount,
+					fmt.Sprintf("%s: %s %.0f is %.1f times the median %.0f", txn.Narration, account, amount, amount/m, m)))
+			}
+		}
+	}
+	return findings, nil
+}
+
+// UnbookedWalletTxns reports wallet transactions (明細) of the month that
+// are not booked in freee yet.
+type UnbookedWalletTxns struct{}
+
+// Name implements Check.
+func (UnbookedWalletTxns) Name() string { return "unbooked" }
+
+// Description implements Check.
+func (UnbookedWalletTxns) Description() string {
+	return "Wallet transactions (明細) of the month not yet booked (未処理) in freee."
+}
+
+// Run implements Check.
+func (UnbookedWalletTxns) Run(in *Input) ([]Finding, error) {
+	if in.WalletTxns == nil {
+		return nil, fmt.Errorf("freee not queried: %w", ErrSkipped)
+	}
+
+	var findings []Finding
+	for _, txn := range in.WalletTxns {
+		if !txn.IsUnbooked() || txn.Date < in.From || txn.Date > in.To {
+			continue
+		}
+		findings = append(findings, Finding{
+			Severity: SeverityWarning,
+			Date:     txn.Date,
+			Key:      beancount.FreeeKey("wallet_txn", txn.ID),
+			Amount:   float64(txn.Amount),
+			Message:  fmt.Sprintf("%s (%s %s:%d)", txn.Description, txn.EntrySide, txn.WalletableType, txn.WalletableID),
+		})
+	}
+	return findings, nil
+}
+
+// finding returns a finding located at a ledger transaction.
+func finding(txn beancount.Transaction, severity Severity, amount float64, message string) Finding {
+	return Finding{
+		Severity: severity,
+		Date:     txn.Date,
+		Key:      txn.FreeeKey(),
+		Amount:   amount,
+		Message:  message,
+		File:     txn.Source.File,
+		Line:     txn.Source.Line,
+	}
+}
+
+// describe identifies a transaction in a message.
+func describe(txn beancount.Transaction) string {
+	if key := txn.FreeeKey(); key != "" {
+		return key
+	}
+	return fmt.Sprintf("%s:%d", txn.Source.File, txn.Source.Line)
+}
+
+// dealID returns the freee deal ID of a transaction synced from a deal.
+func dealID(txn beancount.Transaction) (int64, bool) {
+	if txn.Metadata[beancount.FreeeTypeKey] != "deal" {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(txn.Metadata[beancount.FreeeIDKey], 10, 64)
+	return id, err == nil
+}
+
+// expenseAmount returns the total booked to expense accounts.
+func expenseAmount(txn beancount.Transaction, currency string) float64 {
+	var total float64
+	for account, amount := range txn.Amounts(currency) {
+		if strings.HasPrefix(account, "Expenses:") {
+			total += amount
+		}
+	}
+	return total
+}
+
+// median returns the median of values.
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
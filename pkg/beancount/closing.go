@@ -0,0 +1,115 @@
+package beancount
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ClosingMetadata is the transaction metadata that marks the closing
+// entries of a year; its value is the closed year.
+const ClosingMetadata = "closing_year"
+
+// ClosedMarkerFile marks a year directory as closed. Its files are
+// read-only and the repository refuses to write to them.
+const ClosedMarkerFile = ".closed"
+
+// ErrPeriodClosed is returned when writing to the files of a closed year.
+var ErrPeriodClosed = errors.New("period is closed")
+
+// ClosingFileName returns the name of the closing entries file of a year.
+func ClosingFileName(year string) string {
+	return year + "-closing.beancount"
+}
+
+// OpeningFileName returns the name of the file holding the balances a year
+// opens with, as written when the previous year is closed.
+func OpeningFileName(year string) string {
+	return year + "-opening.beancount"
+}
+
+// IsClosing reports whether a transaction is a closing entry.
+func (t Transaction) IsClosing() bool {
+	return t.Metadata[ClosingMetadata] != ""
+}
+
+// CheckWritable returns an error wrapping ErrPeriodClosed if path is in the
+// directory of a closed year.
+func CheckWritable(path string) error {
+	marker := filepath.Join(filepath.Dir(path), ClosedMarkerFile)
+	if _, err := os.Stat(marker); err == nil {
+		return fmt.Errorf("%s: %w", path, ErrPeriodClosed)
+	}
+	return nil
+}
+
+// IsYearClosed reports whether a year has been closed.
+func (r *FileSystemRepository) IsYearClosed(year string) bool {
+	return r.pathResolver.FileExists(filepath.Join(r.pathResolver.GetYearDir(year), ClosedMarkerFile))
+}
+
+// CloseYear marks a year as closed: the Beancount files of the year are
+// made read-only and the marker that makes writes fail is created.
+func (r *FileSystemRepository) CloseYear(year string) error {
+	yearDir := r.pathResolver.GetYearDir(year)
+	if err := r.setYearFileMode(yearDir, 0444); err != nil {
+		return err
+	}
+
+	content := fmt.Sprintf("Closed by freee-sync close-year at %s.\nReopen with: freee-sync close-year --year %s --reopen\n",
+		time.Now().Format(time.RFC3339), year)
+	if err := os.WriteFile(filepath.Join(yearDir, ClosedMarkerFile), []byte(content), 0444); err != nil {
+		return fmt.Errorf("failed to write closed marker: %w", err)
+	}
+	return nil
+}
+
+// ReopenYear undoes CloseYear. The closing entries are kept.
+func (r *FileSystemRepository) ReopenYear(year string) error {
+	yearDir := r.pathResolver.GetYearDir(year)
+	if err := os.Remove(filepath.Join(yearDir, ClosedMarkerFile)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove closed marker: %w", err)
+	}
+	return r.setYearFileMode(yearDir, 0644)
+}
+
+// setYearFileMode sets the mode of the Beancount files in a year directory.
+func (r *FileSystemRepository) setYearFileMode(yearDir string, mode os.FileMode) error {
+	files, err := filepath.Glob(filepath.Join(yearDir, "*.beancount"))
+	if err != nil {
+		return fmt.Errorf("failed to list year files: %w", err)
+	}
+	for _, file := range files {
+		if err := os.Chmod(file, mode); err != nil {
+			return fmt.Errorf("failed to set mode of %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// FormatBalances formats balance assertions for accounts on date, in
+// account order. Zero balances are skipped.
+func FormatBalances(date, currency string, balances map[string]float64) string {
+	accounts := make([]string, 0, len(balances))
+	for account, amount := range balances {
+		if math.Abs(amount) >= 0.5 {
+			accounts = append(accounts, account)
+		}
+	}
+	sort.Strings(accounts)
+
+	var sb strings.Builder
+	for _, account := range accounts {
+		pad := 44 - displayWidth(account)
+		if pad < 1 {
+			pad = 1
+		}
+		sb.WriteString(fmt.Sprintf("%s balance %s%s%12.0f %s\n", date, account, strings.Repeat(" ", pad), balances[account], currency))
+	}
+	return sb.String()
+}
@@ -0,0 +1,165 @@
+package beancount
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ProtectedTag marks hand-written transactions in a month file that must
+// survive when the file is rebuilt from freee.
+const ProtectedTag = "manual"
+
+var (
+	freeeIDLinePattern   = regexp.MustCompile(`^\s+` + FreeeIDKey + `:\s*"?([0-9]+)"?\s*$`)
+	freeeTypeLinePattern = regexp.MustCompile(`^\s+` + FreeeTypeKey + `:\s*"?([a-z_]+)"?\s*$`)
+)
+
+// Entry is a top-level block of a Beancount file kept as raw text, so that
+// files can be edited entry by entry without reformatting the rest.
+type Entry struct {
+	// Text holds the lines of the entry, including comment lines directly
+	// above it, without trailing blank lines.
+	Text string
+	// Date is the date of the directive, or empty for comment blocks and
+	// undated directives (option, include, ...).
+	Date string
+}
+
+// FreeeKey returns the key of the freee item the entry was synced from
+// (see FreeeKey), or an empty string for hand-written entries.
+func (e Entry) FreeeKey() string {
+	var id, freeeType string
+	for _, line := range strings.Split(e.Text, "\n") {
+		if m := freeeIDLinePattern.FindStringSubmatch(line); m != nil {
+			id = m[1]
+		} else if m := freeeTypeLinePattern.FindStringSubmatch(line); m != nil {
+			freeeType = m[1]
+		}
+	}
+	if id == "" {
+		return ""
+	}
+	return freeeType + ":" + id
+}
+
+// IsDirective reports whether the entry holds a directive rather than only comments.
+func (e Entry) IsDirective() bool {
+	for _, line := range strings.Split(e.Text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !strings.HasPrefix(trimmed, ";") {
+			return true
+		}
+	}
+	return false
+}
+
+// IsProtected reports whether the entry is a transaction tagged with ProtectedTag.
+func (e Entry) IsProtected() bool {
+	ledger, err := ParseString("entry", e.Text)
+	if err != nil {
+		return false
+	}
+	for _, txn := range ledger.Transactions {
+		if contains(txn.Tags, ProtectedTag) {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitEntries splits the content of a Beancount file into its header (the
+// leading comment block, e.g. the one written by EnsureMonthFile) and its
+// entries. Comment lines directly above a directive belong to it.
+func SplitEntries(content string) (string, []Entry) {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+
+	var header []string
+	var entries []Entry
+	var block []string // comment lines waiting for a directive
+	var current *Entry
+	inHeader := true
+
+	flushBlock := func() {
+		if len(block) == 0 {
+			return
+		}
+		if inHeader {
+			header = append(header, block...)
+		} else {
+			entries = append(entries, Entry{Text: strings.Join(block, "\n")})
+		}
+		block = nil
+	}
+	flushCurrent := func() {
+		if current != nil {
+			current.Text = strings.TrimRight(current.Text, "\n")
+			entries = append(entries, *current)
+			current = nil
+		}
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flushCurrent()
+			flushBlock()
+			if inHeader {
+				header = append(header, "")
+			}
+		case line[0] == ' ' || line[0] == '\t':
+			if current != nil {
+				current.Text += "\n" + line
+			} else {
+				block = append(block, line)
+			}
+		case strings.HasPrefix(trimmed, ";"):
+			if current != nil {
+				flushCurrent()
+			}
+			block = append(block, line)
+		default:
+			flushCurrent()
+			inHeader = false
+			text := line
+			if len(block) > 0 {
+				text = strings.Join(block, "\n") + "\n" + line
+				block = nil
+			}
+			date := ""
+			if fields := strings.Fields(trimmed); len(fields) > 0 && datePattern.MatchString(fields[0]) {
+				date = fields[0]
+			}
+			current = &Entry{Text: text, Date: date}
+		}
+	}
+	flushCurrent()
+	flushBlock()
+
+	return strings.TrimRight(strings.Join(header, "\n"), "\n"), entries
+}
+
+// JoinEntries formats a header and entries back into file content, with
+// a blank line after the header and after each entry as AppendTransaction
+// writes them.
+func JoinEntries(header string, entries []Entry) string {
+	var sb strings.Builder
+	if header != "" {
+		sb.WriteString(header)
+		sb.WriteString("\n\n")
+	}
+	for _, e := range entries {
+		sb.WriteString(strings.TrimRight(e.Text, "\n"))
+		sb.WriteString("\n\n")
+	}
+	return sb.String()
+}
+
+// SortEntries orders dated entries by date, keeping the relative order of
+// entries on the same date. Undated entries stay in front.
+func SortEntries(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Date < entries[j].Date
+	})
+}
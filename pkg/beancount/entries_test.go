@@ -0,0 +1,80 @@
+package beancount
+
+import (
+	"strings"
+	"testing"
+)
+
+const monthFile = `; Beancount file for 2024-01
+; Generated at 2024-02-01T00:00:00Z
+
+2024-01-15 * "Lunch"
+  freee_id: "1"
+  freee_type: "deal"
+  Expenses:Food        1000 JPY
+  Assets:Bank         -1000 JPY
+
+2024-01-15 document Expenses:Food "../attachments/2024/01/receipt.pdf"
+  freee_id: "1"
+  freee_type: "deal"
+
+; imported by hand
+2024-01-20 * "Cash adjustment" #manual
+  Expenses:Misc         500 JPY
+  Assets:Cash          -500 JPY
+
+; a note on its own
+
+2024-01-31 balance Assets:Bank -1000 JPY
+`
+
+func TestSplitEntries(t *testing.T) {
+	header, entries := SplitEntries(monthFile)
+
+	if header != "; Beancount file for 2024-01\n; Generated at 2024-02-01T00:00:00Z" {
+		t.Errorf("header = %q", header)
+	}
+
+	tests := []struct {
+		date       string
+		freeeKey   string
+		protected  bool
+		directive  bool
+		textPrefix string
+	}{
+		{"2024-01-15", "deal:1", false, true, "2024-01-15 *"},
+		{"2024-01-15", "deal:1", false, true, "2024-01-15 document"},
+		{"2024-01-20", "", true, true, "; imported by hand\n2024-01-20"},
+		{"", "", false, false, "; a note on its own"},
+		{"2024-01-31", "", false, true, "2024-01-31 balance"},
+	}
+
+	if len(entries) != len(tests) {
+		t.Fatalf("SplitEntries() returned %d entries, expected %d: %+v", len(entries), len(tests), entries)
+	}
+	for i, tt := range tests {
+		e := entries[i]
+		if e.Date != tt.date {
+			t.Errorf("entry %d: Date = %q, expected %q", i, e.Date, tt.date)
+		}
+		if got := e.FreeeKey(); got != tt.freeeKey {
+			t.Errorf("entry %d: FreeeKey() = %q, expected %q", i, got, tt.freeeKey)
+		}
+		if got := e.IsProtected(); got != tt.protected {
+			t.Errorf("entry %d: IsProtected() = %v, expected %v", i, got, tt.protected)
+		}
+		if got := e.IsDirective(); got != tt.directive {
+			t.Errorf("entry %d: IsDirective() = %v, expected %v", i, got, tt.directive)
+		}
+		if !strings.HasPrefix(e.Text, tt.textPrefix) {
+			t.Errorf("entry %d: Text = %q, expected prefix %q", i, e.Text, tt.textPrefix)
+		}
+	}
+}
+
+func TestJoinEntriesRoundTrip(t *testing.T) {
+	header, entries := SplitEntries(monthFile)
+	if got := JoinEntries(header, entries); got != monthFile+"\n" {
+		t.Errorf("JoinEntries() = %q, expected original content with trailing blank line", got)
+	}
+}
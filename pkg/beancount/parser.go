@@ -0,0 +1,562 @@
+package beancount
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Ledger is the parsed content of a Beancount file and the files it includes.
+// Only the directives needed for validation and reporting are retained;
+// other directives (price, note, event, custom, ...) are skipped.
+type Ledger struct {
+	Options      map[string][]string
+	Plugins      []string
+	Files        []string
+	Includes     []string // resolved patterns of the include directives
+	Opens        []Open
+	Closes       []Close
+	Balances     []Balance
+	Pads         []Pad
+	Documents    []Document
+	Transactions []Transaction
+	Errors       []LedgerError
+}
+
+// LedgerError is a problem found while parsing or validating a ledger.
+type LedgerError struct {
+	Source  Source
+	Message string
+}
+
+// Error implements the error interface.
+func (e LedgerError) Error() string {
+	if e.Source.File == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s:%d: %s", e.Source.File, e.Source.Line, e.Message)
+}
+
+// Option returns the last value set for an option, or empty string if unset.
+func (l *Ledger) Option(name string) string {
+	values := l.Options[name]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// HasPlugin reports whether a plugin is enabled in the ledger.
+func (l *Ledger) HasPlugin(name string) bool {
+	for _, p := range l.Plugins {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	datePattern     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	accountPattern  = regexp.MustCompile(`^(Assets|Liabilities|Equity|Income|Expenses)(:[^\s:]+)+$`)
+	metadataPattern = regexp.MustCompile(`^([a-z][a-zA-Z0-9_-]*):\s*(.*)$`)
+)
+
+// ParseFile parses a Beancount file, following include directives.
+func ParseFile(path string) (*Ledger, error) {
+	ledger := newLedger()
+	p := &parser{ledger: ledger, visited: make(map[string]bool)}
+	if err := p.parseFile(path); err != nil {
+		return nil, err
+	}
+	return ledger, nil
+}
+
+// ParseString parses Beancount content held in memory.
+// Include directives are resolved relative to the directory of name.
+func ParseString(name, content string) (*Ledger, error) {
+	ledger := newLedger()
+	p := &parser{ledger: ledger, visited: make(map[string]bool)}
+	if err := p.parseContent(name, content); err != nil {
+		return nil, err
+	}
+	return ledger, nil
+}
+
+func newLedger() *Ledger {
+	return &Ledger{
+		Options: make(map[string][]string),
+	}
+}
+
+// parser holds state while reading one or more files into a Ledger.
+type parser struct {
+	ledger  *Ledger
+	visited map[string]bool
+}
+
+func (p *parser) parseFile(path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path %s: %w", path, err)
+	}
+	if p.visited[absPath] {
+		return nil
+	}
+	p.visited[absPath] = true
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return p.parseContent(path, string(data))
+}
+
+func (p *parser) parseContent(name, content string) error {
+	p.ledger.Files = append(p.ledger.Files, name)
+
+	// current points at the transaction being read, if any
+	var current *Transaction
+	flush := func() {
+		if current != nil {
+			p.ledger.Transactions = append(p.ledger.Transactions, *current)
+			current = nil
+		}
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		src := Source{File: name, Line: lineNo}
+
+		// Strings may span several lines (e.g. custom "fava-query")
+		for hasOpenQuote(line) && scanner.Scan() {
+			lineNo++
+			line += "\n" + strings.TrimRight(scanner.Text(), " \t\r")
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			flush()
+			continue
+		}
+		if strings.HasPrefix(trimmed, ";") || strings.HasPrefix(trimmed, "*") || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		indented := line[0] == ' ' || line[0] == '\t'
+		if indented {
+			if current != nil {
+				p.parseTransactionLine(current, trimmed, src)
+			}
+			// Metadata of other directives is ignored
+			continue
+		}
+
+		flush()
+		tokens, _ := tokenize(trimmed)
+		if len(tokens) == 0 {
+			continue
+		}
+
+		switch tokens[0].text {
+		case "option":
+			if len(tokens) >= 3 {
+				p.ledger.Options[tokens[1].text] = append(p.ledger.Options[tokens[1].text], tokens[2].text)
+			}
+			continue
+		case "plugin":
+			if len(tokens) >= 2 {
+				p.ledger.Plugins = append(p.ledger.Plugins, tokens[1].text)
+			}
+			continue
+		case "include":
+			if len(tokens) >= 2 {
+				p.include(name, tokens[1].text, src)
+			}
+			continue
+		case "pushtag", "poptag", "pushmeta", "popmeta":
+			continue
+		}
+
+		if !datePattern.MatchString(tokens[0].text) {
+			p.errorf(src, "unexpected line: %s", trimmed)
+			continue
+		}
+		if len(tokens) < 2 {
+			p.errorf(src, "incomplete directive: %s", trimmed)
+			continue
+		}
+
+		date := tokens[0].text
+		switch keyword := tokens[1].text; keyword {
+		case "*", "!", "txn":
+			current = p.parseTransactionHeader(date, tokens[1:], src)
+		case "open":
+			if len(tokens) < 3 {
+				p.errorf(src, "open directive requires an account")
+				continue
+			}
+			open := Open{Date: date, Account: tokens[2].text, Source: src}
+			for _, t := range tokens[3:] {
+				if t.quoted {
+					break // booking method
+				}
+				for _, c := range strings.Split(t.text, ",") {
+					if c != "" {
+						open.Currencies = append(open.Currencies, c)
+					}
+				}
+			}
+			p.ledger.Opens = append(p.ledger.Opens, open)
+		case "close":
+			if len(tokens) < 3 {
+				p.errorf(src, "close directive requires an account")
+				continue
+			}
+			p.ledger.Closes = append(p.ledger.Closes, Close{Date: date, Account: tokens[2].text, Source: src})
+		case "balance":
+			if len(tokens) < 5 {
+				p.errorf(src, "balance directive requires an account and amount")
+				continue
+			}
+			number, precision, err := parseNumber(tokens[3].text)
+			if err != nil {
+				p.errorf(src, "invalid balance amount %q", tokens[3].text)
+				continue
+			}
+			p.ledger.Balances = append(p.ledger.Balances, Balance{
+				Date:      date,
+				Account:   tokens[2].text,
+				Amount:    Amount{Number: number, Currency: tokens[4].text},
+				Precision: precision,
+				Source:    src,
+			})
+		case "pad":
+			if len(tokens) < 4 {
+				p.errorf(src, "pad directive requires two accounts")
+				continue
+			}
+			p.ledger.Pads = append(p.ledger.Pads, Pad{Date: date, Account: tokens[2].text, SourceAccount: tokens[3].text, Source: src})
+		case "document":
+			if len(tokens) < 4 {
+				p.errorf(src, "document directive requires an account and path")
+				continue
+			}
+			p.ledger.Documents = append(p.ledger.Documents, Document{Date: date, Account: tokens[2].text, Path: tokens[3].text, Source: src})
+		case "price", "note", "event", "query", "custom", "commodity":
+			// Not needed for validation
+		default:
+			if len(keyword) == 1 && strings.ContainsAny(keyword, "PSTCURM#%&?") {
+				// Other transaction flags
+				current = p.parseTransactionHeader(date, tokens[1:], src)
+				continue
+			}
+			p.errorf(src, "unknown directive %q", keyword)
+		}
+	}
+	flush()
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to scan %s: %w", name, err)
+	}
+	return nil
+}
+
+// include parses the file(s) referenced by an include directive.
+func (p *parser) include(from, pattern string, src Source) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(filepath.Dir(from), pattern)
+	}
+	p.ledger.Includes = append(p.ledger.Includes, pattern)
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil || len(matches) == 0 {
+		p.errorf(src, "included file not found: %s", pattern)
+		return
+	}
+
+	for _, match := range matches {
+		if err := p.parseFile(match); err != nil {
+			p.errorf(src, "failed to include %s: %v", match, err)
+		}
+	}
+}
+
+// parseTransactionHeader parses `FLAG ["payee"] "narration" #tag ^link`.
+func (p *parser) parseTransactionHeader(date string, tokens []token, src Source) *Transaction {
+	txn := &Transaction{
+		Date:     date,
+		Flag:     tokens[0].text,
+		Metadata: make(map[string]string),
+		Source:   src,
+	}
+	if txn.Flag == "txn" {
+		txn.Flag = "*"
+	}
+
+	var strs []string
+	for _, t := range tokens[1:] {
+		switch {
+		case t.quoted:
+			strs = append(strs, t.text)
+		case strings.HasPrefix(t.text, "#"):
+			txn.Tags = append(txn.Tags, t.text[1:])
+		case strings.HasPrefix(t.text, "^"):
+			txn.Links = append(txn.Links, t.text[1:])
+		}
+	}
+
+	switch len(strs) {
+	case 0:
+	case 1:
+		txn.Narration = strs[0]
+	default:
+		txn.Payee = strs[0]
+		txn.Narration = strs[1]
+	}
+
+	return txn
+}
+
+// parseTransactionLine parses an indented line belonging to a transaction:
+// either metadata (transaction- or posting-level) or a posting.
+func (p *parser) parseTransactionLine(txn *Transaction, line string, src Source) {
+	if m := metadataPattern.FindStringSubmatch(line); m != nil {
+		value := unquoteMetadata(m[2])
+		if len(txn.Postings) == 0 {
+			txn.Metadata[m[1]] = value
+			return
+		}
+		last := &txn.Postings[len(txn.Postings)-1]
+		if last.Metadata == nil {
+			last.Metadata = make(map[string]string)
+		}
+		last.Metadata[m[1]] = value
+		return
+	}
+
+	posting, err := parsePosting(line)
+	if err != nil {
+		p.errorf(src, "%v", err)
+		return
+	}
+	txn.Postings = append(txn.Postings, posting)
+}
+
+// parsePosting parses `[FLAG] Account [NUMBER CURRENCY] [{COST}] [@ PRICE] [; comment]`.
+func parsePosting(line string) (Posting, error) {
+	body, comment := splitComment(line)
+	body = strings.NewReplacer("{", " { ", "}", " } ").Replace(body)
+	fields := strings.Fields(body)
+
+	if len(fields) > 0 && (fields[0] == "!" || fields[0] == "*") {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return Posting{}, fmt.Errorf("empty posting")
+	}
+
+	posting := Posting{
+		Account: fields[0],
+		Comment: comment,
+	}
+	if !accountPattern.MatchString(posting.Account) {
+		return Posting{}, fmt.Errorf("invalid account name %q", posting.Account)
+	}
+
+	rest := fields[1:]
+	if len(rest) == 0 {
+		posting.Elided = true
+		return posting, nil
+	}
+	if len(rest) < 2 {
+		return Posting{}, fmt.Errorf("posting to %s has an amount without currency", posting.Account)
+	}
+
+	number, precision, err := parseNumber(rest[0])
+	if err != nil {
+		return Posting{}, fmt.Errorf("invalid amount %q for %s", rest[0], posting.Account)
+	}
+	posting.Amount = number
+	posting.Precision = precision
+	posting.Currency = rest[1]
+	rest = rest[2:]
+
+	// Cost: { NUMBER CURRENCY [, date] [, "label"] }
+	if len(rest) > 0 && rest[0] == "{" {
+		end := 1
+		for end < len(rest) && rest[end] != "}" {
+			end++
+		}
+		if end >= 3 {
+			if cost, _, err := parseNumber(strings.TrimSuffix(rest[1], ",")); err == nil {
+				posting.Price = &Amount{Number: cost, Currency: strings.TrimSuffix(rest[2], ",")}
+			}
+		}
+		if end < len(rest) {
+			rest = rest[end+1:]
+		} else {
+			rest = nil
+		}
+	}
+
+	// Price: @ NUMBER CURRENCY (per unit) or @@ NUMBER CURRENCY (total)
+	if len(rest) >= 3 && (rest[0] == "@" || rest[0] == "@@") && posting.Price == nil {
+		price, _, err := parseNumber(rest[1])
+		if err != nil {
+			return Posting{}, fmt.Errorf("invalid price %q for %s", rest[1], posting.Account)
+		}
+		if rest[0] == "@@" && posting.Amount != 0 {
+			price = price / abs(posting.Amount)
+		}
+		posting.Price = &Amount{Number: price, Currency: rest[2]}
+	}
+
+	return posting, nil
+}
+
+// Weight returns the amount a posting contributes to the transaction balance.
+func (p Posting) Weight() Amount {
+	if p.Price != nil {
+		return Amount{Number: p.Amount * p.Price.Number, Currency: p.Price.Currency}
+	}
+	return Amount{Number: p.Amount, Currency: p.Currency}
+}
+
+// Amounts returns the amount per account of a transaction in a currency.
+// A posting without an amount receives the residual.
+func (t Transaction) Amounts(currency string) map[string]float64 {
+	amounts := make(map[string]float64)
+	var residual float64
+	elided := ""
+	for _, p := range t.Postings {
+		if p.Elided {
+			elided = p.Account
+			continue
+		}
+		if w := p.Weight(); w.Currency == currency {
+			residual -= w.Number
+		}
+		if p.Currency == currency {
+			amounts[p.Account] += p.Amount
+		}
+	}
+	if elided != "" {
+		amounts[elided] += residual
+	}
+	return amounts
+}
+
+// parseNumber parses a Beancount number and returns the number of fractional digits.
+func parseNumber(s string) (float64, int, error) {
+	s = strings.ReplaceAll(s, ",", "")
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	precision := 0
+	if idx := strings.IndexByte(s, '.'); idx >= 0 {
+		precision = len(s) - idx - 1
+	}
+	return value, precision, nil
+}
+
+// splitComment splits a line at the first ';' outside of a quoted string.
+func splitComment(line string) (string, string) {
+	inQuote := false
+	for i, r := range line {
+		switch r {
+		case '"':
+			inQuote = !inQuote
+		case ';':
+			if !inQuote {
+				return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+			}
+		}
+	}
+	return strings.TrimSpace(line), ""
+}
+
+func unquoteMetadata(value string) string {
+	value, _ = splitComment(value)
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// hasOpenQuote reports whether a line ends inside a quoted string.
+func hasOpenQuote(line string) bool {
+	body, _ := splitComment(line)
+	inQuote := false
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			i++
+		case '"':
+			inQuote = !inQuote
+		}
+	}
+	return inQuote
+}
+
+// token is a whitespace-separated word or a quoted string.
+type token struct {
+	text   string
+	quoted bool
+}
+
+// tokenize splits a directive line into tokens, stopping at a comment.
+func tokenize(line string) ([]token, string) {
+	var tokens []token
+	i := 0
+	for i < len(line) {
+		switch c := line[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == ';':
+			return tokens, strings.TrimSpace(line[i+1:])
+		case c == '"':
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end > len(line) {
+				end = len(line)
+			}
+			tokens = append(tokens, token{text: strings.ReplaceAll(line[i+1:min(end, len(line))], `\"`, `"`), quoted: true})
+			i = end + 1
+		default:
+			end := i
+			for end < len(line) && line[end] != ' ' && line[end] != '\t' {
+				end++
+			}
+			tokens = append(tokens, token{text: line[i:end]})
+			i = end
+		}
+	}
+	return tokens, ""
+}
+
+func (p *parser) errorf(src Source, format string, args ...interface{}) {
+	p.ledger.Errors = append(p.ledger.Errors, LedgerError{Source: src, Message: fmt.Sprintf(format, args...)})
+}
+
+func abs(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
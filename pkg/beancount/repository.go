@@ -40,7 +40,9 @@ func NewFileSystemRepository(pathResolver *pathutil.PathResolver) *FileSystemRep
 }
 
 // AppendTransaction appends a transaction to a monthly file.
-// It creates the file if it doesn't exist.
+// It creates the file if it doesn't exist. The file is replaced atomically,
+// so after a crash it holds either the whole transaction or none of it.
+// Files of a closed year are not written (ErrPeriodClosed).
 func (r *FileSystemRepository) AppendTransaction(yearMonth, transaction string, comment ...string) error {
 	filePath, err := r.pathResolver.GetMonthFilePath(yearMonth)
 	if err != nil {
@@ -64,19 +66,64 @@ func (r *FileSystemRepository) AppendTransaction(yearMonth, transaction string,
 	content += "\n" // Add blank line after transaction
 
 	// Append to file
-	f, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0644)
+	existing, err := os.ReadFile(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to open file for appending: %w", err)
+		return fmt.Errorf("failed to read file: %w", err)
 	}
-	defer f.Close()
 
-	if _, err := f.WriteString(content); err != nil {
+	if err := WriteFileAtomic(filePath, append(existing, content...)); err != nil {
 		return fmt.Errorf("failed to write to file: %w", err)
 	}
 
 	return nil
 }
 
+// WriteFileAtomic writes data to a temporary file in the same directory,
+// syncs it and renames it over path. Readers and crashes never observe a
+// partially written file.
+func WriteFileAtomic(path string, data []byte) error {
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set file mode: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+
+	// Persist the rename itself
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
+
 // ReadMonthFile reads the content of a monthly file.
 // Returns empty string if file doesn't exist.
 func (r *FileSystemRepository) ReadMonthFile(yearMonth string) (string, error) {
@@ -128,8 +175,12 @@ func (r *FileSystemRepository) GetMonthFilesInYear(year string) ([]string, error
 
 		name := entry.Name()
 		if filepath.Ext(name) == ".beancount" {
-			// Remove .beancount extension to get YYYY-MM
+			// Remove .beancount extension to get YYYY-MM; other files of
+			// the year, such as the allocation journal, are skipped
 			monthKey := name[:len(name)-len(".beancount")]
+			if _, err := time.Parse("2006-01", monthKey); err != nil {
+				continue
+			}
 			monthFiles = append(monthFiles, monthKey)
 		}
 	}
@@ -138,13 +189,19 @@ func (r *FileSystemRepository) GetMonthFilesInYear(year string) ([]string, error
 }
 
 // EnsureMonthFile ensures a monthly file exists with header.
-// If the file already exists, this is a no-op.
+// If the file already exists, this is a no-op. It fails with
+// ErrPeriodClosed for a month of a closed year, so that callers stop
+// before writing to it.
 func (r *FileSystemRepository) EnsureMonthFile(yearMonth string) error {
 	filePath, err := r.pathResolver.GetMonthFilePath(yearMonth)
 	if err != nil {
 		return fmt.Errorf("failed to get month file path: %w", err)
 	}
 
+	if err := CheckWritable(filePath); err != nil {
+		return err
+	}
+
 	if r.pathResolver.FileExists(filePath) {
 		return nil
 	}
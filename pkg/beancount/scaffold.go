@@ -0,0 +1,167 @@
+package beancount
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Files created for a new ledger, relative to the Beancount root.
+const (
+	MainFile            = "main.beancount"
+	AccountsFile        = "accounts.beancount"
+	OpeningBalancesFile = "opening-balances.beancount"
+)
+
+// OpeningBalancesAccount is the equity account opening balances are booked against.
+const OpeningBalancesAccount = "Equity:OpeningBalances"
+
+// SuspenseAccount is the account pending entries for wallet transactions
+// not yet booked in freee are booked against.
+const SuspenseAccount = "Assets:Current:Suspense"
+
+// accountSections lists the root accounts in the order of the balance
+// sheet and income statement, with their section titles.
+var accountSections = []struct {
+	root  string
+	title string
+}{
+	{"Assets", "ASSETS (資産)"},
+	{"Liabilities", "LIABILITIES (負債)"},
+	{"Equity", "EQUITY (純資産)"},
+	{"Income", "INCOME (収益)"},
+	{"Expenses", "EXPENSES (費用)"},
+}
+
+// FormatOpens formats open directives for accounts, grouped by root
+// account. The description of each account is written as a comment.
+func FormatOpens(date, currency string, accounts map[string]string) string {
+	names := make([]string, 0, len(accounts))
+	for account := range accounts {
+		names = append(names, account)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteString("; Account Definitions\n; 勘定科目定義\n")
+
+	for _, section := range accountSections {
+		var lines []string
+		for _, account := range names {
+			root, _, _ := strings.Cut(account, ":")
+			if root != section.root {
+				continue
+			}
+			pad := 44 - displayWidth(account)
+			if pad < 1 {
+				pad = 1
+			}
+			line := fmt.Sprintf("%s open %s%s%s", date, account, strings.Repeat(" ", pad), currency)
+			if desc := accounts[account]; desc != "" {
+				line += "  ; " + desc
+			}
+			lines = append(lines, line)
+		}
+		if len(lines) == 0 {
+			continue
+		}
+
+		sb.WriteString("\n; ==============================================================================\n")
+		sb.WriteString("; " + section.title + "\n")
+		sb.WriteString("; ==============================================================================\n\n")
+		sb.WriteString(strings.Join(lines, "\n"))
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// displayWidth returns the width of s in a monospace font, counting
+// non-ASCII characters (kana, kanji) as two columns.
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		if r < 0x80 {
+			width++
+		} else {
+			width += 2
+		}
+	}
+	return width
+}
+
+// MainFileContent returns the main file of a new ledger. It includes the
+// account definitions, the opening balances and every month file.
+func MainFileContent(title, currency string) string {
+	return fmt.Sprintf(`; Main Ledger File
+; メイン台帳ファイル
+
+option "title" %q
+option "operating_currency" %q
+
+; Auto-pad accounts to make balances consistent
+plugin "beancount.plugins.auto"
+
+; Check for balance assertions
+plugin "beancount.plugins.check_closing"
+
+include %q
+include %q
+
+; Monthly transaction files written by freee-sync
+include "[0-9][0-9][0-9][0-9]/*.beancount"
+`, title, currency, AccountsFile, OpeningBalancesFile)
+}
+
+// OpeningBalancesContent returns the opening balances file of a new ledger.
+func OpeningBalancesContent() string {
+	return fmt.Sprintf(`; Opening Balances (期首残高)
+;
+; Generate the balances from freee with: freee-sync opening-balances
+; or book the balances of the first day against %s, e.g.:
+;
+; 2024-01-01 * "Opening Balance" "期首残高"
+;   Assets:Current:Bank:Ordinary        1000000 JPY
+;   %s
+`, OpeningBalancesAccount, OpeningBalancesAccount)
+}
+
+// Reaches reports whether an include directive of the ledger matches path,
+// so that a file written there is read with the ledger.
+func (l *Ledger) Reaches(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range l.Includes {
+		if pattern, err = filepath.Abs(pattern); err != nil {
+			continue
+		}
+		if ok, _ := filepath.Match(pattern, abs); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// AddInclude appends an include directive for path, relative to the
+// directory of the main file, to the main file.
+func AddInclude(mainPath, path string) error {
+	rel, err := filepath.Rel(filepath.Dir(mainPath), path)
+	if err != nil {
+		rel = path
+	}
+
+	f, err := os.OpenFile(mainPath, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", mainPath, err)
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintf(f, "include %q\n", filepath.ToSlash(rel)); err != nil {
+		return fmt.Errorf("failed to add include to %s: %w", mainPath, err)
+	}
+	return nil
+}
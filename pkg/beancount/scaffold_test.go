@@ -0,0 +1,92 @@
+package beancount
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatOpens(t *testing.T) {
+	got := FormatOpens("2024-01-01", "JPY", map[string]string{
+		"Expenses:SGA:通信費":         "通信費",
+		"Assets:Current:Cash":      "",
+		OpeningBalancesAccount:     "開始残高",
+		"Liabilities:Current:Amex": "Amex",
+	})
+
+	ledger, err := ParseString("accounts.beancount", got)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+	if len(ledger.Errors) > 0 || len(ledger.Opens) != 4 {
+		t.Fatalf("parsed %d opens, errors %v:\n%s", len(ledger.Opens), ledger.Errors, got)
+	}
+
+	// Sections follow the balance sheet and income statement order
+	order := []string{"Assets:Current:Cash", "Liabilities:Current:Amex", OpeningBalancesAccount, "Expenses:SGA:通信費"}
+	for i, open := range ledger.Opens {
+		if open.Account != order[i] {
+			t.Errorf("open %d = %s, want %s", i, open.Account, order[i])
+		}
+	}
+
+	// Kanji count as two columns, so JPY lines up with the ASCII accounts
+	if !strings.Contains(got, "open Expenses:SGA:通信費"+strings.Repeat(" ", 25)+"JPY  ; 通信費\n") {
+		t.Errorf("account not aligned or comment missing:\n%s", got)
+	}
+}
+
+func TestMainFileContentParses(t *testing.T) {
+	ledger, err := ParseString("main.beancount", MainFileContent("Test", "JPY"))
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+	if got := ledger.Option("operating_currency"); got != "JPY" {
+		t.Errorf("operating_currency = %q, want JPY", got)
+	}
+}
+
+func TestAddInclude(t *testing.T) {
+	root := t.TempDir()
+	mainPath := filepath.Join(root, MainFile)
+	month := filepath.Join(root, "2024", "2024-12.beancount")
+	closing := filepath.Join(root, "2024", ClosingFileName("2024"))
+	if err := os.MkdirAll(filepath.Dir(month), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{month, closing} {
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(mainPath, []byte("include \"2024/2024-12.beancount\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ledger, err := ParseFile(mainPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ledger.Reaches(month) || ledger.Reaches(closing) {
+		t.Fatalf("Reaches() = %v, %v, want only the month file reached", ledger.Reaches(month), ledger.Reaches(closing))
+	}
+
+	if err := AddInclude(mainPath, closing); err != nil {
+		t.Fatalf("AddInclude() error = %v", err)
+	}
+	if ledger, err = ParseFile(mainPath); err != nil || len(ledger.Errors) > 0 {
+		t.Fatalf("ParseFile() = %v, %v", ledger.Errors, err)
+	}
+	if !ledger.Reaches(closing) {
+		t.Error("closing file not reached after AddInclude()")
+	}
+
+	globbed, err := ParseString(mainPath, MainFileContent("Test", "JPY"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !globbed.Reaches(closing) {
+		t.Error("month file glob of a new ledger does not reach the closing file")
+	}
+}
@@ -3,19 +3,77 @@ package beancount
 
 // Transaction represents a Beancount transaction.
 type Transaction struct {
-	Date      string     // YYYY-MM-DD
-	Narration string     // Transaction description
-	Payee     string     // Payee name (optional)
-	Tags      []string   // Tags (e.g., ["invoice-123"])
-	Links     []string   // Links (optional)
+	Date      string            // YYYY-MM-DD
+	Flag      string            // "*" (complete) or "!" (pending)
+	Narration string            // Transaction description
+	Payee     string            // Payee name (optional)
+	Tags      []string          // Tags (e.g., ["invoice-123"])
+	Links     []string          // Links (optional)
 	Metadata  map[string]string // Metadata key-value pairs
-	Postings  []Posting  // Transaction postings
+	Postings  []Posting         // Transaction postings
+	Source    Source            // Location in the ledger (set by the parser)
 }
 
 // Posting represents a posting in a Beancount transaction.
 type Posting struct {
-	Account  string  // Account name (e.g., "Assets:Bank:Checking")
-	Amount   float64 // Amount (positive for debit, negative for credit)
-	Currency string  // Currency code (e.g., "JPY")
-	Comment  string  // Posting comment (optional)
+	Account   string            // Account name (e.g., "Assets:Bank:Checking")
+	Amount    float64           // Amount (positive for debit, negative for credit)
+	Currency  string            // Currency code (e.g., "JPY")
+	Comment   string            // Posting comment (optional)
+	Elided    bool              // Amount omitted; Beancount interpolates it
+	Precision int               // Number of fractional digits written for Amount
+	Price     *Amount           // Per-unit price (@) or cost ({}) used for the balancing weight
+	Metadata  map[string]string // Posting-level metadata
+}
+
+// Amount is a number with a currency.
+type Amount struct {
+	Number   float64
+	Currency string
+}
+
+// Source identifies where a directive was read from.
+type Source struct {
+	File string
+	Line int
+}
+
+// Open represents an "open" directive.
+type Open struct {
+	Date       string
+	Account    string
+	Currencies []string
+	Source     Source
+}
+
+// Close represents a "close" directive.
+type Close struct {
+	Date    string
+	Account string
+	Source  Source
+}
+
+// Balance represents a "balance" assertion.
+type Balance struct {
+	Date      string
+	Account   string
+	Amount    Amount
+	Precision int
+	Source    Source
+}
+
+// Pad represents a "pad" directive.
+type Pad struct {
+	Date          string
+	Account       string
+	SourceAccount string
+	Source        Source
+}
+
+// Document represents a "document" directive.
+type Document struct {
+	Date    string
+	Account string
+	Path    string
+	Source  Source
 }
@@ -0,0 +1,518 @@
+package beancount
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Plugins whose semantics the validator reproduces.
+const (
+	PluginAuto         = "beancount.plugins.auto"
+	PluginAutoAccounts = "beancount.plugins.auto_accounts"
+	PluginCheckClosing = "beancount.plugins.check_closing"
+)
+
+// FreeeIDKey is the metadata key carrying the freee ID of a synced entry.
+const FreeeIDKey = "freee_id"
+
+// FreeeTypeKey is the metadata key carrying the freee resource type of a synced entry.
+const FreeeTypeKey = "freee_type"
+
+// ValidatorConfig configures ledger validation.
+type ValidatorConfig struct {
+	// Tolerances overrides the balancing tolerance per currency ("*" for all).
+	// When unset, tolerances are inferred from the precision of the amounts
+	// as Beancount does, falling back to option "inferred_tolerance_default".
+	Tolerances map[string]float64
+
+	// StrictAccounts requires explicit open directives even when the
+	// ledger enables the auto_accounts plugin.
+	StrictAccounts bool
+}
+
+// Validator checks a ledger for the errors bean-check would report:
+// unbalanced transactions, postings to unopened or closed accounts,
+// failed balance assertions and check_closing violations. It also
+// rejects entries that share a freee_id, which indicate a double sync.
+type Validator struct {
+	ledger       *Ledger
+	config       ValidatorConfig
+	autoAccounts bool
+	opens        map[string]Open
+	closes       map[string]Close
+	freeeIDs     map[string]Source
+}
+
+// NewValidator creates a Validator for a parsed ledger.
+func NewValidator(ledger *Ledger, config ValidatorConfig) *Validator {
+	v := &Validator{
+		ledger:   ledger,
+		config:   config,
+		opens:    make(map[string]Open),
+		closes:   make(map[string]Close),
+		freeeIDs: make(map[string]Source),
+	}
+
+	v.autoAccounts = !config.StrictAccounts &&
+		(ledger.HasPlugin(PluginAuto) || ledger.HasPlugin(PluginAutoAccounts))
+
+	if v.config.Tolerances == nil {
+		v.config.Tolerances = parseToleranceOption(ledger.Options["inferred_tolerance_default"])
+	}
+
+	for _, open := range ledger.Opens {
+		if _, exists := v.opens[open.Account]; !exists {
+			v.opens[open.Account] = open
+		}
+	}
+	for _, c := range ledger.Closes {
+		v.closes[c.Account] = c
+	}
+	for _, txn := range ledger.Transactions {
+		if key := freeeKey(txn); key != "" {
+			if _, exists := v.freeeIDs[key]; !exists {
+				v.freeeIDs[key] = txn.Source
+			}
+		}
+	}
+
+	return v
+}
+
+// Validate checks the whole ledger and returns all errors found,
+// including parse errors, ordered by file and line.
+func (v *Validator) Validate() []LedgerError {
+	var errs []LedgerError
+	errs = append(errs, v.ledger.Errors...)
+	errs = append(errs, v.checkDirectiveAccounts()...)
+
+	seen := make(map[string]Source)
+	for _, txn := range v.sortedTransactions() {
+		errs = append(errs, v.checkBalanced(txn)...)
+		errs = append(errs, v.checkAccounts(txn)...)
+		if err := checkDuplicate(txn, seen); err != nil {
+			errs = append(errs, *err)
+		}
+	}
+
+	errs = append(errs, v.checkAssertions()...)
+
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Source.File != errs[j].Source.File {
+			return errs[i].Source.File < errs[j].Source.File
+		}
+		return errs[i].Source.Line < errs[j].Source.Line
+	})
+
+	return errs
+}
+
+// CheckTransaction checks a candidate transaction against the ledger
+// before it is written: it must balance, post only to accounts open on its
+// date and not reuse a freee_id already present in the ledger or
+// previously accepted by this Validator.
+func (v *Validator) CheckTransaction(txn Transaction) []LedgerError {
+	var errs []LedgerError
+	errs = append(errs, v.checkBalanced(txn)...)
+	errs = append(errs, v.checkAccounts(txn)...)
+
+	if key := freeeKey(txn); key != "" {
+		if first, exists := v.freeeIDs[key]; exists {
+			errs = append(errs, duplicateError(txn, key, first))
+		}
+	}
+
+	return errs
+}
+
+// Accept records a transaction that has been written so that later
+// candidates with the same freee_id are rejected.
+func (v *Validator) Accept(txn Transaction) {
+	if key := freeeKey(txn); key != "" {
+		v.freeeIDs[key] = txn.Source
+	}
+}
+
+// Remove forgets a synced entry, given its FreeeKey, so that its
+// replacement is not reported as a duplicate. It is used when an entry is
+// regenerated from freee.
+func (v *Validator) Remove(key string) {
+	delete(v.freeeIDs, key)
+}
+
+// IsOpen reports whether an account is open on the given date.
+func (v *Validator) IsOpen(account, date string) bool {
+	open, ok := v.opens[account]
+	if !ok || date < open.Date {
+		return false
+	}
+	if c, closed := v.closes[account]; closed && date > c.Date {
+		return false
+	}
+	return true
+}
+
+// checkBalanced verifies that the weights of the postings sum to zero
+// per currency within tolerance. A single posting without an amount
+// absorbs the residual, as Beancount interpolates it.
+func (v *Validator) checkBalanced(txn Transaction) []LedgerError {
+	if len(txn.Postings) == 0 {
+		return nil
+	}
+
+	sums := make(map[string]float64)
+	tolerances := make(map[string]float64)
+	elided := 0
+	for _, p := range txn.Postings {
+		if p.Elided {
+			elided++
+			continue
+		}
+		w := p.Weight()
+		sums[w.Currency] += w.Number
+		if p.Precision > 0 && p.Price == nil {
+			t := 0.5 * math.Pow10(-p.Precision)
+			if t > tolerances[w.Currency] {
+				tolerances[w.Currency] = t
+			}
+		}
+	}
+
+	if elided > 1 {
+		return []LedgerError{{Source: txn.Source, Message: "transaction has more than one posting without an amount"}}
+	}
+	if elided == 1 {
+		return nil
+	}
+
+	var errs []LedgerError
+	for _, currency := range sortedKeys(sums) {
+		tolerance := v.tolerance(currency, tolerances[currency])
+		if math.Abs(sums[currency]) > tolerance {
+			errs = append(errs, LedgerError{
+				Source:  txn.Source,
+				Message: fmt.Sprintf("transaction does not balance: %s %s (%s)", formatNumber(sums[currency]), currency, txn.Narration),
+			})
+		}
+	}
+	return errs
+}
+
+// tolerance returns the balancing tolerance for a currency.
+func (v *Validator) tolerance(currency string, inferred float64) float64 {
+	const epsilon = 1e-9
+
+	if t, ok := v.config.Tolerances[currency]; ok {
+		return math.Max(t, epsilon)
+	}
+	if inferred > 0 {
+		return inferred
+	}
+	if t, ok := v.config.Tolerances["*"]; ok {
+		return math.Max(t, epsilon)
+	}
+	return epsilon
+}
+
+// checkAccounts verifies that every posting account is open on the
+// transaction date, not yet closed, and accepts the posted currency.
+// With auto_accounts, unopened accounts are opened at their first use.
+func (v *Validator) checkAccounts(txn Transaction) []LedgerError {
+	var errs []LedgerError
+	for _, p := range txn.Postings {
+		open, ok := v.opens[p.Account]
+		if !ok {
+			if v.autoAccounts {
+				continue
+			}
+			errs = append(errs, LedgerError{
+				Source:  txn.Source,
+				Message: fmt.Sprintf("account %s is not opened", p.Account),
+			})
+			continue
+		}
+		if txn.Date < open.Date {
+			errs = append(errs, LedgerError{
+				Source:  txn.Source,
+				Message: fmt.Sprintf("account %s is used on %s before it is opened on %s", p.Account, txn.Date, open.Date),
+			})
+		}
+		if c, closed := v.closes[p.Account]; closed && txn.Date > c.Date {
+			errs = append(errs, LedgerError{
+				Source:  txn.Source,
+				Message: fmt.Sprintf("account %s is used on %s after it was closed on %s", p.Account, txn.Date, c.Date),
+			})
+		}
+		if len(open.Currencies) > 0 && !p.Elided && !contains(open.Currencies, p.Currency) {
+			errs = append(errs, LedgerError{
+				Source:  txn.Source,
+				Message: fmt.Sprintf("currency %s is not allowed in account %s", p.Currency, p.Account),
+			})
+		}
+	}
+	return errs
+}
+
+// checkDirectiveAccounts verifies accounts referenced by non-transaction directives.
+func (v *Validator) checkDirectiveAccounts() []LedgerError {
+	var errs []LedgerError
+
+	seenOpen := make(map[string]bool)
+	for _, open := range v.ledger.Opens {
+		if seenOpen[open.Account] {
+			errs = append(errs, LedgerError{Source: open.Source, Message: fmt.Sprintf("duplicate open directive for %s", open.Account)})
+		}
+		seenOpen[open.Account] = true
+	}
+
+	for _, c := range v.ledger.Closes {
+		open, ok := v.opens[c.Account]
+		if !ok {
+			errs = append(errs, LedgerError{Source: c.Source, Message: fmt.Sprintf("closing unopened account %s", c.Account)})
+			continue
+		}
+		if c.Date < open.Date {
+			errs = append(errs, LedgerError{Source: c.Source, Message: fmt.Sprintf("account %s is closed before it is opened", c.Account)})
+		}
+	}
+
+	for _, d := range v.ledger.Documents {
+		if !v.autoAccounts && !v.IsOpen(d.Account, d.Date) {
+			errs = append(errs, LedgerError{Source: d.Source, Message: fmt.Sprintf("document references inactive account %s", d.Account)})
+		}
+	}
+
+	return errs
+}
+
+// assertion is a balance assertion to evaluate: either an explicit
+// balance directive or one implied by check_closing.
+type assertion struct {
+	date      string
+	account   string
+	amount    Amount
+	precision int
+	source    Source
+	implicit  bool
+}
+
+// checkAssertions evaluates balance directives, honouring pad directives,
+// and the zero-balance assertions the check_closing plugin inserts the
+// day after a posting carrying "closing: TRUE".
+func (v *Validator) checkAssertions() []LedgerError {
+	var assertions []assertion
+	for _, b := range v.ledger.Balances {
+		assertions = append(assertions, assertion{date: b.Date, account: b.Account, amount: b.Amount, precision: b.Precision, source: b.Source})
+	}
+	if v.ledger.HasPlugin(PluginCheckClosing) {
+		for _, txn := range v.ledger.Transactions {
+			for _, p := range txn.Postings {
+				if !strings.EqualFold(p.Metadata["closing"], "TRUE") {
+					continue
+				}
+				assertions = append(assertions, assertion{
+					date:     nextDay(txn.Date),
+					account:  p.Account,
+					amount:   Amount{Currency: p.Currency},
+					source:   txn.Source,
+					implicit: true,
+				})
+			}
+		}
+	}
+	if len(assertions) == 0 {
+		return nil
+	}
+	sort.SliceStable(assertions, func(i, j int) bool { return assertions[i].date < assertions[j].date })
+
+	txns := v.sortedTransactions()
+
+	// padded holds amounts inserted by pad directives, keyed by account+currency
+	type padEntry struct {
+		date   string
+		amount float64
+	}
+	padded := make(map[string][]padEntry)
+	lastAssertion := make(map[string]string)
+	usedPads := make(map[int]bool)
+
+	var errs []LedgerError
+	for _, a := range assertions {
+		key := a.account + " " + a.amount.Currency
+
+		balance := 0.0
+		for _, txn := range txns {
+			if txn.Date >= a.date {
+				break
+			}
+			for _, p := range txn.Postings {
+				if p.Elided || p.Currency != a.amount.Currency {
+					continue
+				}
+				if p.Account == a.account || strings.HasPrefix(p.Account, a.account+":") {
+					balance += p.Amount
+				}
+			}
+		}
+		for k, entries := range padded {
+			if k == key || strings.HasPrefix(k, a.account+":") && strings.HasSuffix(k, " "+a.amount.Currency) {
+				for _, e := range entries {
+					if e.date < a.date {
+						balance += e.amount
+					}
+				}
+			}
+		}
+
+		diff := a.amount.Number - balance
+		tolerance := 1e-9
+		if a.precision > 0 {
+			tolerance = 0.5 * math.Pow10(-a.precision)
+		}
+		if math.Abs(diff) > tolerance {
+			// A pad between the previous assertion and this one fills the gap
+			padIdx := -1
+			for i, pad := range v.ledger.Pads {
+				if usedPads[i] || pad.Account != a.account || pad.Date >= a.date || pad.Date < lastAssertion[key] {
+					continue
+				}
+				padIdx = i
+			}
+			if padIdx >= 0 && !a.implicit {
+				usedPads[padIdx] = true
+				padded[key] = append(padded[key], padEntry{date: v.ledger.Pads[padIdx].Date, amount: diff})
+			} else {
+				msg := fmt.Sprintf("balance failed for %s on %s: expected %s %s, accumulated %s %s (%s too %s)",
+					a.account, a.date,
+					formatNumber(a.amount.Number), a.amount.Currency,
+					formatNumber(balance), a.amount.Currency,
+					formatNumber(math.Abs(diff)), moreOrLess(diff))
+				if a.implicit {
+					msg = fmt.Sprintf("check_closing: %s must be zero after closing posting; %s", a.account, msg)
+				}
+				errs = append(errs, LedgerError{Source: a.source, Message: msg})
+			}
+		}
+		lastAssertion[key] = a.date
+	}
+
+	return errs
+}
+
+// sortedTransactions returns the ledger transactions ordered by date,
+// keeping file order for entries on the same date.
+func (v *Validator) sortedTransactions() []Transaction {
+	txns := make([]Transaction, len(v.ledger.Transactions))
+	copy(txns, v.ledger.Transactions)
+	sort.SliceStable(txns, func(i, j int) bool { return txns[i].Date < txns[j].Date })
+	return txns
+}
+
+// checkDuplicate reports a transaction whose freee ID was already seen.
+func checkDuplicate(txn Transaction, seen map[string]Source) *LedgerError {
+	key := freeeKey(txn)
+	if key == "" {
+		return nil
+	}
+	if first, exists := seen[key]; exists {
+		err := duplicateError(txn, key, first)
+		return &err
+	}
+	seen[key] = txn.Source
+	return nil
+}
+
+func duplicateError(txn Transaction, key string, first Source) LedgerError {
+	return LedgerError{
+		Source:  txn.Source,
+		Message: fmt.Sprintf("duplicate %s %s (first recorded at %s:%d)", FreeeIDKey, strings.TrimPrefix(key, ":"), first.File, first.Line),
+	}
+}
+
+// FreeeKey returns the key identifying a synced freee entry, e.g. "deal:123".
+func FreeeKey(freeeType string, freeeID int64) string {
+	return freeeType + ":" + strconv.FormatInt(freeeID, 10)
+}
+
+// FreeeKeys returns the keys of all transactions in the ledger that carry
+// freee_id metadata.
+func (l *Ledger) FreeeKeys() map[string]bool {
+	keys := make(map[string]bool)
+	for _, txn := range l.Transactions {
+		if key := freeeKey(txn); key != "" {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+// FreeeKey returns the key of the freee entry the transaction was synced
+// from, or an empty string if it carries no freee_id metadata.
+func (t Transaction) FreeeKey() string {
+	return freeeKey(t)
+}
+
+// freeeKey returns the identity of a synced entry: freee_type:freee_id.
+func freeeKey(txn Transaction) string {
+	id := txn.Metadata[FreeeIDKey]
+	if id == "" {
+		return ""
+	}
+	return txn.Metadata[FreeeTypeKey] + ":" + id
+}
+
+// parseToleranceOption parses inferred_tolerance_default values ("JPY:0.5", "*:0.005").
+func parseToleranceOption(values []string) map[string]float64 {
+	result := make(map[string]float64)
+	for _, value := range values {
+		parts := strings.SplitN(value, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if t, err := strconv.ParseFloat(parts[1], 64); err == nil {
+			result[parts[0]] = t
+		}
+	}
+	return result
+}
+
+func nextDay(date string) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return t.AddDate(0, 0, 1).Format("2006-01-02")
+}
+
+func moreOrLess(diff float64) string {
+	if diff > 0 {
+		return "little"
+	}
+	return "much"
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package beancount
+
+import (
+	"strings"
+	"testing"
+)
+
+const testAccounts = `
+2024-01-01 open Assets:Bank JPY
+2024-01-01 open Expenses:Supplies JPY
+2024-01-01 open Liabilities:Card
+2024-03-01 open Assets:Savings JPY
+2024-06-30 close Liabilities:Card
+`
+
+func validate(t *testing.T, content string, config ValidatorConfig) []LedgerError {
+	t.Helper()
+	ledger, err := ParseString("test.beancount", content)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+	return NewValidator(ledger, config).Validate()
+}
+
+func TestValidateBalancing(t *testing.T) {
+	tests := []struct {
+		name     string
+		entry    string
+		expected int
+	}{
+		{"balanced", "2024-02-01 * \"Pens\"\n  Expenses:Supplies 1000 JPY\n  Assets:Bank -1000 JPY\n", 0},
+		{"unbalanced", "2024-02-01 * \"Pens\"\n  Expenses:Supplies 1000 JPY\n  Assets:Bank -900 JPY\n", 1},
+		{"elided posting", "2024-02-01 * \"Pens\"\n  Expenses:Supplies 1000 JPY\n  Assets:Bank\n", 0},
+		{"two elided postings", "2024-02-01 * \"Pens\"\n  Expenses:Supplies\n  Assets:Bank\n", 1},
+		{"within inferred tolerance", "2024-02-01 * \"Pens\"\n  Expenses:Supplies 10.004 JPY\n  Assets:Bank -10.00 JPY\n", 0},
+		{"price weight", "2024-02-01 * \"FX\"\n  Expenses:Supplies 1500 JPY\n  Assets:Bank -10 USD @ 150 JPY\n", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validate(t, testAccounts+"\n"+tt.entry, ValidatorConfig{})
+			if len(errs) != tt.expected {
+				t.Errorf("Validate() returned %d errors, expected %d: %v", len(errs), tt.expected, errs)
+			}
+		})
+	}
+}
+
+func TestValidateAccounts(t *testing.T) {
+	tests := []struct {
+		name     string
+		entry    string
+		contains string
+	}{
+		{"unopened account", "2024-02-01 * \"x\"\n  Expenses:Unknown 100 JPY\n  Assets:Bank -100 JPY\n", "is not opened"},
+		{"before open", "2024-02-01 * \"x\"\n  Expenses:Supplies 100 JPY\n  Assets:Savings -100 JPY\n", "before it is opened"},
+		{"after close", "2024-07-01 * \"x\"\n  Expenses:Supplies 100 JPY\n  Liabilities:Card -100 JPY\n", "after it was closed"},
+		{"disallowed currency", "2024-02-01 * \"x\"\n  Expenses:Supplies 100 USD\n  Liabilities:Card -100 USD\n", "currency USD is not allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validate(t, testAccounts+"\n"+tt.entry, ValidatorConfig{})
+			if len(errs) != 1 || !strings.Contains(errs[0].Message, tt.contains) {
+				t.Errorf("Validate() = %v, expected one error containing %q", errs, tt.contains)
+			}
+		})
+	}
+}
+
+func TestValidateAutoAccounts(t *testing.T) {
+	content := "plugin \"beancount.plugins.auto\"\n" + testAccounts + `
+2024-02-01 * "x"
+  Expenses:Unknown 100 JPY
+  Assets:Bank -100 JPY
+`
+	if errs := validate(t, content, ValidatorConfig{}); len(errs) != 0 {
+		t.Errorf("Validate() with auto plugin returned %v, expected no errors", errs)
+	}
+	if errs := validate(t, content, ValidatorConfig{StrictAccounts: true}); len(errs) != 1 {
+		t.Errorf("Validate() in strict mode returned %d errors, expected 1", len(errs))
+	}
+}
+
+func TestValidateDuplicateFreeeID(t *testing.T) {
+	entry := `
+2024-02-01 * "Pens"
+  freee_type: "deal"
+  freee_id: "42"
+  Expenses:Supplies 1000 JPY
+  Assets:Bank -1000 JPY
+`
+	errs := validate(t, testAccounts+entry+entry, ValidatorConfig{})
+	if len(errs) != 1 || !strings.Contains(errs[0].Message, "duplicate freee_id deal:42") {
+		t.Errorf("Validate() = %v, expected one duplicate error", errs)
+	}
+
+	ledger, _ := ParseString("ledger.beancount", testAccounts+entry)
+	candidate, _ := ParseString("new.beancount", entry)
+	v := NewValidator(ledger, ValidatorConfig{})
+	if errs := v.CheckTransaction(candidate.Transactions[0]); len(errs) != 1 {
+		t.Errorf("CheckTransaction() returned %d errors, expected 1 duplicate", len(errs))
+	}
+}
+
+func TestValidateCheckClosing(t *testing.T) {
+	base := "plugin \"beancount.plugins.check_closing\"\n" + testAccounts
+
+	closed := base + `
+2024-02-01 * "Buy"
+  Expenses:Supplies 1000 JPY
+  Liabilities:Card -1000 JPY
+
+2024-02-10 * "Repay"
+  Liabilities:Card 1000 JPY
+    closing: TRUE
+  Assets:Bank -1000 JPY
+`
+	if errs := validate(t, closed, ValidatorConfig{}); len(errs) != 0 {
+		t.Errorf("Validate() = %v, expected no errors", errs)
+	}
+
+	notClosed := strings.Replace(closed, "Liabilities:Card 1000 JPY", "Liabilities:Card 900 JPY", 1)
+	notClosed = strings.Replace(notClosed, "Assets:Bank -1000 JPY\n", "Assets:Bank -900 JPY\n", 1)
+	errs := validate(t, notClosed, ValidatorConfig{})
+	if len(errs) != 1 || !strings.Contains(errs[0].Message, "check_closing") {
+		t.Errorf("Validate() = %v, expected one check_closing error", errs)
+	}
+}
+
+func TestValidateBalanceAssertionWithPad(t *testing.T) {
+	content := testAccounts + `
+2024-01-01 open Equity:Opening
+
+2024-01-02 pad Assets:Bank Equity:Opening
+2024-01-03 balance Assets:Bank 5000 JPY
+
+2024-02-01 * "Pens"
+  Expenses:Supplies 1000 JPY
+  Assets:Bank -1000 JPY
+
+2024-02-02 balance Assets:Bank 4000 JPY
+2024-02-03 balance Assets:Bank 3000 JPY
+`
+	errs := validate(t, content, ValidatorConfig{})
+	if len(errs) != 1 || !strings.Contains(errs[0].Message, "balance failed for Assets:Bank on 2024-02-03") {
+		t.Errorf("Validate() = %v, expected one failed assertion", errs)
+	}
+}
@@ -1,47 +1,105 @@
 // Package config provides configuration management for the accounting system.
-// It loads configuration from environment variables and .env files.
+// It loads configuration from a YAML or TOML config file with named
+// profiles, overridden by environment variables and .env files.
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
+// DefaultMappingPath is the account mapping file used when neither the
+// config file nor ACCOUNT_MAPPING_PATH sets one, relative to the working directory.
+var DefaultMappingPath = filepath.Join("config", "account-mapping.yaml")
+
 // Config represents the application configuration.
 type Config struct {
-	Freee     FreeeConfig
-	Beancount BeancountConfig
-	Debug     bool
-	NodeEnv   string
+	Freee       FreeeConfig     `yaml:"freee"`
+	Beancount   BeancountConfig `yaml:"beancount"`
+	MappingPath string          `yaml:"mapping"`
+	Debug       bool            `yaml:"debug"`
+	NodeEnv     string          `yaml:"-"`
+
+	// File is the config file the configuration was read from, if any.
+	File string `yaml:"-"`
+	// Profile is the selected profile, if any.
+	Profile string `yaml:"-"`
 }
 
 // FreeeConfig represents freee API configuration.
 type FreeeConfig struct {
-	ClientID     string
-	ClientSecret string
-	RedirectURI  string
-	AccessToken  string
-	CompanyID    int64
-	APIURL       string
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+	RedirectURI  string `yaml:"redirect_uri"`
+	AccessToken  string `yaml:"access_token"`
+	CompanyID    int64  `yaml:"company_id"`
+	APIURL       string `yaml:"api_url"`
+	// TokenStore is the file OAuth tokens are stored in. When no access
+	// token is configured, the access token is read from it.
+	TokenStore string `yaml:"token_store"`
 }
 
 // BeancountConfig represents Beancount-related configuration.
 type BeancountConfig struct {
-	Root           string
-	DBPath         string
-	AttachmentsDir string
+	Root           string `yaml:"root"`
+	DBPath         string `yaml:"db_path"`
+	AttachmentsDir string `yaml:"attachments_dir"`
+}
+
+// Options selects the files and the profile Load reads.
+type Options struct {
+	// File is the config file. When empty, FREEE_CONFIG or the first
+	// file found by SearchPaths is used; no file at all is not an error.
+	File string
+	// EnvFile is the .env file. When empty, .env in the current
+	// directory is loaded if it exists.
+	EnvFile string
+	// Profile is the profile to use. When empty, FREEE_PROFILE or the
+	// default_profile of the file is used.
+	Profile string
+	// Defaults are the settings before the file and the environment are
+	// applied. When nil, Defaults() is used.
+	Defaults *Config
+}
+
+// Defaults returns the built-in settings: the local freee emulator and a
+// ledger in ./beancount.
+func Defaults() *Config {
+	return &Config{
+		Freee: FreeeConfig{
+			APIURL: "http://localhost:8080",
+		},
+		Beancount: BeancountConfig{
+			Root: "./beancount",
+		},
+		MappingPath: DefaultMappingPath,
+	}
 }
 
 // Load loads configuration from environment variables.
 // It automatically loads .env file from the current directory if available.
 // You can optionally specify a custom .env file path.
 func Load(envPath ...string) (*Config, error) {
-	// Load .env file
-	if len(envPath) > 0 && envPath[0] != "" {
-		if err := godotenv.Load(envPath[0]); err != nil {
+	var opts Options
+	if len(envPath) > 0 {
+		opts.EnvFile = envPath[0]
+	}
+	return LoadWith(opts)
+}
+
+// LoadWith loads configuration in order of precedence: defaults,
+// the top level of the config file, the selected profile, then environment
+// variables (including those from the .env file).
+func LoadWith(opts Options) (*Config, error) {
+	// Load .env file; variables already set in the environment win
+	if opts.EnvFile != "" {
+		if err := godotenv.Load(opts.EnvFile); err != nil {
 			return nil, fmt.Errorf("failed to load .env file: %w", err)
 		}
 	} else {
@@ -49,125 +107,174 @@ func Load(envPath ...string) (*Config, error) {
 		_ = godotenv.Load()
 	}
 
-	// Parse CompanyID
-	companyID, err := parseInt64Env("FREEE_COMPANY_ID", 0)
-	if err != nil {
-		return nil, fmt.Errorf("invalid FREEE_COMPANY_ID: %w", err)
+	defaults := opts.Defaults
+	if defaults == nil {
+		defaults = Defaults()
 	}
+	config := new(Config)
+	*config = *defaults
+	config.NodeEnv = getEnvOrDefault("NODE_ENV", "development")
 
-	config := &Config{
-		Freee: FreeeConfig{
-			ClientID:     os.Getenv("FREEE_CLIENT_ID"),
-			ClientSecret: os.Getenv("FREEE_CLIENT_SECRET"),
-			RedirectURI:  os.Getenv("FREEE_REDIRECT_URI"),
-			AccessToken:  os.Getenv("FREEE_ACCESS_TOKEN"),
-			CompanyID:    companyID,
-			APIURL:       getEnvOrDefault("FREEE_API_URL", "http://localhost:8080"),
-		},
-		Beancount: BeancountConfig{
-			Root:           getEnvOrDefault("BEANCOUNT_ROOT", "./beancount"),
-			DBPath:         os.Getenv("BEANCOUNT_DB_PATH"),
-			AttachmentsDir: os.Getenv("BEANCOUNT_ATTACHMENTS_DIR"),
-		},
-		Debug:   os.Getenv("DEBUG") == "true",
-		NodeEnv: getEnvOrDefault("NODE_ENV", "development"),
+	path := opts.File
+	if path == "" {
+		path = os.Getenv("FREEE_CONFIG")
+	}
+	if path == "" {
+		path = findConfigFile()
+	}
+
+	profile := opts.Profile
+	if profile == "" {
+		profile = os.Getenv("FREEE_PROFILE")
+	}
+
+	if path != "" {
+		file, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := file.apply(config, profile); err != nil {
+			return nil, err
+		}
+	} else if profile != "" {
+		return nil, &UnknownProfileError{Profile: profile}
+	}
+
+	if err := config.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	for _, f := range []Field{FreeeTokenStore, BeancountRoot, BeancountDBPath, BeancountAttachmentsDir, MappingPath} {
+		*config.stringField(f) = expandHome(*config.stringField(f))
+	}
+
+	if config.Freee.AccessToken == "" && config.Freee.TokenStore != "" {
+		token, err := readStoredToken(config.Freee.TokenStore)
+		if err != nil {
+			return nil, err
+		}
+		config.Freee.AccessToken = token
 	}
 
 	return config, nil
 }
 
-// Validate validates the configuration.
-// It checks if all required fields are set.
-func (c *Config) Validate(required ...[]string) error {
-	var missing []string
-
-	for _, path := range required {
-		if len(path) == 0 {
+// applyEnv overrides the configuration with the environment variables of
+// the fields that are set.
+func (c *Config) applyEnv() error {
+	for _, f := range fields {
+		value := os.Getenv(f.Env())
+		if value == "" {
 			continue
 		}
-
-		var value string
-		switch path[0] {
-		case "freee":
-			if len(path) < 2 {
-				continue
-			}
-			switch path[1] {
-			case "clientId":
-				value = c.Freee.ClientID
-			case "clientSecret":
-				value = c.Freee.ClientSecret
-			case "redirectUri":
-				value = c.Freee.RedirectURI
-			case "accessToken":
-				value = c.Freee.AccessToken
-			case "companyId":
-				if c.Freee.CompanyID == 0 {
-					value = ""
-				} else {
-					value = "set"
-				}
-			case "apiUrl":
-				value = c.Freee.APIURL
-			}
-		case "beancount":
-			if len(path) < 2 {
-				continue
-			}
-			switch path[1] {
-			case "root":
-				value = c.Beancount.Root
-			case "dbPath":
-				value = c.Beancount.DBPath
-			case "attachmentsDir":
-				value = c.Beancount.AttachmentsDir
+		if f == FreeeCompanyID {
+			companyID, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return &InvalidValueError{Field: f, Value: value, Source: f.Env(), Err: err}
 			}
+			c.Freee.CompanyID = companyID
+			continue
 		}
+		*c.stringField(f) = value
+	}
 
-		if value == "" {
-			missing = append(missing, joinPath(path))
+	if value := os.Getenv("DEBUG"); value != "" {
+		c.Debug = value == "true"
+	}
+	return nil
+}
+
+// stringField returns the string setting of a field. It must not be
+// called with FreeeCompanyID.
+func (c *Config) stringField(f Field) *string {
+	switch f {
+	case FreeeClientID:
+		return &c.Freee.ClientID
+	case FreeeClientSecret:
+		return &c.Freee.ClientSecret
+	case FreeeRedirectURI:
+		return &c.Freee.RedirectURI
+	case FreeeAccessToken:
+		return &c.Freee.AccessToken
+	case FreeeAPIURL:
+		return &c.Freee.APIURL
+	case FreeeTokenStore:
+		return &c.Freee.TokenStore
+	case BeancountRoot:
+		return &c.Beancount.Root
+	case BeancountDBPath:
+		return &c.Beancount.DBPath
+	case BeancountAttachmentsDir:
+		return &c.Beancount.AttachmentsDir
+	case MappingPath:
+		return &c.MappingPath
+	}
+	panic(fmt.Sprintf("config: no string setting for %s", f))
+}
+
+// IsSet reports whether a field has a value.
+func (c *Config) IsSet(f Field) bool {
+	if f == FreeeCompanyID {
+		return c.Freee.CompanyID != 0
+	}
+	return *c.stringField(f) != ""
+}
+
+// Validate validates the configuration.
+// It checks if all required fields are set and returns a
+// *MissingFieldsError listing the ones that are not.
+func (c *Config) Validate(required ...Field) error {
+	var missing []Field
+	for _, f := range required {
+		if !c.IsSet(f) {
+			missing = append(missing, f)
 		}
 	}
 
 	if len(missing) > 0 {
-		return fmt.Errorf("missing required configuration: %v\nPlease check your .env file or environment variables", missing)
+		return &MissingFieldsError{Fields: missing, File: c.File, Profile: c.Profile}
 	}
 
 	return nil
 }
 
-// getEnvOrDefault returns the value of the environment variable or a default value if not set.
-func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// readStoredToken reads the access token from a token store written by
+// the receipt fetcher or the checker. A missing store is not an error.
+func readStoredToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read token store: %w", err)
 	}
-	return defaultValue
-}
 
-// parseInt64Env parses an int64 from an environment variable.
-// Returns defaultValue if the environment variable is not set.
-func parseInt64Env(key string, defaultValue int64) (int64, error) {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue, nil
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(data, &token); err != nil {
+		return "", &InvalidValueError{Field: FreeeTokenStore, Value: path, Source: path, Err: err}
 	}
+	return token.AccessToken, nil
+}
 
-	parsed, err := strconv.ParseInt(value, 10, 64)
+// expandHome replaces a leading ~/ in path with the home directory.
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
 	if err != nil {
-		return 0, fmt.Errorf("invalid integer value for %s: %s", key, value)
+		return path
 	}
-
-	return parsed, nil
+	return filepath.Join(home, rest)
 }
 
-// joinPath joins a path slice into a dot-separated string.
-func joinPath(path []string) string {
-	result := ""
-	for i, p := range path {
-		if i > 0 {
-			result += "."
-		}
-		result += p
+// getEnvOrDefault returns the value of the environment variable or a default value if not set.
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
 	}
-	return result
+	return defaultValue
 }
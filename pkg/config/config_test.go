@@ -0,0 +1,226 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testYAML = `default_profile: personal
+freee:
+  api_url: https://api.freee.co.jp
+beancount:
+  root: ./ledger
+profiles:
+  personal:
+    freee:
+      company_id: 111
+      access_token: personal-token
+  corp:
+    freee:
+      company_id: 222
+      token_store: %s
+    beancount:
+      root: ./corp
+    mapping: config/corp-mapping.yaml
+`
+
+const testTOML = `# shared settings
+default_profile = "personal"
+
+[freee]
+api_url = "https://api.freee.co.jp"
+
+[beancount]
+root = './ledger' # relative to the working directory
+
+[profiles.personal.freee]
+company_id = 111
+access_token = "personal-token"
+
+[profiles.corp]
+freee.company_id = 222
+freee.token_store = "%s"
+beancount.root = "./corp"
+mapping = "config/corp-mapping.yaml"
+`
+
+// isolate clears the environment variables Load reads and moves to an
+// empty working and user config directory.
+func isolate(t *testing.T) {
+	t.Helper()
+	for _, f := range fields {
+		t.Setenv(f.Env(), "")
+	}
+	t.Setenv("FREEE_CONFIG", "")
+	t.Setenv("FREEE_PROFILE", "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+}
+
+func TestLoadWithProfiles(t *testing.T) {
+	for _, format := range []struct {
+		ext     string
+		content string
+	}{
+		{".yaml", testYAML},
+		{".toml", testTOML},
+	} {
+		t.Run(format.ext, func(t *testing.T) {
+			isolate(t)
+
+			tokenStore := filepath.Join(t.TempDir(), "token.json")
+			if err := os.WriteFile(tokenStore, []byte(`{"access_token":"stored-token","refresh_token":"r"}`), 0600); err != nil {
+				t.Fatal(err)
+			}
+			path := FileName + format.ext
+			if err := os.WriteFile(path, []byte(strings.Replace(format.content, "%s", tokenStore, 1)), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			tests := []struct {
+				name        string
+				profile     string
+				env         map[string]string
+				wantCompany int64
+				wantToken   string
+				wantRoot    string
+				wantMapping string
+			}{
+				{"default profile", "", nil, 111, "personal-token", "./ledger", DefaultMappingPath},
+				{"selected profile", "corp", nil, 222, "stored-token", "./corp", "config/corp-mapping.yaml"},
+				{"profile from env", "", map[string]string{"FREEE_PROFILE": "corp"}, 222, "stored-token", "./corp", "config/corp-mapping.yaml"},
+				{"env overrides profile", "corp", map[string]string{"FREEE_COMPANY_ID": "333", "BEANCOUNT_ROOT": "/srv/ledger"}, 333, "stored-token", "/srv/ledger", "config/corp-mapping.yaml"},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					for key, value := range tt.env {
+						t.Setenv(key, value)
+					}
+
+					cfg, err := LoadWith(Options{Profile: tt.profile})
+					if err != nil {
+						t.Fatalf("LoadWith() error = %v", err)
+					}
+					if cfg.File != path {
+						t.Errorf("File = %q, want %q", cfg.File, path)
+					}
+					if cfg.Freee.APIURL != "https://api.freee.co.jp" {
+						t.Errorf("APIURL = %q, want shared value", cfg.Freee.APIURL)
+					}
+					if cfg.Freee.CompanyID != tt.wantCompany {
+						t.Errorf("CompanyID = %d, want %d", cfg.Freee.CompanyID, tt.wantCompany)
+					}
+					if cfg.Freee.AccessToken != tt.wantToken {
+						t.Errorf("AccessToken = %q, want %q", cfg.Freee.AccessToken, tt.wantToken)
+					}
+					if cfg.Beancount.Root != tt.wantRoot {
+						t.Errorf("Root = %q, want %q", cfg.Beancount.Root, tt.wantRoot)
+					}
+					if cfg.MappingPath != tt.wantMapping {
+						t.Errorf("MappingPath = %q, want %q", cfg.MappingPath, tt.wantMapping)
+					}
+				})
+			}
+
+			_, err := LoadWith(Options{Profile: "missing"})
+			var profileErr *UnknownProfileError
+			if !errors.As(err, &profileErr) || len(profileErr.Available) != 2 {
+				t.Errorf("LoadWith(missing) error = %v, want *UnknownProfileError listing 2 profiles", err)
+			}
+		})
+	}
+}
+
+func TestLoadWithoutFile(t *testing.T) {
+	isolate(t)
+	t.Setenv("FREEE_COMPANY_ID", "42")
+	t.Setenv("FREEE_TOKEN_PATH", "~/token.json")
+
+	cfg, err := LoadWith(Options{})
+	if err != nil {
+		t.Fatalf("LoadWith() error = %v", err)
+	}
+	if cfg.File != "" || cfg.Freee.CompanyID != 42 || cfg.Beancount.Root != "./beancount" || cfg.MappingPath != DefaultMappingPath {
+		t.Errorf("LoadWith() = %+v, want env and defaults", cfg)
+	}
+	if home, _ := os.UserHomeDir(); cfg.Freee.TokenStore != filepath.Join(home, "token.json") {
+		t.Errorf("TokenStore = %q, want ~ expanded", cfg.Freee.TokenStore)
+	}
+
+	if _, err := LoadWith(Options{Profile: "corp"}); !errors.As(err, new(*UnknownProfileError)) {
+		t.Errorf("LoadWith(corp) error = %v, want *UnknownProfileError", err)
+	}
+
+	t.Setenv("FREEE_COMPANY_ID", "abc")
+	_, err = LoadWith(Options{})
+	var valueErr *InvalidValueError
+	if !errors.As(err, &valueErr) || valueErr.Field != FreeeCompanyID {
+		t.Errorf("LoadWith() error = %v, want *InvalidValueError for %s", err, FreeeCompanyID)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := &Config{
+		Freee:     FreeeConfig{APIURL: "http://localhost:8080"},
+		Beancount: BeancountConfig{Root: "./beancount"},
+		File:      "freee-automation.yaml",
+		Profile:   "corp",
+	}
+
+	if err := cfg.Validate(FreeeAPIURL, BeancountRoot); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+
+	err := cfg.Validate(FreeeAPIURL, FreeeAccessToken, FreeeCompanyID)
+	var missing *MissingFieldsError
+	if !errors.As(err, &missing) {
+		t.Fatalf("Validate() error = %v, want *MissingFieldsError", err)
+	}
+	if len(missing.Fields) != 2 || !missing.Has(FreeeAccessToken) || !missing.Has(FreeeCompanyID) {
+		t.Errorf("Fields = %v, want access token and company ID", missing.Fields)
+	}
+	want := `missing required configuration: freee.access_token (FREEE_ACCESS_TOKEN), freee.company_id (FREEE_COMPANY_ID); set them in profile "corp" of freee-automation.yaml or the environment`
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestReadFileTOML(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "freee-automation.toml")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	f, err := readFile(write("default_profile = \"corp\"\n[profiles.corp]\nfreee = { company_id = 7, api_url = \"https://api.example.com\" }\n"))
+	if err != nil {
+		t.Fatalf("readFile() error = %v", err)
+	}
+	var cfg Config
+	if err := f.apply(&cfg, ""); err != nil {
+		t.Fatalf("apply() error = %v", err)
+	}
+	if cfg.Freee.CompanyID != 7 || cfg.Freee.APIURL != "https://api.example.com" {
+		t.Errorf("Freee = %+v, want the inline table of profile corp", cfg.Freee)
+	}
+
+	for _, input := range []string{
+		"[profiles",
+		"key",
+		`key = "unterminated`,
+		"a = 1\na = 2",
+		"a = 1\n[a]",
+	} {
+		if _, err := readFile(write(input)); err == nil {
+			t.Errorf("readFile(%q) error = nil, want error", input)
+		}
+	}
+}
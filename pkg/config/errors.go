@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Field identifies a setting. Its value is the key in the config file.
+type Field string
+
+// Settings that can be validated and set by environment variables.
+const (
+	FreeeClientID           Field = "freee.client_id"
+	FreeeClientSecret       Field = "freee.client_secret"
+	FreeeRedirectURI        Field = "freee.redirect_uri"
+	FreeeAccessToken        Field = "freee.access_token"
+	FreeeCompanyID          Field = "freee.company_id"
+	FreeeAPIURL             Field = "freee.api_url"
+	FreeeTokenStore         Field = "freee.token_store"
+	BeancountRoot           Field = "beancount.root"
+	BeancountDBPath         Field = "beancount.db_path"
+	BeancountAttachmentsDir Field = "beancount.attachments_dir"
+	MappingPath             Field = "mapping"
+)
+
+// fields lists every Field in the order environment variables are applied.
+var fields = []Field{
+	FreeeClientID,
+	FreeeClientSecret,
+	FreeeRedirectURI,
+	FreeeAccessToken,
+	FreeeCompanyID,
+	FreeeAPIURL,
+	FreeeTokenStore,
+	BeancountRoot,
+	BeancountDBPath,
+	BeancountAttachmentsDir,
+	MappingPath,
+}
+
+// envNames maps fields to the environment variables overriding them.
+var envNames = map[Field]string{
+	FreeeClientID:           "FREEE_CLIENT_ID",
+	FreeeClientSecret:       "FREEE_CLIENT_SECRET",
+	FreeeRedirectURI:        "FREEE_REDIRECT_URI",
+	FreeeAccessToken:        "FREEE_ACCESS_TOKEN",
+	FreeeCompanyID:          "FREEE_COMPANY_ID",
+	FreeeAPIURL:             "FREEE_API_URL",
+	FreeeTokenStore:         "FREEE_TOKEN_PATH",
+	BeancountRoot:           "BEANCOUNT_ROOT",
+	BeancountDBPath:         "BEANCOUNT_DB_PATH",
+	BeancountAttachmentsDir: "BEANCOUNT_ATTACHMENTS_DIR",
+	MappingPath:             "ACCOUNT_MAPPING_PATH",
+}
+
+// Env returns the environment variable that overrides the field.
+func (f Field) Env() string {
+	return envNames[f]
+}
+
+// MissingFieldsError is returned by Validate when required fields are not set.
+type MissingFieldsError struct {
+	Fields  []Field
+	File    string
+	Profile string
+}
+
+func (e *MissingFieldsError) Error() string {
+	names := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		names[i] = fmt.Sprintf("%s (%s)", f, f.Env())
+	}
+
+	where := "the environment or a config file"
+	switch {
+	case e.File != "" && e.Profile != "":
+		where = fmt.Sprintf("profile %q of %s or the environment", e.Profile, e.File)
+	case e.File != "":
+		where = e.File + " or the environment"
+	}
+	return fmt.Sprintf("missing required configuration: %s; set them in %s", strings.Join(names, ", "), where)
+}
+
+// Has reports whether f is one of the missing fields.
+func (e *MissingFieldsError) Has(f Field) bool {
+	for _, missing := range e.Fields {
+		if missing == f {
+			return true
+		}
+	}
+	return false
+}
+
+// InvalidValueError is returned when a setting has a value of the wrong type.
+type InvalidValueError struct {
+	Field  Field
+	Value  string
+	Source string // the environment variable or file the value came from
+	Err    error
+}
+
+func (e *InvalidValueError) Error() string {
+	return fmt.Sprintf("invalid value %q for %s in %s: %v", e.Value, e.Field, e.Source, e.Err)
+}
+
+func (e *InvalidValueError) Unwrap() error {
+	return e.Err
+}
+
+// UnknownProfileError is returned when the selected profile is not defined.
+type UnknownProfileError struct {
+	Profile   string
+	File      string
+	Available []string
+}
+
+func (e *UnknownProfileError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("profile %q selected but no config file found", e.Profile)
+	}
+	available := append([]string(nil), e.Available...)
+	sort.Strings(available)
+	return fmt.Sprintf("profile %q is not defined in %s (available: %s)", e.Profile, e.File, strings.Join(available, ", "))
+}
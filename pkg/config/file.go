@@ -0,0 +1,159 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the base name of the config file searched for in the
+// working directory.
+const FileName = "freee-automation"
+
+// fileExts are the supported config file extensions, in search order.
+var fileExts = []string{".yaml", ".yml", ".toml"}
+
+// IsConfigFile reports whether path has the extension of a config file.
+// Any other file passed as a config file is a .env file.
+func IsConfigFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range fileExts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchPaths returns the paths Load looks for a config file at when none
+// is given: freee-automation.{yaml,yml,toml} in the working directory and
+// in ./config, then config.{yaml,yml,toml} in the user config directory
+// (e.g. ~/.config/freee-automation).
+func SearchPaths() []string {
+	var paths []string
+	for _, dir := range []string{".", "config"} {
+		for _, ext := range fileExts {
+			paths = append(paths, filepath.Join(dir, FileName+ext))
+		}
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		for _, ext := range fileExts {
+			paths = append(paths, filepath.Join(dir, FileName, "config"+ext))
+		}
+	}
+	return paths
+}
+
+// findConfigFile returns the first existing file of SearchPaths, or "".
+func findConfigFile() string {
+	for _, path := range SearchPaths() {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// file is a parsed config file. The top level holds settings shared by
+// all profiles; each profile under profiles overrides them.
+//
+//	default_profile: personal
+//	beancount:
+//	  root: ./beancount
+//	profiles:
+//	  personal:
+//	    freee:
+//	      company_id: 1234567
+type file struct {
+	path           string
+	root           yaml.Node
+	defaultProfile string
+	profiles       map[string]yaml.Node
+}
+
+// readFile reads a YAML or TOML config file.
+func readFile(path string) (*file, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	f := &file{path: path}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		var values map[string]any
+		if err := toml.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		// Round-trip through YAML so both formats decode the same way
+		if data, err = yaml.Marshal(values); err != nil {
+			return nil, fmt.Errorf("failed to convert config file %s: %w", path, err)
+		}
+	case ".yaml", ".yml":
+	default:
+		return nil, fmt.Errorf("unsupported config file %s: expected .yaml, .yml or .toml", path)
+	}
+
+	if err := yaml.Unmarshal(data, &f.root); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	var doc struct {
+		DefaultProfile string               `yaml:"default_profile"`
+		Profiles       map[string]yaml.Node `yaml:"profiles"`
+	}
+	if err := f.root.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	f.defaultProfile = doc.DefaultProfile
+	f.profiles = doc.Profiles
+
+	return f, nil
+}
+
+// apply decodes the shared settings and then those of the profile, or of
+// the default profile if profile is empty, into config.
+func (f *file) apply(config *Config, profile string) error {
+	config.File = f.path
+
+	if f.root.Kind == 0 {
+		// Empty file
+		if profile != "" {
+			return &UnknownProfileError{Profile: profile, File: f.path}
+		}
+		return nil
+	}
+	if err := f.root.Decode(config); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", f.path, err)
+	}
+
+	if profile == "" {
+		profile = f.defaultProfile
+	}
+	if profile == "" {
+		return nil
+	}
+
+	node, ok := f.profiles[profile]
+	if !ok {
+		return &UnknownProfileError{Profile: profile, File: f.path, Available: f.profileNames()}
+	}
+	if err := node.Decode(config); err != nil {
+		return fmt.Errorf("invalid profile %q in config file %s: %w", profile, f.path, err)
+	}
+	config.Profile = profile
+
+	return nil
+}
+
+func (f *file) profileNames() []string {
+	names := make([]string, 0, len(f.profiles))
+	for name := range f.profiles {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,456 @@
+// Package consumptiontax summarizes the consumption tax (消費税) of synced
+// deals and manual journals by tax code and rate and computes the figures
+// of the consumption tax return (消費税申告) worksheets under the general
+// method (一般課税).
+package consumptiontax
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/db"
+)
+
+// Direction is the side of a deal a tax code applies to.
+type Direction string
+
+const (
+	DirectionSales     Direction = "sales"     // 売上
+	DirectionPurchases Direction = "purchases" // 仕入
+	DirectionNone      Direction = "none"      // 対象外
+)
+
+// Kind is the taxation of a tax code.
+type Kind string
+
+const (
+	KindTaxable    Kind = "taxable"     // 課税
+	KindZeroRated  Kind = "zero_rated"  // 免税 (exports)
+	KindExempt     Kind = "exempt"      // 非課税
+	KindNonTaxable Kind = "non_taxable" // 不課税 / 対象外
+)
+
+// Class is what a tax code means for the return.
+type Class struct {
+	Direction Direction `json:"direction"`
+	Kind      Kind      `json:"kind"`
+	Rate      int       `json:"rate,omitempty"` // percent, taxable codes only
+	Reduced   bool      `json:"reduced,omitempty"`
+	// Deduction is the percentage of the VAT of a purchase that may be
+	// deducted: 100 with a qualified invoice (適格請求書), 80 or 50 under the
+	// transitional measures for purchases without one.
+	Deduction int  `json:"deduction,omitempty"`
+	Return    bool `json:"return,omitempty"` // 返還: reduces the sales or purchases
+}
+
+// Qualified reports whether a taxable purchase has a qualified invoice.
+func (c Class) Qualified() bool {
+	return c.Deduction == 100
+}
+
+// Label names the class in Japanese, e.g. 課税仕入 10% (控80).
+func (c Class) Label() string {
+	var label string
+	switch {
+	case c.Kind == KindNonTaxable:
+		return "対象外"
+	case c.Direction == DirectionSales && c.Kind == KindTaxable:
+		label = "課税売上"
+	case c.Direction == DirectionSales && c.Kind == KindZeroRated:
+		label = "免税売上"
+	case c.Direction == DirectionSales:
+		label = "非課税売上"
+	case c.Kind == KindTaxable:
+		label = "課税仕入"
+	case c.Kind == KindZeroRated:
+		label = "免税仕入"
+	default:
+		label = "非課税仕入"
+	}
+	if c.Return {
+		label += "返還"
+	}
+	if c.Kind == KindTaxable {
+		label += " " + c.RateLabel()
+		if c.Direction == DirectionPurchases && !c.Qualified() {
+			label += fmt.Sprintf(" (控%d)", c.Deduction)
+		}
+	}
+	return label
+}
+
+// RateLabel returns the rate, e.g. 10% or 8%(軽).
+func (c Class) RateLabel() string {
+	if c.Reduced {
+		return fmt.Sprintf("%d%%(軽)", c.Rate)
+	}
+	return fmt.Sprintf("%d%%", c.Rate)
+}
+
+// nationalRates is the national part (国税分) of each rate in units of
+// 0.01%, the rest being local consumption tax (地方消費税).
+var nationalRates = map[string]int64{
+	"10%":   780,
+	"8%(軽)": 624,
+	"8%":    630,
+}
+
+var (
+	ratePattern      = regexp.MustCompile(`(\d+)[%％]`)
+	deductionPattern = regexp.MustCompile(`控(\d+)`)
+)
+
+// Classify returns the class of a tax code from its Japanese name in freee
+// (name_ja), e.g. 課税売上10%, 課対仕入8%（軽）, 課対仕入（控80）10%, 非課売上
+// or 対象外. It reports false for names it does not recognize.
+func Classify(name string) (Class, bool) {
+	switch {
+	case strings.Contains(name, "対象外"), strings.Contains(name, "不課税"):
+		return Class{Direction: DirectionNone, Kind: KindNonTaxable}, true
+	}
+
+	var c Class
+	switch {
+	case strings.Contains(name, "売"):
+		c.Direction = DirectionSales
+	case strings.Contains(name, "仕入"), strings.Contains(name, "輸入"):
+		c.Direction = DirectionPurchases
+	default:
+		return Class{}, false
+	}
+	c.Return = strings.Contains(name, "返還")
+
+	switch {
+	case strings.Contains(name, "非課"):
+		c.Kind = KindExempt
+		return c, true
+	case strings.Contains(name, "免税"), strings.Contains(name, "輸出"):
+		c.Kind = KindZeroRated
+		return c, true
+	case strings.Contains(name, "課税"), strings.Contains(name, "課対"),
+		strings.Contains(name, "共対"), strings.Contains(name, "非対"):
+		c.Kind = KindTaxable
+	default:
+		return Class{}, false
+	}
+
+	m := ratePattern.FindStringSubmatch(name)
+	if m == nil {
+		return Class{}, false
+	}
+	c.Rate, _ = strconv.Atoi(m[1])
+	c.Reduced = strings.Contains(name, "軽")
+	if _, ok := nationalRates[c.RateLabel()]; !ok {
+		return Class{}, false
+	}
+
+	c.Deduction = 100
+	if m := deductionPattern.FindStringSubmatch(name); m != nil {
+		c.Deduction, _ = strconv.Atoi(m[1])
+	}
+	return c, true
+}
+
+// Row is the total of the lines of a class.
+type Row struct {
+	Class    Class  `json:"class"`
+	Label    string `json:"label"`
+	TaxCodes []int  `json:"tax_codes"`
+	Lines    int    `json:"lines"`
+	Gross    int64  `json:"gross"` // including VAT
+	Net      int64  `json:"net"`   // excluding VAT
+	Vat      int64  `json:"vat"`
+}
+
+// Unclassified is the total of the lines of a tax code Classify did not
+// recognize.
+type Unclassified struct {
+	TaxCode int    `json:"tax_code"`
+	Name    string `json:"name,omitempty"`
+	Lines   int    `json:"lines"`
+	Gross   int64  `json:"gross"`
+	Vat     int64  `json:"vat"`
+}
+
+// RateFigures are the worksheet figures of a rate.
+type RateFigures struct {
+	Rate         string `json:"rate"`
+	TaxableSales int64  `json:"taxable_sales"` // 課税資産の譲渡等の対価の額 (税抜)
+	TaxBase      int64  `json:"tax_base"`      // 課税標準額 (千円未満切捨て)
+	Tax          int64  `json:"tax"`           // 消費税額 (国税分)
+	PurchaseVat  int64  `json:"purchase_vat"`  // 仕入税額 (deductible share of the VAT paid)
+	PurchaseTax  int64  `json:"purchase_tax"`  // 控除対象仕入税額 before the taxable sales ratio (国税分)
+	Purchases    int64  `json:"purchases"`     // 課税仕入れに係る支払対価の額 (税込)
+	Nonqualified int64  `json:"nonqualified"`  // of which without a qualified invoice (税込)
+}
+
+// Worksheet are the figures of the consumption tax return.
+type Worksheet struct {
+	Rates             []RateFigures `json:"rates"`
+	TaxableSales      int64         `json:"taxable_sales"`       // 課税売上高 (税抜, 免税売上を含む)
+	ExemptSales       int64         `json:"exempt_sales"`        // 非課税売上高
+	TaxableSalesRatio float64       `json:"taxable_sales_ratio"` // 課税売上割合
+	FullDeduction     bool          `json:"full_deduction"`      // 全額控除
+	Tax               int64         `json:"tax"`                 // 消費税額
+	DeductibleTax     int64         `json:"deductible_tax"`      // 控除対象仕入税額
+	NetTax            int64         `json:"net_tax"`             // 差引税額 (百円未満切捨て); negative is a refund
+	LocalTax          int64         `json:"local_tax"`           // 地方消費税 (譲渡割額)
+	Payable           int64         `json:"payable"`             // 納付税額
+}
+
+// JournalLine is a detail line of a manual journal (振替伝票) with a tax
+// code. Unlike deal lines, its sign depends on the side it is booked on.
+type JournalLine struct {
+	JournalID int64  `json:"journal_id"`
+	IssueDate string `json:"issue_date"`
+	EntrySide string `json:"entry_side"` // debit or credit
+	TaxCode   int    `json:"tax_code"`
+	Amount    int64  `json:"amount"` // including VAT
+	Vat       int64  `json:"vat"`
+}
+
+// Report is the consumption tax summary of a period.
+type Report struct {
+	From         string         `json:"from"`
+	To           string         `json:"to"`
+	Deals        int            `json:"deals"`
+	Journals     int            `json:"journals"` // manual journals with lines of a tax code
+	Rows         []Row          `json:"rows"`
+	Unclassified []Unclassified `json:"unclassified"`
+	Worksheet    Worksheet      `json:"worksheet"`
+}
+
+// fullDeductionRatio and fullDeductionSales are the limits of full
+// deduction of the purchase tax: a taxable sales ratio of 95% or more and
+// taxable sales of at most 500 million yen.
+const (
+	fullDeductionRatio = 0.95
+	fullDeductionSales = 500_000_000
+)
+
+// Summarize totals the lines of deals and manual journals by class and
+// computes the worksheet. names are the Japanese names of the tax codes
+// (name_ja). Amounts include VAT (税込経理). A journal line booked on the
+// side opposite to its class, such as a debit of a sales code, reduces
+// the total.
+func Summarize(from, to string, lines []db.DealLine, journals []JournalLine, names map[int]string) Report {
+	report := Report{From: from, To: to, Rows: []Row{}, Unclassified: []Unclassified{}}
+
+	rows := make(map[Class]*Row)
+	unclassified := make(map[int]*Unclassified)
+	add := func(taxCode int, side string, amount, vat int64) {
+		class, ok := Classify(names[taxCode])
+		if !ok {
+			u := unclassified[taxCode]
+			if u == nil {
+				u = &Unclassified{TaxCode: taxCode, Name: names[taxCode]}
+				unclassified[taxCode] = u
+			}
+			u.Lines++
+			u.Gross += amount
+			u.Vat += vat
+			return
+		}
+
+		if (class.Direction == DirectionSales && side == "debit") || (class.Direction == DirectionPurchases && side == "credit") {
+			amount, vat = -amount, -vat
+		}
+		row := rows[class]
+		if row == nil {
+			row = &Row{Class: class, Label: class.Label()}
+			rows[class] = row
+		}
+		if !containsInt(row.TaxCodes, taxCode) {
+			row.TaxCodes = append(row.TaxCodes, taxCode)
+		}
+		row.Lines++
+		row.Gross += amount
+		row.Net += amount - vat
+		row.Vat += vat
+	}
+
+	deals := make(map[int64]bool)
+	for _, line := range lines {
+		deals[line.DealID] = true
+		add(line.TaxCode, "", line.Amount, line.Vat)
+	}
+	report.Deals = len(deals)
+
+	journalIDs := make(map[int64]bool)
+	for _, line := range journals {
+		journalIDs[line.JournalID] = true
+		add(line.TaxCode, line.EntrySide, line.Amount, line.Vat)
+	}
+	report.Journals = len(journalIDs)
+
+	for _, row := range rows {
+		sort.Ints(row.TaxCodes)
+		report.Rows = append(report.Rows, *row)
+	}
+	sort.Slice(report.Rows, func(i, j int) bool { return rowOrder(report.Rows[i].Class) < rowOrder(report.Rows[j].Class) })
+	for _, u := range unclassified {
+		report.Unclassified = append(report.Unclassified, *u)
+	}
+	sort.Slice(report.Unclassified, func(i, j int) bool { return report.Unclassified[i].TaxCode < report.Unclassified[j].TaxCode })
+
+	report.Worksheet = worksheet(report.Rows)
+	return report
+}
+
+// worksheet computes the return figures from the rows. Sales tax is
+// computed from the totals (割戻し計算) and purchase tax from the VAT of
+// the deals (積上げ計算). Below the full deduction limits the purchase tax
+// is prorated by the taxable sales ratio (一括比例配分方式).
+func worksheet(rows []Row) Worksheet {
+	w := Worksheet{Rates: []RateFigures{}}
+	figures := make(map[string]*RateFigures)
+	rate := func(label string) *RateFigures {
+		if figures[label] == nil {
+			figures[label] = &RateFigures{Rate: label}
+		}
+		return figures[label]
+	}
+
+	for _, row := range rows {
+		c := row.Class
+		sign := int64(1)
+		if c.Return {
+			sign = -1
+		}
+
+		switch {
+		case c.Direction == DirectionSales && c.Kind == KindTaxable:
+			rate(c.RateLabel()).TaxableSales += sign * row.Net
+			w.TaxableSales += sign * row.Net
+		case c.Direction == DirectionSales && c.Kind == KindZeroRated:
+			w.TaxableSales += sign * row.Net
+		case c.Direction == DirectionSales && c.Kind == KindExempt:
+			w.ExemptSales += sign * row.Net
+		case c.Direction == DirectionPurchases && c.Kind == KindTaxable:
+			f := rate(c.RateLabel())
+			f.Purchases += sign * row.Gross
+			f.PurchaseVat += sign * row.Vat * int64(c.Deduction) / 100
+			if !c.Qualified() {
+				f.Nonqualified += sign * row.Gross
+			}
+		}
+	}
+
+	w.TaxableSalesRatio = 1
+	if total := w.TaxableSales + w.ExemptSales; total > 0 {
+		w.TaxableSalesRatio = float64(w.TaxableSales) / float64(total)
+	}
+	w.FullDeduction = w.TaxableSalesRatio >= fullDeductionRatio && w.TaxableSales <= fullDeductionSales
+
+	var purchaseTax int64
+	for _, label := range []string{"10%", "8%(軽)", "8%"} {
+		f := figures[label]
+		if f == nil {
+			continue
+		}
+		national := nationalRates[label]
+		percent, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimSuffix(label, "(軽)"), "%"))
+		f.TaxBase = f.TaxableSales / 1000 * 1000
+		f.Tax = f.TaxBase * national / 10000
+		f.PurchaseTax = f.PurchaseVat * national / int64(percent*100)
+		w.Tax += f.Tax
+		purchaseTax += f.PurchaseTax
+		w.Rates = append(w.Rates, *f)
+	}
+
+	w.DeductibleTax = purchaseTax
+	if !w.FullDeduction {
+		w.DeductibleTax = int64(float64(purchaseTax) * w.TaxableSalesRatio)
+	}
+
+	// A refund (還付) is not rounded
+	w.NetTax = w.Tax - w.DeductibleTax
+	if w.NetTax > 0 {
+		w.NetTax = w.NetTax / 100 * 100
+		w.LocalTax = w.NetTax * 22 / 78 / 100 * 100
+	} else {
+		w.LocalTax = w.NetTax * 22 / 78
+	}
+	w.Payable = w.NetTax + w.LocalTax
+	return w
+}
+
+// rowOrder orders rows as the return does: sales before purchases, taxable
+// before zero-rated before exempt, higher rates first.
+func rowOrder(c Class) int {
+	order := map[Direction]int{DirectionSales: 0, DirectionPurchases: 1, DirectionNone: 2}[c.Direction] * 100000
+	order += map[Kind]int{KindTaxable: 0, KindZeroRated: 1, KindExempt: 2, KindNonTaxable: 3}[c.Kind] * 10000
+	order += (100 - c.Rate) * 100
+	if c.Reduced {
+		order -= 50
+	}
+	order += 100 - c.Deduction
+	if c.Return {
+		order++
+	}
+	return order
+}
+
+// Markdown formats the report as a Markdown document.
+func (r Report) Markdown() string {
+	var sb strings.Builder
+	w := r.Worksheet
+
+	fmt.Fprintf(&sb, "# 消費税集計 %s .. %s\n\n", r.From, r.To)
+	fmt.Fprintf(&sb, "- Deals: %d\n", r.Deals)
+	fmt.Fprintf(&sb, "- Manual journals: %d\n", r.Journals)
+	if len(r.Unclassified) > 0 {
+		fmt.Fprintf(&sb, "- Unclassified tax codes: %d (not included below)\n", len(r.Unclassified))
+	}
+
+	sb.WriteString("\n## 税区分別集計\n\n| 区分 | 税区分コード | 件数 | 税込 | 税抜 | 消費税 |\n|---|---|---:|---:|---:|---:|\n")
+	for _, row := range r.Rows {
+		codes := make([]string, len(row.TaxCodes))
+		for i, code := range row.TaxCodes {
+			codes[i] = strconv.Itoa(code)
+		}
+		fmt.Fprintf(&sb, "| %s | %s | %d | %d | %d | %d |\n",
+			row.Label, strings.Join(codes, ", "), row.Lines, row.Gross, row.Net, row.Vat)
+	}
+
+	if len(r.Unclassified) > 0 {
+		sb.WriteString("\n## 未分類の税区分\n\nMap these tax codes or check their names in freee.\n\n| 税区分コード | 名称 | 件数 | 税込 | 消費税 |\n|---|---|---:|---:|---:|\n")
+		for _, u := range r.Unclassified {
+			fmt.Fprintf(&sb, "| %d | %s | %d | %d | %d |\n", u.TaxCode, u.Name, u.Lines, u.Gross, u.Vat)
+		}
+	}
+
+	sb.WriteString("\n## 付表 (税率別)\n\n| 税率 | 課税売上 (税抜) | 課税標準額 | 消費税額 | 課税仕入 (税込) | うち適格請求書なし | 控除対象仕入税額 |\n|---|---:|---:|---:|---:|---:|---:|\n")
+	for _, f := range w.Rates {
+		fmt.Fprintf(&sb, "| %s | %d | %d | %d | %d | %d | %d |\n",
+			f.Rate, f.TaxableSales, f.TaxBase, f.Tax, f.Purchases, f.Nonqualified, f.PurchaseTax)
+	}
+
+	deduction := "全額控除"
+	if !w.FullDeduction {
+		deduction = "一括比例配分方式"
+	}
+	sb.WriteString("\n## 申告書\n\n| 項目 | 金額 |\n|---|---:|\n")
+	fmt.Fprintf(&sb, "| 課税売上高 (免税売上を含む) | %d |\n", w.TaxableSales)
+	fmt.Fprintf(&sb, "| 非課税売上高 | %d |\n", w.ExemptSales)
+	fmt.Fprintf(&sb, "| 課税売上割合 | %.2f%% |\n", w.TaxableSalesRatio*100)
+	fmt.Fprintf(&sb, "| 消費税額 | %d |\n", w.Tax)
+	fmt.Fprintf(&sb, "| 控除対象仕入税額 (%s) | %d |\n", deduction, w.DeductibleTax)
+	fmt.Fprintf(&sb, "| 差引税額 | %d |\n", w.NetTax)
+	fmt.Fprintf(&sb, "| 地方消費税 (譲渡割額) | %d |\n", w.LocalTax)
+	fmt.Fprintf(&sb, "| 納付税額 | %d |\n", w.Payable)
+
+	return sb.String()
+}
+
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
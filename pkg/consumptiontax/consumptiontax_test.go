@@ -0,0 +1,137 @@
+package consumptiontax
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/db"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name  string
+		want  Class
+		label string
+	}{
+		{"課税売上10%", Class{Direction: DirectionSales, Kind: KindTaxable, Rate: 10, Deduction: 100}, "課税売上 10%"},
+		{"課税売上8%（軽）", Class{Direction: DirectionSales, Kind: KindTaxable, Rate: 8, Reduced: true, Deduction: 100}, "課税売上 8%(軽)"},
+		{"課対仕入10%", Class{Direction: DirectionPurchases, Kind: KindTaxable, Rate: 10, Deduction: 100}, "課税仕入 10%"},
+		{"課対仕入（控80）10%", Class{Direction: DirectionPurchases, Kind: KindTaxable, Rate: 10, Deduction: 80}, "課税仕入 10% (控80)"},
+		{"課対仕入8%（軽）（控50）", Class{Direction: DirectionPurchases, Kind: KindTaxable, Rate: 8, Reduced: true, Deduction: 50}, "課税仕入 8%(軽) (控50)"},
+		{"課税売上返還10%", Class{Direction: DirectionSales, Kind: KindTaxable, Rate: 10, Deduction: 100, Return: true}, "課税売上返還 10%"},
+		{"非課売上", Class{Direction: DirectionSales, Kind: KindExempt}, "非課税売上"},
+		{"輸出売上", Class{Direction: DirectionSales, Kind: KindZeroRated}, "免税売上"},
+		{"非課仕入", Class{Direction: DirectionPurchases, Kind: KindExempt}, "非課税仕入"},
+		{"対象外", Class{Direction: DirectionNone, Kind: KindNonTaxable}, "対象外"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Classify(tt.name)
+			if !ok || got != tt.want {
+				t.Fatalf("Classify(%q) = %+v, %v, expected %+v", tt.name, got, ok, tt.want)
+			}
+			if got.Label() != tt.label {
+				t.Errorf("Label() = %q, expected %q", got.Label(), tt.label)
+			}
+		})
+	}
+
+	for _, name := range []string{"", "その他", "課税売上5%", "課対仕入"} {
+		if c, ok := Classify(name); ok {
+			t.Errorf("Classify(%q) = %+v, expected unrecognized", name, c)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	names := map[int]string{
+		21:  "課税売上10%",
+		129: "課税売上8%（軽）",
+		136: "課対仕入10%",
+		189: "課対仕入（控80）10%",
+		0:   "対象外",
+	}
+	lines := []db.DealLine{
+		{DealID: 1, TaxCode: 21, Amount: 1_100_000, Vat: 100_000},
+		{DealID: 2, TaxCode: 129, Amount: 108_000, Vat: 8_000},
+		{DealID: 3, TaxCode: 136, Amount: 220_000, Vat: 20_000},
+		{DealID: 3, TaxCode: 136, Amount: 110_000, Vat: 10_000},
+		{DealID: 4, TaxCode: 189, Amount: 55_000, Vat: 5_000},
+		{DealID: 5, TaxCode: 0, Amount: 30_000},
+		{DealID: 6, TaxCode: 999, Amount: 1_000, Vat: 90},
+	}
+
+	report := Summarize("2024-01-01", "2024-12-31", lines, nil, names)
+
+	if report.Deals != 6 || len(report.Rows) != 5 {
+		t.Fatalf("Summarize() = %d deals, %+v", report.Deals, report.Rows)
+	}
+	if r := report.Rows[2]; r.Label != "課税仕入 10%" || r.Lines != 2 || r.Net != 300_000 || r.Vat != 30_000 {
+		t.Errorf("Summarize() row = %+v, expected the qualified purchases", r)
+	}
+	if len(report.Unclassified) != 1 || report.Unclassified[0].TaxCode != 999 {
+		t.Errorf("Summarize() unclassified = %+v", report.Unclassified)
+	}
+
+	w := report.Worksheet
+	if len(w.Rates) != 2 {
+		t.Fatalf("Worksheet.Rates = %+v", w.Rates)
+	}
+	ten := w.Rates[0]
+	if ten.TaxBase != 1_000_000 || ten.Tax != 78_000 || ten.PurchaseVat != 34_000 || ten.PurchaseTax != 26_520 || ten.Nonqualified != 55_000 {
+		t.Errorf("Worksheet 10%% = %+v", ten)
+	}
+	if reduced := w.Rates[1]; reduced.Rate != "8%(軽)" || reduced.Tax != 6_240 {
+		t.Errorf("Worksheet 8%%(軽) = %+v", reduced)
+	}
+
+	want := Worksheet{
+		TaxableSales: 1_100_000, TaxableSalesRatio: 1, FullDeduction: true,
+		Tax: 84_240, DeductibleTax: 26_520, NetTax: 57_700, LocalTax: 16_200, Payable: 73_900,
+	}
+	w.Rates = nil
+	if !reflect.DeepEqual(w, want) {
+		t.Errorf("Worksheet = %+v, expected %+v", w, want)
+	}
+}
+
+func TestSummarizeProratesPurchaseTax(t *testing.T) {
+	names := map[int]string{21: "課税売上10%", 2: "非課売上", 136: "課対仕入10%"}
+	lines := []db.DealLine{
+		{DealID: 1, TaxCode: 21, Amount: 880_000, Vat: 80_000},
+		{DealID: 2, TaxCode: 2, Amount: 200_000},
+		{DealID: 3, TaxCode: 136, Amount: 110_000, Vat: 10_000},
+	}
+
+	w := Summarize("2024-01-01", "2024-12-31", lines, nil, names).Worksheet
+	if w.FullDeduction || w.TaxableSalesRatio != 0.8 {
+		t.Fatalf("Worksheet = %+v, expected a taxable sales ratio of 80%%", w)
+	}
+	// 10,000 × 78% = 7,800, prorated by 80%
+	if w.DeductibleTax != 6_240 {
+		t.Errorf("Worksheet.DeductibleTax = %d, expected 6240", w.DeductibleTax)
+	}
+}
+
+func TestSummarizeManualJournals(t *testing.T) {
+	names := map[int]string{21: "課税売上10%", 136: "課対仕入10%"}
+	lines := []db.DealLine{{DealID: 1, TaxCode: 21, Amount: 1_100_000, Vat: 100_000}}
+	journals := []JournalLine{
+		// A sales return and a purchase booked by 振替伝票
+		{JournalID: 7, EntrySide: "debit", TaxCode: 21, Amount: 110_000, Vat: 10_000},
+		{JournalID: 8, EntrySide: "debit", TaxCode: 136, Amount: 55_000, Vat: 5_000},
+		{JournalID: 8, EntrySide: "credit", TaxCode: 136, Amount: 11_000, Vat: 1_000},
+	}
+
+	report := Summarize("2024-01-01", "2024-12-31", lines, journals, names)
+	if report.Deals != 1 || report.Journals != 2 || len(report.Rows) != 2 {
+		t.Fatalf("Summarize() = %d deals, %d journals, %+v", report.Deals, report.Journals, report.Rows)
+	}
+	if sales := report.Rows[0]; sales.Lines != 2 || sales.Net != 900_000 || sales.Vat != 90_000 {
+		t.Errorf("sales row = %+v, expected the return deducted", sales)
+	}
+	if purchases := report.Rows[1]; purchases.Lines != 2 || purchases.Gross != 44_000 || purchases.Vat != 4_000 {
+		t.Errorf("purchases row = %+v, expected the credit deducted", purchases)
+	}
+}
@@ -0,0 +1,195 @@
+package converter
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+)
+
+// DrawingsAccount is the account the private share of allocated expenses
+// is booked to (事業主貸).
+const DrawingsAccount = "Equity:Drawings"
+
+// AllocationConfig configures home-business allocation (家事按分): the
+// private share of expenses used for both business and private purposes,
+// such as rent, phone or electricity, is moved to the drawings account.
+type AllocationConfig struct {
+	// Account receives the private share; DrawingsAccount when empty.
+	Account string `yaml:"account"`
+	// Rules are tried in order; the first matching rule applies.
+	Rules []AllocationRule `yaml:"rules"`
+}
+
+// AllocationRule allocates the expense lines it matches. Empty criteria
+// match any line; a rule needs at least one of AccountItem, Partner and
+// Description.
+type AllocationRule struct {
+	Name string `yaml:"name"`
+	// AccountItem is the freee account item of the line, e.g. 地代家賃.
+	AccountItem string `yaml:"account_item"`
+	// Partner is the freee partner code of the deal.
+	Partner string `yaml:"partner"`
+	// Description matches lines whose description contains it.
+	Description string `yaml:"description"`
+	// BusinessPercent is the business share in percent (0-100).
+	BusinessPercent float64 `yaml:"business_percent"`
+	// From and To bound the issue dates the rule is effective for
+	// (YYYY-MM-DD, inclusive). Empty bounds are open.
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+	// YearEnd leaves the lines untouched during sync; the private share
+	// is booked by the year-end allocation journal instead.
+	YearEnd bool `yaml:"year_end"`
+}
+
+// Validate checks that the rule has criteria and a valid percentage.
+func (r AllocationRule) Validate() error {
+	if r.AccountItem == "" && r.Partner == "" && r.Description == "" {
+		return fmt.Errorf("allocation rule %q has no account_item, partner or description", r.Name)
+	}
+	if r.BusinessPercent < 0 || r.BusinessPercent > 100 {
+		return fmt.Errorf("allocation rule %q: business_percent %g is not between 0 and 100", r.Name, r.BusinessPercent)
+	}
+	if r.From != "" && r.To != "" && r.From > r.To {
+		return fmt.Errorf("allocation rule %q: from %s is after to %s", r.Name, r.From, r.To)
+	}
+	return nil
+}
+
+// matches reports whether the rule applies to a line of an expense deal.
+func (r AllocationRule) matches(deal freee.Deal, detail freee.Detail) bool {
+	if deal.Type != "expense" {
+		return false
+	}
+	if (r.From != "" && deal.IssueDate < r.From) || (r.To != "" && deal.IssueDate > r.To) {
+		return false
+	}
+	if r.AccountItem != "" && r.AccountItem != detail.AccountItemName {
+		return false
+	}
+	if r.Partner != "" && r.Partner != ptrToString(deal.PartnerCode) {
+		return false
+	}
+	if r.Description != "" && !strings.Contains(ptrToString(detail.Description), r.Description) {
+		return false
+	}
+	return true
+}
+
+// private returns the private share of amount, rounded to the yen.
+func (r AllocationRule) private(amount int64) int64 {
+	return int64(math.Round(float64(amount) * (100 - r.BusinessPercent) / 100))
+}
+
+// label describes the rule in posting comments.
+func (r AllocationRule) label() string {
+	return fmt.Sprintf("家事按分 %s (事業 %g%%)", r.Name, r.BusinessPercent)
+}
+
+// allocation returns the rule allocating a line of a deal, or nil.
+func (c *Converter) allocation(deal freee.Deal, detail freee.Detail) *AllocationRule {
+	rules := c.mapper.config.Allocation.Rules
+	for i := range rules {
+		if rules[i].matches(deal, detail) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// allocationAccount returns the account private shares are booked to.
+func (c *Converter) allocationAccount() string {
+	if account := c.mapper.config.Allocation.Account; account != "" {
+		return account
+	}
+	return DrawingsAccount
+}
+
+// AllocationTotal is the private share of the lines a year-end rule
+// matched on one account.
+type AllocationTotal struct {
+	Rule    string `json:"rule"`
+	Account string `json:"account"`
+	Total   int64  `json:"total"`
+	Private int64  `json:"private"`
+}
+
+// ConvertYearEndAllocation builds the year-end allocation journal on date
+// for the expense deals of a year: the private share of every line matched
+// by a year-end rule is moved from its accounts to the drawings account.
+// Lines matched by a per-transaction rule were allocated when synced and
+// are skipped.
+func (c *Converter) ConvertYearEndAllocation(deals []freee.Deal, date string) (BeancountTransaction, []AllocationTotal) {
+	type key struct{ rule, account string }
+	totals := make(map[key]*AllocationTotal)
+	var order []key
+
+	for _, deal := range deals {
+		for _, detail := range deal.Details {
+			rule := c.allocation(deal, detail)
+			if rule == nil || !rule.YearEnd || c.isWithholding(deal, detail) {
+				continue
+			}
+
+			// The line amount includes VAT; the VAT is allocated from the
+			// tax account when it is booked there
+			account := c.mapper.ResolveAccount(detail.AccountItemName)
+			lines := map[string]int64{account: detail.Amount}
+			if taxAccount := c.mapper.GetTaxAccount("tax_10"); taxAccount != nil && detail.Vat > 0 {
+				lines[account] -= detail.Vat
+				lines[*taxAccount] += detail.Vat
+			}
+
+			for account, amount := range lines {
+				k := key{rule.label(), account}
+				if totals[k] == nil {
+					totals[k] = &AllocationTotal{Rule: k.rule, Account: k.account}
+					order = append(order, k)
+				}
+				totals[k].Total += amount
+				totals[k].Private += rule.private(amount)
+			}
+		}
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		if order[i].rule != order[j].rule {
+			return order[i].rule < order[j].rule
+		}
+		return order[i].account < order[j].account
+	})
+
+	txn := BeancountTransaction{
+		Date:      date,
+		Narration: fmt.Sprintf("家事按分 %s", date[:4]),
+		Metadata:  map[string]string{"allocation": "year_end"},
+	}
+	var result []AllocationTotal
+	var private int64
+	for _, k := range order {
+		total := totals[k]
+		if total.Private == 0 {
+			continue
+		}
+		result = append(result, *total)
+		txn.Postings = append(txn.Postings, BeancountPosting{
+			Account:  total.Account,
+			Amount:   -float64(total.Private),
+			Currency: c.currency,
+			Comment:  total.Rule,
+		})
+		private += total.Private
+	}
+	if private != 0 {
+		txn.Postings = append(txn.Postings, BeancountPosting{
+			Account:  c.allocationAccount(),
+			Amount:   float64(private),
+			Currency: c.currency,
+		})
+	}
+
+	return txn, result
+}
@@ -0,0 +1,139 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+)
+
+func TestConvertDealAllocation(t *testing.T) {
+	taxAccount := "Assets:Current:ConsumptionTaxReceivable"
+	mapper := &Mapper{
+		config: AccountMappingConfig{Allocation: AllocationConfig{Rules: []AllocationRule{
+			{Name: "家賃", AccountItem: "地代家賃", BusinessPercent: 30, From: "2025-01-01"},
+			{Name: "携帯", Partner: "docomo", BusinessPercent: 50},
+			{Name: "電気", Description: "電気料金", BusinessPercent: 20, YearEnd: true},
+		}}},
+		freeeToBean: map[string]string{
+			"地代家賃":  "Expenses:Rent",
+			"通信費":   "Expenses:Communications",
+			"水道光熱費": "Expenses:Utilities",
+		},
+		taxCodeMap: map[string]TaxCodeMapping{"tax_10": {Code: "tax_10", BeancountAccount: &taxAccount}},
+	}
+	cvtr := NewConverter(mapper, "JPY")
+	docomo := "docomo"
+	electricity := "電気料金 3月分"
+
+	tests := []struct {
+		name string
+		deal freee.Deal
+		want map[string]float64
+	}{
+		{
+			name: "rent split by account item",
+			deal: freee.Deal{Type: "expense", IssueDate: "2025-03-01", Amount: 100000, Details: []freee.Detail{
+				{AccountItemName: "地代家賃", Amount: 100000},
+			}},
+			want: map[string]float64{
+				"Expenses:Rent":                30000,
+				DrawingsAccount:                70000,
+				"Assets:Current:Bank:Ordinary": -100000,
+			},
+		},
+		{
+			name: "rent before the rule is effective",
+			deal: freee.Deal{Type: "expense", IssueDate: "2024-12-01", Amount: 100000, Details: []freee.Detail{
+				{AccountItemName: "地代家賃", Amount: 100000},
+			}},
+			want: map[string]float64{
+				"Expenses:Rent":                100000,
+				"Assets:Current:Bank:Ordinary": -100000,
+			},
+		},
+		{
+			name: "phone split by partner including VAT",
+			deal: freee.Deal{Type: "expense", IssueDate: "2025-03-10", Amount: 11000, PartnerCode: &docomo, Details: []freee.Detail{
+				{AccountItemName: "通信費", Amount: 11000, Vat: 1000},
+			}},
+			want: map[string]float64{
+				"Expenses:Communications":      5000,
+				taxAccount:                     500,
+				DrawingsAccount:                5500,
+				"Assets:Current:Bank:Ordinary": -11000,
+			},
+		},
+		{
+			name: "year-end rule leaves the line untouched",
+			deal: freee.Deal{Type: "expense", IssueDate: "2025-03-20", Amount: 8000, Details: []freee.Detail{
+				{AccountItemName: "水道光熱費", Amount: 8000, Description: &electricity},
+			}},
+			want: map[string]float64{
+				"Expenses:Utilities":           8000,
+				"Assets:Current:Bank:Ordinary": -8000,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn := cvtr.ConvertDeal(tt.deal)
+
+			got := make(map[string]float64)
+			for _, p := range txn.Postings {
+				got[p.Account] += p.Amount
+			}
+			for account, amount := range tt.want {
+				if got[account] != amount {
+					t.Errorf("%s = %.0f, want %.0f", account, got[account], amount)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("got postings to %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("year-end journal", func(t *testing.T) {
+		deals := []freee.Deal{
+			{Type: "expense", IssueDate: "2025-03-20", Details: []freee.Detail{{AccountItemName: "水道光熱費", Amount: 8000, Description: &electricity}}},
+			{Type: "expense", IssueDate: "2025-04-20", Details: []freee.Detail{{AccountItemName: "水道光熱費", Amount: 7000, Description: &electricity}}},
+			{Type: "expense", IssueDate: "2025-03-01", Details: []freee.Detail{{AccountItemName: "地代家賃", Amount: 100000}}},
+		}
+
+		txn, totals := cvtr.ConvertYearEndAllocation(deals, "2025-12-31")
+		if len(totals) != 1 || totals[0].Total != 15000 || totals[0].Private != 12000 {
+			t.Fatalf("totals = %+v, want 12000 private of 15000", totals)
+		}
+		if len(txn.Postings) != 2 {
+			t.Fatalf("got %d postings, want 2", len(txn.Postings))
+		}
+		if p := txn.Postings[0]; p.Account != "Expenses:Utilities" || p.Amount != -12000 {
+			t.Errorf("expense posting = %s %.0f, want Expenses:Utilities -12000", p.Account, p.Amount)
+		}
+		if p := txn.Postings[1]; p.Account != DrawingsAccount || p.Amount != 12000 {
+			t.Errorf("drawings posting = %s %.0f, want %s 12000", p.Account, p.Amount, DrawingsAccount)
+		}
+	})
+}
+
+func TestAllocationRuleValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    AllocationRule
+		wantErr bool
+	}{
+		{"valid", AllocationRule{Name: "家賃", AccountItem: "地代家賃", BusinessPercent: 30}, false},
+		{"no criteria", AllocationRule{Name: "家賃", BusinessPercent: 30}, true},
+		{"percent over 100", AllocationRule{Name: "家賃", AccountItem: "地代家賃", BusinessPercent: 120}, true},
+		{"from after to", AllocationRule{Name: "家賃", AccountItem: "地代家賃", From: "2025-12-01", To: "2025-01-01"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package converter
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
+)
+
+// Equity accounts of closing entries, used when the freee account items
+// are not mapped.
+const (
+	RetainedEarningsAccount = "Equity:RetainedEarnings" // 繰越利益剰余金
+	CapitalAccount          = "Equity:Capital"          // 元入金
+	ContributionsAccount    = "Equity:Contributions"    // 事業主借
+)
+
+// ClosingAccounts returns the equity account the result of a year is
+// closed into and the other accounts closed into it. A corporation closes
+// into 繰越利益剰余金; a sole proprietor closes into 元入金, together with
+// 事業主貸 and 事業主借, which start every year at zero.
+func (m *Mapper) ClosingAccounts(soleProprietor bool) (string, []string) {
+	if !soleProprietor {
+		return m.GetBeancountAccountWithFallback("繰越利益剰余金", RetainedEarningsAccount), nil
+	}
+	return m.GetBeancountAccountWithFallback("元入金", CapitalAccount), []string{
+		m.GetBeancountAccountWithFallback("事業主貸", DrawingsAccount),
+		m.GetBeancountAccountWithFallback("事業主借", ContributionsAccount),
+	}
+}
+
+// YearClosing is the result of closing a year.
+type YearClosing struct {
+	// Entry moves the income and expense balances of the year, and the
+	// balances of the other closed accounts, to the equity account.
+	Entry BeancountTransaction
+	// Profit is the net income of the year (positive for a profit).
+	Profit float64
+	// Balances are the balance sheet balances after closing, debit positive.
+	Balances map[string]float64
+}
+
+// ConvertClosing builds the closing entries of the year from..to from
+// ledger transactions. Income and expense accounts are closed with their
+// amounts over the year, the accounts in others with their balances at the
+// end of the year. Transactions after to are ignored.
+func (c *Converter) ConvertClosing(txns []beancount.Transaction, from, to, equity string, others []string) YearClosing {
+	closed := make(map[string]bool, len(others))
+	for _, account := range others {
+		closed[account] = true
+	}
+
+	balances := make(map[string]float64)
+	results := make(map[string]float64)
+	for _, txn := range txns {
+		if txn.Date > to {
+			continue
+		}
+		for account, amount := range txn.Amounts(c.currency) {
+			if isBalanceSheet(account) {
+				balances[account] += amount
+			} else if txn.Date >= from {
+				results[account] += amount
+			}
+		}
+	}
+
+	amounts := make(map[string]float64)
+	var profit float64
+	for account, amount := range results {
+		amounts[account] = amount
+		profit -= amount
+	}
+	for account := range closed {
+		amounts[account] = balances[account]
+	}
+
+	accounts := make([]string, 0, len(amounts))
+	for account, amount := range amounts {
+		if math.Abs(amount) >= 0.5 {
+			accounts = append(accounts, account)
+		}
+	}
+	sort.Strings(accounts)
+
+	entry := BeancountTransaction{
+		Date:      to,
+		Narration: fmt.Sprintf("決算振替 %s", to[:4]),
+		Metadata:  map[string]string{beancount.ClosingMetadata: to[:4]},
+	}
+	var total float64
+	for _, account := range accounts {
+		entry.Postings = append(entry.Postings, BeancountPosting{
+			Account:  account,
+			Amount:   -amounts[account],
+			Currency: c.currency,
+		})
+		total += amounts[account]
+		balances[account] -= amounts[account]
+	}
+	if len(entry.Postings) > 0 {
+		entry.Postings = append(entry.Postings, BeancountPosting{
+			Account:  equity,
+			Amount:   total,
+			Currency: c.currency,
+			Comment:  closingComment(profit),
+		})
+		balances[equity] += total
+	}
+
+	for account := range balances {
+		if !isBalanceSheet(account) || math.Abs(balances[account]) < 0.5 {
+			delete(balances, account)
+		}
+	}
+
+	return YearClosing{Entry: entry, Profit: profit, Balances: balances}
+}
+
+// closingComment describes the result a closing entry books to equity.
+func closingComment(profit float64) string {
+	if profit < 0 {
+		return fmt.Sprintf("当期純損失 %.0f", -profit)
+	}
+	return fmt.Sprintf("当期純利益 %.0f", profit)
+}
+
+// isBalanceSheet reports whether an account belongs to the balance sheet.
+func isBalanceSheet(account string) bool {
+	root, _, _ := strings.Cut(account, ":")
+	return root == "Assets" || root == "Liabilities" || root == "Equity"
+}
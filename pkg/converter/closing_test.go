@@ -0,0 +1,106 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
+)
+
+const closingLedger = `
+2024-06-30 * "Sales"
+  Assets:Bank          300000 JPY
+  Income:Sales
+
+2024-12-31 * "Closing 2024"
+  closing_year: "2024"
+  Income:Sales         300000 JPY
+  Equity:Capital
+
+2025-03-10 * "Sales"
+  Assets:Bank          500000 JPY
+  Income:Sales
+
+2025-04-25 * "Rent"
+  Expenses:Rent        100000 JPY
+  Assets:Bank
+
+2025-05-01 * "Private"
+  Equity:Drawings       30000 JPY
+  Assets:Bank
+
+2026-01-15 * "Next year"
+  Expenses:Rent        100000 JPY
+  Assets:Bank
+`
+
+func TestConvertClosing(t *testing.T) {
+	ledger, err := beancount.ParseString("test.beancount", closingLedger)
+	if err != nil || len(ledger.Errors) > 0 {
+		t.Fatalf("failed to parse ledger: %v %v", err, ledger.Errors)
+	}
+
+	tests := []struct {
+		name     string
+		others   []string
+		postings map[string]float64
+		balances map[string]float64
+	}{
+		{
+			name: "corporation",
+			postings: map[string]float64{
+				"Income:Sales":   500000,
+				"Expenses:Rent":  -100000,
+				"Equity:Capital": -400000,
+			},
+			balances: map[string]float64{
+				"Assets:Bank":     670000,
+				"Equity:Capital":  -700000,
+				"Equity:Drawings": 30000,
+			},
+		},
+		{
+			name:   "sole proprietor",
+			others: []string{"Equity:Drawings", "Equity:Contributions"},
+			postings: map[string]float64{
+				"Income:Sales":    500000,
+				"Expenses:Rent":   -100000,
+				"Equity:Drawings": -30000,
+				"Equity:Capital":  -370000,
+			},
+			balances: map[string]float64{
+				"Assets:Bank":    670000,
+				"Equity:Capital": -670000,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cvtr := NewConverter(&Mapper{}, "JPY")
+			closing := cvtr.ConvertClosing(ledger.Transactions, "2025-01-01", "2025-12-31", "Equity:Capital", tt.others)
+
+			if closing.Profit != 400000 {
+				t.Errorf("profit = %.0f, want 400000", closing.Profit)
+			}
+			if closing.Entry.Date != "2025-12-31" || closing.Entry.Metadata[beancount.ClosingMetadata] != "2025" {
+				t.Errorf("entry = %s %v, want 2025-12-31 closing 2025", closing.Entry.Date, closing.Entry.Metadata)
+			}
+			if len(closing.Entry.Postings) != len(tt.postings) {
+				t.Fatalf("postings = %+v, want %v", closing.Entry.Postings, tt.postings)
+			}
+			for _, p := range closing.Entry.Postings {
+				if p.Amount != tt.postings[p.Account] {
+					t.Errorf("%s = %.0f, want %.0f", p.Account, p.Amount, tt.postings[p.Account])
+				}
+			}
+			if len(closing.Balances) != len(tt.balances) {
+				t.Fatalf("balances = %v, want %v", closing.Balances, tt.balances)
+			}
+			for account, want := range tt.balances {
+				if closing.Balances[account] != want {
+					t.Errorf("balance of %s = %.0f, want %.0f", account, closing.Balances[account], want)
+				}
+			}
+		})
+	}
+}
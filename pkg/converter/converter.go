@@ -3,6 +3,7 @@ package converter
 import (
 	"fmt"
 	"math"
+	"sort"
 	"strings"
 
 	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
@@ -11,10 +12,19 @@ import (
 // BeancountTransaction represents a Beancount transaction.
 type BeancountTransaction struct {
 	Date      string
+	Flag      string // "*" when empty; "!" marks a pending entry
 	Narration string
 	Payee     string
 	Tags      []string
+	Metadata  map[string]string
 	Postings  []BeancountPosting
+	Documents []BeancountDocument
+}
+
+// BeancountDocument represents a document directive emitted with a transaction.
+type BeancountDocument struct {
+	Account string
+	Path    string // Relative to the Beancount file
 }
 
 // BeancountPosting represents a posting in a Beancount transaction.
@@ -53,58 +63,117 @@ func (c *Converter) ConvertDeal(deal freee.Deal) BeancountTransaction {
 		amountMultiplier = -1.0
 	}
 
-	// Process each detail line in the deal
+	// Process each detail line in the deal. Line amounts include VAT.
+	// Withholding tax deducted from an income deal is booked as a debit,
+	// so the gross sale reconciles with the net deposit. The deal amount
+	// counts the withholding lines with the sign they were entered with.
+	total := float64(deal.Amount)
+	if withheld, recorded := c.withholding(deal); withheld > 0 {
+		total -= float64(recorded + withheld)
+	}
 	for _, detail := range deal.Details {
-		beancountAccount := c.mapper.GetBeancountAccount(detail.AccountItemName)
+		if c.isWithholding(deal, detail) {
+			postings = append(postings, BeancountPosting{
+				Account:  c.mapper.WithholdingAccount(detail.AccountItemName),
+				Amount:   math.Abs(float64(detail.Amount)),
+				Currency: c.currency,
+				Comment:  "源泉徴収税",
+			})
+			continue
+		}
+
+		beancountAccount := c.mapper.ResolveAccount(detail.AccountItemName)
 
-		if beancountAccount == "" {
-			// Use unmapped account if no mapping found
-			sanitized := sanitizeAccountName(detail.AccountItemName)
-			beancountAccount = fmt.Sprintf("Expenses:Unmapped:%s", sanitized)
+		// The private share of lines allocated per transaction (家事按分)
+		// moves to the drawings account
+		amount, vat, private := detail.Amount, detail.Vat, int64(0)
+		taxAccount := c.mapper.GetTaxAccount("tax_10") // Default to 10% tax
+		if vat <= 0 || taxAccount == nil {
+			// VAT not booked separately stays in the line amount
+			vat = 0
+		}
+		amount -= vat
+		rule := c.allocation(deal, detail)
+		if rule != nil && rule.YearEnd {
+			rule = nil
+		}
+		if rule != nil {
+			private = rule.private(amount)
+			amount -= private
 		}
 
 		// Add posting for the main account (excluding VAT)
 		postings = append(postings, BeancountPosting{
 			Account:  beancountAccount,
-			Amount:   float64(detail.Amount) * amountMultiplier,
+			Amount:   float64(amount) * amountMultiplier,
 			Currency: c.currency,
 			Comment:  ptrToString(detail.Description),
 		})
 
 		// Add VAT posting if applicable
-		if detail.Vat > 0 {
-			taxAccount := c.mapper.GetTaxAccount("tax_10") // Default to 10% tax
-			if taxAccount != nil {
-				postings = append(postings, BeancountPosting{
-					Account:  *taxAccount,
-					Amount:   float64(detail.Vat) * amountMultiplier,
-					Currency: c.currency,
-					Comment:  "消費税",
-				})
+		if vat > 0 {
+			if rule != nil {
+				privateVat := rule.private(vat)
+				vat -= privateVat
+				private += privateVat
 			}
+			postings = append(postings, BeancountPosting{
+				Account:  *taxAccount,
+				Amount:   float64(vat) * amountMultiplier,
+				Currency: c.currency,
+				Comment:  "消費税",
+			})
+		}
+
+		if private != 0 {
+			postings = append(postings, BeancountPosting{
+				Account:  c.allocationAccount(),
+				Amount:   float64(private) * amountMultiplier,
+				Currency: c.currency,
+				Comment:  rule.label(),
+			})
 		}
 	}
 
 	// Add payment postings
 	if len(deal.Payments) > 0 {
+		paid := 0.0
 		for _, payment := range deal.Payments {
-			walletAccount := getWalletAccount(payment.FromWalletableType, payment.FromWalletableID)
+			walletAccount := c.mapper.GetWalletableAccount(payment.FromWalletableType, payment.FromWalletableID)
+			if walletAccount == "" {
+				walletAccount = getWalletAccount(payment.FromWalletableType, payment.FromWalletableID)
+			}
+			// Outflow for expenses, deposit for income
 			postings = append(postings, BeancountPosting{
 				Account:  walletAccount,
-				Amount:   -float64(payment.Amount), // Negative for outflow
+				Amount:   float64(payment.Amount) * -amountMultiplier,
 				Currency: c.currency,
 				Comment:  fmt.Sprintf("Payment from %s", payment.FromWalletableType),
 			})
+			paid += float64(payment.Amount)
+		}
+
+		// The unpaid rest stays in receivables or payables
+		if unpaid := total - paid; unpaid != 0 {
+			account := c.mapper.ResolveAccount("未払金")
+			if deal.Type == "income" {
+				account = c.mapper.ResolveAccount("売掛金")
+			}
+			postings = append(postings, BeancountPosting{
+				Account:  account,
+				Amount:   unpaid * -amountMultiplier,
+				Currency: c.currency,
+				Comment:  "未決済",
+			})
 		}
 	} else {
 		// If no payment specified, add a balancing entry to a default account
-		totalAmount := float64(deal.Amount)
 		defaultAccount := "Assets:Current:Bank:Ordinary"
 
 		// Opposite sign from the detail amounts to balance the transaction
 		postings = append(postings, BeancountPosting{
 			Account:  defaultAccount,
-			Amount:   totalAmount * -amountMultiplier,
+			Amount:   total * -amountMultiplier,
 			Currency: c.currency,
 		})
 	}
@@ -114,67 +183,129 @@ func (c *Converter) ConvertDeal(deal freee.Deal) BeancountTransaction {
 		Narration: buildDealNarration(deal),
 		Payee:     ptrToString(deal.PartnerCode),
 		Tags:      buildTags(deal.RefNumber),
+		Metadata:  buildMetadata("deal", deal.ID),
 		Postings:  postings,
 	}
 }
 
-// ConvertJournal converts a Journal to Beancount transaction.
-func (c *Converter) ConvertJournal(journal freee.Journal) BeancountTransaction {
+// ConvertManualJournal converts a manual journal (振替伝票) to Beancount
+// transaction.
+func (c *Converter) ConvertManualJournal(journal freee.ManualJournal) BeancountTransaction {
 	var postings []BeancountPosting
 
 	for _, detail := range journal.Details {
-		beancountAccount := c.mapper.GetBeancountAccount(detail.AccountItemName)
-
-		if beancountAccount == "" {
-			sanitized := sanitizeAccountName(detail.AccountItemName)
-			beancountAccount = fmt.Sprintf("Expenses:Unmapped:%s", sanitized)
-		}
+		beancountAccount := c.mapper.ResolveAccount(detail.AccountItemName)
 
 		// Debit = positive, Credit = negative
-		amount := float64(detail.Amount)
-		if detail.EntryType == "credit" {
-			amount = -amount
+		sign := 1.0
+		if detail.EntrySide == "credit" {
+			sign = -1
 		}
+		postings = append(postings, c.splitVat(beancountAccount, detail.Amount, detail.Vat, sign, ptrToString(detail.Description))...)
+	}
 
-		postings = append(postings, BeancountPosting{
-			Account:  beancountAccount,
-			Amount:   amount,
-			Currency: c.currency,
-			Comment:  ptrToString(detail.Description),
-		})
-
-		// Add VAT posting if applicable
-		if detail.Vat > 0 {
-			taxAccount := c.mapper.GetTaxAccount("tax_10")
-			if taxAccount != nil {
-				vatAmount := float64(detail.Vat)
-				if detail.EntryType == "credit" {
-					vatAmount = -vatAmount
-				}
-				postings = append(postings, BeancountPosting{
-					Account:  *taxAccount,
-					Amount:   vatAmount,
-					Currency: c.currency,
-					Comment:  "消費税",
-				})
-			}
-		}
+	metadata := buildMetadata("journal", journal.ID)
+	if journal.Adjustment {
+		metadata["freee_adjustment"] = "true"
 	}
 
 	return BeancountTransaction{
 		Date:      journal.IssueDate,
-		Narration: buildJournalNarration(journal),
+		Narration: buildManualJournalNarration(journal),
+		Metadata:  metadata,
 		Postings:  postings,
 	}
 }
 
+// splitVat books an amount including VAT to account, moving the VAT to the
+// tax account when one is mapped. sign is 1 for debits and -1 for credits.
+func (c *Converter) splitVat(account string, amount, vat int64, sign float64, comment string) []BeancountPosting {
+	taxAccount := c.mapper.GetTaxAccount("tax_10")
+	if vat == 0 || taxAccount == nil {
+		return []BeancountPosting{{Account: account, Amount: sign * float64(amount), Currency: c.currency, Comment: comment}}
+	}
+
+	return []BeancountPosting{
+		{Account: account, Amount: sign * float64(amount-vat), Currency: c.currency, Comment: comment},
+		{Account: *taxAccount, Amount: sign * float64(vat), Currency: c.currency, Comment: "消費税"},
+	}
+}
+
+// ConvertTransfer converts a Transfer to a Beancount transaction moving
+// the amount from one walletable account to the other.
+func (c *Converter) ConvertTransfer(transfer freee.Transfer) BeancountTransaction {
+	walletAccount := func(walletableType string, walletableID int64) string {
+		if account := c.mapper.GetWalletableAccount(walletableType, walletableID); account != "" {
+			return account
+		}
+		return getWalletAccount(walletableType, walletableID)
+	}
+
+	narration := transfer.Description
+	if narration == "" {
+		narration = "口座振替"
+	}
+
+	return BeancountTransaction{
+		Date:      transfer.Date,
+		Narration: narration,
+		Metadata:  buildMetadata("transfer", transfer.ID),
+		Postings: []BeancountPosting{
+			{
+				Account:  walletAccount(transfer.ToWalletableType, transfer.ToWalletableID),
+				Amount:   float64(transfer.Amount),
+				Currency: c.currency,
+			},
+			{
+				Account:  walletAccount(transfer.FromWalletableType, transfer.FromWalletableID),
+				Amount:   -float64(transfer.Amount),
+				Currency: c.currency,
+			},
+		},
+	}
+}
+
+// AttachDocuments links receipt files to a transaction. Each path is added
+// as document metadata (document, document-2, ...) and as a document
+// directive on the transaction's first account, so Fava shows the files
+// next to the transaction. Paths are relative to the Beancount file.
+func (c *Converter) AttachDocuments(txn *BeancountTransaction, paths []string) {
+	if len(paths) == 0 || len(txn.Postings) == 0 {
+		return
+	}
+	if txn.Metadata == nil {
+		txn.Metadata = make(map[string]string)
+	}
+
+	for i, path := range paths {
+		key := "document"
+		if i > 0 {
+			key = fmt.Sprintf("document-%d", i+1)
+		}
+		txn.Metadata[key] = path
+		txn.Documents = append(txn.Documents, BeancountDocument{
+			Account: txn.Postings[0].Account,
+			Path:    path,
+		})
+	}
+}
+
 // FormatTransaction formats a Beancount transaction as a string.
 func (c *Converter) FormatTransaction(txn BeancountTransaction) string {
+	return formatBeancount(txn)
+}
+
+// formatBeancount formats a transaction in Beancount syntax.
+func formatBeancount(txn BeancountTransaction) string {
 	var sb strings.Builder
 
 	// Transaction header
+	flag := txn.Flag
+	if flag == "" {
+		flag = "*"
+	}
 	sb.WriteString(txn.Date)
-	sb.WriteString(" *")
+	sb.WriteString(" " + flag)
 	if txn.Payee != "" {
 		sb.WriteString(fmt.Sprintf(" \"%s\"", txn.Payee))
 	}
@@ -185,6 +316,16 @@ func (c *Converter) FormatTransaction(txn BeancountTransaction) string {
 	}
 	sb.WriteString("\n")
 
+	// Metadata (sorted for stable output)
+	keys := make([]string, 0, len(txn.Metadata))
+	for k := range txn.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		sb.WriteString(fmt.Sprintf("  %s: \"%s\"\n", k, txn.Metadata[k]))
+	}
+
 	// Postings
 	for _, posting := range txn.Postings {
 		sb.WriteString("  ")
@@ -210,6 +351,20 @@ func (c *Converter) FormatTransaction(txn BeancountTransaction) string {
 		sb.WriteString("\n")
 	}
 
+	// Document directives
+	if len(txn.Documents) > 0 {
+		sb.WriteString("\n")
+		for _, doc := range txn.Documents {
+			sb.WriteString(fmt.Sprintf("%s document %s \"%s\"\n", txn.Date, doc.Account, doc.Path))
+			// The freee origin lets month files be edited per synced item
+			for _, k := range []string{"freee_id", "freee_type"} {
+				if v, ok := txn.Metadata[k]; ok {
+					sb.WriteString(fmt.Sprintf("  %s: \"%s\"\n", k, v))
+				}
+			}
+		}
+	}
+
 	return sb.String()
 }
 
@@ -234,6 +389,15 @@ func buildTags(refNumber *string) []string {
 	return []string{*refNumber}
 }
 
+// buildMetadata records the freee origin of a transaction so the ledger
+// validator can detect entries synced twice.
+func buildMetadata(freeeType string, freeeID int64) map[string]string {
+	return map[string]string{
+		"freee_type": freeeType,
+		"freee_id":   fmt.Sprintf("%d", freeeID),
+	}
+}
+
 func buildDealNarration(deal freee.Deal) string {
 	if len(deal.Details) == 1 && deal.Details[0].Description != nil {
 		return *deal.Details[0].Description
@@ -252,7 +416,7 @@ func buildDealNarration(deal freee.Deal) string {
 	return fmt.Sprintf("%s: %s", typeLabel, strings.Join(accountNames, ", "))
 }
 
-func buildJournalNarration(journal freee.Journal) string {
+func buildManualJournalNarration(journal freee.ManualJournal) string {
 	var descriptions []string
 	for _, d := range journal.Details {
 		if d.Description != nil && *d.Description != "" {
@@ -0,0 +1,197 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+	"gopkg.in/yaml.v3"
+)
+
+// Usage counts how often account items, tax codes and walletables are
+// referenced by freee entries.
+type Usage struct {
+	AccountItems   map[string]int   // account item name -> uses
+	AccountItemIDs map[string]int64 // account item name -> ID, if known
+	TaxCodes       map[int]int      // tax code -> uses
+	Walletables    map[string]int   // WalletableKey -> uses
+}
+
+// NewUsage creates an empty Usage.
+func NewUsage() *Usage {
+	return &Usage{
+		AccountItems:   make(map[string]int),
+		AccountItemIDs: make(map[string]int64),
+		TaxCodes:       make(map[int]int),
+		Walletables:    make(map[string]int),
+	}
+}
+
+// AddDeal counts the references of a deal.
+func (u *Usage) AddDeal(deal freee.Deal) {
+	for _, detail := range deal.Details {
+		u.addAccountItem(detail.AccountItemName, detail.AccountItemID)
+		u.TaxCodes[detail.TaxCode]++
+	}
+	for _, payment := range deal.Payments {
+		u.Walletables[WalletableKey(payment.FromWalletableType, payment.FromWalletableID)]++
+	}
+}
+
+// AddManualJournal counts the references of a manual journal.
+func (u *Usage) AddManualJournal(journal freee.ManualJournal) {
+	for _, detail := range journal.Details {
+		u.addAccountItem(detail.AccountItemName, detail.AccountItemID)
+		u.TaxCodes[detail.TaxCode]++
+	}
+}
+
+// AddTransfer counts the walletables of a transfer.
+func (u *Usage) AddTransfer(transfer freee.Transfer) {
+	u.Walletables[WalletableKey(transfer.FromWalletableType, transfer.FromWalletableID)]++
+	u.Walletables[WalletableKey(transfer.ToWalletableType, transfer.ToWalletableID)]++
+}
+
+func (u *Usage) addAccountItem(name string, id int64) {
+	u.AccountItems[name]++
+	if id != 0 {
+		u.AccountItemIDs[name] = id
+	}
+}
+
+// categoryPrefixes maps freee account categories to Beancount account
+// prefixes. Both the English categories returned by the emulator and the
+// Japanese categories returned by freee are listed.
+var categoryPrefixes = map[string]string{
+	"asset":     "Assets:Current",
+	"liability": "Liabilities:Current",
+	"equity":    "Equity",
+	"income":    "Income",
+	"expense":   "Expenses:SGA",
+
+	"現金・預金":    "Assets:Current",
+	"売上債権":     "Assets:Current",
+	"有価証券":     "Assets:Current",
+	"棚卸資産":     "Assets:Current",
+	"他流動資産":    "Assets:Current",
+	"有形固定資産":   "Assets:Fixed",
+	"無形固定資産":   "Assets:Fixed",
+	"投資その他の資産": "Assets:Fixed",
+	"仕入債務":     "Liabilities:Current",
+	"他流動負債":    "Liabilities:Current",
+	"固定負債":     "Liabilities:LongTerm",
+	"資本金":      "Equity",
+	"資本剰余金":    "Equity",
+	"利益剰余金":    "Equity",
+	"元入金":      "Equity",
+	"売上高":      "Income:Sales",
+	"営業外収益":    "Income",
+	"特別利益":     "Income",
+	"売上原価":     "Expenses:COGS",
+	"販売管理費":    "Expenses:SGA",
+	"経費":       "Expenses:SGA",
+	"営業外費用":    "Expenses:Nonoperating",
+	"特別損失":     "Expenses:Nonoperating",
+}
+
+// walletablePrefixes maps freee walletable types to Beancount account prefixes.
+var walletablePrefixes = map[string]string{
+	"bank_account": "Assets:Current:Bank",
+	"credit_card":  "Liabilities:Current:CreditCard",
+	"wallet":       "Assets:Current:Wallet",
+}
+
+// SuggestAccount suggests a Beancount account for an account item based on
+// its account_category. Unknown categories fall back to the account the
+// converter uses for unmapped items.
+func SuggestAccount(name, category string) string {
+	prefix, ok := categoryPrefixes[category]
+	if !ok {
+		return UnmappedPrefix + sanitizeAccountName(name)
+	}
+	return fmt.Sprintf("%s:%s", prefix, sanitizeAccountName(name))
+}
+
+// SuggestWalletableAccount suggests a Beancount account for a walletable
+// based on its type.
+func SuggestWalletableAccount(walletableType, name string) string {
+	prefix, ok := walletablePrefixes[walletableType]
+	if !ok {
+		prefix = "Assets:Current"
+	}
+	return fmt.Sprintf("%s:%s", prefix, sanitizeAccountName(name))
+}
+
+// PatchEntry is a suggested key-to-account mapping.
+type PatchEntry struct {
+	Key     string
+	Account string
+	Comment string // Written as a line comment
+}
+
+// MappingPatch holds suggested additions to the account mapping file.
+type MappingPatch struct {
+	Accounts    []PatchEntry
+	Walletables []PatchEntry
+	TaxCodes    []TaxCodeMapping
+}
+
+// IsEmpty reports whether the patch suggests nothing.
+func (p MappingPatch) IsEmpty() bool {
+	return len(p.Accounts) == 0 && len(p.Walletables) == 0 && len(p.TaxCodes) == 0
+}
+
+// AddTaxCode adds a tax code without a Beancount account; the rate and
+// account are left for the user to fill in.
+func (p *MappingPatch) AddTaxCode(code int, description string) {
+	p.TaxCodes = append(p.TaxCodes, TaxCodeMapping{
+		Code:        strconv.Itoa(code),
+		Description: description,
+	})
+}
+
+// Marshal renders the patch as YAML in the format read by NewMapper.
+func (p MappingPatch) Marshal() ([]byte, error) {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+
+	if len(p.Accounts) > 0 {
+		root.Content = append(root.Content, scalarNode("accounts"), patchEntriesNode(p.Accounts))
+	}
+	if len(p.Walletables) > 0 {
+		root.Content = append(root.Content, scalarNode("walletables"), patchEntriesNode(p.Walletables))
+	}
+	if len(p.TaxCodes) > 0 {
+		var taxCodes yaml.Node
+		if err := taxCodes.Encode(p.TaxCodes); err != nil {
+			return nil, fmt.Errorf("failed to encode tax codes: %w", err)
+		}
+		root.Content = append(root.Content, scalarNode("tax_codes"), &taxCodes)
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func patchEntriesNode(entries []PatchEntry) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, entry := range entries {
+		value := scalarNode(entry.Account)
+		value.LineComment = entry.Comment
+		node.Content = append(node.Content, scalarNode(entry.Key), value)
+	}
+	return node
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
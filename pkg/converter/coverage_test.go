@@ -0,0 +1,117 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+)
+
+func TestSuggestAccount(t *testing.T) {
+	tests := []struct {
+		name     string
+		category string
+		want     string
+	}{
+		{"新聞図書費", "expense", "Expenses:SGA:新聞図書費"},
+		{"売掛金", "asset", "Assets:Current:売掛金"},
+		{"長期借入金", "固定負債", "Liabilities:LongTerm:長期借入金"},
+		{"謎の科目", "", "Expenses:Unmapped:謎の科目"},
+		{"Foo Bar", "income", "Income:FooBar"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SuggestAccount(tt.name, tt.category); got != tt.want {
+				t.Errorf("SuggestAccount(%q, %q) = %q, want %q", tt.name, tt.category, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsageAddDeal(t *testing.T) {
+	usage := NewUsage()
+	usage.AddDeal(freee.Deal{
+		Details: []freee.Detail{
+			{AccountItemName: "通信費", TaxCode: 136},
+			{AccountItemName: "通信費", TaxCode: 136},
+			{AccountItemName: "雑費", TaxCode: 0},
+		},
+		Payments: []freee.Payment{{FromWalletableType: "credit_card", FromWalletableID: 2}},
+	})
+
+	if usage.AccountItems["通信費"] != 2 || usage.AccountItems["雑費"] != 1 {
+		t.Errorf("AccountItems = %v", usage.AccountItems)
+	}
+	if usage.TaxCodes[136] != 2 || usage.TaxCodes[0] != 1 {
+		t.Errorf("TaxCodes = %v", usage.TaxCodes)
+	}
+	if usage.Walletables["credit_card:2"] != 1 {
+		t.Errorf("Walletables = %v", usage.Walletables)
+	}
+}
+
+func TestMappingPatchRoundTrip(t *testing.T) {
+	var patch MappingPatch
+	patch.Accounts = []PatchEntry{{Key: "新聞図書費", Account: "Expenses:SGA:新聞図書費", Comment: "expense"}}
+	patch.Walletables = []PatchEntry{{Key: "credit_card:2", Account: "Liabilities:Current:CreditCard:Amex"}}
+	patch.AddTaxCode(136, "課対仕入10%")
+
+	data, err := patch.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "patch.yaml")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mapper, err := NewMapper(path)
+	if err != nil {
+		t.Fatalf("NewMapper() error = %v\n%s", err, data)
+	}
+	if got := mapper.GetBeancountAccount("新聞図書費"); got != "Expenses:SGA:新聞図書費" {
+		t.Errorf("GetBeancountAccount() = %q\n%s", got, data)
+	}
+	if got := mapper.GetWalletableAccount("credit_card", 2); got != "Liabilities:Current:CreditCard:Amex" {
+		t.Errorf("GetWalletableAccount() = %q\n%s", got, data)
+	}
+	if !mapper.HasTaxCode("136") {
+		t.Errorf("HasTaxCode(136) = false\n%s", data)
+	}
+}
+
+func TestMappingFromMaster(t *testing.T) {
+	patch := MappingFromMaster(
+		[]freee.AccountItem{
+			{ID: 1, Name: "通信費", AccountCategory: "expense"},
+			{ID: 2, Name: "売掛金", AccountCategory: "asset"},
+		},
+		[]freee.Walletable{{ID: 3, Name: "Amex", Type: "credit_card"}},
+		[]freee.TaxCode{{Code: 136, NameJa: "課対仕入10%"}},
+	)
+
+	if len(patch.Accounts) != 2 || patch.Accounts[0].Account != "Assets:Current:売掛金" {
+		t.Errorf("Accounts = %+v", patch.Accounts)
+	}
+	if len(patch.TaxCodes) != 1 || patch.TaxCodes[0].Code != "136" {
+		t.Errorf("TaxCodes = %+v", patch.TaxCodes)
+	}
+
+	accounts := patch.OpenAccounts()
+	want := map[string]string{
+		"Assets:Current:売掛金":                  "売掛金",
+		"Expenses:SGA:通信費":                    "通信費",
+		"Liabilities:Current:CreditCard:Amex": "Amex",
+	}
+	for account, desc := range want {
+		if accounts[account] != desc {
+			t.Errorf("OpenAccounts()[%s] = %q, want %q", account, accounts[account], desc)
+		}
+	}
+	if len(accounts) != len(want) {
+		t.Errorf("OpenAccounts() = %v", accounts)
+	}
+}
@@ -0,0 +1,46 @@
+package converter
+
+import (
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+)
+
+// ConvertJournalExport converts the rows of a freee journal export to
+// Beancount transactions, one per slip. Consecutive rows with the same
+// date and 伝票番号 belong to the same slip. Accounts are resolved by name
+// since the export carries no account item IDs.
+func (c *Converter) ConvertJournalExport(lines []freee.JournalExportLine) []BeancountTransaction {
+	var txns []BeancountTransaction
+	var current *BeancountTransaction
+	var currentDate, currentNumber string
+
+	for _, line := range lines {
+		if current == nil || line.Date != currentDate || line.TxnNumber != currentNumber {
+			txns = append(txns, BeancountTransaction{
+				Date:      line.Date,
+				Narration: "仕訳",
+				Metadata:  map[string]string{"freee_type": "journal_export"},
+			})
+			current = &txns[len(txns)-1]
+			currentDate, currentNumber = line.Date, line.TxnNumber
+
+			if line.TxnNumber != "" {
+				current.Metadata["freee_txn_number"] = line.TxnNumber
+			}
+			if line.Adjustment {
+				current.Metadata["freee_adjustment"] = "true"
+			}
+		}
+		if current.Narration == "仕訳" && line.Description != "" {
+			current.Narration = line.Description
+		}
+
+		if line.Debit.Account != "" {
+			current.Postings = append(current.Postings, c.splitVat(c.mapper.ResolveAccount(line.Debit.Account), line.Debit.Amount, line.Debit.Vat, 1, "")...)
+		}
+		if line.Credit.Account != "" {
+			current.Postings = append(current.Postings, c.splitVat(c.mapper.ResolveAccount(line.Credit.Account), line.Credit.Amount, line.Credit.Vat, -1, "")...)
+		}
+	}
+
+	return txns
+}
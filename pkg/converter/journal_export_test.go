@@ -0,0 +1,118 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+	"golang.org/x/text/encoding/japanese"
+)
+
+func TestConvertJournalExport(t *testing.T) {
+	taxAccount := "Assets:Current:TaxReceivable"
+	mapper := &Mapper{
+		freeeToBean: map[string]string{
+			"消耗品費":  "Expenses:Supplies",
+			"普通預金":  "Assets:Current:Bank:Ordinary",
+			"未払金":   "Liabilities:Current:AccountsPayable",
+			"減価償却費": "Expenses:Depreciation",
+		},
+		taxCodeMap: map[string]TaxCodeMapping{"tax_10": {Code: "tax_10", BeancountAccount: &taxAccount}},
+	}
+	cvtr := NewConverter(mapper, "JPY")
+
+	data := "\ufeff日付,伝票番号,決算整理仕訳,借方勘定科目,借方金額,借方税額,貸方勘定科目,貸方金額,貸方税額,摘要\n" +
+		"2025/03/05,1,,消耗品費,\"1,100\",100,,,,文具\n" +
+		"2025/03/05,1,,,,,普通預金,600,0,文具\n" +
+		",,,,,,未払金,500,0,\n" +
+		"2025/12/31,2,決算整理,減価償却費,30000,0,工具器具備品,30000,0,\n"
+
+	lines, err := freee.ParseJournalExport([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseJournalExport() error = %v", err)
+	}
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want 4", len(lines))
+	}
+
+	txns := cvtr.ConvertJournalExport(lines)
+	if len(txns) != 2 {
+		t.Fatalf("got %d transactions, want 2", len(txns))
+	}
+
+	tests := []struct {
+		name       string
+		txn        BeancountTransaction
+		date       string
+		narration  string
+		postings   []BeancountPosting
+		adjustment bool
+	}{
+		{
+			name:      "purchase split across rows",
+			txn:       txns[0],
+			date:      "2025-03-05",
+			narration: "文具",
+			postings: []BeancountPosting{
+				{Account: "Expenses:Supplies", Amount: 1000},
+				{Account: taxAccount, Amount: 100},
+				{Account: "Assets:Current:Bank:Ordinary", Amount: -600},
+				{Account: "Liabilities:Current:AccountsPayable", Amount: -500},
+			},
+		},
+		{
+			name:      "adjustment with unmapped account",
+			txn:       txns[1],
+			date:      "2025-12-31",
+			narration: "仕訳",
+			postings: []BeancountPosting{
+				{Account: "Expenses:Depreciation", Amount: 30000},
+				{Account: UnmappedPrefix + "工具器具備品", Amount: -30000},
+			},
+			adjustment: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.txn.Date != tt.date || tt.txn.Narration != tt.narration {
+				t.Errorf("got %s %q, want %s %q", tt.txn.Date, tt.txn.Narration, tt.date, tt.narration)
+			}
+			if len(tt.txn.Postings) != len(tt.postings) {
+				t.Fatalf("got %d postings, want %d", len(tt.txn.Postings), len(tt.postings))
+			}
+			for i, want := range tt.postings {
+				if got := tt.txn.Postings[i]; got.Account != want.Account || got.Amount != want.Amount {
+					t.Errorf("posting %d = %s %.0f, want %s %.0f", i, got.Account, got.Amount, want.Account, want.Amount)
+				}
+			}
+			if got := tt.txn.Metadata["freee_adjustment"] == "true"; got != tt.adjustment {
+				t.Errorf("adjustment = %v, want %v", got, tt.adjustment)
+			}
+		})
+	}
+}
+
+func TestParseJournalExportRejectsMissingColumns(t *testing.T) {
+	if _, err := freee.ParseJournalExport([]byte("日付,借方勘定科目,借方金額\n2025/03/05,消耗品費,100\n")); err == nil {
+		t.Error("ParseJournalExport() accepted an export without credit columns")
+	}
+}
+
+func TestParseJournalExportShiftJIS(t *testing.T) {
+	data, err := japanese.ShiftJIS.NewEncoder().Bytes([]byte("日付,借方勘定科目,借方金額,貸方勘定科目,貸方金額,摘要\n2025/03/05,消耗品費,100,普通預金,100,文具\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := freee.ParseJournalExport(data)
+	if err != nil {
+		t.Fatalf("ParseJournalExport() error = %v", err)
+	}
+	if len(lines) != 1 || lines[0].Debit.Account != "消耗品費" || lines[0].Description != "文具" {
+		t.Errorf("ParseJournalExport() = %+v, want the decoded row", lines)
+	}
+
+	if _, err := freee.ParseJournalExport([]byte("日付,\xff\xfe\n")); err == nil {
+		t.Error("ParseJournalExport() accepted an export that is neither UTF-8 nor Shift_JIS")
+	}
+}
@@ -8,6 +8,9 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// UnmappedPrefix is the parent of the accounts used for unmapped account items.
+const UnmappedPrefix = "Expenses:Unmapped:"
+
 // AccountMapping represents a mapping between freee and Beancount account names.
 type AccountMapping struct {
 	Freee     string `yaml:"freee"`
@@ -33,21 +36,42 @@ type AccountMappingConfig struct {
 		Current  []AccountMapping `yaml:"current"`
 		Longterm []AccountMapping `yaml:"longterm"`
 	} `yaml:"liabilities"`
-	Equity   []AccountMapping   `yaml:"equity"`
-	Income   []AccountMapping   `yaml:"income"`
+	Equity   []AccountMapping `yaml:"equity"`
+	Income   []AccountMapping `yaml:"income"`
 	Expenses struct {
 		COGS         []AccountMapping `yaml:"cogs"`
 		SGA          []AccountMapping `yaml:"sga"`
 		Nonoperating []AccountMapping `yaml:"nonoperating"`
 	} `yaml:"expenses"`
 	TaxCodes []TaxCodeMapping `yaml:"tax_codes"`
+
+	// Accounts is the flat form used by config/account-mapping.yaml:
+	// freee account item name to Beancount account.
+	Accounts map[string]string `yaml:"accounts"`
+	// Walletables maps "<walletable_type>:<walletable_id>" to a Beancount account.
+	Walletables map[string]string `yaml:"walletables"`
+	// Withholding configures the withholding tax deducted from income deals.
+	Withholding WithholdingConfig `yaml:"withholding"`
+	// Allocation configures home-business allocation (家事按分).
+	Allocation AllocationConfig `yaml:"allocation"`
+}
+
+// WithholdingConfig configures the withholding tax (源泉徴収) deducted from
+// income deals, which freee records as an extra detail line of the deal.
+type WithholdingConfig struct {
+	// AccountItems are the freee account items of the withholding line.
+	AccountItems []string `yaml:"account_items"`
+	// Account is the Beancount account withheld tax is booked to, such as
+	// 仮払源泉税 for a corporation or 事業主貸 for a sole proprietor. The
+	// mapping of the line's account item is used when empty.
+	Account string `yaml:"account"`
 }
 
 // Mapper maps freee account names to Beancount account names.
 type Mapper struct {
-	config           AccountMappingConfig
-	freeeToBean      map[string]string
-	taxCodeMap       map[string]TaxCodeMapping
+	config      AccountMappingConfig
+	freeeToBean map[string]string
+	taxCodeMap  map[string]TaxCodeMapping
 }
 
 // NewMapper creates a new Mapper from a YAML configuration file.
@@ -62,6 +86,12 @@ func NewMapper(configPath string) (*Mapper, error) {
 		return nil, fmt.Errorf("failed to parse YAML: %w", err)
 	}
 
+	for _, rule := range config.Allocation.Rules {
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
 	mapper := &Mapper{
 		config:      config,
 		freeeToBean: make(map[string]string),
@@ -112,6 +142,11 @@ func (m *Mapper) buildMappingMaps() {
 		m.freeeToBean[mapping.Freee] = mapping.Beancount
 	}
 
+	// Flat mappings
+	for freeeName, beancountAccount := range m.config.Accounts {
+		m.freeeToBean[freeeName] = beancountAccount
+	}
+
 	// Tax codes
 	for _, taxCode := range m.config.TaxCodes {
 		m.taxCodeMap[taxCode.Code] = taxCode
@@ -124,6 +159,15 @@ func (m *Mapper) GetBeancountAccount(freeeName string) string {
 	return m.freeeToBean[freeeName]
 }
 
+// ResolveAccount returns the Beancount account for a freee account name,
+// falling back to UnmappedPrefix followed by the name if no mapping is found.
+func (m *Mapper) ResolveAccount(freeeName string) string {
+	if account := m.freeeToBean[freeeName]; account != "" {
+		return account
+	}
+	return UnmappedPrefix + sanitizeAccountName(freeeName)
+}
+
 // GetBeancountAccountWithFallback returns the Beancount account name with a fallback.
 func (m *Mapper) GetBeancountAccountWithFallback(freeeName, fallback string) string {
 	if account := m.freeeToBean[freeeName]; account != "" {
@@ -158,6 +202,43 @@ func (m *Mapper) GetTaxAccount(taxCode string) *string {
 	return nil
 }
 
+// HasTaxCode checks if a tax code mapping exists.
+func (m *Mapper) HasTaxCode(taxCode string) bool {
+	_, ok := m.taxCodeMap[taxCode]
+	return ok
+}
+
+// GetWalletableAccount returns the Beancount account for a freee walletable.
+// Returns empty string if no mapping is found.
+func (m *Mapper) GetWalletableAccount(walletableType string, walletableID int64) string {
+	return m.config.Walletables[WalletableKey(walletableType, walletableID)]
+}
+
+// WalletableKey returns the key used for walletables in the mapping file.
+func WalletableKey(walletableType string, walletableID int64) string {
+	return fmt.Sprintf("%s:%d", walletableType, walletableID)
+}
+
+// IsWithholdingItem reports whether a freee account item is configured as
+// the withholding line of income deals.
+func (m *Mapper) IsWithholdingItem(freeeName string) bool {
+	for _, name := range m.config.Withholding.AccountItems {
+		if name == freeeName {
+			return true
+		}
+	}
+	return false
+}
+
+// WithholdingAccount returns the Beancount account withheld tax is booked
+// to, falling back to the mapping of the withholding line's account item.
+func (m *Mapper) WithholdingAccount(freeeName string) string {
+	if m.config.Withholding.Account != "" {
+		return m.config.Withholding.Account
+	}
+	return m.ResolveAccount(freeeName)
+}
+
 // HasMapping checks if a mapping exists for a freee account.
 func (m *Mapper) HasMapping(freeeName string) bool {
 	_, ok := m.freeeToBean[freeeName]
@@ -0,0 +1,104 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeMapping(t *testing.T, content string) *Mapper {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "account-mapping.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mapper, err := NewMapper(path)
+	if err != nil {
+		t.Fatalf("NewMapper() error = %v", err)
+	}
+	return mapper
+}
+
+func TestNewMapperNested(t *testing.T) {
+	mapper := writeMapping(t, `
+assets:
+  current:
+    - freee: 普通預金
+      beancount: Assets:Current:Bank:Ordinary
+      type: asset
+liabilities:
+  longterm:
+    - freee: 長期借入金
+      beancount: Liabilities:LongTerm:Loans
+income:
+  - freee: 売上高
+    beancount: Income:Sales
+expenses:
+  sga:
+    - freee: 通信費
+      beancount: Expenses:SGA:Communication
+tax_codes:
+  - code: "136"
+    rate: 0.1
+    description: 課対仕入10%
+    beancount_account: Assets:Current:PrepaidConsumptionTax
+`)
+
+	tests := map[string]string{
+		"普通預金":  "Assets:Current:Bank:Ordinary",
+		"長期借入金": "Liabilities:LongTerm:Loans",
+		"売上高":   "Income:Sales",
+		"通信費":   "Expenses:SGA:Communication",
+		"雑費":    "",
+	}
+	for freeeName, want := range tests {
+		if got := mapper.GetBeancountAccount(freeeName); got != want {
+			t.Errorf("GetBeancountAccount(%q) = %q, want %q", freeeName, got, want)
+		}
+	}
+
+	if got := mapper.GetTaxRate("136"); got != 0.1 {
+		t.Errorf("GetTaxRate(136) = %v, want 0.1", got)
+	}
+	if got := mapper.GetTaxAccount("136"); got == nil || *got != "Assets:Current:PrepaidConsumptionTax" {
+		t.Errorf("GetTaxAccount(136) = %v", got)
+	}
+	if got := mapper.GetWalletableAccount("bank_account", 1); got != "" {
+		t.Errorf("GetWalletableAccount() = %q, want no mapping", got)
+	}
+}
+
+func TestNewMapperFlat(t *testing.T) {
+	mapper := writeMapping(t, `
+expenses:
+  sga:
+    - freee: 通信費
+      beancount: Expenses:SGA:Communication
+accounts:
+  普通預金: Assets:Current:Bank:Ordinary
+  通信費: Expenses:SGA:Telecom
+walletables:
+  credit_card:2: Liabilities:Current:CreditCard:Amex
+`)
+
+	if got := mapper.GetBeancountAccount("普通預金"); got != "Assets:Current:Bank:Ordinary" {
+		t.Errorf("GetBeancountAccount(普通預金) = %q", got)
+	}
+	// Flat mappings are read after the nested sections
+	if got := mapper.GetBeancountAccount("通信費"); got != "Expenses:SGA:Telecom" {
+		t.Errorf("GetBeancountAccount(通信費) = %q, want the flat mapping", got)
+	}
+	if got := mapper.GetWalletableAccount("credit_card", 2); got != "Liabilities:Current:CreditCard:Amex" {
+		t.Errorf("GetWalletableAccount(credit_card, 2) = %q", got)
+	}
+}
+
+func TestNewMapperRepositoryMapping(t *testing.T) {
+	mapper, err := NewMapper(filepath.Join("..", "..", "config", "account-mapping.yaml"))
+	if err != nil {
+		t.Fatalf("NewMapper() error = %v", err)
+	}
+	if got := mapper.GetBeancountAccount("普通預金"); got != "Assets:Current:Bank:Ordinary" {
+		t.Errorf("GetBeancountAccount(普通預金) = %q", got)
+	}
+}
@@ -0,0 +1,91 @@
+package converter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+)
+
+// UnmappedItem is a freee account item that has a balance but no mapping.
+type UnmappedItem struct {
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Balance  int64  `json:"balance"`
+}
+
+// ConvertOpeningBalances converts the opening balances of a trial balance
+// sheet into a transaction on date. Each mapped account receives the sum
+// of the balances of its account items; equity receives the rest, so the
+// transaction balances even when the current year's profit is not in any
+// balance sheet account. Account items with a balance but no mapping are
+// returned instead of booked.
+func (c *Converter) ConvertOpeningBalances(trial *freee.TrialBalance, date, equity string) (BeancountTransaction, []UnmappedItem) {
+	amounts := make(map[string]float64)
+	items := make(map[string][]string)
+	var unmapped []UnmappedItem
+
+	for _, line := range trial.Balances {
+		if !line.IsAccountItem() || line.OpeningBalance == 0 {
+			continue
+		}
+		account := c.mapper.GetBeancountAccount(line.AccountItemName)
+		if account == "" {
+			unmapped = append(unmapped, UnmappedItem{
+				Name:     line.AccountItemName,
+				Category: line.AccountCategoryName,
+				Balance:  line.OpeningBalance,
+			})
+			continue
+		}
+		// Balances are positive on the normal side of the account
+		amounts[account] += float64(line.OpeningBalance) * debitSign(account)
+		items[account] = append(items[account], line.AccountItemName)
+	}
+
+	accounts := make([]string, 0, len(amounts))
+	for account := range amounts {
+		accounts = append(accounts, account)
+	}
+	sort.Strings(accounts)
+
+	txn := BeancountTransaction{
+		Date:      date,
+		Payee:     "Opening Balance",
+		Narration: fmt.Sprintf("期首残高 (freee 試算表 %s)", date),
+	}
+
+	var total float64
+	for _, account := range accounts {
+		if amounts[account] == 0 {
+			continue
+		}
+		txn.Postings = append(txn.Postings, BeancountPosting{
+			Account:  account,
+			Amount:   amounts[account],
+			Currency: c.currency,
+			Comment:  strings.Join(items[account], ", "),
+		})
+		total += amounts[account]
+	}
+	if total != 0 {
+		txn.Postings = append(txn.Postings, BeancountPosting{
+			Account:  equity,
+			Amount:   -total,
+			Currency: c.currency,
+		})
+	}
+
+	return txn, unmapped
+}
+
+// debitSign returns 1 for accounts whose balance is on the debit side
+// (Assets, Expenses) and -1 for the others.
+func debitSign(account string) float64 {
+	root, _, _ := strings.Cut(account, ":")
+	if root == "Assets" || root == "Expenses" {
+		return 1
+	}
+	return -1
+}
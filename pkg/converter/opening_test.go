@@ -0,0 +1,60 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+)
+
+func TestConvertOpeningBalances(t *testing.T) {
+	mapper := &Mapper{freeeToBean: map[string]string{
+		"現金":      "Assets:Current:Cash",
+		"普通預金":    "Assets:Current:Bank:Ordinary",
+		"定期預金":    "Assets:Current:Bank:Ordinary",
+		"未払金":     "Liabilities:Current:AccountsPayable",
+		"繰越利益剰余金": "Equity:RetainedEarnings",
+	}}
+	cvtr := NewConverter(mapper, "JPY")
+
+	trial := &freee.TrialBalance{Balances: []freee.TrialBalanceLine{
+		{AccountCategoryName: "現金・預金", TotalLine: true, OpeningBalance: 900000},
+		{AccountItemID: 1, AccountItemName: "現金", OpeningBalance: 100000},
+		{AccountItemID: 2, AccountItemName: "普通預金", OpeningBalance: 500000},
+		{AccountItemID: 3, AccountItemName: "定期預金", OpeningBalance: 300000},
+		{AccountItemID: 4, AccountItemName: "売掛金", AccountCategoryName: "売上債権", OpeningBalance: 50000},
+		{AccountItemID: 5, AccountItemName: "未払金", OpeningBalance: 200000},
+		{AccountItemID: 6, AccountItemName: "繰越利益剰余金", OpeningBalance: 600000},
+		{AccountItemID: 7, AccountItemName: "前払費用", OpeningBalance: 0},
+	}}
+
+	txn, unmapped := cvtr.ConvertOpeningBalances(trial, "2024-04-01", "Equity:OpeningBalances")
+
+	if len(unmapped) != 1 || unmapped[0].Name != "売掛金" || unmapped[0].Balance != 50000 {
+		t.Errorf("unmapped = %+v, want 売掛金 50000", unmapped)
+	}
+
+	want := []struct {
+		account string
+		amount  float64
+	}{
+		{"Assets:Current:Bank:Ordinary", 800000},
+		{"Assets:Current:Cash", 100000},
+		{"Equity:RetainedEarnings", -600000},
+		{"Liabilities:Current:AccountsPayable", -200000},
+		{"Equity:OpeningBalances", -100000}, // profit of the year so far
+	}
+	if len(txn.Postings) != len(want) {
+		t.Fatalf("got %d postings, want %d:\n%s", len(txn.Postings), len(want), formatBeancount(txn))
+	}
+	for i, w := range want {
+		if p := txn.Postings[i]; p.Account != w.account || p.Amount != w.amount {
+			t.Errorf("posting %d = %s %.0f, want %s %.0f", i, p.Account, p.Amount, w.account, w.amount)
+		}
+	}
+	if got := txn.Postings[0].Comment; got != "普通預金, 定期預金" {
+		t.Errorf("comment = %q, want the freee account items", got)
+	}
+	if txn.Date != "2024-04-01" {
+		t.Errorf("Date = %q", txn.Date)
+	}
+}
@@ -0,0 +1,46 @@
+package converter
+
+import (
+	"fmt"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+)
+
+// PendingFlag is the Beancount flag of entries for wallet transactions
+// that are not booked in freee yet.
+const PendingFlag = "!"
+
+// ConvertWalletTxn converts an unbooked wallet transaction to a pending
+// transaction between its walletable account and the suspense account.
+// The entry stands in for the deal until the transaction is booked in freee.
+func (c *Converter) ConvertWalletTxn(txn freee.WalletTxn, suspense string) BeancountTransaction {
+	walletAccount := c.mapper.GetWalletableAccount(txn.WalletableType, txn.WalletableID)
+	if walletAccount == "" {
+		walletAccount = getWalletAccount(txn.WalletableType, txn.WalletableID)
+	}
+
+	// Money leaves the walletable for expenses and enters it for income
+	amount := float64(txn.Amount)
+	if amount < 0 {
+		amount = -amount
+	}
+	if txn.EntrySide == "expense" {
+		amount = -amount
+	}
+
+	narration := txn.Description
+	if narration == "" {
+		narration = fmt.Sprintf("未処理明細 %d", txn.ID)
+	}
+
+	return BeancountTransaction{
+		Date:      txn.Date,
+		Flag:      PendingFlag,
+		Narration: narration,
+		Metadata:  buildMetadata("wallet_txn", txn.ID),
+		Postings: []BeancountPosting{
+			{Account: walletAccount, Amount: amount, Currency: c.currency},
+			{Account: suspense, Amount: -amount, Currency: c.currency, Comment: "freee 自動で経理 未処理"},
+		},
+	}
+}
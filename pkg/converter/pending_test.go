@@ -0,0 +1,62 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+)
+
+func TestConvertWalletTxn(t *testing.T) {
+	mapper := &Mapper{config: AccountMappingConfig{Walletables: map[string]string{
+		"credit_card:3": "Liabilities:Current:CreditCard:Visa",
+	}}}
+	cvtr := NewConverter(mapper, "JPY")
+
+	tests := []struct {
+		name     string
+		txn      freee.WalletTxn
+		account  string
+		amount   float64
+		suspense float64
+	}{
+		{
+			name:     "card charge",
+			txn:      freee.WalletTxn{ID: 7, Date: "2025-03-05", Amount: 3300, EntrySide: "expense", WalletableType: "credit_card", WalletableID: 3, Description: "AMAZON"},
+			account:  "Liabilities:Current:CreditCard:Visa",
+			amount:   -3300,
+			suspense: 3300,
+		},
+		{
+			name:     "bank deposit",
+			txn:      freee.WalletTxn{ID: 8, Date: "2025-03-10", Amount: 50000, EntrySide: "income", WalletableType: "bank_account", WalletableID: 1},
+			account:  "Assets:Current:Bank:Ordinary",
+			amount:   50000,
+			suspense: -50000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn := cvtr.ConvertWalletTxn(tt.txn, "Assets:Current:Suspense")
+
+			if len(txn.Postings) != 2 {
+				t.Fatalf("got %d postings, want 2", len(txn.Postings))
+			}
+			if p := txn.Postings[0]; p.Account != tt.account || p.Amount != tt.amount {
+				t.Errorf("walletable posting = %s %.0f, want %s %.0f", p.Account, p.Amount, tt.account, tt.amount)
+			}
+			if p := txn.Postings[1]; p.Account != "Assets:Current:Suspense" || p.Amount != tt.suspense {
+				t.Errorf("suspense posting = %s %.0f, want %.0f", p.Account, p.Amount, tt.suspense)
+			}
+
+			formatted := formatBeancount(txn)
+			if !strings.HasPrefix(formatted, tt.txn.Date+" ! ") {
+				t.Errorf("entry is not flagged pending:\n%s", formatted)
+			}
+			if !strings.Contains(formatted, `freee_type: "wallet_txn"`) {
+				t.Errorf("entry has no freee_type metadata:\n%s", formatted)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package converter
+
+import (
+	"sort"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+)
+
+// MappingFromMaster builds a mapping for a new ledger from freee master
+// data: every account item placed by its account_category, every
+// walletable by its type and every tax code without an account.
+func MappingFromMaster(items []freee.AccountItem, walletables []freee.Walletable, taxCodes []freee.TaxCode) MappingPatch {
+	var patch MappingPatch
+
+	for _, item := range items {
+		patch.Accounts = append(patch.Accounts, PatchEntry{
+			Key:     item.Name,
+			Account: SuggestAccount(item.Name, item.AccountCategory),
+			Comment: item.AccountCategory,
+		})
+	}
+	for _, w := range walletables {
+		patch.Walletables = append(patch.Walletables, PatchEntry{
+			Key:     WalletableKey(w.Type, w.ID),
+			Account: SuggestWalletableAccount(w.Type, w.Name),
+			Comment: w.Name,
+		})
+	}
+	for _, tc := range taxCodes {
+		patch.AddTaxCode(tc.Code, tc.NameJa)
+	}
+
+	sort.SliceStable(patch.Accounts, func(i, j int) bool { return patch.Accounts[i].Account < patch.Accounts[j].Account })
+	sort.SliceStable(patch.Walletables, func(i, j int) bool { return patch.Walletables[i].Key < patch.Walletables[j].Key })
+
+	return patch
+}
+
+// OpenAccounts returns the Beancount accounts the patch maps to, each with
+// the freee name behind it.
+func (p MappingPatch) OpenAccounts() map[string]string {
+	accounts := make(map[string]string)
+	for _, entry := range p.Accounts {
+		accounts[entry.Account] = entry.Key
+	}
+	for _, entry := range p.Walletables {
+		accounts[entry.Account] = entry.Comment
+	}
+	for _, tc := range p.TaxCodes {
+		if tc.BeancountAccount != nil {
+			accounts[*tc.BeancountAccount] = tc.Description
+		}
+	}
+	return accounts
+}
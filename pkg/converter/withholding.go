@@ -0,0 +1,27 @@
+package converter
+
+import (
+	"math"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+)
+
+// isWithholding reports whether a detail of a deal is the withholding tax
+// (源泉徴収) deducted from an income deal: a line booked to a configured
+// withholding account item.
+func (c *Converter) isWithholding(deal freee.Deal, detail freee.Detail) bool {
+	return deal.Type == "income" && c.mapper.IsWithholdingItem(detail.AccountItemName)
+}
+
+// withholding returns the tax withheld from an income deal and the sum of
+// its withholding lines as recorded in freee, which is negative when the
+// lines are entered as deductions.
+func (c *Converter) withholding(deal freee.Deal) (withheld, recorded int64) {
+	for _, detail := range deal.Details {
+		if c.isWithholding(deal, detail) {
+			withheld += int64(math.Abs(float64(detail.Amount)))
+			recorded += detail.Amount
+		}
+	}
+	return withheld, recorded
+}
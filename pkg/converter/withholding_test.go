@@ -0,0 +1,119 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+)
+
+func TestConvertDealWithholding(t *testing.T) {
+	taxAccount := "Liabilities:Current:ConsumptionTaxPayable"
+	newMapper := func(withholding WithholdingConfig) *Mapper {
+		return &Mapper{
+			config: AccountMappingConfig{
+				Walletables: map[string]string{"bank_account:1": "Assets:Current:Bank:Ordinary"},
+				Withholding: withholding,
+			},
+			freeeToBean: map[string]string{
+				"売上高":   "Income:Sales",
+				"事業主貸":  "Equity:Drawings",
+				"仮払源泉税": "Assets:Current:PrepaidWithholdingTax",
+				"売掛金":   "Assets:Current:AccountsReceivable",
+			},
+			taxCodeMap: map[string]TaxCodeMapping{"tax_10": {Code: "tax_10", BeancountAccount: &taxAccount}},
+		}
+	}
+	sale := freee.Detail{AccountItemName: "売上高", Amount: 110000, Vat: 10000}
+	drawings := WithholdingConfig{AccountItems: []string{"事業主貸"}}
+	payment := func(amount int64) []freee.Payment {
+		return []freee.Payment{{Amount: amount, FromWalletableType: "bank_account", FromWalletableID: 1}}
+	}
+
+	tests := []struct {
+		name        string
+		withholding WithholdingConfig
+		deal        freee.Deal
+		want        map[string]float64
+	}{
+		{
+			name:        "deducted line booked to the line's account",
+			withholding: drawings,
+			deal: freee.Deal{Type: "income", Amount: 99790, Payments: payment(99790), Details: []freee.Detail{
+				sale,
+				{AccountItemName: "事業主貸", Amount: -10210},
+			}},
+			want: map[string]float64{
+				"Income:Sales":                 -100000,
+				taxAccount:                     -10000,
+				"Equity:Drawings":              10210,
+				"Assets:Current:Bank:Ordinary": 99790,
+			},
+		},
+		{
+			name:        "positive line booked to the configured account",
+			withholding: WithholdingConfig{AccountItems: []string{"仮払源泉税"}, Account: "Assets:Current:PrepaidWithholdingTax"},
+			deal: freee.Deal{Type: "income", Amount: 120210, Payments: payment(99790), Details: []freee.Detail{
+				sale,
+				{AccountItemName: "仮払源泉税", Amount: 10210},
+			}},
+			want: map[string]float64{
+				"Income:Sales":                         -100000,
+				taxAccount:                             -10000,
+				"Assets:Current:PrepaidWithholdingTax": 10210,
+				"Assets:Current:Bank:Ordinary":         99790,
+			},
+		},
+		{
+			name:        "partial deposit leaves the rest receivable",
+			withholding: drawings,
+			deal: freee.Deal{Type: "income", Amount: 99790, Payments: payment(50000), Details: []freee.Detail{
+				sale,
+				{AccountItemName: "事業主貸", Amount: -10210},
+			}},
+			want: map[string]float64{
+				"Income:Sales":                      -100000,
+				taxAccount:                          -10000,
+				"Equity:Drawings":                   10210,
+				"Assets:Current:Bank:Ordinary":      50000,
+				"Assets:Current:AccountsReceivable": 49790,
+			},
+		},
+		{
+			name:        "negative line of another item is not withholding",
+			withholding: drawings,
+			deal: freee.Deal{Type: "income", Amount: 99000, Payments: payment(99000), Details: []freee.Detail{
+				sale,
+				{AccountItemName: "売上高", Amount: -11000},
+			}},
+			want: map[string]float64{
+				"Income:Sales":                 -89000,
+				taxAccount:                     -10000,
+				"Assets:Current:Bank:Ordinary": 99000,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn := NewConverter(newMapper(tt.withholding), "JPY").ConvertDeal(tt.deal)
+
+			got := make(map[string]float64)
+			sum := 0.0
+			for _, p := range txn.Postings {
+				got[p.Account] += p.Amount
+				sum += p.Amount
+			}
+			if sum != 0 {
+				t.Errorf("transaction does not balance: %.0f", sum)
+			}
+			for account, amount := range tt.want {
+				if got[account] != amount {
+					t.Errorf("%s = %.0f, want %.0f", account, got[account], amount)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("got postings to %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package converter
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Writer writes converted transactions in an output format.
+type Writer interface {
+	// WriteTransaction writes one transaction.
+	WriteTransaction(txn BeancountTransaction) error
+	// Flush writes any buffered output.
+	Flush() error
+}
+
+// WriterFactory creates a Writer that writes to w.
+type WriterFactory func(w io.Writer) Writer
+
+var writers = make(map[string]WriterFactory)
+
+// RegisterWriter makes an output format available to NewWriter.
+func RegisterWriter(format string, factory WriterFactory) {
+	writers[format] = factory
+}
+
+// WriterFormats returns the registered output formats, sorted.
+func WriterFormats() []string {
+	formats := make([]string, 0, len(writers))
+	for format := range writers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// NewWriter creates a Writer for a registered output format.
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	factory, ok := writers[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q: expected one of %s", format, strings.Join(WriterFormats(), ", "))
+	}
+	return factory(w), nil
+}
+
+func init() {
+	RegisterWriter("beancount", func(w io.Writer) Writer { return &beancountWriter{w: w} })
+	RegisterWriter("ledger", func(w io.Writer) Writer { return &ledgerWriter{w: w, dateSep: "/"} })
+	RegisterWriter("hledger", func(w io.Writer) Writer { return &ledgerWriter{w: w, dateSep: "-", hledger: true} })
+	RegisterWriter("csv", func(w io.Writer) Writer { return &csvWriter{w: csv.NewWriter(w)} })
+}
+
+// beancountWriter writes transactions as sync writes them to the ledger.
+type beancountWriter struct {
+	w       io.Writer
+	written bool
+}
+
+func (b *beancountWriter) WriteTransaction(txn BeancountTransaction) error {
+	if b.written {
+		if _, err := io.WriteString(b.w, "\n"); err != nil {
+			return err
+		}
+	}
+	b.written = true
+	_, err := io.WriteString(b.w, formatBeancount(txn))
+	return err
+}
+
+func (b *beancountWriter) Flush() error {
+	return nil
+}
+
+// ledgerWriter writes transactions for ledger-cli or hledger. Metadata
+// becomes "key: value" comments, which both read as tags with values.
+// Document directives have no equivalent; the document metadata keeps
+// the paths.
+type ledgerWriter struct {
+	w       io.Writer
+	dateSep string
+	hledger bool // payee|note descriptions and name: tags
+	written bool
+}
+
+func (l *ledgerWriter) WriteTransaction(txn BeancountTransaction) error {
+	var sb strings.Builder
+	if l.written {
+		sb.WriteString("\n")
+	}
+	l.written = true
+
+	// Header: hledger splits "payee | note", ledger-cli takes the payee
+	// from the Payee metadata
+	sb.WriteString(strings.ReplaceAll(txn.Date, "-", l.dateSep))
+	sb.WriteString(" * ")
+	if l.hledger && txn.Payee != "" {
+		sb.WriteString(txn.Payee + " | ")
+	}
+	sb.WriteString(txn.Narration)
+	if l.hledger && len(txn.Tags) > 0 {
+		sb.WriteString("  ; " + strings.Join(txn.Tags, ":, ") + ":")
+	}
+	sb.WriteString("\n")
+
+	if !l.hledger {
+		if txn.Payee != "" {
+			sb.WriteString(fmt.Sprintf("    ; Payee: %s\n", txn.Payee))
+		}
+		if len(txn.Tags) > 0 {
+			sb.WriteString(fmt.Sprintf("    ; :%s:\n", strings.Join(txn.Tags, ":")))
+		}
+	}
+
+	keys := make([]string, 0, len(txn.Metadata))
+	for k := range txn.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		sb.WriteString(fmt.Sprintf("    ; %s: %s\n", k, txn.Metadata[k]))
+	}
+
+	for _, posting := range txn.Postings {
+		amount := formatNumber(posting.Amount) + " " + posting.Currency
+		spaces := int(math.Max(2, 60-float64(len(posting.Account))))
+		sb.WriteString("    " + posting.Account + strings.Repeat(" ", spaces) + amount)
+		if posting.Comment != "" {
+			sb.WriteString("  ; " + posting.Comment)
+		}
+		sb.WriteString("\n")
+	}
+
+	_, err := io.WriteString(l.w, sb.String())
+	return err
+}
+
+func (l *ledgerWriter) Flush() error {
+	return nil
+}
+
+// csvColumns is the header of the CSV journal.
+var csvColumns = []string{
+	"date", "freee_type", "freee_id", "payee", "narration", "tags",
+	"account", "amount", "currency", "comment",
+}
+
+// csvWriter writes a flat journal with one row per posting.
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func (c *csvWriter) writeHeader() error {
+	if c.header {
+		return nil
+	}
+	c.header = true
+	return c.w.Write(csvColumns)
+}
+
+func (c *csvWriter) WriteTransaction(txn BeancountTransaction) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	for _, posting := range txn.Postings {
+		err := c.w.Write([]string{
+			txn.Date,
+			txn.Metadata["freee_type"],
+			txn.Metadata["freee_id"],
+			txn.Payee,
+			txn.Narration,
+			strings.Join(txn.Tags, " "),
+			posting.Account,
+			formatNumber(posting.Amount),
+			posting.Currency,
+			posting.Comment,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *csvWriter) Flush() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// formatNumber formats an amount without trailing zeros.
+func formatNumber(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
@@ -0,0 +1,75 @@
+package converter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriters(t *testing.T) {
+	txn := BeancountTransaction{
+		Date:      "2024-03-05",
+		Narration: "Office supplies",
+		Payee:     "ACME",
+		Tags:      []string{"INV-1"},
+		Metadata:  map[string]string{"freee_type": "deal", "freee_id": "42"},
+		Postings: []BeancountPosting{
+			{Account: "Expenses:SGA:消耗品費", Amount: 1100, Currency: "JPY", Comment: "pens"},
+			{Account: "Assets:Current:Cash", Amount: -1100, Currency: "JPY"},
+		},
+	}
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"beancount", []string{
+			`2024-03-05 * "ACME" "Office supplies" #INV-1`,
+			`  freee_id: "42"`,
+			`-1100 JPY`,
+		}},
+		{"ledger", []string{
+			"2024/03/05 * Office supplies\n",
+			"    ; Payee: ACME\n",
+			"    ; :INV-1:\n",
+			"    ; freee_id: 42\n",
+			"1100 JPY  ; pens\n",
+		}},
+		{"hledger", []string{
+			"2024-03-05 * ACME | Office supplies  ; INV-1:\n",
+			"    ; freee_type: deal\n",
+			"-1100 JPY\n",
+		}},
+		{"csv", []string{
+			"date,freee_type,freee_id,payee,narration,tags,account,amount,currency,comment\n",
+			"2024-03-05,deal,42,ACME,Office supplies,INV-1,Expenses:SGA:消耗品費,1100,JPY,pens\n",
+			"2024-03-05,deal,42,ACME,Office supplies,INV-1,Assets:Current:Cash,-1100,JPY,\n",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(tt.format, &buf)
+			if err != nil {
+				t.Fatalf("NewWriter() error = %v", err)
+			}
+			if err := w.WriteTransaction(txn); err != nil {
+				t.Fatalf("WriteTransaction() error = %v", err)
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("output does not contain %q:\n%s", want, buf.String())
+				}
+			}
+		})
+	}
+
+	if _, err := NewWriter("qif", &bytes.Buffer{}); err == nil {
+		t.Error("NewWriter(qif) error = nil, want unknown format")
+	}
+}
@@ -3,6 +3,7 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 
@@ -16,8 +17,49 @@ type Connection struct {
 }
 
 // Open opens a SQLite database connection.
-// It enables WAL mode for better concurrency and foreign key constraints.
+// It enables WAL mode for better concurrency and foreign key constraints,
+// then applies pending schema migrations. If the database already holds
+// data, a backup is written before migrating.
 func Open(dbPath string) (*Connection, error) {
+	conn, err := OpenWithoutMigrations(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := conn.PendingMigrations()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to check migrations: %w", err)
+	}
+
+	if len(pending) > 0 {
+		hasData, err := conn.hasUserTables()
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if hasData {
+			backupPath, err := conn.Backup()
+			if err != nil {
+				conn.Close()
+				return nil, err
+			}
+			slog.Info("Backed up database before migration", "backup", backupPath, "pending", len(pending))
+		}
+
+		// Initialize schema
+		if err := InitializeSchema(conn); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to initialize schema: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+// OpenWithoutMigrations opens a SQLite database connection without
+// touching the schema. Use this to inspect or migrate explicitly.
+func OpenWithoutMigrations(dbPath string) (*Connection, error) {
 	// Ensure database file's parent directory exists
 	dbDir := filepath.Dir(dbPath)
 	if err := os.MkdirAll(dbDir, 0755); err != nil {
@@ -25,8 +67,9 @@ func Open(dbPath string) (*Connection, error) {
 	}
 
 	// Open database with SQLite driver
-	// Connection string enables foreign keys and WAL mode
-	connStr := fmt.Sprintf("file:%s?_foreign_keys=on&_journal_mode=WAL", dbPath)
+	// Connection string enables foreign keys and WAL mode, and waits for
+	// locks held by other processes (e.g. serve and a cron sync)
+	connStr := fmt.Sprintf("file:%s?_foreign_keys=on&_journal_mode=WAL&_busy_timeout=5000", dbPath)
 	db, err := sql.Open("sqlite3", connStr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
@@ -38,18 +81,10 @@ func Open(dbPath string) (*Connection, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	conn := &Connection{
+	return &Connection{
 		db:     db,
 		dbPath: dbPath,
-	}
-
-	// Initialize schema
-	if err := InitializeSchema(conn); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to initialize schema: %w", err)
-	}
-
-	return conn, nil
+	}, nil
 }
 
 // Close closes the database connection.
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// DealDetail is the cached description of a deal.
+type DealDetail struct {
+	DealID      int64  `json:"deal_id"`
+	IssueDate   string `json:"issue_date"`
+	Type        string `json:"type"`                   // income or expense
+	PartnerID   int64  `json:"partner_id,omitempty"`   // 0 if the deal has no partner
+	PartnerCode string `json:"partner_code,omitempty"` // empty if the deal has no partner code
+	AccountItem string `json:"account_item"`
+	Description string `json:"description,omitempty"`
+	Amount      int64  `json:"amount"`
+
+	// Lines are the detail lines of the deal. Only SaveDealDetails uses
+	// them; they are read back with GetSyncedDealLines.
+	Lines []DealLine `json:"lines,omitempty"`
+}
+
+// DealLine is a cached detail line (明細行) of a deal.
+type DealLine struct {
+	DealID      int64  `json:"deal_id"`
+	IssueDate   string `json:"issue_date"`
+	Type        string `json:"type"` // income or expense
+	AccountItem string `json:"account_item"`
+	TaxCode     int    `json:"tax_code"`
+	Amount      int64  `json:"amount"` // including VAT
+	Vat         int64  `json:"vat"`
+}
+
+// SaveDealDetails caches the details of deals, replacing those cached before.
+func (s *SyncHistory) SaveDealDetails(details []DealDetail) error {
+	now := time.Now()
+	return s.conn.Transaction(func(tx *sql.Tx) error {
+		for _, d := range details {
+			_, err := tx.Exec(`
+				INSERT INTO deal_details (deal_id, issue_date, deal_type, partner_id, partner_code, account_item, description, amount, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT(deal_id) DO UPDATE SET
+					issue_date = excluded.issue_date,
+					deal_type = excluded.deal_type,
+					partner_id = excluded.partner_id,
+					partner_code = excluded.partner_code,
+					account_item = excluded.account_item,
+					description = excluded.description,
+					amount = excluded.amount,
+					updated_at = excluded.updated_at
+			`,
+				d.DealID, d.IssueDate, d.Type,
+				sql.NullInt64{Int64: d.PartnerID, Valid: d.PartnerID != 0},
+				sql.NullString{String: d.PartnerCode, Valid: d.PartnerCode != ""},
+				d.AccountItem, d.Description, d.Amount, now,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to save details of deal %d: %w", d.DealID, err)
+			}
+
+			if _, err := tx.Exec(`DELETE FROM deal_lines WHERE deal_id = ?`, d.DealID); err != nil {
+				return fmt.Errorf("failed to replace lines of deal %d: %w", d.DealID, err)
+			}
+			for i, line := range d.Lines {
+				_, err := tx.Exec(`
+					INSERT INTO deal_lines (deal_id, line, account_item, tax_code, amount, vat)
+					VALUES (?, ?, ?, ?, ?, ?)
+				`, d.DealID, i+1, line.AccountItem, line.TaxCode, line.Amount, line.Vat)
+				if err != nil {
+					return fmt.Errorf("failed to save lines of deal %d: %w", d.DealID, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// GetSyncedDealDetails returns the cached details of the deals synced to
+// the ledger with an issue date in from..to (YYYY-MM-DD), in date order.
+// Deals synced before their details were cached are counted in missing.
+func (s *SyncHistory) GetSyncedDealDetails(from, to string) (details []DealDetail, missing int, err error) {
+	query := `
+		SELECT h.freee_id, h.issue_date, d.deal_type, d.partner_id, d.partner_code,
+			d.account_item, d.description, h.amount
+		FROM sync_history h
+		LEFT JOIN deal_details d ON d.deal_id = h.freee_id
+		WHERE h.sync_type = ? AND h.status = ? AND h.issue_date BETWEEN ? AND ?
+		ORDER BY h.issue_date, h.freee_id
+	`
+
+	rows, err := s.conn.Query(query, SyncTypeDeal, SyncStatusCommitted, from, to)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get deal details: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d DealDetail
+		var dealType, accountItem, description, partnerCode sql.NullString
+		var partnerID sql.NullInt64
+		if err := rows.Scan(&d.DealID, &d.IssueDate, &dealType, &partnerID, &partnerCode, &accountItem, &description, &d.Amount); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan deal details: %w", err)
+		}
+		if !dealType.Valid {
+			missing++
+			continue
+		}
+		d.Type = dealType.String
+		d.PartnerID = partnerID.Int64
+		d.PartnerCode = partnerCode.String
+		d.AccountItem = accountItem.String
+		d.Description = description.String
+		details = append(details, d)
+	}
+
+	return details, missing, rows.Err()
+}
+
+// GetSyncedDealLines returns the cached detail lines of the deals synced to
+// the ledger with an issue date in from..to (YYYY-MM-DD), in date order.
+// Deals synced before their lines were cached are counted in missing.
+func (s *SyncHistory) GetSyncedDealLines(from, to string) (lines []DealLine, missing int, err error) {
+	query := `
+		SELECT h.freee_id, h.issue_date, d.deal_type, l.account_item, l.tax_code, l.amount, l.vat
+		FROM sync_history h
+		LEFT JOIN deal_details d ON d.deal_id = h.freee_id
+		LEFT JOIN deal_lines l ON l.deal_id = h.freee_id
+		WHERE h.sync_type = ? AND h.status = ? AND h.issue_date BETWEEN ? AND ?
+		ORDER BY h.issue_date, h.freee_id, l.line
+	`
+
+	rows, err := s.conn.Query(query, SyncTypeDeal, SyncStatusCommitted, from, to)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get deal lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l DealLine
+		var dealType, accountItem sql.NullString
+		var taxCode, amount, vat sql.NullInt64
+		if err := rows.Scan(&l.DealID, &l.IssueDate, &dealType, &accountItem, &taxCode, &amount, &vat); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan deal line: %w", err)
+		}
+		if !dealType.Valid || !taxCode.Valid {
+			missing++
+			continue
+		}
+		l.Type = dealType.String
+		l.AccountItem = accountItem.String
+		l.TaxCode = int(taxCode.Int64)
+		l.Amount = amount.Int64
+		l.Vat = vat.Int64
+		lines = append(lines, l)
+	}
+
+	return lines, missing, rows.Err()
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// SyncLock is the lock held by commands that write the ledger.
+const SyncLock = "sync"
+
+// ErrLockHeld is returned when a lock is held by another owner.
+var ErrLockHeld = errors.New("lock is held by another process")
+
+// LockOwner returns an owner name identifying the current process.
+func LockOwner() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
+
+// AcquireLock takes a named lock for owner until ttl elapses. The lock is
+// taken over if it has expired, so a crashed holder blocks others for at
+// most ttl. It returns an error wrapping ErrLockHeld if another owner holds it.
+func (s *SyncHistory) AcquireLock(name, owner string, ttl time.Duration) error {
+	now := time.Now().UTC()
+	result, err := s.conn.Exec(`
+		INSERT INTO locks (name, owner, acquired_at, expires_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			owner = excluded.owner,
+			acquired_at = excluded.acquired_at,
+			expires_at = excluded.expires_at
+		WHERE locks.owner = excluded.owner OR locks.expires_at <= ?
+	`, name, owner, now, now.Add(ttl).Unix(), now.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	} else if n == 0 {
+		return s.lockHeldError(name)
+	}
+
+	return nil
+}
+
+// RefreshLock extends a lock held by owner by ttl. It returns an error
+// wrapping ErrLockHeld if the lock was lost to another owner.
+func (s *SyncHistory) RefreshLock(name, owner string, ttl time.Duration) error {
+	result, err := s.conn.Exec(`
+		UPDATE locks SET expires_at = ? WHERE name = ? AND owner = ?
+	`, time.Now().Add(ttl).Unix(), name, owner)
+	if err != nil {
+		return fmt.Errorf("failed to refresh lock %s: %w", name, err)
+	}
+
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to refresh lock %s: %w", name, err)
+	} else if n == 0 {
+		return s.lockHeldError(name)
+	}
+
+	return nil
+}
+
+// ReleaseLock releases a lock held by owner. Releasing a lock that is not
+// held by owner is a no-op.
+func (s *SyncHistory) ReleaseLock(name, owner string) error {
+	_, err := s.conn.Exec(`DELETE FROM locks WHERE name = ? AND owner = ?`, name, owner)
+	if err != nil {
+		return fmt.Errorf("failed to release lock %s: %w", name, err)
+	}
+	return nil
+}
+
+// lockHeldError describes the current holder of a lock.
+func (s *SyncHistory) lockHeldError(name string) error {
+	var owner string
+	var expiresAt int64
+	err := s.conn.QueryRow(`SELECT owner, expires_at FROM locks WHERE name = ?`, name).Scan(&owner, &expiresAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: %s", ErrLockHeld, name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read lock %s: %w", name, err)
+	}
+
+	return fmt.Errorf("%w: %s held by %s until %s", ErrLockHeld, name, owner,
+		time.Unix(expiresAt, 0).Local().Format(time.RFC3339))
+}
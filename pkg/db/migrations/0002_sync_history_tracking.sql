@@ -0,0 +1,8 @@
+-- Track the freee company, the content written to Beancount and the
+-- last update of each sync history record.
+ALTER TABLE sync_history ADD COLUMN company_id INTEGER;   -- freee company ID
+ALTER TABLE sync_history ADD COLUMN content_hash TEXT;    -- SHA-256 of the written entry
+ALTER TABLE sync_history ADD COLUMN updated_at TIMESTAMP; -- Last time the record changed
+
+CREATE INDEX IF NOT EXISTS idx_sync_history_company
+    ON sync_history(company_id);
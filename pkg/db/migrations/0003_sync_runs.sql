@@ -0,0 +1,26 @@
+-- Sync runs table
+-- One row per execution of `freee-sync sync`
+CREATE TABLE IF NOT EXISTS sync_runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP,
+    status TEXT NOT NULL,              -- 'running', 'succeeded', 'partial' or 'failed'
+    date_from TEXT NOT NULL,           -- YYYY-MM-DD
+    date_to TEXT NOT NULL,             -- YYYY-MM-DD
+    company_id INTEGER,                -- freee company ID
+    fetched_count INTEGER NOT NULL DEFAULT 0,
+    new_count INTEGER NOT NULL DEFAULT 0,
+    updated_count INTEGER NOT NULL DEFAULT 0,
+    failed_count INTEGER NOT NULL DEFAULT 0,
+    errors TEXT NOT NULL DEFAULT '[]', -- JSON array of error messages
+    files TEXT NOT NULL DEFAULT '[]'   -- JSON array of Beancount files touched
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_runs_started
+    ON sync_runs(started_at);
+
+-- Link each sync history record to the run that wrote it
+ALTER TABLE sync_history ADD COLUMN run_id INTEGER REFERENCES sync_runs(id);
+
+CREATE INDEX IF NOT EXISTS idx_sync_history_run
+    ON sync_history(run_id);
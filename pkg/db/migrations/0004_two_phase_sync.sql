@@ -0,0 +1,8 @@
+-- Two-phase sync records
+-- A record is inserted as 'pending' before its entry is written to the
+-- Beancount file and marked 'committed' afterwards. Pending records left
+-- behind by an interrupted run are reconciled against the files on startup.
+ALTER TABLE sync_history ADD COLUMN status TEXT NOT NULL DEFAULT 'committed';
+
+CREATE INDEX IF NOT EXISTS idx_sync_history_status
+    ON sync_history(status);
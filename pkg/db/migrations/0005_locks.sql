@@ -0,0 +1,8 @@
+-- Advisory locks
+-- Prevent overlapping sync runs across processes (cron, serve)
+CREATE TABLE IF NOT EXISTS locks (
+    name TEXT PRIMARY KEY,
+    owner TEXT NOT NULL,            -- host:pid of the holder
+    acquired_at TIMESTAMP NOT NULL,
+    expires_at INTEGER NOT NULL     -- Unix time; an expired lock may be taken over
+);
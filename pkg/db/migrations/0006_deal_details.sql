@@ -0,0 +1,18 @@
+-- Deal details cache
+-- The fields of synced deals that analyses such as recurring transaction
+-- detection need, so that they run without querying freee. Joined with
+-- sync_history on deal_id = freee_id.
+CREATE TABLE IF NOT EXISTS deal_details (
+    deal_id INTEGER PRIMARY KEY,       -- Deal ID from freee
+    issue_date TEXT NOT NULL,          -- YYYY-MM-DD
+    deal_type TEXT NOT NULL,           -- 'income' or 'expense'
+    partner_id INTEGER,                -- Partner (取引先) ID from freee
+    partner_code TEXT,                 -- Partner code from freee
+    account_item TEXT NOT NULL,        -- Account item of the largest line
+    description TEXT NOT NULL DEFAULT '',
+    amount INTEGER NOT NULL,
+    updated_at TIMESTAMP NOT NULL      -- When the details were cached
+);
+
+CREATE INDEX IF NOT EXISTS idx_deal_details_issue_date
+    ON deal_details(issue_date);
@@ -0,0 +1,13 @@
+-- Deal lines cache
+-- The detail lines (明細行) of synced deals with their tax codes, so that
+-- the consumption tax report runs without querying freee. Replaced as a
+-- whole whenever the details of the deal are cached again.
+CREATE TABLE IF NOT EXISTS deal_lines (
+    deal_id INTEGER NOT NULL,          -- Deal ID from freee
+    line INTEGER NOT NULL,             -- Position of the line in the deal
+    account_item TEXT NOT NULL,
+    tax_code INTEGER NOT NULL,         -- Tax code (税区分) from freee
+    amount INTEGER NOT NULL,           -- Amount including VAT
+    vat INTEGER NOT NULL,              -- VAT included in amount
+    PRIMARY KEY (deal_id, line)
+);
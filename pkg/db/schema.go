@@ -1,58 +1,216 @@
 // Package db provides SQLite database management for sync history and metadata.
 package db
 
-// Schema defines the SQL statements to create database tables.
-const Schema = `
--- Sync history table
--- Tracks which freee deals/journals have been synced to Beancount
-CREATE TABLE IF NOT EXISTS sync_history (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    sync_type TEXT NOT NULL,           -- 'deal' or 'journal'
-    freee_id INTEGER NOT NULL,         -- ID from freee API
-    issue_date TEXT NOT NULL,          -- YYYY-MM-DD
-    amount INTEGER NOT NULL,           -- Amount in JPY (integer)
-    beancount_file TEXT NOT NULL,      -- Path to Beancount file
-    synced_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    UNIQUE(sync_type, freee_id)
-);
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFiles holds the up-migrations, named NNNN_description.sql.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
 
-CREATE INDEX IF NOT EXISTS idx_sync_history_type_id
-    ON sync_history(sync_type, freee_id);
-
-CREATE INDEX IF NOT EXISTS idx_sync_history_date
-    ON sync_history(issue_date);
-
--- Document attachments table
--- Tracks which documents (receipts, invoices) have been attached to transactions
-CREATE TABLE IF NOT EXISTS document_attachments (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    transaction_date TEXT NOT NULL,    -- YYYY-MM-DD
-    ref_number TEXT,                   -- Reference number from freee
-    deal_id INTEGER,                   -- Deal ID from freee (optional)
-    document_path TEXT NOT NULL,       -- Path to the document file
-    attached_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+// migrationsTable tracks which migrations have been applied.
+const migrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
+`
 
-CREATE INDEX IF NOT EXISTS idx_doc_attachments_deal
-    ON document_attachments(deal_id);
+// Migration represents a versioned schema change.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
 
-CREATE INDEX IF NOT EXISTS idx_doc_attachments_path
-    ON document_attachments(document_path);
+// MigrationStatus represents a migration and whether it has been applied.
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt sql.NullString
+}
 
--- Sync metadata table
--- Stores key-value metadata about sync operations
-CREATE TABLE IF NOT EXISTS sync_metadata (
-    key TEXT PRIMARY KEY,
-    value TEXT NOT NULL,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-`
+// Migrations returns all embedded migrations ordered by version.
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	var migrations []Migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || path.Ext(name) != ".sql" {
+			continue
+		}
+
+		versionStr, desc, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name: %s", name)
+		}
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", name, err)
+		}
+		if other, exists := seen[version]; exists {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, other, name)
+		}
+		seen[version] = name
+
+		data, err := migrationFiles.ReadFile(path.Join("migrations", name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", name, err)
+		}
+
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    desc,
+			SQL:     string(data),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
 
 // InitializeSchema initializes the database schema.
-// It creates all tables if they don't exist.
+// It applies all pending migrations.
 func InitializeSchema(conn *Connection) error {
-	if _, err := conn.Exec(Schema); err != nil {
-		return err
+	_, err := conn.Migrate()
+	return err
+}
+
+// MigrationStatus returns every known migration with its applied state.
+func (c *Connection) MigrationStatus() ([]MigrationStatus, error) {
+	if _, err := c.Exec(migrationsTable); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := c.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]sql.NullString)
+	for rows.Next() {
+		var version int
+		var appliedAt sql.NullString
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		appliedAt, ok := applied[m.Version]
+		statuses[i] = MigrationStatus{
+			Migration: m,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		}
+	}
+
+	return statuses, nil
+}
+
+// PendingMigrations returns the migrations that have not been applied yet.
+func (c *Connection) PendingMigrations() ([]Migration, error) {
+	statuses, err := c.MigrationStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, s := range statuses {
+		if !s.Applied {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Migrate applies all pending migrations in version order.
+// Each migration runs in its own transaction together with its
+// schema_migrations record, so a failure leaves earlier migrations applied.
+func (c *Connection) Migrate() ([]Migration, error) {
+	pending, err := c.PendingMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, m := range pending {
+		err := c.Transaction(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(m.SQL); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.Version, m.Name)
+			return err
+		})
+		if err != nil {
+			return applied, fmt.Errorf("failed to apply migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		applied = append(applied, m)
+	}
+
+	return applied, nil
+}
+
+// Backup writes a consistent copy of the database next to the original
+// file and returns its path. It is used before applying migrations. The
+// name has nanosecond resolution and a counter, since VACUUM INTO fails
+// if the file exists, e.g. when two processes migrate at the same time.
+func (c *Connection) Backup() (string, error) {
+	stamp := time.Now().Format("20060102T150405.000000000")
+	backupPath := fmt.Sprintf("%s.%s.bak", c.dbPath, stamp)
+	for n := 1; ; n++ {
+		if _, err := os.Stat(backupPath); os.IsNotExist(err) {
+			break
+		}
+		backupPath = fmt.Sprintf("%s.%s-%d.bak", c.dbPath, stamp, n)
+	}
+	if _, err := c.Exec(`VACUUM INTO ?`, backupPath); err != nil {
+		return "", fmt.Errorf("failed to back up database: %w", err)
+	}
+	return backupPath, nil
+}
+
+// hasUserTables reports whether the database already contains tables,
+// i.e. whether it holds data worth backing up before a migration.
+func (c *Connection) hasUserTables() (bool, error) {
+	var count int
+	err := c.QueryRow(`
+		SELECT COUNT(*) FROM sqlite_master
+		WHERE type = 'table' AND name NOT IN ('schema_migrations', 'sqlite_sequence')
+	`).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect database: %w", err)
 	}
-	return nil
+	return count > 0, nil
 }
@@ -0,0 +1,120 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrationsOrdered(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Migrations() error = %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("Migrations() returned no migrations")
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("migration %d has version %d, expected consecutive versions", i, m.Version)
+		}
+	}
+}
+
+func TestOpenMigratesLegacyDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "sync.db")
+
+	// Database created before versioned migrations
+	legacy, err := OpenWithoutMigrations(dbPath)
+	if err != nil {
+		t.Fatalf("OpenWithoutMigrations() error = %v", err)
+	}
+	if _, err := legacy.Exec(`
+		CREATE TABLE sync_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			sync_type TEXT NOT NULL,
+			freee_id INTEGER NOT NULL,
+			issue_date TEXT NOT NULL,
+			amount INTEGER NOT NULL,
+			beancount_file TEXT NOT NULL,
+			synced_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(sync_type, freee_id)
+		);
+		INSERT INTO sync_history (sync_type, freee_id, issue_date, amount, beancount_file)
+		VALUES ('deal', 1, '2024-01-15', 1000, '2024/2024-01.beancount');
+	`); err != nil {
+		t.Fatalf("failed to create legacy schema: %v", err)
+	}
+	legacy.Close()
+
+	conn, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer conn.Close()
+
+	pending, err := conn.PendingMigrations()
+	if err != nil {
+		t.Fatalf("PendingMigrations() error = %v", err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("PendingMigrations() = %d after Open(), expected 0", len(pending))
+	}
+
+	// Existing rows survive and new columns are usable
+	history := NewSyncHistory(conn)
+	if err := history.RecordSync(SyncRecord{
+		SyncType: SyncTypeDeal, FreeeID: 1, IssueDate: "2024-01-15", Amount: 1000,
+		BeancountFile: "2024/2024-01.beancount", CompanyID: 42, ContentHash: "abc",
+	}); err != nil {
+		t.Fatalf("RecordSync() error = %v", err)
+	}
+	record, err := history.GetSyncRecord(SyncTypeDeal, 1)
+	if err != nil || record == nil {
+		t.Fatalf("GetSyncRecord() = %v, %v", record, err)
+	}
+	if record.CompanyID != 42 || record.ContentHash != "abc" {
+		t.Errorf("GetSyncRecord() = %+v, expected company 42 and hash abc", record)
+	}
+
+	backups, _ := filepath.Glob(dbPath + ".*.bak")
+	if len(backups) != 1 {
+		t.Errorf("expected one pre-migration backup, found %d", len(backups))
+	}
+}
+
+func TestOpenNewDatabaseSkipsBackup(t *testing.T) {
+	dir := t.TempDir()
+	conn, err := Open(filepath.Join(dir, "sync.db"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	conn.Close()
+
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if filepath.Ext(e.Name()) == ".bak" {
+			t.Errorf("unexpected backup %s for a new database", e.Name())
+		}
+	}
+}
+
+func TestBackupNamesAreUnique(t *testing.T) {
+	conn, err := Open(filepath.Join(t.TempDir(), "sync.db"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer conn.Close()
+
+	seen := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		path, err := conn.Backup()
+		if err != nil {
+			t.Fatalf("Backup() #%d error = %v", i+1, err)
+		}
+		if seen[path] {
+			t.Errorf("Backup() reused %s", path)
+		}
+		seen[path] = true
+	}
+}
@@ -10,19 +10,39 @@ import (
 type SyncType string
 
 const (
-	SyncTypeDeal    SyncType = "deal"
+	SyncTypeDeal SyncType = "deal"
+	// SyncTypeJournal records a manual journal (振替伝票).
 	SyncTypeJournal SyncType = "journal"
+	// SyncTypeTransfer records a transfer between walletables (口座振替).
+	SyncTypeTransfer SyncType = "transfer"
+	// SyncTypeWalletTxn records a pending entry for an unbooked wallet
+	// transaction; it is removed once the transaction is booked.
+	SyncTypeWalletTxn SyncType = "wallet_txn"
+)
+
+// SyncStatus represents the state of a sync record.
+type SyncStatus string
+
+const (
+	// SyncStatusPending marks a record whose entry may not have reached the file yet.
+	SyncStatusPending SyncStatus = "pending"
+	// SyncStatusCommitted marks a record whose entry has been written to the file.
+	SyncStatusCommitted SyncStatus = "committed"
 )
 
 // SyncRecord represents a sync history record.
 type SyncRecord struct {
-	ID            int64
-	SyncType      SyncType
-	FreeeID       int64
-	IssueDate     string
-	Amount        int64
-	BeancountFile string
-	SyncedAt      time.Time
+	ID            int64      `json:"id"`
+	SyncType      SyncType   `json:"sync_type"`
+	FreeeID       int64      `json:"freee_id"`
+	IssueDate     string     `json:"issue_date"`
+	Amount        int64      `json:"amount"`
+	BeancountFile string     `json:"beancount_file"`
+	CompanyID     int64      `json:"company_id,omitempty"`   // freee company ID (0 if unknown)
+	ContentHash   string     `json:"content_hash,omitempty"` // SHA-256 of the entry written to BeancountFile
+	RunID         int64      `json:"run_id,omitempty"`       // Sync run that wrote the record (0 if unknown)
+	Status        SyncStatus `json:"status"`
+	SyncedAt      time.Time  `json:"synced_at"`
 }
 
 // DocumentAttachment represents a document attachment record.
@@ -45,17 +65,100 @@ func NewSyncHistory(conn *Connection) *SyncHistory {
 	return &SyncHistory{conn: conn}
 }
 
-// RecordSync records a sync operation.
+// RecordSync records a committed sync operation.
 // If the record already exists (same sync_type + freee_id), it updates it.
 func (s *SyncHistory) RecordSync(record SyncRecord) error {
+	return s.upsertSyncRecord(record, SyncStatusCommitted)
+}
+
+// BeginSync records a sync operation as pending. Call it before writing the
+// entry to the Beancount file, then CommitSync once the write succeeded or
+// AbortSync if it failed. A pending record left behind by a crash is
+// resolved by reconciling it against the file on the next run.
+func (s *SyncHistory) BeginSync(record SyncRecord) error {
+	return s.upsertSyncRecord(record, SyncStatusPending)
+}
+
+// CommitSync marks a pending sync record as committed.
+func (s *SyncHistory) CommitSync(syncType SyncType, freeeID int64) error {
+	query := `
+		UPDATE sync_history SET status = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE sync_type = ? AND freee_id = ?
+	`
+
+	result, err := s.conn.Exec(query, string(SyncStatusCommitted), string(syncType), freeeID)
+	if err != nil {
+		return fmt.Errorf("failed to commit sync: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("failed to commit sync: no record for %s %d", syncType, freeeID)
+	}
+
+	return nil
+}
+
+// AbortSync removes a pending sync record whose entry was not written.
+// Committed records are left untouched.
+func (s *SyncHistory) AbortSync(syncType SyncType, freeeID int64) error {
+	query := `DELETE FROM sync_history WHERE sync_type = ? AND freee_id = ? AND status = ?`
+
+	if _, err := s.conn.Exec(query, string(syncType), freeeID, string(SyncStatusPending)); err != nil {
+		return fmt.Errorf("failed to abort sync: %w", err)
+	}
+
+	return nil
+}
+
+// SetContentHash stores the content hash of a record without touching the
+// rest of it. It backfills records written before hashes were recorded.
+func (s *SyncHistory) SetContentHash(syncType SyncType, freeeID int64, contentHash string) error {
 	query := `
-		INSERT INTO sync_history (sync_type, freee_id, issue_date, amount, beancount_file)
-		VALUES (?, ?, ?, ?, ?)
+		UPDATE sync_history SET content_hash = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE sync_type = ? AND freee_id = ?
+	`
+
+	if _, err := s.conn.Exec(query, nullString(contentHash), string(syncType), freeeID); err != nil {
+		return fmt.Errorf("failed to set content hash: %w", err)
+	}
+
+	return nil
+}
+
+// GetPendingSyncs retrieves all records that were never committed.
+func (s *SyncHistory) GetPendingSyncs() ([]SyncRecord, error) {
+	query := `SELECT ` + syncRecordColumns + `
+		FROM sync_history
+		WHERE status = ?
+		ORDER BY beancount_file, id
+	`
+
+	records, err := s.querySyncRecords(query, string(SyncStatusPending))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending syncs: %w", err)
+	}
+
+	return records, nil
+}
+
+func (s *SyncHistory) upsertSyncRecord(record SyncRecord, status SyncStatus) error {
+	query := `
+		INSERT INTO sync_history (sync_type, freee_id, issue_date, amount, beancount_file, company_id, content_hash, run_id, status, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
 		ON CONFLICT(sync_type, freee_id) DO UPDATE SET
 			issue_date = excluded.issue_date,
 			amount = excluded.amount,
 			beancount_file = excluded.beancount_file,
-			synced_at = CURRENT_TIMESTAMP
+			company_id = excluded.company_id,
+			content_hash = excluded.content_hash,
+			run_id = excluded.run_id,
+			status = excluded.status,
+			synced_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
 	`
 
 	_, err := s.conn.Exec(query,
@@ -64,6 +167,10 @@ func (s *SyncHistory) RecordSync(record SyncRecord) error {
 		record.IssueDate,
 		record.Amount,
 		record.BeancountFile,
+		nullInt64(record.CompanyID),
+		nullString(record.ContentHash),
+		nullInt64(record.RunID),
+		string(status),
 	)
 
 	if err != nil {
@@ -89,27 +196,69 @@ func (s *SyncHistory) IsSynced(syncType SyncType, freeeID int64) (bool, error) {
 	return count > 0, nil
 }
 
-// GetSyncRecord retrieves a sync record by freee ID.
-func (s *SyncHistory) GetSyncRecord(syncType SyncType, freeeID int64) (*SyncRecord, error) {
-	query := `
-		SELECT id, sync_type, freee_id, issue_date, amount, beancount_file, synced_at
-		FROM sync_history
-		WHERE sync_type = ? AND freee_id = ?
-	`
+// syncRecordColumns is the column list read by scanSyncRecord.
+const syncRecordColumns = `
+	id, sync_type, freee_id, issue_date, amount, beancount_file,
+	COALESCE(company_id, 0), COALESCE(content_hash, ''), COALESCE(run_id, 0), status, synced_at
+`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
+// scanSyncRecord scans a row selected with syncRecordColumns.
+func scanSyncRecord(row rowScanner) (SyncRecord, error) {
 	var record SyncRecord
-	var syncTypeStr string
+	var syncTypeStr, statusStr string
 
-	err := s.conn.QueryRow(query, string(syncType), freeeID).Scan(
+	err := row.Scan(
 		&record.ID,
 		&syncTypeStr,
 		&record.FreeeID,
 		&record.IssueDate,
 		&record.Amount,
 		&record.BeancountFile,
+		&record.CompanyID,
+		&record.ContentHash,
+		&record.RunID,
+		&statusStr,
 		&record.SyncedAt,
 	)
 
+	record.SyncType = SyncType(syncTypeStr)
+	record.Status = SyncStatus(statusStr)
+	return record, err
+}
+
+// querySyncRecords runs a query selecting syncRecordColumns and scans all rows.
+func (s *SyncHistory) querySyncRecords(query string, args ...interface{}) ([]SyncRecord, error) {
+	rows, err := s.conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []SyncRecord
+	for rows.Next() {
+		record, err := scanSyncRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sync record: %w", err)
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// GetSyncRecord retrieves a sync record by freee ID.
+func (s *SyncHistory) GetSyncRecord(syncType SyncType, freeeID int64) (*SyncRecord, error) {
+	query := `SELECT ` + syncRecordColumns + `
+		FROM sync_history
+		WHERE sync_type = ? AND freee_id = ?
+	`
+
+	record, err := scanSyncRecord(s.conn.QueryRow(query, string(syncType), freeeID))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -117,51 +266,27 @@ func (s *SyncHistory) GetSyncRecord(syncType SyncType, freeeID int64) (*SyncReco
 		return nil, fmt.Errorf("failed to get sync record: %w", err)
 	}
 
-	record.SyncType = SyncType(syncTypeStr)
 	return &record, nil
 }
 
 // GetSyncRecordsByType retrieves all sync records for a specific type.
 func (s *SyncHistory) GetSyncRecordsByType(syncType SyncType) ([]SyncRecord, error) {
-	query := `
-		SELECT id, sync_type, freee_id, issue_date, amount, beancount_file, synced_at
+	query := `SELECT ` + syncRecordColumns + `
 		FROM sync_history
 		WHERE sync_type = ?
 		ORDER BY issue_date DESC
 	`
 
-	rows, err := s.conn.Query(query, string(syncType))
+	records, err := s.querySyncRecords(query, string(syncType))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get sync records by type: %w", err)
 	}
-	defer rows.Close()
-
-	var records []SyncRecord
-	for rows.Next() {
-		var record SyncRecord
-		var syncTypeStr string
-
-		if err := rows.Scan(
-			&record.ID,
-			&syncTypeStr,
-			&record.FreeeID,
-			&record.IssueDate,
-			&record.Amount,
-			&record.BeancountFile,
-			&record.SyncedAt,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan sync record: %w", err)
-		}
-
-		record.SyncType = SyncType(syncTypeStr)
-		records = append(records, record)
-	}
 
 	return records, nil
 }
 
-// GetSyncedIDs retrieves all synced freee IDs for a specific type.
-// This is useful for bulk filtering.
+// GetSyncedIDs retrieves all synced freee IDs for a specific type,
+// including pending ones. This is useful for bulk filtering.
 func (s *SyncHistory) GetSyncedIDs(syncType SyncType) ([]int64, error) {
 	query := `
 		SELECT freee_id FROM sync_history WHERE sync_type = ?
@@ -185,6 +310,21 @@ func (s *SyncHistory) GetSyncedIDs(syncType SyncType) ([]int64, error) {
 	return ids, nil
 }
 
+// CountSynced returns the number of items of a type synced to the ledger
+// with an issue date in from..to (YYYY-MM-DD).
+func (s *SyncHistory) CountSynced(syncType SyncType, from, to string) (int, error) {
+	query := `
+		SELECT COUNT(*) FROM sync_history
+		WHERE sync_type = ? AND status = ? AND issue_date BETWEEN ? AND ?
+	`
+
+	var count int
+	if err := s.conn.QueryRow(query, string(syncType), SyncStatusCommitted, from, to).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count synced items: %w", err)
+	}
+	return count, nil
+}
+
 // DeleteSyncRecord deletes a sync record.
 // Use case: Force re-sync of a specific deal/journal.
 func (s *SyncHistory) DeleteSyncRecord(syncType SyncType, freeeID int64) (bool, error) {
@@ -203,6 +343,40 @@ func (s *SyncHistory) DeleteSyncRecord(syncType SyncType, freeeID int64) (bool,
 	return rows > 0, nil
 }
 
+// DeleteSyncRecordsByMonth deletes all sync records whose issue date falls
+// in a month (YYYY-MM) and returns how many were deleted.
+func (s *SyncHistory) DeleteSyncRecordsByMonth(yearMonth string) (int64, error) {
+	query := `DELETE FROM sync_history WHERE issue_date LIKE ?`
+
+	result, err := s.conn.Exec(query, yearMonth+"-%")
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sync records: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows, nil
+}
+
+// GetSyncRecordsByFile retrieves all sync records written to a Beancount file.
+func (s *SyncHistory) GetSyncRecordsByFile(beancountFile string) ([]SyncRecord, error) {
+	query := `SELECT ` + syncRecordColumns + `
+		FROM sync_history
+		WHERE beancount_file = ?
+		ORDER BY issue_date, sync_type, freee_id
+	`
+
+	records, err := s.querySyncRecords(query, beancountFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sync records by file: %w", err)
+	}
+
+	return records, nil
+}
+
 // RecordDocumentAttachment records a document attachment.
 func (s *SyncHistory) RecordDocumentAttachment(attachment DocumentAttachment) error {
 	query := `
@@ -260,6 +434,69 @@ func (s *SyncHistory) GetDocumentAttachments(dealID int64) ([]DocumentAttachment
 	return attachments, nil
 }
 
+// CountDocumentAttachments returns the number of documents attached to
+// each deal with a transaction date in from..to (YYYY-MM-DD).
+func (s *SyncHistory) CountDocumentAttachments(from, to string) (map[int64]int, error) {
+	query := `
+		SELECT deal_id, COUNT(*) FROM document_attachments
+		WHERE deal_id IS NOT NULL AND transaction_date BETWEEN ? AND ?
+		GROUP BY deal_id
+	`
+
+	rows, err := s.conn.Query(query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count document attachments: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[int64]int)
+	for rows.Next() {
+		var dealID int64
+		var count int
+		if err := rows.Scan(&dealID, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan document attachment count: %w", err)
+		}
+		counts[dealID] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// ListDocumentAttachments returns the documents attached to deals with a
+// transaction date in from..to (YYYY-MM-DD).
+func (s *SyncHistory) ListDocumentAttachments(from, to string) ([]DocumentAttachment, error) {
+	query := `
+		SELECT id, transaction_date, ref_number, deal_id, document_path, attached_at
+		FROM document_attachments
+		WHERE deal_id IS NOT NULL AND transaction_date BETWEEN ? AND ?
+		ORDER BY transaction_date, id
+	`
+
+	rows, err := s.conn.Query(query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list document attachments: %w", err)
+	}
+	defer rows.Close()
+
+	var attachments []DocumentAttachment
+	for rows.Next() {
+		var attachment DocumentAttachment
+		if err := rows.Scan(
+			&attachment.ID,
+			&attachment.TransactionDate,
+			&attachment.RefNumber,
+			&attachment.DealID,
+			&attachment.DocumentPath,
+			&attachment.AttachedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan document attachment: %w", err)
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, rows.Err()
+}
+
 // IsDocumentAttached checks if a document has been attached.
 func (s *SyncHistory) IsDocumentAttached(documentPath string) (bool, error) {
 	query := `
@@ -280,6 +517,7 @@ func (s *SyncHistory) IsDocumentAttached(documentPath string) (bool, error) {
 type Stats struct {
 	TotalDeals     int
 	TotalJournals  int
+	TotalTransfers int
 	TotalDocuments int
 	LastSync       sql.NullString
 }
@@ -289,17 +527,23 @@ func (s *SyncHistory) GetStats() (*Stats, error) {
 	var stats Stats
 
 	// Get deal count
-	err := s.conn.QueryRow(`SELECT COUNT(*) FROM sync_history WHERE sync_type = 'deal'`).Scan(&stats.TotalDeals)
+	err := s.conn.QueryRow(`SELECT COUNT(*) FROM sync_history WHERE sync_type = 'deal' AND status = 'committed'`).Scan(&stats.TotalDeals)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get deal count: %w", err)
 	}
 
 	// Get journal count
-	err = s.conn.QueryRow(`SELECT COUNT(*) FROM sync_history WHERE sync_type = 'journal'`).Scan(&stats.TotalJournals)
+	err = s.conn.QueryRow(`SELECT COUNT(*) FROM sync_history WHERE sync_type = 'journal' AND status = 'committed'`).Scan(&stats.TotalJournals)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get journal count: %w", err)
 	}
 
+	// Get transfer count
+	err = s.conn.QueryRow(`SELECT COUNT(*) FROM sync_history WHERE sync_type = 'transfer' AND status = 'committed'`).Scan(&stats.TotalTransfers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfer count: %w", err)
+	}
+
 	// Get document count
 	err = s.conn.QueryRow(`SELECT COUNT(*) FROM document_attachments`).Scan(&stats.TotalDocuments)
 	if err != nil {
@@ -348,3 +592,13 @@ func (s *SyncHistory) SetMetadata(key, value string) error {
 
 	return nil
 }
+
+// nullInt64 converts zero to NULL for optional integer columns.
+func nullInt64(v int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: v, Valid: v != 0}
+}
+
+// nullString converts an empty string to NULL for optional text columns.
+func nullString(v string) sql.NullString {
+	return sql.NullString{String: v, Valid: v != ""}
+}
@@ -0,0 +1,233 @@
+package db
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestHistory(t *testing.T) *SyncHistory {
+	t.Helper()
+	conn, err := Open(filepath.Join(t.TempDir(), "sync.db"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewSyncHistory(conn)
+}
+
+func TestTwoPhaseSync(t *testing.T) {
+	history := openTestHistory(t)
+
+	record := SyncRecord{
+		SyncType: SyncTypeDeal, FreeeID: 1, IssueDate: "2024-01-15", Amount: 1000,
+		BeancountFile: "2024/2024-01.beancount",
+	}
+	if err := history.BeginSync(record); err != nil {
+		t.Fatalf("BeginSync() error = %v", err)
+	}
+
+	pending, err := history.GetPendingSyncs()
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("GetPendingSyncs() = %v, %v, expected one record", pending, err)
+	}
+	stats, _ := history.GetStats()
+	if stats.TotalDeals != 0 {
+		t.Errorf("GetStats().TotalDeals = %d, pending records must not be counted", stats.TotalDeals)
+	}
+
+	if err := history.CommitSync(SyncTypeDeal, 1); err != nil {
+		t.Fatalf("CommitSync() error = %v", err)
+	}
+	got, _ := history.GetSyncRecord(SyncTypeDeal, 1)
+	if got == nil || got.Status != SyncStatusCommitted {
+		t.Fatalf("GetSyncRecord() = %+v, expected committed", got)
+	}
+
+	// Aborting never removes a committed record
+	if err := history.AbortSync(SyncTypeDeal, 1); err != nil {
+		t.Fatalf("AbortSync() error = %v", err)
+	}
+	if got, _ := history.GetSyncRecord(SyncTypeDeal, 1); got == nil {
+		t.Error("AbortSync() removed a committed record")
+	}
+
+	record.FreeeID = 2
+	if err := history.BeginSync(record); err != nil {
+		t.Fatalf("BeginSync() error = %v", err)
+	}
+	if err := history.AbortSync(SyncTypeDeal, 2); err != nil {
+		t.Fatalf("AbortSync() error = %v", err)
+	}
+	if got, _ := history.GetSyncRecord(SyncTypeDeal, 2); got != nil {
+		t.Errorf("AbortSync() left pending record %+v", got)
+	}
+
+	if err := history.CommitSync(SyncTypeDeal, 3); err == nil {
+		t.Error("CommitSync() of unknown record succeeded")
+	}
+}
+
+func TestFailInterruptedRuns(t *testing.T) {
+	history := openTestHistory(t)
+
+	run := &SyncRun{DateFrom: "2024-01-01", DateTo: "2024-01-31"}
+	if err := history.StartRun(run); err != nil {
+		t.Fatalf("StartRun() error = %v", err)
+	}
+
+	count, err := history.FailInterruptedRuns()
+	if err != nil || count != 1 {
+		t.Fatalf("FailInterruptedRuns() = %d, %v, expected 1", count, err)
+	}
+
+	got, err := history.GetRun(run.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetRun() = %v, %v", got, err)
+	}
+	if got.Status != RunStatusFailed || got.FinishedAt == nil || len(got.Errors) != 1 {
+		t.Errorf("GetRun() = %+v, expected failed run with one error", got)
+	}
+}
+
+func TestWatermark(t *testing.T) {
+	history := openTestHistory(t)
+
+	got, err := history.GetWatermark("deals", 1)
+	if err != nil || !got.IsZero() {
+		t.Fatalf("GetWatermark() = %v, %v, expected zero time", got, err)
+	}
+
+	later := time.Date(2024, 2, 1, 10, 0, 0, 500, time.UTC)
+	earlier := later.Add(-time.Hour)
+
+	if err := history.SetWatermark("deals", 1, later); err != nil {
+		t.Fatalf("SetWatermark() error = %v", err)
+	}
+	if err := history.SetWatermark("deals", 1, earlier); err != nil {
+		t.Fatalf("SetWatermark() error = %v", err)
+	}
+
+	got, _ = history.GetWatermark("deals", 1)
+	if !got.Equal(later) {
+		t.Errorf("GetWatermark() = %v, expected %v (watermark must not move backwards)", got, later)
+	}
+
+	// Watermarks are kept per resource and company
+	if got, _ := history.GetWatermark("journals", 1); !got.IsZero() {
+		t.Errorf("GetWatermark(journals) = %v, expected zero time", got)
+	}
+	if got, _ := history.GetWatermark("deals", 2); !got.IsZero() {
+		t.Errorf("GetWatermark(company 2) = %v, expected zero time", got)
+	}
+}
+
+func TestLock(t *testing.T) {
+	history := openTestHistory(t)
+
+	if err := history.AcquireLock(SyncLock, "a", time.Minute); err != nil {
+		t.Fatalf("AcquireLock(a) error = %v", err)
+	}
+	// Re-acquiring by the holder succeeds
+	if err := history.AcquireLock(SyncLock, "a", time.Minute); err != nil {
+		t.Fatalf("AcquireLock(a) again error = %v", err)
+	}
+	if err := history.AcquireLock(SyncLock, "b", time.Minute); !errors.Is(err, ErrLockHeld) {
+		t.Fatalf("AcquireLock(b) error = %v, expected ErrLockHeld", err)
+	}
+	if err := history.RefreshLock(SyncLock, "b", time.Minute); !errors.Is(err, ErrLockHeld) {
+		t.Fatalf("RefreshLock(b) error = %v, expected ErrLockHeld", err)
+	}
+
+	// Releasing by another owner is a no-op
+	if err := history.ReleaseLock(SyncLock, "b"); err != nil {
+		t.Fatalf("ReleaseLock(b) error = %v", err)
+	}
+	if err := history.AcquireLock(SyncLock, "b", time.Minute); !errors.Is(err, ErrLockHeld) {
+		t.Fatalf("AcquireLock(b) after foreign release error = %v, expected ErrLockHeld", err)
+	}
+
+	if err := history.ReleaseLock(SyncLock, "a"); err != nil {
+		t.Fatalf("ReleaseLock(a) error = %v", err)
+	}
+	if err := history.AcquireLock(SyncLock, "b", -time.Second); err != nil {
+		t.Fatalf("AcquireLock(b) after release error = %v", err)
+	}
+
+	// An expired lock is taken over
+	if err := history.AcquireLock(SyncLock, "a", time.Minute); err != nil {
+		t.Fatalf("AcquireLock(a) over expired lock error = %v", err)
+	}
+}
+
+func TestGetSyncedDealDetails(t *testing.T) {
+	history := openTestHistory(t)
+
+	for id, date := range map[int64]string{1: "2024-01-10", 2: "2024-01-20", 3: "2024-02-05"} {
+		record := SyncRecord{SyncType: SyncTypeDeal, FreeeID: id, IssueDate: date, Amount: 1000, BeancountFile: "x.beancount"}
+		if err := history.BeginSync(record); err != nil {
+			t.Fatalf("BeginSync() error = %v", err)
+		}
+		if err := history.CommitSync(SyncTypeDeal, id); err != nil {
+			t.Fatalf("CommitSync() error = %v", err)
+		}
+	}
+	err := history.SaveDealDetails([]DealDetail{
+		{DealID: 1, IssueDate: "2024-01-10", Type: "expense", PartnerCode: "aws", AccountItem: "通信費", Amount: 900},
+		{DealID: 3, IssueDate: "2024-02-05", Type: "expense", AccountItem: "地代家賃", Description: "家賃", Amount: 1000},
+	})
+	if err != nil {
+		t.Fatalf("SaveDealDetails() error = %v", err)
+	}
+
+	details, missing, err := history.GetSyncedDealDetails("2024-01-01", "2024-01-31")
+	if err != nil {
+		t.Fatalf("GetSyncedDealDetails() error = %v", err)
+	}
+	if missing != 1 || len(details) != 1 {
+		t.Fatalf("GetSyncedDealDetails() = %+v, %d missing, expected one deal and one missing", details, missing)
+	}
+	// The synced amount wins over the cached one
+	if d := details[0]; d.DealID != 1 || d.PartnerCode != "aws" || d.Amount != 1000 {
+		t.Errorf("GetSyncedDealDetails()[0] = %+v", d)
+	}
+}
+
+func TestGetSyncedDealLines(t *testing.T) {
+	history := openTestHistory(t)
+
+	for _, id := range []int64{1, 2} {
+		record := SyncRecord{SyncType: SyncTypeDeal, FreeeID: id, IssueDate: "2024-01-10", Amount: 1100, BeancountFile: "x.beancount"}
+		if err := history.BeginSync(record); err != nil {
+			t.Fatalf("BeginSync() error = %v", err)
+		}
+		if err := history.CommitSync(SyncTypeDeal, id); err != nil {
+			t.Fatalf("CommitSync() error = %v", err)
+		}
+	}
+
+	detail := DealDetail{DealID: 1, IssueDate: "2024-01-10", Type: "expense", AccountItem: "消耗品費", Amount: 1100, Lines: []DealLine{
+		{AccountItem: "消耗品費", TaxCode: 136, Amount: 1100, Vat: 100},
+		{AccountItem: "会議費", TaxCode: 163, Amount: 540, Vat: 40},
+	}}
+	if err := history.SaveDealDetails([]DealDetail{detail}); err != nil {
+		t.Fatalf("SaveDealDetails() error = %v", err)
+	}
+	// Caching again replaces the lines
+	detail.Lines = detail.Lines[:1]
+	if err := history.SaveDealDetails([]DealDetail{detail}); err != nil {
+		t.Fatalf("SaveDealDetails() error = %v", err)
+	}
+
+	lines, missing, err := history.GetSyncedDealLines("2024-01-01", "2024-01-31")
+	if err != nil {
+		t.Fatalf("GetSyncedDealLines() error = %v", err)
+	}
+	if missing != 1 || len(lines) != 1 {
+		t.Fatalf("GetSyncedDealLines() = %+v, %d missing, expected one line and one missing deal", lines, missing)
+	}
+	if l := lines[0]; l.DealID != 1 || l.Type != "expense" || l.TaxCode != 136 || l.Vat != 100 {
+		t.Errorf("GetSyncedDealLines()[0] = %+v", l)
+	}
+}
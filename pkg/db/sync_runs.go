@@ -0,0 +1,254 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RunStatus represents the outcome of a sync run.
+type RunStatus string
+
+const (
+	RunStatusRunning   RunStatus = "running"
+	RunStatusSucceeded RunStatus = "succeeded"
+	RunStatusPartial   RunStatus = "partial"
+	RunStatusFailed    RunStatus = "failed"
+)
+
+// SyncRun represents one execution of the sync command.
+type SyncRun struct {
+	ID         int64      `json:"id"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Status     RunStatus  `json:"status"`
+	DateFrom   string     `json:"date_from"`
+	DateTo     string     `json:"date_to"`
+	CompanyID  int64      `json:"company_id"`
+	Fetched    int        `json:"fetched"`
+	New        int        `json:"new"`
+	Updated    int        `json:"updated"`
+	Failed     int        `json:"failed"`
+	Errors     []string   `json:"errors"`
+	Files      []string   `json:"files"`
+}
+
+// AddError records an error message and counts a failed item.
+func (r *SyncRun) AddError(msg string) {
+	r.Errors = append(r.Errors, msg)
+	r.Failed++
+}
+
+// AddFile records a Beancount file touched by the run (once).
+func (r *SyncRun) AddFile(path string) {
+	for _, f := range r.Files {
+		if f == path {
+			return
+		}
+	}
+	r.Files = append(r.Files, path)
+}
+
+// FinalStatus derives the status of a finished run from its counts.
+func (r *SyncRun) FinalStatus() RunStatus {
+	switch {
+	case r.Failed == 0:
+		return RunStatusSucceeded
+	case r.New+r.Updated > 0:
+		return RunStatusPartial
+	default:
+		return RunStatusFailed
+	}
+}
+
+// StartRun inserts a new run in the running state and sets its ID.
+func (s *SyncHistory) StartRun(run *SyncRun) error {
+	if run.StartedAt.IsZero() {
+		run.StartedAt = time.Now().UTC()
+	}
+	run.Status = RunStatusRunning
+
+	result, err := s.conn.Exec(`
+		INSERT INTO sync_runs (started_at, status, date_from, date_to, company_id)
+		VALUES (?, ?, ?, ?, ?)
+	`, run.StartedAt, string(run.Status), run.DateFrom, run.DateTo, nullInt64(run.CompanyID))
+	if err != nil {
+		return fmt.Errorf("failed to start sync run: %w", err)
+	}
+
+	run.ID, err = result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get sync run ID: %w", err)
+	}
+
+	return nil
+}
+
+// FinishRun stores the final counts, errors and files of a run.
+// If the status is still running, it is derived from the counts.
+func (s *SyncHistory) FinishRun(run *SyncRun) error {
+	now := time.Now().UTC()
+	run.FinishedAt = &now
+	if run.Status == "" || run.Status == RunStatusRunning {
+		run.Status = run.FinalStatus()
+	}
+
+	errorsJSON, err := marshalStrings(run.Errors)
+	if err != nil {
+		return err
+	}
+	filesJSON, err := marshalStrings(run.Files)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.conn.Exec(`
+		UPDATE sync_runs SET
+			finished_at = ?,
+			status = ?,
+			fetched_count = ?,
+			new_count = ?,
+			updated_count = ?,
+			failed_count = ?,
+			errors = ?,
+			files = ?
+		WHERE id = ?
+	`, now, string(run.Status), run.Fetched, run.New, run.Updated, run.Failed, errorsJSON, filesJSON, run.ID)
+	if err != nil {
+		return fmt.Errorf("failed to finish sync run: %w", err)
+	}
+
+	return nil
+}
+
+// FailInterruptedRuns marks runs still in the running state as failed.
+// It is called with SyncLock held, when no other run can be in progress,
+// and returns the number of runs that were left behind by a crash.
+func (s *SyncHistory) FailInterruptedRuns() (int64, error) {
+	result, err := s.conn.Exec(`
+		UPDATE sync_runs SET
+			status = ?,
+			finished_at = ?,
+			errors = json_insert(errors, '$[#]', 'interrupted before completion')
+		WHERE status = ?
+	`, string(RunStatusFailed), time.Now().UTC(), string(RunStatusRunning))
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark interrupted runs: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows, nil
+}
+
+// syncRunColumns is the column list read by scanSyncRun.
+const syncRunColumns = `
+	id, started_at, finished_at, status, date_from, date_to, COALESCE(company_id, 0),
+	fetched_count, new_count, updated_count, failed_count, errors, files
+`
+
+// scanSyncRun scans a row selected with syncRunColumns.
+func scanSyncRun(row rowScanner) (SyncRun, error) {
+	var run SyncRun
+	var finishedAt sql.NullTime
+	var status, errorsJSON, filesJSON string
+
+	if err := row.Scan(
+		&run.ID,
+		&run.StartedAt,
+		&finishedAt,
+		&status,
+		&run.DateFrom,
+		&run.DateTo,
+		&run.CompanyID,
+		&run.Fetched,
+		&run.New,
+		&run.Updated,
+		&run.Failed,
+		&errorsJSON,
+		&filesJSON,
+	); err != nil {
+		return run, err
+	}
+
+	run.Status = RunStatus(status)
+	if finishedAt.Valid {
+		run.FinishedAt = &finishedAt.Time
+	}
+	if err := json.Unmarshal([]byte(errorsJSON), &run.Errors); err != nil {
+		return run, fmt.Errorf("invalid errors for run %d: %w", run.ID, err)
+	}
+	if err := json.Unmarshal([]byte(filesJSON), &run.Files); err != nil {
+		return run, fmt.Errorf("invalid files for run %d: %w", run.ID, err)
+	}
+
+	return run, nil
+}
+
+// GetRun retrieves a sync run by ID. Returns nil if it does not exist.
+func (s *SyncHistory) GetRun(id int64) (*SyncRun, error) {
+	query := `SELECT ` + syncRunColumns + ` FROM sync_runs WHERE id = ?`
+
+	run, err := scanSyncRun(s.conn.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sync run: %w", err)
+	}
+
+	return &run, nil
+}
+
+// ListRuns retrieves the most recent sync runs, newest first.
+func (s *SyncHistory) ListRuns(limit int) ([]SyncRun, error) {
+	query := `SELECT ` + syncRunColumns + ` FROM sync_runs ORDER BY id DESC LIMIT ?`
+
+	rows, err := s.conn.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sync runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []SyncRun
+	for rows.Next() {
+		run, err := scanSyncRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sync run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
+
+// GetRunRecords retrieves the sync history records written by a run.
+func (s *SyncHistory) GetRunRecords(runID int64) ([]SyncRecord, error) {
+	query := `SELECT ` + syncRecordColumns + `
+		FROM sync_history
+		WHERE run_id = ?
+		ORDER BY issue_date, sync_type, freee_id
+	`
+
+	records, err := s.querySyncRecords(query, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get run records: %w", err)
+	}
+
+	return records, nil
+}
+
+func marshalStrings(values []string) (string, error) {
+	if values == nil {
+		values = []string{}
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode list: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// Resources tracked by incremental sync watermarks.
+const (
+	WatermarkDeals    = "deals"
+	WatermarkJournals = "journals"
+	// Transfers and wallet transactions have no update time; their
+	// watermark is the start of the last successful run and bounds the
+	// dates fetched.
+	WatermarkTransfers  = "transfers"
+	WatermarkWalletTxns = "wallet_txns"
+)
+
+// watermarkKey returns the sync_metadata key holding the watermark of a
+// resource ("deals", "journals", ...) for a company.
+func watermarkKey(resource string, companyID int64) string {
+	return fmt.Sprintf("watermark:%s:%d", resource, companyID)
+}
+
+// GetWatermark retrieves the updated_at watermark of the last successful
+// sync of a resource. It returns the zero time if none has been stored.
+func (s *SyncHistory) GetWatermark(resource string, companyID int64) (time.Time, error) {
+	value, err := s.GetMetadata(watermarkKey(resource, companyID))
+	if err != nil {
+		return time.Time{}, err
+	}
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	watermark, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid watermark for %s: %w", resource, err)
+	}
+
+	return watermark, nil
+}
+
+// SetWatermark stores the updated_at watermark of a resource. A watermark
+// never moves backwards, so an older value is ignored.
+func (s *SyncHistory) SetWatermark(resource string, companyID int64, watermark time.Time) error {
+	current, err := s.GetWatermark(resource, companyID)
+	if err != nil {
+		return err
+	}
+	if !watermark.After(current) {
+		return nil
+	}
+
+	return s.SetMetadata(watermarkKey(resource, companyID), watermark.UTC().Format(time.RFC3339Nano))
+}
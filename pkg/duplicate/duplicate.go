@@ -0,0 +1,261 @@
+// Package duplicate finds deals booked twice, such as a receipt entered by
+// auto-fetch and again by hand in freee, or in freee and again in the ledger.
+package duplicate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Entry is a booked deal or ledger transaction.
+type Entry struct {
+	Source      string   `json:"source"` // freee or beancount
+	ID          string   `json:"id"`     // e.g. deal:123, or file:line for ledger entries
+	Type        string   `json:"type"`   // income or expense
+	Date        string   `json:"date"`   // YYYY-MM-DD
+	Amount      int64    `json:"amount"`
+	Partner     string   `json:"partner,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Receipts    []string `json:"receipts,omitempty"` // SHA-256 of the attached receipts
+}
+
+// text is what the similarity of entries is judged on.
+func (e Entry) text() string {
+	return strings.TrimSpace(e.Partner + " " + e.Description)
+}
+
+// Options configures what is a suspected duplicate.
+type Options struct {
+	// WindowDays is how many days apart entries of the same amount may be.
+	WindowDays int
+	// MinSimilarity is the similarity (0..1) of partner and description
+	// above which entries of the same amount are duplicates. Entries
+	// without a partner or description are judged on amount and date alone.
+	MinSimilarity float64
+}
+
+// Match is a suspected duplicate of an entry.
+type Match struct {
+	Entry      Entry    `json:"entry"`
+	Similarity float64  `json:"similarity"`
+	Reasons    []string `json:"reasons"`
+}
+
+// Group is a set of entries suspected to be the same deal.
+type Group struct {
+	Entries []Entry  `json:"entries"`
+	Reasons []string `json:"reasons"`
+}
+
+// Check returns the entries of existing that candidate is suspected to
+// duplicate, most similar first. It is the pre-check before booking
+// candidate.
+func Check(candidate Entry, existing []Entry, opts Options) []Match {
+	var matches []Match
+	for _, e := range existing {
+		if e.ID == candidate.ID && e.Source == candidate.Source {
+			continue
+		}
+		if similarity, reasons, ok := compare(candidate, e, opts); ok {
+			matches = append(matches, Match{Entry: e, Similarity: similarity, Reasons: reasons})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Similarity > matches[j].Similarity })
+	return matches
+}
+
+// Find groups the suspected duplicates among entries. Entries suspected to
+// duplicate the same entry are in one group; groups are in date order.
+func Find(entries []Entry, opts Options) []Group {
+	sorted := append([]Entry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date < sorted[j].Date })
+
+	parent := make([]int, len(sorted))
+	for i := range parent {
+		parent[i] = i
+	}
+	var root func(int) int
+	root = func(i int) int {
+		if parent[i] != i {
+			parent[i] = root(parent[i])
+		}
+		return parent[i]
+	}
+
+	reasons := make(map[int][]string)
+	for i := range sorted {
+		for j := i + 1; j < len(sorted); j++ {
+			_, why, ok := compare(sorted[i], sorted[j], opts)
+			if !ok {
+				continue
+			}
+			ri, rj := root(i), root(j)
+			if ri != rj {
+				parent[rj] = ri
+				reasons[ri] = append(reasons[ri], reasons[rj]...)
+				delete(reasons, rj)
+			}
+			reasons[ri] = appendUnique(reasons[ri], why...)
+		}
+	}
+
+	members := make(map[int][]Entry)
+	var roots []int
+	for i, e := range sorted {
+		r := root(i)
+		if members[r] == nil {
+			roots = append(roots, r)
+		}
+		members[r] = append(members[r], e)
+	}
+
+	var groups []Group
+	for _, r := range roots {
+		if len(members[r]) > 1 {
+			groups = append(groups, Group{Entries: members[r], Reasons: reasons[r]})
+		}
+	}
+	return groups
+}
+
+// compare reports whether a and b are suspected duplicates, with the
+// similarity of their text and the reasons.
+func compare(a, b Entry, opts Options) (float64, []string, bool) {
+	similarity := Similarity(a.text(), b.text())
+
+	for _, ha := range a.Receipts {
+		for _, hb := range b.Receipts {
+			if ha == hb {
+				return similarity, []string{"same receipt"}, true
+			}
+		}
+	}
+
+	if a.Type != b.Type || a.Amount != b.Amount {
+		return 0, nil, false
+	}
+	days, ok := daysApart(a.Date, b.Date)
+	if !ok || days > opts.WindowDays {
+		return 0, nil, false
+	}
+
+	reasons := []string{"same amount"}
+	if days == 0 {
+		reasons = append(reasons, "same date")
+	} else {
+		reasons = append(reasons, fmt.Sprintf("%d days apart", days))
+	}
+	switch {
+	case a.text() == "" || b.text() == "":
+		return similarity, reasons, true
+	case similarity >= opts.MinSimilarity:
+		return similarity, append(reasons, fmt.Sprintf("%.0f%% similar", similarity*100)), true
+	default:
+		return 0, nil, false
+	}
+}
+
+// HashFile returns the SHA-256 of a receipt file, for Entry.Receipts.
+func HashFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read receipt: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// daysApart returns the number of days between two dates.
+func daysApart(a, b string) (int, bool) {
+	ta, errA := time.Parse("2006-01-02", a)
+	tb, errB := time.Parse("2006-01-02", b)
+	if errA != nil || errB != nil {
+		return 0, false
+	}
+	days := int(ta.Sub(tb).Hours() / 24)
+	if days < 0 {
+		days = -days
+	}
+	return days, true
+}
+
+// Similarity returns how similar two partner names or descriptions are,
+// from 0 to 1: 1 if one contains the other, otherwise the Dice coefficient
+// of their character bigrams. Case, width, spaces and punctuation are
+// ignored, so "AMAZON.CO.JP" and "ａｍａｚｏｎ" are alike.
+func Similarity(a, b string) float64 {
+	na, nb := normalize(a), normalize(b)
+	if len(na) == 0 || len(nb) == 0 {
+		return 0
+	}
+	if strings.Contains(string(na), string(nb)) || strings.Contains(string(nb), string(na)) {
+		return 1
+	}
+
+	ba, bb := bigrams(na), bigrams(nb)
+	if len(ba) == 0 || len(bb) == 0 {
+		return 0
+	}
+	counts := make(map[string]int, len(ba))
+	for _, g := range ba {
+		counts[g]++
+	}
+	shared := 0
+	for _, g := range bb {
+		if counts[g] > 0 {
+			counts[g]--
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(ba)+len(bb))
+}
+
+// normalize folds case and full-width letters and digits, and drops
+// spaces and punctuation.
+func normalize(s string) []rune {
+	var runes []rune
+	for _, r := range s {
+		if r >= '！' && r <= '～' {
+			r -= '！' - '!'
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			runes = append(runes, unicode.ToLower(r))
+		}
+	}
+	return runes
+}
+
+// bigrams returns the pairs of adjacent characters of s.
+func bigrams(s []rune) []string {
+	if len(s) < 2 {
+		return nil
+	}
+	grams := make([]string, 0, len(s)-1)
+	for i := 0; i+1 < len(s); i++ {
+		grams = append(grams, string(s[i:i+2]))
+	}
+	return grams
+}
+
+// appendUnique appends the values not in list yet.
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		found := false
+		for _, l := range list {
+			if l == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -0,0 +1,89 @@
+package duplicate
+
+import "testing"
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		min  float64
+		max  float64
+	}{
+		{"AMAZON.CO.JP", "Amazon", 1, 1},
+		{"ａｍａｚｏｎ", "AMAZON", 1, 1},
+		{"Amazon", "Google", 0, 0.1},
+		{"東京電力 電気料金", "東京電力エナジーパートナー", 0.2, 0.5},
+		{"", "Amazon", 0, 0},
+	}
+
+	for _, tt := range tests {
+		got := Similarity(tt.a, tt.b)
+		if got < tt.min || got > tt.max {
+			t.Errorf("Similarity(%q, %q) = %.2f, expected %.2f..%.2f", tt.a, tt.b, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	opts := Options{WindowDays: 3, MinSimilarity: 0.5}
+	candidate := Entry{Source: "wallet_txn", ID: "wallet_txn:1", Type: "expense", Date: "2024-03-10", Amount: 1980, Description: "AMAZON.CO.JP"}
+
+	tests := []struct {
+		name  string
+		entry Entry
+		want  bool
+	}{
+		{"same deal by hand", Entry{ID: "deal:1", Type: "expense", Date: "2024-03-12", Amount: 1980, Partner: "Amazon"}, true},
+		{"no description", Entry{ID: "deal:2", Type: "expense", Date: "2024-03-10", Amount: 1980}, true},
+		{"other vendor", Entry{ID: "deal:3", Type: "expense", Date: "2024-03-10", Amount: 1980, Description: "Google Workspace"}, false},
+		{"outside window", Entry{ID: "deal:4", Type: "expense", Date: "2024-03-14", Amount: 1980, Description: "Amazon"}, false},
+		{"other amount", Entry{ID: "deal:5", Type: "expense", Date: "2024-03-10", Amount: 1000, Description: "Amazon"}, false},
+		{"income", Entry{ID: "deal:6", Type: "income", Date: "2024-03-10", Amount: 1980, Description: "Amazon"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := Check(candidate, []Entry{tt.entry}, opts)
+			if got := len(matches) == 1; got != tt.want {
+				t.Errorf("Check() = %+v, expected duplicate %v", matches, tt.want)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	opts := Options{WindowDays: 3, MinSimilarity: 0.5}
+	entries := []Entry{
+		{Source: "freee", ID: "deal:1", Type: "expense", Date: "2024-03-10", Amount: 1980, Description: "Amazon"},
+		{Source: "freee", ID: "deal:2", Type: "expense", Date: "2024-03-01", Amount: 5000, Description: "Office chair", Receipts: []string{"abc"}},
+		{Source: "beancount", ID: "2024/2024-03.beancount:12", Type: "expense", Date: "2024-03-11", Amount: 1980, Description: "AMAZON.CO.JP"},
+		{Source: "freee", ID: "deal:3", Type: "expense", Date: "2024-03-20", Amount: 5500, Description: "Desk", Receipts: []string{"abc"}},
+		{Source: "freee", ID: "deal:4", Type: "expense", Date: "2024-03-12", Amount: 1980, Description: "Amazon"},
+		{Source: "freee", ID: "deal:5", Type: "expense", Date: "2024-03-10", Amount: 700, Description: "Coffee"},
+	}
+
+	groups := Find(entries, opts)
+	if len(groups) != 2 {
+		t.Fatalf("Find() = %+v, expected two groups", groups)
+	}
+
+	if ids := groupIDs(groups[0]); ids != "deal:2 deal:3" {
+		t.Errorf("Find()[0] = %s, expected the entries with the same receipt", ids)
+	}
+	if groups[0].Reasons[0] != "same receipt" {
+		t.Errorf("Find()[0].Reasons = %v", groups[0].Reasons)
+	}
+	if ids := groupIDs(groups[1]); ids != "deal:1 2024/2024-03.beancount:12 deal:4" {
+		t.Errorf("Find()[1] = %s, expected the Amazon entries", ids)
+	}
+}
+
+func groupIDs(g Group) string {
+	var ids string
+	for i, e := range g.Entries {
+		if i > 0 {
+			ids += " "
+		}
+		ids += e.ID
+	}
+	return ids
+}
@@ -0,0 +1,64 @@
+package duplicate
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
+)
+
+// LedgerEntry returns a ledger transaction for duplicate detection, or
+// false if it books no expenses or income and so is not a deal. Entries
+// synced from freee are identified by their freee key, others by their
+// location. With hashReceipts the files of the document metadata are
+// hashed into Receipts.
+func LedgerEntry(txn beancount.Transaction, currency string, hashReceipts bool) (Entry, bool) {
+	if txn.IsClosing() {
+		return Entry{}, false
+	}
+
+	entry := Entry{
+		Source:      "beancount",
+		ID:          txn.FreeeKey(),
+		Date:        txn.Date,
+		Partner:     txn.Payee,
+		Description: txn.Narration,
+	}
+	if entry.ID == "" {
+		entry.ID = fmt.Sprintf("%s:%d", txn.Source.File, txn.Source.Line)
+	}
+	var expenses, income float64
+	for account, amount := range txn.Amounts(currency) {
+		switch {
+		case strings.HasPrefix(account, "Expenses:"):
+			expenses += amount
+		case strings.HasPrefix(account, "Income:"):
+			income -= amount
+		}
+	}
+	switch {
+	case expenses > 0:
+		entry.Type, entry.Amount = "expense", int64(expenses+0.5)
+	case income > 0:
+		entry.Type, entry.Amount = "income", int64(income+0.5)
+	default:
+		return Entry{}, false
+	}
+
+	if hashReceipts {
+		for key, path := range txn.Metadata {
+			if key != "document" && !strings.HasPrefix(key, "document-") {
+				continue
+			}
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(filepath.Dir(txn.Source.File), path)
+			}
+			if hash, err := HashFile(path); err == nil {
+				entry.Receipts = append(entry.Receipts, hash)
+			}
+		}
+	}
+
+	return entry, true
+}
@@ -126,19 +126,55 @@ func (c *Client) ListDeals(params map[string]string) ([]Deal, error) {
 	return dealsResp.Deals, nil
 }
 
+// GetDeal retrieves a single deal by ID.
+func (c *Client) GetDeal(id int64) (*Deal, error) {
+	endpoint := fmt.Sprintf("%s/api/1/deals/%d", c.baseURL, id)
+
+	queryParams := url.Values{}
+	queryParams.Set("company_id", fmt.Sprintf("%d", c.companyID))
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s?%s", endpoint, queryParams.Encode()), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.accessToken))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.parseError(resp)
+	}
+
+	var dealResp DealResponse
+	if err := json.NewDecoder(resp.Body).Decode(&dealResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &dealResp.Deal, nil
+}
+
 // FetchAllDeals fetches all deals in a date range with pagination.
 func (c *Client) FetchAllDeals(dateFrom, dateTo string) ([]Deal, error) {
+	return c.FetchDealsUpdatedSince(dateFrom, dateTo, time.Time{})
+}
+
+// FetchDealsUpdatedSince fetches deals updated at or after since with pagination.
+// Empty dateFrom/dateTo and a zero since leave the respective filter out.
+func (c *Client) FetchDealsUpdatedSince(dateFrom, dateTo string, since time.Time) ([]Deal, error) {
 	var allDeals []Deal
 	offset := 0
 	limit := 100
 
 	for {
-		params := map[string]string{
-			"issue_date_from": dateFrom,
-			"issue_date_to":   dateTo,
-			"limit":           fmt.Sprintf("%d", limit),
-			"offset":          fmt.Sprintf("%d", offset),
-		}
+		params := fetchParams(dateFrom, dateTo, since)
+		params["limit"] = fmt.Sprintf("%d", limit)
+		params["offset"] = fmt.Sprintf("%d", offset)
 
 		deals, err := c.ListDeals(params)
 		if err != nil {
@@ -149,7 +185,12 @@ func (c *Client) FetchAllDeals(dateFrom, dateTo string) ([]Deal, error) {
 			break
 		}
 
-		allDeals = append(allDeals, deals...)
+		// Filter locally as well, in case the server ignores updated_at_from
+		for _, item := range deals {
+			if since.IsZero() || !item.UpdatedAt.Before(since) {
+				allDeals = append(allDeals, item)
+			}
+		}
 
 		if len(deals) < limit {
 			break
@@ -161,76 +202,100 @@ func (c *Client) FetchAllDeals(dateFrom, dateTo string) ([]Deal, error) {
 	return allDeals, nil
 }
 
-// ListJournals lists journals with optional parameters.
-func (c *Client) ListJournals(params map[string]string) ([]Journal, error) {
-	endpoint := fmt.Sprintf("%s/api/1/journals", c.baseURL)
+// DownloadReceipt downloads the file of a receipt.
+// It returns the file content and its MIME type.
+func (c *Client) DownloadReceipt(receiptID int64) ([]byte, string, error) {
+	data, contentType, err := c.getFile(fmt.Sprintf("/api/1/receipts/%d/download", receiptID))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download receipt: %w", err)
+	}
+	return data, contentType, nil
+}
+
+// getFile performs an authenticated GET request against the API and
+// returns the response body and its MIME type. company_id is added to the
+// query parameters.
+func (c *Client) getFile(path string) ([]byte, string, error) {
+	endpoint := fmt.Sprintf("%s%s", c.baseURL, path)
 
-	// Build query parameters
 	queryParams := url.Values{}
 	queryParams.Set("company_id", fmt.Sprintf("%d", c.companyID))
-	for k, v := range params {
-		queryParams.Set(k, v)
-	}
 
 	req, err := http.NewRequest("GET", fmt.Sprintf("%s?%s", endpoint, queryParams.Encode()), nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.accessToken))
-	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return nil, "", fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, c.parseError(resp)
+		return nil, "", c.parseError(resp)
 	}
 
-	var journalsResp JournalsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&journalsResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read response: %w", err)
 	}
 
-	return journalsResp.Journals, nil
+	return data, resp.Header.Get("Content-Type"), nil
 }
 
-// FetchAllJournals fetches all journals in a date range with pagination.
-func (c *Client) FetchAllJournals(dateFrom, dateTo string) ([]Journal, error) {
-	var allJournals []Journal
-	offset := 0
-	limit := 100
+// getJSON performs an authenticated GET request against the API and decodes
+// the JSON response into out. company_id is added to the query parameters.
+func (c *Client) getJSON(path string, params map[string]string, out interface{}) error {
+	endpoint := fmt.Sprintf("%s%s", c.baseURL, path)
 
-	for {
-		params := map[string]string{
-			"issue_date_from": dateFrom,
-			"issue_date_to":   dateTo,
-			"limit":           fmt.Sprintf("%d", limit),
-			"offset":          fmt.Sprintf("%d", offset),
-		}
+	queryParams := url.Values{}
+	queryParams.Set("company_id", fmt.Sprintf("%d", c.companyID))
+	for k, v := range params {
+		queryParams.Set(k, v)
+	}
 
-		journals, err := c.ListJournals(params)
-		if err != nil {
-			return nil, fmt.Errorf("failed to list journals (offset=%d): %w", offset, err)
-		}
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s?%s", endpoint, queryParams.Encode()), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
 
-		if len(journals) == 0 {
-			break
-		}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.accessToken))
+	req.Header.Set("Content-Type", "application/json")
 
-		allJournals = append(allJournals, journals...)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
 
-		if len(journals) < limit {
-			break
-		}
+	// Asynchronous endpoints such as the journal export answer 202 Accepted
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return c.parseError(resp)
+	}
 
-		offset += limit
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return allJournals, nil
+	return nil
+}
+
+// fetchParams builds the list filters of deals.
+func fetchParams(dateFrom, dateTo string, since time.Time) map[string]string {
+	params := make(map[string]string)
+	if dateFrom != "" {
+		params["issue_date_from"] = dateFrom
+	}
+	if dateTo != "" {
+		params["issue_date_to"] = dateTo
+	}
+	if !since.IsZero() {
+		params["updated_at_from"] = since.UTC().Format(time.RFC3339)
+	}
+	return params
 }
 
 // parseError parses an error response from freee API.
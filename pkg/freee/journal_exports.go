@@ -0,0 +1,231 @@
+package freee
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+// Journal export statuses reported by freee while an export is prepared.
+const (
+	JournalExportEnqueued = "enqueued"
+	JournalExportWorking  = "working"
+	JournalExportUploaded = "uploaded"
+	JournalExportFailed   = "failed"
+)
+
+// JournalExport represents an asynchronous journal export (仕訳帳
+// ダウンロード). freee prepares the file in the background; the export is
+// polled until its status is uploaded and then downloaded.
+type JournalExport struct {
+	ID           int64    `json:"id"`
+	CompanyID    int64    `json:"company_id"`
+	DownloadType string   `json:"download_type"` // csv, pdf, yayoi, generic or generic_v2
+	StartDate    string   `json:"start_date"`
+	EndDate      string   `json:"end_date"`
+	Status       string   `json:"status,omitempty"`
+	Messages     []string `json:"messages,omitempty"`
+}
+
+// JournalExportResponse represents the response from the /api/1/journals
+// endpoints.
+type JournalExportResponse struct {
+	Journals JournalExport `json:"journals"`
+}
+
+// JournalExportLine is a row of a journal export. A row carries a debit
+// side, a credit side or both; the rows of one slip share the date and
+// the 伝票番号.
+type JournalExportLine struct {
+	Date        string // YYYY-MM-DD
+	TxnNumber   string // 伝票番号
+	Adjustment  bool   // 決算整理仕訳
+	Debit       JournalExportSide
+	Credit      JournalExportSide
+	Description string
+}
+
+// JournalExportSide is the debit or credit side of a journal export row.
+// Amount includes Vat; an empty Account means the side is unused.
+type JournalExportSide struct {
+	Account     string
+	TaxCategory string
+	Amount      int64
+	Vat         int64
+}
+
+// RequestJournalExport asks freee to prepare a journal export of the
+// entries dated in a range.
+func (c *Client) RequestJournalExport(downloadType, startDate, endDate string) (*JournalExport, error) {
+	params := map[string]string{
+		"download_type": downloadType,
+		"start_date":    startDate,
+		"end_date":      endDate,
+	}
+
+	var resp JournalExportResponse
+	if err := c.getJSON("/api/1/journals", params, &resp); err != nil {
+		return nil, fmt.Errorf("failed to request journal export: %w", err)
+	}
+	return &resp.Journals, nil
+}
+
+// GetJournalExportStatus retrieves the status of a journal export.
+func (c *Client) GetJournalExportStatus(id int64) (*JournalExport, error) {
+	var resp JournalExportResponse
+	if err := c.getJSON(fmt.Sprintf("/api/1/journals/reports/%d/status", id), nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get journal export status: %w", err)
+	}
+	return &resp.Journals, nil
+}
+
+// DownloadJournalExport downloads the file of an uploaded journal export.
+func (c *Client) DownloadJournalExport(id int64) ([]byte, error) {
+	data, _, err := c.getFile(fmt.Sprintf("/api/1/journals/reports/%d/download", id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download journal export: %w", err)
+	}
+	return data, nil
+}
+
+// ExportJournals requests a journal export, polls its status every
+// interval until it is uploaded and downloads it. It gives up when the
+// export fails or is not ready within timeout.
+func (c *Client) ExportJournals(downloadType, startDate, endDate string, interval, timeout time.Duration) ([]byte, error) {
+	export, err := c.RequestJournalExport(downloadType, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		status, err := c.GetJournalExportStatus(export.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		switch status.Status {
+		case JournalExportUploaded:
+			return c.DownloadJournalExport(export.ID)
+		case JournalExportFailed:
+			return nil, fmt.Errorf("journal export %d failed: %s", export.ID, strings.Join(status.Messages, " "))
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			return nil, fmt.Errorf("journal export %d not ready after %s (status %q)", export.ID, timeout, status.Status)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// ParseJournalExport parses a journal export in the 汎用形式 CSV layout.
+// Columns are looked up by their header so that extra columns are
+// ignored. Rows without a date continue the slip of the previous row.
+// The file may be UTF-8 or Shift_JIS, freee's default for CSV downloads.
+func ParseJournalExport(data []byte) ([]JournalExportLine, error) {
+	data, err := decodeExport(data)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal export: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]int, len(records[0]))
+	for i, name := range records[0] {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, required := range []string{"日付", "借方勘定科目", "借方金額", "貸方勘定科目", "貸方金額"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("journal export has no %s column", required)
+		}
+	}
+
+	var lines []JournalExportLine
+	var previous JournalExportLine
+	for n, record := range records[1:] {
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		side := func(prefix string) (JournalExportSide, error) {
+			s := JournalExportSide{Account: field(prefix + "勘定科目"), TaxCategory: field(prefix + "税区分")}
+			var err error
+			if s.Amount, err = parseExportAmount(field(prefix + "金額")); err != nil {
+				return s, err
+			}
+			s.Vat, err = parseExportAmount(field(prefix + "税額"))
+			return s, err
+		}
+
+		line := JournalExportLine{
+			Date:        strings.ReplaceAll(field("日付"), "/", "-"),
+			TxnNumber:   field("伝票番号"),
+			Adjustment:  field("決算整理仕訳") != "" && field("決算整理仕訳") != "0",
+			Description: field("摘要"),
+		}
+		if line.Date == "" {
+			line.Date, line.TxnNumber, line.Adjustment = previous.Date, previous.TxnNumber, previous.Adjustment
+		}
+		if line.Debit, err = side("借方"); err != nil {
+			return nil, fmt.Errorf("journal export row %d: %w", n+2, err)
+		}
+		if line.Credit, err = side("貸方"); err != nil {
+			return nil, fmt.Errorf("journal export row %d: %w", n+2, err)
+		}
+		if line.Debit.Account == "" && line.Credit.Account == "" {
+			continue
+		}
+
+		lines = append(lines, line)
+		previous = line
+	}
+
+	return lines, nil
+}
+
+// decodeExport returns a journal export as UTF-8 without a byte order
+// mark. Data that is not valid UTF-8 is decoded as Shift_JIS.
+func decodeExport(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if utf8.Valid(data) {
+		return data, nil
+	}
+
+	decoded, err := japanese.ShiftJIS.NewDecoder().Bytes(data)
+	if err != nil || bytes.ContainsRune(decoded, utf8.RuneError) {
+		return nil, fmt.Errorf("journal export is neither UTF-8 nor Shift_JIS encoded")
+	}
+	return decoded, nil
+}
+
+// parseExportAmount parses an amount of a journal export, which may
+// contain thousands separators. An empty amount is zero.
+func parseExportAmount(s string) (int64, error) {
+	s = strings.ReplaceAll(s, ",", "")
+	if s == "" {
+		return 0, nil
+	}
+	amount, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return amount, nil
+}
@@ -0,0 +1,74 @@
+package freee
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ListManualJournals lists manual journals with optional parameters.
+func (c *Client) ListManualJournals(params map[string]string) ([]ManualJournal, error) {
+	var resp ManualJournalsResponse
+	if err := c.getJSON("/api/1/manual_journals", params, &resp); err != nil {
+		return nil, err
+	}
+	return resp.ManualJournals, nil
+}
+
+// GetManualJournal retrieves a single manual journal by ID.
+func (c *Client) GetManualJournal(id int64) (*ManualJournal, error) {
+	var resp ManualJournalResponse
+	if err := c.getJSON(fmt.Sprintf("/api/1/manual_journals/%d", id), nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get manual journal: %w", err)
+	}
+	return &resp.ManualJournal, nil
+}
+
+// FetchAllManualJournals fetches all manual journals in a date range with pagination.
+func (c *Client) FetchAllManualJournals(dateFrom, dateTo string) ([]ManualJournal, error) {
+	return c.FetchManualJournalsUpdatedSince(dateFrom, dateTo, time.Time{})
+}
+
+// FetchManualJournalsUpdatedSince fetches manual journals updated at or
+// after since with pagination. Empty dateFrom/dateTo and a zero since leave
+// the respective filter out.
+func (c *Client) FetchManualJournalsUpdatedSince(dateFrom, dateTo string, since time.Time) ([]ManualJournal, error) {
+	var all []ManualJournal
+	offset := 0
+	limit := 100
+
+	for {
+		params := map[string]string{
+			"limit":  strconv.Itoa(limit),
+			"offset": strconv.Itoa(offset),
+		}
+		if dateFrom != "" {
+			params["start_issue_date"] = dateFrom
+		}
+		if dateTo != "" {
+			params["end_issue_date"] = dateTo
+		}
+		if !since.IsZero() {
+			params["updated_at_from"] = since.UTC().Format(time.RFC3339)
+		}
+
+		journals, err := c.ListManualJournals(params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list manual journals (offset=%d): %w", offset, err)
+		}
+
+		// Filter locally as well, in case the server ignores updated_at_from
+		for _, item := range journals {
+			if since.IsZero() || !item.UpdatedAt.Before(since) {
+				all = append(all, item)
+			}
+		}
+
+		if len(journals) < limit {
+			break
+		}
+		offset += limit
+	}
+
+	return all, nil
+}
@@ -0,0 +1,106 @@
+package freee
+
+import "fmt"
+
+// AccountItem represents an account item (勘定科目) in freee.
+type AccountItem struct {
+	ID              int64  `json:"id"`
+	Name            string `json:"name"`
+	AccountCategory string `json:"account_category"`
+	DefaultTaxCode  int    `json:"default_tax_code"`
+}
+
+// Walletable represents a bank account, credit card or wallet (口座) in freee.
+type Walletable struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"` // bank_account, credit_card or wallet
+}
+
+// TaxCode represents a tax code (税区分) in freee.
+type TaxCode struct {
+	Code   int    `json:"code"`
+	Name   string `json:"name"`
+	NameJa string `json:"name_ja"`
+}
+
+// AccountItemsResponse represents the response from /api/1/account_items endpoint.
+type AccountItemsResponse struct {
+	AccountItems []AccountItem `json:"account_items"`
+}
+
+// WalletablesResponse represents the response from /api/1/walletables endpoint.
+type WalletablesResponse struct {
+	Walletables []Walletable `json:"walletables"`
+}
+
+// TaxCodesResponse represents the response from /api/1/taxes/codes endpoint.
+type TaxCodesResponse struct {
+	Taxes []TaxCode `json:"taxes"`
+}
+
+// ListAccountItems lists the account items of the company.
+func (c *Client) ListAccountItems() ([]AccountItem, error) {
+	var resp AccountItemsResponse
+	if err := c.getJSON("/api/1/account_items", nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to list account items: %w", err)
+	}
+	return resp.AccountItems, nil
+}
+
+// ListWalletables lists the walletables of the company.
+func (c *Client) ListWalletables() ([]Walletable, error) {
+	var resp WalletablesResponse
+	if err := c.getJSON("/api/1/walletables", nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to list walletables: %w", err)
+	}
+	return resp.Walletables, nil
+}
+
+// ListTaxCodes lists the tax codes known to freee.
+func (c *Client) ListTaxCodes() ([]TaxCode, error) {
+	var resp TaxCodesResponse
+	if err := c.getJSON("/api/1/taxes/codes", nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to list tax codes: %w", err)
+	}
+	return resp.Taxes, nil
+}
+
+// Company represents a company (事業所) in freee.
+type Company struct {
+	ID          int64        `json:"id"`
+	Name        string       `json:"name"`
+	DisplayName string       `json:"display_name"`
+	FiscalYears []FiscalYear `json:"fiscal_years"`
+}
+
+// FiscalYear represents a fiscal year (会計期間) of a company.
+type FiscalYear struct {
+	StartDate string `json:"start_date"` // YYYY-MM-DD
+	EndDate   string `json:"end_date"`   // YYYY-MM-DD
+}
+
+// CompanyResponse represents the response from /api/1/companies/{id} endpoint.
+type CompanyResponse struct {
+	Company Company `json:"company"`
+}
+
+// GetCompany retrieves the company with its fiscal years.
+func (c *Client) GetCompany() (*Company, error) {
+	var resp CompanyResponse
+	path := fmt.Sprintf("/api/1/companies/%d", c.companyID)
+	if err := c.getJSON(path, nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get company: %w", err)
+	}
+	return &resp.Company, nil
+}
+
+// FiscalYearOf returns the fiscal year containing date (YYYY-MM-DD).
+func (c *Company) FiscalYearOf(date string) (FiscalYear, bool) {
+	for _, fy := range c.FiscalYears {
+		if fy.StartDate <= date && date <= fy.EndDate {
+			return fy, true
+		}
+	}
+	return FiscalYear{}, false
+}
@@ -0,0 +1,68 @@
+package freee
+
+import "fmt"
+
+// TrialBalance represents a trial balance report (試算表) in freee.
+type TrialBalance struct {
+	CompanyID int64              `json:"company_id"`
+	StartDate string             `json:"start_date"` // YYYY-MM-DD
+	EndDate   string             `json:"end_date"`   // YYYY-MM-DD
+	Balances  []TrialBalanceLine `json:"balances"`
+}
+
+// TrialBalanceLine represents a line of a trial balance report.
+// Category subtotal lines have no account item.
+type TrialBalanceLine struct {
+	AccountItemID       int64  `json:"account_item_id,omitempty"`
+	AccountItemName     string `json:"account_item_name,omitempty"`
+	AccountCategoryName string `json:"account_category_name,omitempty"`
+	HierarchyLevel      int    `json:"hierarchy_level"`
+	TotalLine           bool   `json:"total_line,omitempty"`
+	OpeningBalance      int64  `json:"opening_balance"`
+	DebitAmount         int64  `json:"debit_amount"`
+	CreditAmount        int64  `json:"credit_amount"`
+	ClosingBalance      int64  `json:"closing_balance"`
+}
+
+// IsAccountItem reports whether the line is an account item rather than
+// a category subtotal.
+func (l TrialBalanceLine) IsAccountItem() bool {
+	return l.AccountItemID != 0 && !l.TotalLine
+}
+
+// TrialBSResponse represents the response from /api/1/reports/trial_bs endpoint.
+type TrialBSResponse struct {
+	TrialBS TrialBalance `json:"trial_bs"`
+}
+
+// TrialPLResponse represents the response from /api/1/reports/trial_pl endpoint.
+type TrialPLResponse struct {
+	TrialPL TrialBalance `json:"trial_pl"`
+}
+
+// GetTrialBS retrieves the trial balance sheet (貸借対照表) for a date range.
+// Balances are positive on the normal side of each account.
+func (c *Client) GetTrialBS(startDate, endDate string) (*TrialBalance, error) {
+	var resp TrialBSResponse
+	if err := c.getJSON("/api/1/reports/trial_bs", reportParams(startDate, endDate), &resp); err != nil {
+		return nil, fmt.Errorf("failed to get trial balance sheet: %w", err)
+	}
+	return &resp.TrialBS, nil
+}
+
+// GetTrialPL retrieves the trial profit and loss statement (損益計算書)
+// for a date range. Balances are positive on the normal side of each account.
+func (c *Client) GetTrialPL(startDate, endDate string) (*TrialBalance, error) {
+	var resp TrialPLResponse
+	if err := c.getJSON("/api/1/reports/trial_pl", reportParams(startDate, endDate), &resp); err != nil {
+		return nil, fmt.Errorf("failed to get trial profit and loss: %w", err)
+	}
+	return &resp.TrialPL, nil
+}
+
+func reportParams(startDate, endDate string) map[string]string {
+	return map[string]string{
+		"start_date": startDate,
+		"end_date":   endDate,
+	}
+}
@@ -17,6 +17,7 @@ type Deal struct {
 	RefNumber   *string   `json:"ref_number,omitempty"`
 	PartnerID   *int64    `json:"partner_id,omitempty"`
 	PartnerCode *string   `json:"partner_code,omitempty"`
+	Receipts    []Receipt `json:"receipts,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
@@ -53,25 +54,39 @@ type Payment struct {
 	FromWalletableID   int64  `json:"from_walletable_id"`
 }
 
-// Journal represents a journal entry in freee accounting API.
-type Journal struct {
-	ID        int64           `json:"id"`
-	CompanyID int64           `json:"company_id"`
-	IssueDate string          `json:"issue_date"` // YYYY-MM-DD
-	Details   []JournalDetail `json:"details"`
-	CreatedAt time.Time       `json:"created_at"`
-	UpdatedAt time.Time       `json:"updated_at"`
+// Receipt represents a receipt (証憑ファイル) attached to a deal.
+type Receipt struct {
+	ID          int64  `json:"id"`
+	Status      string `json:"status"`
+	Description string `json:"description"`
+	MimeType    string `json:"mime_type"`
+	IssueDate   string `json:"issue_date"` // YYYY-MM-DD
 }
 
-// JournalDetail represents a detail line in a journal entry.
-type JournalDetail struct {
+// ManualJournal represents a manual journal entry (振替伝票) in freee
+// accounting API.
+type ManualJournal struct {
+	ID         int64                 `json:"id"`
+	CompanyID  int64                 `json:"company_id"`
+	IssueDate  string                `json:"issue_date"` // YYYY-MM-DD
+	Adjustment bool                  `json:"adjustment"` // 決算整理仕訳
+	TxnNumber  *string               `json:"txn_number,omitempty"`
+	Details    []ManualJournalDetail `json:"details"`
+	CreatedAt  time.Time             `json:"created_at"`
+	UpdatedAt  time.Time             `json:"updated_at"`
+}
+
+// ManualJournalDetail represents a detail line in a manual journal entry.
+// Amount includes Vat.
+type ManualJournalDetail struct {
 	ID              int64   `json:"id"`
+	EntrySide       string  `json:"entry_side"` // debit or credit
 	AccountItemID   int64   `json:"account_item_id"`
 	AccountItemName string  `json:"account_item_name"`
 	TaxCode         int     `json:"tax_code"`
+	PartnerID       *int64  `json:"partner_id,omitempty"`
 	Amount          int64   `json:"amount"`
 	Vat             int64   `json:"vat"`
-	EntryType       string  `json:"entry_type"` // debit or credit
 	Description     *string `json:"description,omitempty"`
 }
 
@@ -80,9 +95,19 @@ type DealsResponse struct {
 	Deals []Deal `json:"deals"`
 }
 
-// JournalsResponse represents the response from /api/1/journals endpoint.
-type JournalsResponse struct {
-	Journals []Journal `json:"journals"`
+// DealResponse represents the response from /api/1/deals/{id} endpoint.
+type DealResponse struct {
+	Deal Deal `json:"deal"`
+}
+
+// ManualJournalResponse represents the response from /api/1/manual_journals/{id} endpoint.
+type ManualJournalResponse struct {
+	ManualJournal ManualJournal `json:"manual_journal"`
+}
+
+// ManualJournalsResponse represents the response from /api/1/manual_journals endpoint.
+type ManualJournalsResponse struct {
+	ManualJournals []ManualJournal `json:"manual_journals"`
 }
 
 // TokenResponse represents OAuth2 token response.
@@ -79,6 +79,12 @@ func (p *PathResolver) GetAttachmentsDir() string {
 	return p.attachmentsDir
 }
 
+// GetMainFilePath returns the path of the main ledger file.
+// Example: ~/accounting/beancount/main.beancount
+func (p *PathResolver) GetMainFilePath() string {
+	return filepath.Join(p.beancountRoot, "main.beancount")
+}
+
 // GetYearDir returns the directory path for a year.
 // Example: ~/accounting/beancount/2024
 func (p *PathResolver) GetYearDir(year string) string {
@@ -0,0 +1,234 @@
+// Package reconcile compares the Beancount ledger with freee's trial balance.
+package reconcile
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+)
+
+// Figures holds an amount per Beancount account. Amounts are positive on
+// the normal side of the account, as in freee's reports: debit for Assets
+// and Expenses, credit for Liabilities, Equity and Income.
+type Figures map[string]float64
+
+// IsBalanceSheet reports whether an account belongs to the balance sheet.
+func IsBalanceSheet(account string) bool {
+	root, _, _ := strings.Cut(account, ":")
+	return root == "Assets" || root == "Liabilities" || root == "Equity"
+}
+
+// normalSign returns the sign that turns a Beancount amount (debit
+// positive) into an amount on the normal side of the account.
+func normalSign(account string) float64 {
+	root, _, _ := strings.Cut(account, ":")
+	if root == "Assets" || root == "Expenses" {
+		return 1
+	}
+	return -1
+}
+
+// ParsePeriod parses a period given as YYYY, YYYY-MM or
+// YYYY-MM-DD..YYYY-MM-DD and returns its first and last day.
+func ParsePeriod(period string) (string, string, error) {
+	if from, to, ok := strings.Cut(period, ".."); ok {
+		for _, date := range []string{from, to} {
+			if _, err := time.Parse("2006-01-02", date); err != nil {
+				return "", "", fmt.Errorf("invalid period %q: %q is not a YYYY-MM-DD date", period, date)
+			}
+		}
+		if from > to {
+			return "", "", fmt.Errorf("invalid period %q: start is after end", period)
+		}
+		return from, to, nil
+	}
+
+	if first, err := time.Parse("2006-01", period); err == nil {
+		return first.Format("2006-01-02"), first.AddDate(0, 1, -1).Format("2006-01-02"), nil
+	}
+	if first, err := time.Parse("2006", period); err == nil {
+		return first.Format("2006-01-02"), first.AddDate(1, 0, -1).Format("2006-01-02"), nil
+	}
+
+	return "", "", fmt.Errorf("invalid period %q: expected YYYY, YYYY-MM or YYYY-MM-DD..YYYY-MM-DD", period)
+}
+
+// FreeeFigures maps the account item lines of a trial balance to Beancount
+// accounts with accountFor and sums their closing balances. It also returns
+// the freee account item names behind each account.
+func FreeeFigures(trial *freee.TrialBalance, accountFor func(name string) string) (Figures, map[string][]string) {
+	figures := make(Figures)
+	items := make(map[string][]string)
+	for _, line := range trial.Balances {
+		if !line.IsAccountItem() {
+			continue
+		}
+		account := accountFor(line.AccountItemName)
+		figures[account] += float64(line.ClosingBalance)
+		items[account] = append(items[account], line.AccountItemName)
+	}
+	return figures, items
+}
+
+// LedgerFigures computes the figures freee reports for a period from
+// ledger transactions: balance sheet accounts as of the end date, income
+// and expense accounts over the period. Only postings in currency count.
+func LedgerFigures(txns []beancount.Transaction, from, to, currency string) Figures {
+	figures := make(Figures)
+	for _, txn := range txns {
+		if txn.Date > to {
+			continue
+		}
+		for account, amount := range txn.Amounts(currency) {
+			// Closing entries zero the income statement, which freee
+			// reports before closing
+			if !IsBalanceSheet(account) && (txn.Date < from || txn.IsClosing()) {
+				continue
+			}
+			figures[account] += amount * normalSign(account)
+		}
+	}
+	return figures
+}
+
+// Difference is an account whose figure differs between freee and the ledger.
+type Difference struct {
+	Account      string        `json:"account"`
+	FreeeItems   []string      `json:"freee_items,omitempty"` // freee account items mapped to the account
+	Freee        float64       `json:"freee"`
+	Ledger       float64       `json:"ledger"`
+	Explanations []Explanation `json:"explanations,omitempty"`
+}
+
+// Diff returns the ledger figure minus the freee figure.
+func (d Difference) Diff() float64 {
+	return d.Ledger - d.Freee
+}
+
+// Unexplained returns the part of the difference not covered by the
+// explanations, e.g. from entries before the period or from conversion.
+func (d Difference) Unexplained() float64 {
+	rest := d.Diff()
+	for _, e := range d.Explanations {
+		rest -= e.Ledger - e.Freee
+	}
+	return rest
+}
+
+// Compare returns the accounts whose figures differ by more than
+// tolerance, sorted by account.
+func Compare(freeeFigures, ledgerFigures Figures, items map[string][]string, tolerance float64) []Difference {
+	accounts := make(map[string]bool)
+	for account := range freeeFigures {
+		accounts[account] = true
+	}
+	for account := range ledgerFigures {
+		accounts[account] = true
+	}
+
+	var diffs []Difference
+	for account := range accounts {
+		d := Difference{
+			Account:    account,
+			FreeeItems: items[account],
+			Freee:      freeeFigures[account],
+			Ledger:     ledgerFigures[account],
+		}
+		if math.Abs(d.Diff()) > tolerance {
+			diffs = append(diffs, d)
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Account < diffs[j].Account })
+	return diffs
+}
+
+// Explanation is an entry that contributes a different amount to an
+// account in freee than in the ledger.
+type Explanation struct {
+	Key    string  `json:"key"` // freee key ("deal:123") or ledger location of a hand-written entry
+	Label  string  `json:"label,omitempty"`
+	Freee  float64 `json:"freee"`
+	Ledger float64 `json:"ledger"`
+}
+
+// Contributions records the amount each entry contributes to each account.
+type Contributions struct {
+	amounts map[string]map[string]float64 // account -> key -> amount
+	labels  map[string]string
+}
+
+// NewContributions creates empty Contributions.
+func NewContributions() *Contributions {
+	return &Contributions{
+		amounts: make(map[string]map[string]float64),
+		labels:  make(map[string]string),
+	}
+}
+
+// Add records a Beancount amount (debit positive) of an entry.
+func (c *Contributions) Add(key, label, account string, amount float64) {
+	if c.amounts[account] == nil {
+		c.amounts[account] = make(map[string]float64)
+	}
+	c.amounts[account][key] += amount * normalSign(account)
+	if label != "" {
+		c.labels[key] = label
+	}
+}
+
+// AddTransaction records the postings of a ledger transaction in currency.
+// Transactions without freee_id metadata are keyed by their location.
+func (c *Contributions) AddTransaction(txn beancount.Transaction, currency string) {
+	key := txn.FreeeKey()
+	if key == "" {
+		key = fmt.Sprintf("%s:%d", txn.Source.File, txn.Source.Line)
+	}
+	label := strings.TrimSpace(txn.Date + " " + txn.Narration)
+	for account, amount := range txn.Amounts(currency) {
+		if !IsBalanceSheet(account) && txn.IsClosing() {
+			continue
+		}
+		c.Add(key, label, account, amount)
+	}
+}
+
+// Explain attaches to each difference the entries whose contribution to
+// the account differs between freee (expected) and the ledger (actual).
+func Explain(diffs []Difference, expected, actual *Contributions, tolerance float64) {
+	for i := range diffs {
+		account := diffs[i].Account
+		keys := make(map[string]bool)
+		for key := range expected.amounts[account] {
+			keys[key] = true
+		}
+		for key := range actual.amounts[account] {
+			keys[key] = true
+		}
+
+		var explanations []Explanation
+		for key := range keys {
+			e := Explanation{
+				Key:    key,
+				Freee:  expected.amounts[account][key],
+				Ledger: actual.amounts[account][key],
+			}
+			if math.Abs(e.Ledger-e.Freee) <= tolerance {
+				continue
+			}
+			e.Label = expected.labels[key]
+			if e.Label == "" {
+				e.Label = actual.labels[key]
+			}
+			explanations = append(explanations, e)
+		}
+
+		sort.Slice(explanations, func(a, b int) bool { return explanations[a].Key < explanations[b].Key })
+		diffs[i].Explanations = explanations
+	}
+}
@@ -0,0 +1,138 @@
+package reconcile
+
+import (
+	"testing"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/beancount"
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/freee"
+)
+
+func TestParsePeriod(t *testing.T) {
+	tests := []struct {
+		period   string
+		wantFrom string
+		wantTo   string
+		wantErr  bool
+	}{
+		{"2024", "2024-01-01", "2024-12-31", false},
+		{"2024-02", "2024-02-01", "2024-02-29", false},
+		{"2024-04-01..2025-03-31", "2024-04-01", "2025-03-31", false},
+		{"2025-03-31..2024-04-01", "", "", true},
+		{"2024-13", "", "", true},
+		{"last month", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.period, func(t *testing.T) {
+			from, to, err := ParsePeriod(tt.period)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePeriod(%q) error = %v, wantErr %v", tt.period, err, tt.wantErr)
+			}
+			if from != tt.wantFrom || to != tt.wantTo {
+				t.Errorf("ParsePeriod(%q) = %s, %s, want %s, %s", tt.period, from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+func parseLedger(t *testing.T, content string) *beancount.Ledger {
+	t.Helper()
+	ledger, err := beancount.ParseString("test.beancount", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ledger.Errors) > 0 {
+		t.Fatal(ledger.Errors[0])
+	}
+	return ledger
+}
+
+const testLedger = `
+2024-01-31 * "Opening"
+  Assets:Bank       100000 JPY
+  Equity:Opening
+
+2024-02-10 * "Supplies"
+  freee_id: "1"
+  freee_type: "deal"
+  Expenses:Supplies   1100 JPY
+  Assets:Bank        -1100 JPY
+
+2024-02-20 * "Sales"
+  freee_id: "2"
+  freee_type: "deal"
+  Assets:Bank          5000 JPY
+  Income:Sales        -5000 JPY
+
+2024-02-29 * "Closing"
+  closing_year: "2024"
+  Income:Sales         5000 JPY
+  Equity:Opening
+
+2024-03-01 * "Next month"
+  Expenses:Supplies    500 JPY
+  Assets:Bank
+`
+
+func TestLedgerFigures(t *testing.T) {
+	ledger := parseLedger(t, testLedger)
+	figures := LedgerFigures(ledger.Transactions, "2024-02-01", "2024-02-29", "JPY")
+
+	want := Figures{
+		"Assets:Bank":       103900, // Balance sheet accounts include earlier entries
+		"Equity:Opening":    105000, // Closing entries only count on the balance sheet
+		"Expenses:Supplies": 1100,
+		"Income:Sales":      5000,
+	}
+	for account, amount := range want {
+		if figures[account] != amount {
+			t.Errorf("figures[%s] = %v, want %v", account, figures[account], amount)
+		}
+	}
+	if len(figures) != len(want) {
+		t.Errorf("figures = %v", figures)
+	}
+}
+
+func TestCompareAndExplain(t *testing.T) {
+	trial := &freee.TrialBalance{Balances: []freee.TrialBalanceLine{
+		{AccountItemID: 1, AccountItemName: "消耗品費", ClosingBalance: 1650},
+		{AccountCategoryName: "経費", TotalLine: true, ClosingBalance: 1650},
+		{AccountItemID: 2, AccountItemName: "売上高", ClosingBalance: 5000},
+	}}
+	accounts := map[string]string{"消耗品費": "Expenses:Supplies", "売上高": "Income:Sales"}
+	freeeFigures, items := FreeeFigures(trial, func(name string) string { return accounts[name] })
+
+	ledger := parseLedger(t, testLedger)
+	ledgerFigures := LedgerFigures(ledger.Transactions, "2024-02-01", "2024-02-29", "JPY")
+	ledgerFigures = Figures{"Expenses:Supplies": ledgerFigures["Expenses:Supplies"], "Income:Sales": ledgerFigures["Income:Sales"]}
+
+	diffs := Compare(freeeFigures, ledgerFigures, items, 0.5)
+	if len(diffs) != 1 || diffs[0].Account != "Expenses:Supplies" {
+		t.Fatalf("Compare() = %+v", diffs)
+	}
+	if diffs[0].Diff() != -550 {
+		t.Errorf("Diff() = %v, want -550", diffs[0].Diff())
+	}
+
+	// freee has deal 1 at 1100 and a deal 3 missing from the ledger
+	expected := NewContributions()
+	expected.Add("deal:1", "2024-02-10 Supplies", "Expenses:Supplies", 1100)
+	expected.Add("deal:3", "2024-02-25 Paper", "Expenses:Supplies", 550)
+
+	actual := NewContributions()
+	for _, txn := range ledger.Transactions {
+		if txn.Date >= "2024-02-01" && txn.Date <= "2024-02-29" {
+			actual.AddTransaction(txn, "JPY")
+		}
+	}
+
+	Explain(diffs, expected, actual, 0.5)
+	explanations := diffs[0].Explanations
+	if len(explanations) != 1 {
+		t.Fatalf("Explanations = %+v", explanations)
+	}
+	if e := explanations[0]; e.Key != "deal:3" || e.Freee != 550 || e.Ledger != 0 {
+		t.Errorf("Explanation = %+v", e)
+	}
+}
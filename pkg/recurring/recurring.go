@@ -0,0 +1,253 @@
+// Package recurring learns recurring transactions (subscriptions, rent,
+// utilities) from past deals and reports the occurrences of a month that
+// are missing, late or changed.
+package recurring
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/db"
+)
+
+// Options configures learning and checking.
+type Options struct {
+	// Lookback is the number of months before the checked month patterns
+	// are learned from.
+	Lookback int
+	// MinMonths is the number of those months a deal must occur in to be
+	// recurring. One of them must be among the last two, so that ended
+	// subscriptions are not expected.
+	MinMonths int
+	// GraceDays is how many days after its usual day an occurrence is late.
+	GraceDays int
+	// AmountTolerance is the fraction by which an amount may leave the
+	// range seen before without being reported as changed.
+	AmountTolerance float64
+}
+
+// Pattern is a deal that recurs monthly.
+type Pattern struct {
+	Key         string `json:"key"`
+	Type        string `json:"type"`
+	Partner     string `json:"partner,omitempty"`
+	AccountItem string `json:"account_item"`
+	Description string `json:"description,omitempty"`
+	Months      int    `json:"months"` // months it occurred in
+	Day         int    `json:"day"`    // usual day of the month
+	MinAmount   int64  `json:"min_amount"`
+	MaxAmount   int64  `json:"max_amount"`
+	LastDate    string `json:"last_date"`
+	LastAmount  int64  `json:"last_amount"`
+}
+
+// Label names the pattern for people.
+func (p Pattern) Label() string {
+	parts := []string{p.AccountItem}
+	if p.Partner != "" {
+		parts = append([]string{p.Partner}, parts...)
+	}
+	if p.Description != "" {
+		parts = append(parts, p.Description)
+	}
+	return strings.Join(parts, " / ")
+}
+
+// Status is the outcome of a pattern in the checked month.
+type Status string
+
+const (
+	StatusOK      Status = "ok"      // occurred as usual
+	StatusDue     Status = "due"     // not yet occurred, but not late yet
+	StatusMissing Status = "missing" // not occurred and past its day
+	StatusLate    Status = "late"    // occurred after its day
+	StatusChanged Status = "changed" // occurred with an unusual amount
+)
+
+// IsProblem reports whether a status needs attention.
+func (s Status) IsProblem() bool {
+	return s == StatusMissing || s == StatusLate || s == StatusChanged
+}
+
+// Occurrence is the outcome of a pattern in the checked month.
+type Occurrence struct {
+	Pattern  Pattern `json:"pattern"`
+	Status   Status  `json:"status"`
+	Expected string  `json:"expected"`          // usual date in the month
+	DealID   int64   `json:"deal_id,omitempty"` // deal that occurred
+	Date     string  `json:"date,omitempty"`
+	Amount   int64   `json:"amount,omitempty"`
+	Note     string  `json:"note,omitempty"`
+}
+
+// key groups the deals of a pattern: by partner and account item, or by
+// account item and description for deals without a partner.
+func key(d db.DealDetail) string {
+	switch {
+	case d.PartnerCode != "":
+		return strings.Join([]string{d.Type, "partner:" + d.PartnerCode, d.AccountItem}, "|")
+	case d.PartnerID != 0:
+		return strings.Join([]string{d.Type, fmt.Sprintf("partner_id:%d", d.PartnerID), d.AccountItem}, "|")
+	default:
+		return strings.Join([]string{d.Type, d.AccountItem, normalize(d.Description)}, "|")
+	}
+}
+
+// normalize drops the parts of a description that change every month,
+// such as digits ("2024年3月分", invoice numbers), and folds case.
+func normalize(description string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(description) {
+		if (r >= '0' && r <= '9') || (r >= '０' && r <= '９') {
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// Learn returns the patterns of the deals in the Lookback months before
+// month (YYYY-MM). Deals of other dates are ignored.
+func Learn(deals []db.DealDetail, month string, opts Options) ([]Pattern, error) {
+	start, err := time.Parse("2006-01", month)
+	if err != nil {
+		return nil, fmt.Errorf("invalid month %q: %w", month, err)
+	}
+	first := start.AddDate(0, -opts.Lookback, 0).Format("2006-01")
+	recent := start.AddDate(0, -2, 0).Format("2006-01")
+
+	groups := make(map[string][]db.DealDetail)
+	var keys []string
+	for _, d := range deals {
+		if m := d.IssueDate[:7]; m < first || m >= month {
+			continue
+		}
+		k := key(d)
+		if groups[k] == nil {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], d)
+	}
+
+	var patterns []Pattern
+	for _, k := range keys {
+		group := groups[k]
+		sort.SliceStable(group, func(i, j int) bool { return group[i].IssueDate < group[j].IssueDate })
+
+		months := make(map[string]bool)
+		var days []int
+		for _, d := range group {
+			months[d.IssueDate[:7]] = true
+			var day int
+			fmt.Sscanf(d.IssueDate[8:], "%d", &day)
+			days = append(days, day)
+		}
+		last := group[len(group)-1]
+
+		// Monthly: in enough months, about once a month, and still going
+		if len(months) < opts.MinMonths || len(group) > len(months)+1 || last.IssueDate[:7] < recent {
+			continue
+		}
+
+		p := Pattern{
+			Key:         k,
+			Type:        last.Type,
+			Partner:     last.PartnerCode,
+			AccountItem: last.AccountItem,
+			Description: last.Description,
+			Months:      len(months),
+			Day:         medianDay(days),
+			MinAmount:   group[0].Amount,
+			MaxAmount:   group[0].Amount,
+			LastDate:    last.IssueDate,
+			LastAmount:  last.Amount,
+		}
+		for _, d := range group {
+			p.MinAmount = min(p.MinAmount, d.Amount)
+			p.MaxAmount = max(p.MaxAmount, d.Amount)
+		}
+		patterns = append(patterns, p)
+	}
+
+	sort.SliceStable(patterns, func(i, j int) bool {
+		if patterns[i].Day != patterns[j].Day {
+			return patterns[i].Day < patterns[j].Day
+		}
+		return patterns[i].Key < patterns[j].Key
+	})
+	return patterns, nil
+}
+
+// Check returns the outcome of each pattern in month (YYYY-MM) as of
+// asOf (YYYY-MM-DD): a pattern that has not occurred is missing only once
+// its grace period has passed.
+func Check(patterns []Pattern, deals []db.DealDetail, month, asOf string, opts Options) ([]Occurrence, error) {
+	start, err := time.Parse("2006-01", month)
+	if err != nil {
+		return nil, fmt.Errorf("invalid month %q: %w", month, err)
+	}
+	lastDay := start.AddDate(0, 1, -1).Day()
+
+	byKey := make(map[string][]db.DealDetail)
+	for _, d := range deals {
+		if d.IssueDate[:7] == month {
+			byKey[key(d)] = append(byKey[key(d)], d)
+		}
+	}
+
+	var occurrences []Occurrence
+	for _, p := range patterns {
+		expected := start.AddDate(0, 0, min(p.Day, lastDay)-1)
+		o := Occurrence{Pattern: p, Status: StatusOK, Expected: expected.Format("2006-01-02")}
+		deadline := expected.AddDate(0, 0, opts.GraceDays).Format("2006-01-02")
+
+		matches := byKey[p.Key]
+		if len(matches) == 0 {
+			o.Status = StatusDue
+			if asOf > deadline {
+				o.Status = StatusMissing
+				o.Note = fmt.Sprintf("expected around %s (last %s, %d)", o.Expected, p.LastDate, p.LastAmount)
+			}
+			occurrences = append(occurrences, o)
+			continue
+		}
+
+		d := matches[0]
+		o.DealID, o.Date, o.Amount = d.DealID, d.IssueDate, d.Amount
+
+		var notes []string
+		low := float64(p.MinAmount) * (1 - opts.AmountTolerance)
+		high := float64(p.MaxAmount) * (1 + opts.AmountTolerance)
+		if amount := float64(d.Amount); amount < math.Floor(low) || amount > math.Ceil(high) {
+			o.Status = StatusChanged
+			if p.MinAmount == p.MaxAmount {
+				notes = append(notes, fmt.Sprintf("amount %d, usually %d", d.Amount, p.MinAmount))
+			} else {
+				notes = append(notes, fmt.Sprintf("amount %d, usually %d..%d", d.Amount, p.MinAmount, p.MaxAmount))
+			}
+		}
+		if d.IssueDate > deadline {
+			if o.Status == StatusOK {
+				o.Status = StatusLate
+			}
+			notes = append(notes, fmt.Sprintf("booked %s, usually around %s", d.IssueDate, o.Expected))
+		}
+		if len(matches) > 1 {
+			notes = append(notes, fmt.Sprintf("%d deals this month", len(matches)))
+		}
+		o.Note = strings.Join(notes, "; ")
+		occurrences = append(occurrences, o)
+	}
+
+	return occurrences, nil
+}
+
+// medianDay returns the median of days of the month.
+func medianDay(days []int) int {
+	sorted := append([]int(nil), days...)
+	sort.Ints(sorted)
+	return sorted[len(sorted)/2]
+}
@@ -0,0 +1,101 @@
+package recurring
+
+import (
+	"testing"
+
+	"github.com/shunichi-ikebuchi/accounting-system/pkg/db"
+)
+
+func deal(id int64, date, partner, item, description string, amount int64) db.DealDetail {
+	return db.DealDetail{DealID: id, IssueDate: date, Type: "expense", PartnerCode: partner, AccountItem: item, Description: description, Amount: amount}
+}
+
+func TestLearn(t *testing.T) {
+	deals := []db.DealDetail{
+		// Monthly subscription
+		deal(1, "2024-01-05", "aws", "通信費", "", 1000),
+		deal(2, "2024-02-04", "aws", "通信費", "", 1100),
+		deal(3, "2024-03-06", "aws", "通信費", "", 1050),
+		// Rent without a partner, the description changes every month
+		deal(4, "2024-01-25", "", "地代家賃", "家賃 1月分", 80000),
+		deal(5, "2024-02-26", "", "地代家賃", "家賃 2月分", 80000),
+		deal(6, "2024-03-25", "", "地代家賃", "家賃 3月分", 80000),
+		// Only twice
+		deal(7, "2024-02-10", "github", "通信費", "", 500),
+		deal(8, "2024-03-10", "github", "通信費", "", 500),
+		// Ended in January
+		deal(9, "2023-11-15", "old", "通信費", "", 300),
+		deal(10, "2023-12-15", "old", "通信費", "", 300),
+		deal(11, "2024-01-15", "old", "通信費", "", 300),
+		// In the checked month, not learned from
+		deal(12, "2024-04-05", "aws", "通信費", "", 1000),
+	}
+
+	patterns, err := Learn(deals, "2024-04", Options{Lookback: 6, MinMonths: 3})
+	if err != nil {
+		t.Fatalf("Learn() error = %v", err)
+	}
+	if len(patterns) != 2 {
+		t.Fatalf("Learn() = %+v, expected aws and rent", patterns)
+	}
+
+	aws, rent := patterns[0], patterns[1]
+	if aws.Partner != "aws" || aws.Day != 5 || aws.MinAmount != 1000 || aws.MaxAmount != 1100 || aws.Months != 3 {
+		t.Errorf("Learn() aws = %+v", aws)
+	}
+	if rent.AccountItem != "地代家賃" || rent.Day != 25 || rent.LastDate != "2024-03-25" {
+		t.Errorf("Learn() rent = %+v", rent)
+	}
+
+	if _, err := Learn(deals, "2024-4", Options{}); err == nil {
+		t.Error("Learn() accepted an invalid month")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	opts := Options{GraceDays: 5, AmountTolerance: 0.05}
+	pattern := Pattern{Key: key(deal(0, "", "aws", "通信費", "", 0)), Partner: "aws", AccountItem: "通信費", Day: 10, MinAmount: 1000, MaxAmount: 1100}
+
+	tests := []struct {
+		name  string
+		deals []db.DealDetail
+		asOf  string
+		want  Status
+	}{
+		{"ok", []db.DealDetail{deal(1, "2024-04-11", "aws", "通信費", "", 1080)}, "2024-04-30", StatusOK},
+		{"within tolerance", []db.DealDetail{deal(1, "2024-04-10", "aws", "通信費", "", 1150)}, "2024-04-30", StatusOK},
+		{"changed", []db.DealDetail{deal(1, "2024-04-10", "aws", "通信費", "", 1500)}, "2024-04-30", StatusChanged},
+		{"late", []db.DealDetail{deal(1, "2024-04-20", "aws", "通信費", "", 1000)}, "2024-04-30", StatusLate},
+		{"changed and late", []db.DealDetail{deal(1, "2024-04-20", "aws", "通信費", "", 500)}, "2024-04-30", StatusChanged},
+		{"missing", nil, "2024-04-16", StatusMissing},
+		{"due", nil, "2024-04-15", StatusDue},
+		{"other partner", []db.DealDetail{deal(1, "2024-04-10", "gcp", "通信費", "", 1000)}, "2024-05-01", StatusMissing},
+		{"other month", []db.DealDetail{deal(1, "2024-05-10", "aws", "通信費", "", 1000)}, "2024-04-30", StatusMissing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Check([]Pattern{pattern}, tt.deals, "2024-04", tt.asOf, opts)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if len(got) != 1 || got[0].Status != tt.want {
+				t.Errorf("Check() = %+v, expected %s", got, tt.want)
+			}
+			if got[0].Expected != "2024-04-10" {
+				t.Errorf("Check() expected = %s, want 2024-04-10", got[0].Expected)
+			}
+		})
+	}
+}
+
+func TestCheckShortMonth(t *testing.T) {
+	pattern := Pattern{Key: "k", Day: 31}
+	got, err := Check([]Pattern{pattern}, nil, "2024-02", "2024-02-10", Options{})
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if got[0].Expected != "2024-02-29" || got[0].Status != StatusDue {
+		t.Errorf("Check() = %+v, expected due on 2024-02-29", got[0])
+	}
+}